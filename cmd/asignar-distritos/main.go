@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"hospital-api/internal/config"
+	"hospital-api/internal/database"
+	"hospital-api/internal/services"
+)
+
+func main() {
+	// Configurar flags de línea de comandos
+	pendientes := flag.Bool("pendientes", false, "Procesar solo los historiales sin distrito asignado")
+	help := flag.Bool("help", false, "Mostrar ayuda")
+	flag.Parse()
+
+	if *help {
+		printHelp()
+		os.Exit(0)
+	}
+
+	log.Println("🗺️  Asignando distritos y barrios a los historiales...")
+
+	// Cargar configuración
+	_, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("❌ Error cargando configuración: %v", err)
+	}
+
+	// Conectar a la base de datos
+	database.ConnectDatabase()
+
+	resultado, err := services.NewDistritoService().AsignarDistritosHistoriales(*pendientes)
+	if err != nil {
+		log.Fatalf("❌ Error asignando distritos: %v", err)
+	}
+
+	log.Println("🎉 Asignación completada")
+	log.Printf("   📋 %d historiales revisados", resultado.Revisados)
+	log.Printf("   ✏️  %d historiales actualizados", resultado.Actualizados)
+	log.Printf("   ⚠️  %d historiales fuera de los límites importados", resultado.SinDistrito)
+}
+
+func printHelp() {
+	log.Println("🗺️  Asignación de distritos por punto en polígono")
+	log.Println("")
+	log.Println("Ubica las coordenadas de cada historial clínico en los límites oficiales importados")
+	log.Println("(POST /api/v1/distritos/limites/importar) y actualiza su distrito y barrio.")
+	log.Println("")
+	log.Println("Uso:")
+	log.Println("  go run cmd/asignar-distritos/main.go [flags]")
+	log.Println("")
+	log.Println("Flags:")
+	log.Println("  -pendientes  Procesar solo los historiales sin distrito asignado")
+	log.Println("  -help        Mostrar esta ayuda")
+}
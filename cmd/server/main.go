@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"hospital-api/internal/config"
 	"hospital-api/internal/database"
 	"hospital-api/internal/routes"
+	"hospital-api/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,6 +31,16 @@ func main() {
 	// Conectar a la base de datos
 	database.ConnectDatabase()
 
+	// Se cancela al recibir SIGINT o SIGTERM para detener el servidor y los procesos en segundo plano
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Motor de alertas de brote en segundo plano
+	services.NewAlertaService().IniciarMotor(ctx, cfg.Alertas.Intervalo)
+
+	// Envío de webhooks con reintentos en segundo plano
+	services.NewWebhookService().IniciarDespachador(ctx)
+
 	// Configurar rutas
 	router := routes.SetupRoutes()
 
@@ -42,10 +59,26 @@ func main() {
 	log.Printf("   📊 CRUD /api/v1/historial")
 	log.Printf("   🦠 GET  /api/v1/epidemiologia/stats")
 	log.Printf("   🗺️  GET  /api/v1/epidemiologia/contagious")
+	log.Printf("   🚨 GET  /api/v1/alertas")
+	log.Printf("   📡 GET  /api/v1/eventos/stream")
 	log.Printf("   ✅ GET  /api/v1/health")
 
 	// Iniciar servidor
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Error al iniciar servidor: %v", err)
+	srv := &http.Server{Addr: ":" + port, Handler: router}
+	// Shutdown no cancela los contextos de las peticiones: los streams SSE se cierran aparte
+	srv.RegisterOnShutdown(services.CerrarSuscripciones)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Error al iniciar servidor: %v", err)
+		}
+	}()
+
+	// Esperar la señal y dar tiempo a que terminen las peticiones en curso
+	<-ctx.Done()
+	log.Println("🛑 Deteniendo servidor...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error al detener servidor: %v", err)
 	}
 }
@@ -3,15 +3,25 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// AppConfig configuración cargada por LoadConfig
+var AppConfig *Config
+
 // Config estructura para la configuración de la aplicación
 type Config struct {
 	Database DatabaseConfig
 	Server   ServerConfig
 	JWT      JWTConfig
+	Email    EmailConfig
+	Login    LoginConfig
+	Alertas  AlertasConfig
+	Webhooks WebhooksConfig
 }
 
 // DatabaseConfig configuración de la base de datos
@@ -26,13 +36,51 @@ type DatabaseConfig struct {
 
 // ServerConfig configuración del servidor
 type ServerConfig struct {
-	Port    string
-	GinMode string
+	Port           string
+	GinMode        string
+	TrustedProxies []string // Proxies cuyo X-Forwarded-For se acepta para obtener la IP del cliente
 }
 
 // JWTConfig configuración JWT
 type JWTConfig struct {
-	Secret string
+	Secret          string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+// EmailConfig configuración del envío de correos
+type EmailConfig struct {
+	Driver       string // "smtp" o "log" (desarrollo local)
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
+	From         string
+	LogFile      string // Archivo donde el driver "log" escribe los correos; vacío usa el log estándar
+	AppURL       string // URL base del frontend para los enlaces de los correos
+}
+
+// LoginConfig umbrales de protección contra fuerza bruta en el login
+type LoginConfig struct {
+	MaxIntentosEmail int           // Fallos por email antes del bloqueo
+	MaxIntentosIP    int           // Fallos por IP antes del bloqueo
+	Ventana          time.Duration // Período en el que se acumulan los fallos
+	Bloqueo          time.Duration // Duración del bloqueo temporal
+	RetrasoBase      time.Duration // Retraso tras el primer fallo; se duplica con cada fallo
+	RetrasoMax       time.Duration // Retraso máximo por intento
+}
+
+// AlertasConfig configuración del motor de alertas de brote
+type AlertasConfig struct {
+	Intervalo time.Duration // Cada cuánto se evalúan las reglas; 0 desactiva la evaluación periódica
+}
+
+// WebhooksConfig reintentos de las entregas de webhooks
+type WebhooksConfig struct {
+	MaxIntentos int           // Intentos antes de marcar la entrega como fallida
+	BackoffBase time.Duration // Espera tras el primer fallo; se duplica con cada fallo
+	BackoffMax  time.Duration // Espera máxima entre intentos
+	Timeout     time.Duration // Tiempo máximo de cada POST
 }
 
 // LoadConfig carga la configuración desde variables de entorno
@@ -52,17 +100,56 @@ func LoadConfig() (*Config, error) {
 			SSLMode:  getEnv("DB_SSL_MODE", "disable"),
 		},
 		Server: ServerConfig{
-			Port:    getEnv("PORT", "8080"),
-			GinMode: getEnv("GIN_MODE", "debug"),
+			Port:           getEnv("PORT", "8080"),
+			GinMode:        getEnv("GIN_MODE", "debug"),
+			TrustedProxies: getEnvList("TRUSTED_PROXIES"),
 		},
 		JWT: JWTConfig{
-			Secret: getEnv("JWT_SECRET", "default-secret-change-in-production"),
+			Secret:          getEnv("JWT_SECRET", "default-secret-change-in-production"),
+			AccessTokenTTL:  getEnvDuration("JWT_ACCESS_TTL", 15*time.Minute),
+			RefreshTokenTTL: getEnvDuration("JWT_REFRESH_TTL", 7*24*time.Hour),
+		},
+		Email: EmailConfig{
+			Driver:       getEnv("EMAIL_DRIVER", "log"),
+			SMTPHost:     getEnv("SMTP_HOST", ""),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUser:     getEnv("SMTP_USER", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			From:         getEnv("EMAIL_FROM", "no-reply@hospital-api.local"),
+			LogFile:      getEnv("EMAIL_LOG_FILE", ""),
+			AppURL:       getEnv("APP_URL", "http://localhost:3000"),
+		},
+		Login: LoginConfig{
+			MaxIntentosEmail: getEnvInt("LOGIN_MAX_INTENTOS_EMAIL", 5),
+			MaxIntentosIP:    getEnvInt("LOGIN_MAX_INTENTOS_IP", 20),
+			Ventana:          getEnvDuration("LOGIN_VENTANA", 15*time.Minute),
+			Bloqueo:          getEnvDuration("LOGIN_BLOQUEO", 15*time.Minute),
+			RetrasoBase:      getEnvDuration("LOGIN_RETRASO_BASE", 500*time.Millisecond),
+			RetrasoMax:       getEnvDuration("LOGIN_RETRASO_MAX", 5*time.Second),
+		},
+		Alertas: AlertasConfig{
+			Intervalo: getEnvDuration("ALERTAS_INTERVALO", 15*time.Minute),
+		},
+		Webhooks: WebhooksConfig{
+			MaxIntentos: getEnvInt("WEBHOOK_MAX_INTENTOS", 8),
+			BackoffBase: getEnvDuration("WEBHOOK_BACKOFF_BASE", 30*time.Second),
+			BackoffMax:  getEnvDuration("WEBHOOK_BACKOFF_MAX", time.Hour),
+			Timeout:     getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
 		},
 	}
 
+	AppConfig = config
 	return config, nil
 }
 
+// GetConfig retorna la configuración cargada, cargándola si aún no se hizo
+func GetConfig() *Config {
+	if AppConfig == nil {
+		LoadConfig()
+	}
+	return AppConfig
+}
+
 // getEnv obtiene una variable de entorno o retorna un valor por defecto
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -70,3 +157,36 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvDuration obtiene una duración (ej. "15m", "24h") de una variable de entorno o retorna un valor por defecto
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+		log.Printf("Valor inválido para %s: %s, usando %s", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+// getEnvInt obtiene un entero de una variable de entorno o retorna un valor por defecto
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+		log.Printf("Valor inválido para %s: %s, usando %d", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+// getEnvList obtiene una lista separada por comas de una variable de entorno
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -68,16 +68,43 @@ func ConnectDatabase() {
 func AutoMigrate() error {
 	log.Println("Ejecutando migraciones automáticas...")
 
+	if err := verificarExtensiones(); err != nil {
+		return err
+	}
+
 	err := DB.AutoMigrate(
 		&models.Hospital{},
+		&models.Usuario{},
 		&models.Paciente{},
+		&models.Enfermedad{},
+		&models.EnfermedadSinonimo{},
 		&models.HistorialClinico{},
+		&models.RefreshToken{},
+		&models.TokenRevocado{},
+		&models.TokenVerificacion{},
+		&models.IntentoLogin{},
+		&models.AuditLog{},
+		&models.HistorialRevision{},
+		&models.NotificacionEpidemiologica{},
+		&models.ReglaAlerta{},
+		&models.AlertaBrote{},
+		&models.SuscripcionWebhook{},
+		&models.EntregaWebhook{},
+		&models.IntentoWebhook{},
+		&models.Distrito{},
+		&models.ConexionDistrito{},
+		&models.Barrio{},
 	)
 
 	if err != nil {
 		return fmt.Errorf("error en migración automática: %w", err)
 	}
 
+	// Objetos que GORM no gestiona (triggers, extensiones, índices especiales)
+	if err := runSQLMigrations(); err != nil {
+		return fmt.Errorf("error en migración SQL: %w", err)
+	}
+
 	log.Println("Migraciones completadas exitosamente")
 	return nil
 }
@@ -0,0 +1,675 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// sqlMigration sentencia SQL idempotente que se ejecuta después de AutoMigrate
+type sqlMigration struct {
+	nombre string
+	sql    string
+}
+
+// sqlMigrations se ejecutan en orden en cada arranque, por lo que deben poder repetirse sin efectos
+var sqlMigrations = []sqlMigration{
+	{
+		nombre: "audit_log de solo inserción",
+		sql: `
+			CREATE OR REPLACE FUNCTION audit_log_solo_insercion() RETURNS trigger AS $$
+			BEGIN
+				RAISE EXCEPTION 'audit_log es de solo inserción: no se permite %', TG_OP;
+			END;
+			$$ LANGUAGE plpgsql;
+
+			DROP TRIGGER IF EXISTS audit_log_sin_modificaciones ON audit_log;
+			CREATE TRIGGER audit_log_sin_modificaciones
+				BEFORE UPDATE OR DELETE ON audit_log
+				FOR EACH ROW EXECUTE FUNCTION audit_log_solo_insercion();
+
+			DROP TRIGGER IF EXISTS audit_log_sin_truncate ON audit_log;
+			CREATE TRIGGER audit_log_sin_truncate
+				BEFORE TRUNCATE ON audit_log
+				FOR EACH STATEMENT EXECUTE FUNCTION audit_log_solo_insercion();
+		`,
+	},
+	{
+		nombre: "historial_revisiones de solo inserción",
+		sql: `
+			CREATE OR REPLACE FUNCTION historial_revisiones_solo_insercion() RETURNS trigger AS $$
+			BEGIN
+				RAISE EXCEPTION 'historial_revisiones es de solo inserción: no se permite %', TG_OP;
+			END;
+			$$ LANGUAGE plpgsql;
+
+			DROP TRIGGER IF EXISTS historial_revisiones_sin_modificaciones ON historial_revisiones;
+			CREATE TRIGGER historial_revisiones_sin_modificaciones
+				BEFORE UPDATE OR DELETE ON historial_revisiones
+				FOR EACH ROW EXECUTE FUNCTION historial_revisiones_solo_insercion();
+
+			DROP TRIGGER IF EXISTS historial_revisiones_sin_truncate ON historial_revisiones;
+			CREATE TRIGGER historial_revisiones_sin_truncate
+				BEFORE TRUNCATE ON historial_revisiones
+				FOR EACH STATEMENT EXECUTE FUNCTION historial_revisiones_solo_insercion();
+		`,
+	},
+	{
+		nombre: "cuentas de usuario separadas de los hospitales",
+		sql: `
+			-- Bases anteriores a las cuentas de usuario: cada hospital pasa a tener un administrador con su
+			-- email y contraseña actuales, y las credenciales dejan de guardarse en hospitales
+			DO $$
+			BEGIN
+				IF EXISTS (
+					SELECT 1 FROM information_schema.columns
+					WHERE table_schema = current_schema() AND table_name = 'hospitales' AND column_name = 'password'
+				) THEN
+					EXECUTE $sql$
+						INSERT INTO usuarios (id_hospital, nombre, email, password, rol, activo, created_at, updated_at)
+						SELECT h.id, 'Administrador ' || h.nombre, h.email, h.password, 'admin', true, NOW(), NOW()
+						FROM hospitales h
+						WHERE h.password IS NOT NULL
+						  AND NOT EXISTS (SELECT 1 FROM usuarios u WHERE u.email = h.email)
+					$sql$;
+					ALTER TABLE hospitales DROP COLUMN password;
+				END IF;
+			END
+			$$;
+		`,
+	},
+	{
+		nombre: "hospitales anteriores a la verificación de email",
+		sql: `
+			-- Los hospitales registrados antes de la verificación (sin tokens de verificación emitidos) se
+			-- consideran verificados para que sus usuarios puedan seguir iniciando sesión. Todo registro
+			-- posterior emite un token en la misma transacción, por lo que no se ve afectado.
+			UPDATE hospitales h SET email_verificado = true
+			WHERE h.email_verificado = false
+			  AND NOT EXISTS (
+				SELECT 1 FROM tokens_verificacion t
+				JOIN usuarios u ON u.id = t.id_usuario
+				WHERE u.id_hospital = h.id AND t.tipo = 'verificacion_email'
+			  );
+		`,
+	},
+	{
+		nombre: "hospital de registro de pacientes anteriores",
+		sql: `
+			-- Los pacientes creados antes de registrar su hospital quedan asignados al hospital de su primer
+			-- historial; los que no tienen ninguno los reclama un administrador (POST /pacientes/:id/claim)
+			WITH asignados AS (
+				UPDATE pacientes p
+				SET id_hospital = primero.id_hospital, version = p.version + 1, updated_at = NOW()
+				FROM (
+					SELECT DISTINCT ON (hc.id_paciente) hc.id_paciente, hc.id_hospital
+					FROM historial_clinico hc
+					ORDER BY hc.id_paciente, hc.fecha_ingreso, hc.id
+				) primero
+				WHERE primero.id_paciente = p.id AND p.id_hospital IS NULL
+				RETURNING p.id, p.id_hospital, p.version
+			)
+			INSERT INTO audit_log (id_hospital, rol, accion, entidad, entidad_id, cambios, detalle, ip, created_at)
+			SELECT id_hospital, 'sistema', 'actualizar', 'paciente', id::text,
+				jsonb_build_object(
+					'id_hospital', jsonb_build_object('antes', NULL, 'despues', id_hospital),
+					'version', jsonb_build_object('antes', version - 1, 'despues', version)
+				),
+				jsonb_build_object('migracion', 'hospital de registro de pacientes anteriores'),
+				'', NOW()
+			FROM asignados;
+		`,
+	},
+	{
+		nombre: "búsqueda de pacientes sin acentos y por similitud",
+		sql: `
+			CREATE EXTENSION IF NOT EXISTS unaccent;
+			CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+			-- unaccent no es IMMUTABLE; el wrapper con diccionario explícito permite usarlo en índices
+			CREATE OR REPLACE FUNCTION f_unaccent(text) RETURNS text AS $$
+				SELECT public.unaccent('public.unaccent'::regdictionary, $1)
+			$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;
+
+			CREATE INDEX IF NOT EXISTS idx_pacientes_nombre_trgm
+				ON pacientes USING gin (f_unaccent(lower(nombre)) gin_trgm_ops);
+			CREATE INDEX IF NOT EXISTS idx_pacientes_ci_normalizado
+				ON pacientes ((regexp_replace(upper(ci), '[^0-9A-Z]', '', 'g')) text_pattern_ops);
+		`,
+	},
+	{
+		nombre: "índices de listados de historial clínico",
+		sql: `
+			-- Paginación por cursor: cada campo ordenable va acompañado de id como desempate
+			CREATE INDEX IF NOT EXISTS idx_historial_fecha_ingreso_id ON historial_clinico (fecha_ingreso, id);
+			CREATE INDEX IF NOT EXISTS idx_historial_consultation_date_id ON historial_clinico (consultation_date, id);
+			CREATE INDEX IF NOT EXISTS idx_historial_created_at_id ON historial_clinico (created_at, id);
+			CREATE INDEX IF NOT EXISTS idx_historial_enfermedad_id ON historial_clinico (enfermedad, id);
+			CREATE INDEX IF NOT EXISTS idx_historial_district_id ON historial_clinico (patient_district, id);
+
+			-- Filtros
+			CREATE INDEX IF NOT EXISTS idx_historial_enfermedad_lower ON historial_clinico (lower(enfermedad));
+			CREATE INDEX IF NOT EXISTS idx_historial_district_lower ON historial_clinico (lower(patient_district));
+			CREATE INDEX IF NOT EXISTS idx_historial_hospital_fecha ON historial_clinico (id_hospital, fecha_ingreso);
+			CREATE INDEX IF NOT EXISTS idx_historial_paciente_fecha ON historial_clinico (id_paciente, fecha_ingreso);
+			CREATE INDEX IF NOT EXISTS idx_historial_geolocation ON historial_clinico (patient_latitude, patient_longitude);
+		`,
+	},
+	{
+		nombre: "catálogo de enfermedades CIE-10",
+		sql: `
+			-- Normalización usada para comparar nombres, códigos y sinónimos (equivalente a utils.NormalizarTexto)
+			CREATE OR REPLACE FUNCTION f_normalizar(text) RETURNS text AS $$
+				SELECT btrim(regexp_replace(lower(f_unaccent($1)), '[^a-z0-9]+', ' ', 'g'))
+			$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;
+
+			INSERT INTO enfermedades (codigo_cie10, nombre, contagiosa, incubacion_min_dias, incubacion_max_dias, notificacion_obligatoria, created_at, updated_at)
+			VALUES
+				('A90', 'Dengue', true, 3, 14, true, NOW(), NOW()),
+				('A92.0', 'Chikungunya', true, 1, 12, true, NOW(), NOW()),
+				('A92.5', 'Zika', true, 3, 14, true, NOW(), NOW()),
+				('B05', 'Sarampión', true, 7, 21, true, NOW(), NOW()),
+				('B06', 'Rubéola', true, 12, 23, true, NOW(), NOW()),
+				('J11', 'Influenza', true, 1, 4, false, NOW(), NOW()),
+				('J09', 'Gripe AH1N1', true, 1, 7, true, NOW(), NOW()),
+				('U07.1', 'COVID-19', true, 2, 14, true, NOW(), NOW()),
+				('J40', 'Bronquitis', false, NULL, NULL, false, NOW(), NOW()),
+				('A15', 'Tuberculosis', true, 14, 365, true, NOW(), NOW()),
+				('B54', 'Malaria', true, 7, 30, true, NOW(), NOW()),
+				('B57', 'Enfermedad de Chagas', true, 5, 14, true, NOW(), NOW()),
+				('A95', 'Fiebre amarilla', true, 3, 6, true, NOW(), NOW()),
+				('A27', 'Leptospirosis', false, 2, 30, true, NOW(), NOW()),
+				('A82', 'Rabia', false, 14, 90, true, NOW(), NOW()),
+				('A37', 'Tos ferina', true, 5, 21, true, NOW(), NOW()),
+				('B01', 'Varicela', true, 10, 21, false, NOW(), NOW()),
+				('B26', 'Parotiditis', true, 12, 25, false, NOW(), NOW()),
+				('B15', 'Hepatitis A', true, 15, 50, true, NOW(), NOW()),
+				('A00', 'Cólera', true, 1, 5, true, NOW(), NOW()),
+				('A01.0', 'Fiebre tifoidea', true, 6, 30, true, NOW(), NOW()),
+				('A39.0', 'Meningitis meningocócica', true, 2, 10, true, NOW(), NOW()),
+				('A09', 'Diarrea aguda infecciosa', true, 1, 3, false, NOW(), NOW())
+			ON CONFLICT (codigo_cie10) DO NOTHING;
+
+			INSERT INTO enfermedad_sinonimos (id_enfermedad, sinonimo)
+			SELECT e.id, f_normalizar(s.sinonimo)
+			FROM (VALUES
+				('A90', 'dengue clasico'),
+				('A90', 'dengue grave'),
+				('A90', 'dengue con signos de alarma'),
+				('A90', 'dengue sin signos de alarma'),
+				('A90', 'fiebre dengue'),
+				('A90', 'fiebre del dengue'),
+				('A90', 'denv'),
+				('A92.0', 'chikungunya'),
+				('A92.0', 'chikv'),
+				('A92.0', 'chicungunya'),
+				('A92.0', 'fiebre chikungunya'),
+				('A92.5', 'zika virus'),
+				('A92.5', 'virus zika'),
+				('A92.5', 'enfermedad por virus zika'),
+				('A92.5', 'zikv'),
+				('B05', 'sarampion'),
+				('B05', 'measles'),
+				('B06', 'rubeola'),
+				('B06', 'rubella'),
+				('J11', 'gripe'),
+				('J11', 'gripe estacional'),
+				('J11', 'influenza estacional'),
+				('J11', 'flu'),
+				('J09', 'ah1n1'),
+				('J09', 'h1n1'),
+				('J09', 'influenza ah1n1'),
+				('J09', 'influenza a h1n1'),
+				('J09', 'gripe a h1n1'),
+				('J09', 'gripe porcina'),
+				('U07.1', 'covid'),
+				('U07.1', 'covid 19'),
+				('U07.1', 'sars cov 2'),
+				('U07.1', 'coronavirus'),
+				('J40', 'bronquitis aguda'),
+				('J40', 'bronquitis no especificada'),
+				('A15', 'tbc'),
+				('A15', 'tb'),
+				('A15', 'tuberculosis pulmonar'),
+				('B54', 'paludismo'),
+				('B57', 'chagas'),
+				('B57', 'mal de chagas'),
+				('B57', 'tripanosomiasis americana'),
+				('A95', 'yellow fever'),
+				('A82', 'rabia humana'),
+				('A37', 'tosferina'),
+				('A37', 'pertussis'),
+				('A37', 'coqueluche'),
+				('B01', 'viruela loca'),
+				('B01', 'chickenpox'),
+				('B26', 'paperas'),
+				('B15', 'hepatitis viral a'),
+				('A00', 'colera'),
+				('A01.0', 'tifoidea'),
+				('A39.0', 'meningococo'),
+				('A39.0', 'enfermedad meningococica'),
+				('A09', 'diarrea aguda'),
+				('A09', 'eda'),
+				('A09', 'enfermedad diarreica aguda'),
+				('A09', 'gastroenteritis infecciosa')
+			) AS s(codigo, sinonimo)
+			JOIN enfermedades e ON e.codigo_cie10 = s.codigo
+			ON CONFLICT (sinonimo) DO NOTHING;
+		`,
+	},
+	{
+		nombre: "versionado de historiales modificados por migraciones",
+		sql: `
+			-- Instantánea de un historial con los mismos campos que guarda la aplicación en historial_revisiones
+			CREATE OR REPLACE FUNCTION f_snapshot_historial(h historial_clinico) RETURNS jsonb AS $$
+				SELECT to_jsonb(h) - 'deleted_at' - 'ubicacion'
+			$$ LANGUAGE sql STABLE;
+
+			-- Campos que cambian entre dos instantáneas en el formato de audit_log.cambios
+			CREATE OR REPLACE FUNCTION f_cambios_jsonb(antes jsonb, despues jsonb) RETURNS jsonb AS $$
+				SELECT COALESCE(jsonb_object_agg(k, jsonb_build_object('antes', antes -> k, 'despues', despues -> k)), '{}'::jsonb)
+				FROM (SELECT jsonb_object_keys(antes || despues) AS k) claves
+				WHERE k NOT IN ('created_at', 'updated_at') AND (antes -> k) IS DISTINCT FROM (despues -> k)
+			$$ LANGUAGE sql IMMUTABLE;
+
+			-- Registra la versión que una migración dejó en un historial, igual que registrarRevisionActualizacion
+			-- (guardando antes su estado original si no tenía versiones), y su entrada de auditoría con rol sistema.
+			-- La migración debe haber incrementado version y actualizado updated_at.
+			CREATE OR REPLACE FUNCTION f_versionar_historial_migrado(antes historial_clinico, migracion text) RETURNS void AS $$
+			DECLARE
+				despues historial_clinico;
+			BEGIN
+				SELECT * INTO despues FROM historial_clinico WHERE id = antes.id;
+
+				IF NOT EXISTS (SELECT 1 FROM historial_revisiones WHERE id_historial = antes.id) THEN
+					INSERT INTO historial_revisiones (id_historial, version, datos, created_at)
+					VALUES (antes.id, 1, f_snapshot_historial(antes), NOW());
+				END IF;
+				INSERT INTO historial_revisiones (id_historial, version, datos, created_at)
+				SELECT antes.id, MAX(r.version) + 1, f_snapshot_historial(despues), NOW()
+				FROM historial_revisiones r WHERE r.id_historial = antes.id;
+
+				INSERT INTO audit_log (id_hospital, rol, accion, entidad, entidad_id, cambios, detalle, ip, created_at)
+				VALUES (despues.id_hospital, 'sistema', 'actualizar', 'historial_clinico', antes.id::text,
+					f_cambios_jsonb(f_snapshot_historial(antes), f_snapshot_historial(despues)),
+					jsonb_build_object('migracion', migracion), '', NOW());
+			END;
+			$$ LANGUAGE plpgsql;
+		`,
+	},
+	{
+		nombre: "enlace de historiales con el catálogo de enfermedades",
+		sql: `
+			-- Solo se completa id_enfermedad: el texto original del historial se conserva. Gana la coincidencia
+			-- exacta con el nombre, el código CIE-10 o un sinónimo; si no la hay, el término más largo con que
+			-- empieza el texto ("dengue hemorrágico", "influenza tipo b")
+			DO $$
+			DECLARE
+				enlace record;
+				antes historial_clinico;
+			BEGIN
+				FOR enlace IN
+					SELECT DISTINCT ON (h.id) h.id AS id_historial, c.id AS id_enfermedad
+					FROM historial_clinico h
+					JOIN (
+						SELECT id, f_normalizar(nombre) AS termino, true AS prefijo FROM enfermedades
+						UNION SELECT id, f_normalizar(codigo_cie10), false FROM enfermedades
+						UNION SELECT id_enfermedad, sinonimo, true FROM enfermedad_sinonimos
+					) c ON f_normalizar(h.enfermedad) = c.termino
+						OR (c.prefijo AND f_normalizar(h.enfermedad) LIKE c.termino || ' %')
+					WHERE h.id_enfermedad IS NULL
+					ORDER BY h.id, (f_normalizar(h.enfermedad) = c.termino) DESC, length(c.termino) DESC
+				LOOP
+					SELECT * INTO antes FROM historial_clinico WHERE id = enlace.id_historial FOR UPDATE;
+					UPDATE historial_clinico
+					SET id_enfermedad = enlace.id_enfermedad, version = version + 1, updated_at = NOW()
+					WHERE id = enlace.id_historial;
+					PERFORM f_versionar_historial_migrado(antes, 'enlace de historiales con el catálogo de enfermedades');
+				END LOOP;
+			END
+			$$;
+		`,
+	},
+	{
+		nombre: "vías de transmisión y datos epidemiológicos derivados del catálogo",
+		sql: `
+			UPDATE enfermedades e SET via_transmision = v.via
+			FROM (VALUES
+				('A90', 'vectorial'), ('A92.0', 'vectorial'), ('A92.5', 'vectorial'), ('B54', 'vectorial'),
+				('B57', 'vectorial'), ('A95', 'vectorial'),
+				('B05', 'aerea'), ('B06', 'aerea'), ('J11', 'aerea'), ('J09', 'aerea'), ('U07.1', 'aerea'),
+				('A15', 'aerea'), ('A37', 'aerea'), ('B01', 'aerea'), ('B26', 'aerea'), ('A39.0', 'aerea'),
+				('A27', 'contacto'), ('A82', 'contacto'),
+				('B15', 'alimentos_agua'), ('A00', 'alimentos_agua'), ('A01.0', 'alimentos_agua'), ('A09', 'alimentos_agua'),
+				('J40', 'no_transmisible')
+			) v(codigo, via)
+			WHERE e.codigo_cie10 = v.codigo AND e.via_transmision = '';
+
+			-- Historiales enlazados que aún no tomaron sus datos del catálogo; la contagiosidad
+			-- registrada a mano se reemplaza por la del catálogo
+			DO $$
+			DECLARE
+				antes historial_clinico;
+			BEGIN
+				FOR antes IN
+					SELECT h.* FROM historial_clinico h
+					JOIN enfermedades e ON e.id = h.id_enfermedad
+					WHERE h.via_transmision = '' AND e.via_transmision <> '' AND NOT h.contagioso_manual
+					ORDER BY h.id
+					FOR UPDATE OF h
+				LOOP
+					UPDATE historial_clinico h SET
+						is_contagious = e.contagiosa,
+						via_transmision = e.via_transmision,
+						notificacion_obligatoria = e.notificacion_obligatoria,
+						version = h.version + 1,
+						updated_at = NOW()
+					FROM enfermedades e
+					WHERE h.id = antes.id AND e.id = h.id_enfermedad;
+					PERFORM f_versionar_historial_migrado(antes, 'datos epidemiológicos derivados del catálogo');
+				END LOOP;
+			END
+			$$;
+		`,
+	},
+	{
+		nombre: "notificaciones pendientes de historiales anteriores",
+		sql: `
+			-- Los historiales de enfermedades de notificación obligatoria registrados antes de las notificaciones
+			-- (o que tomaron la obligación del catálogo al migrar) quedan con su notificación pendiente, igual
+			-- que registrarNotificacion; el índice único evita duplicar las que ya existen
+			WITH creadas AS (
+				INSERT INTO notificacion_epidemiologica (id_historial, id_enfermedad, id_hospital, estado, observaciones, created_at, updated_at)
+				SELECT h.id, h.id_enfermedad, h.id_hospital, 'pendiente', '', NOW(), NOW()
+				FROM historial_clinico h
+				WHERE h.notificacion_obligatoria AND h.id_enfermedad IS NOT NULL AND h.deleted_at IS NULL
+				ORDER BY h.id
+				ON CONFLICT (id_historial, id_enfermedad) DO NOTHING
+				RETURNING *
+			)
+			INSERT INTO audit_log (id_hospital, rol, accion, entidad, entidad_id, cambios, detalle, ip, created_at)
+			SELECT c.id_hospital, 'sistema', 'crear', 'notificacion_epidemiologica', c.id::text,
+				f_cambios_jsonb('{}'::jsonb, to_jsonb(c)),
+				jsonb_build_object('migracion', 'notificaciones pendientes de historiales anteriores'),
+				'', NOW()
+			FROM creadas c;
+		`,
+	},
+	{
+		nombre: "una sola alerta activa por regla y distrito",
+		sql: `
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_alertas_brote_activa
+				ON alertas_brote (id_regla, distrito) WHERE estado = 'activa';
+		`,
+	},
+	{
+		nombre: "intervalos seriales de referencia del catálogo",
+		sql: `
+			-- Valores publicados para las enfermedades con transmisión persona a persona o con un ciclo
+			-- vectorial bien caracterizado; el resto se configura desde la API
+			UPDATE enfermedades e SET
+				intervalo_serial_distribucion = v.distribucion,
+				intervalo_serial_media = v.media,
+				intervalo_serial_desviacion = v.desviacion
+			FROM (VALUES
+				('A90', 'gamma', 15.0, 5.0),
+				('A92.5', 'gamma', 20.0, 7.0),
+				('B05', 'gamma', 11.7, 2.0),
+				('B06', 'gamma', 18.3, 3.6),
+				('J11', 'gamma', 2.6, 1.5),
+				('J09', 'gamma', 2.6, 1.5),
+				('U07.1', 'lognormal', 4.7, 2.9),
+				('B01', 'gamma', 14.0, 2.9),
+				('B26', 'gamma', 18.0, 3.5)
+			) v(codigo, distribucion, media, desviacion)
+			WHERE e.codigo_cie10 = v.codigo AND e.intervalo_serial_media IS NULL;
+		`,
+	},
+	{
+		nombre: "periodos infecciosos de referencia del catálogo",
+		sql: `
+			-- Duración media de la transmisibilidad (o de la viremia, en las vectoriales) para el modelo SEIR;
+			-- el resto usa el periodo genérico hasta que se configure desde la API
+			UPDATE enfermedades e SET periodo_infeccioso_dias = v.dias
+			FROM (VALUES
+				('A90', 5.0),
+				('A92.0', 6.0),
+				('A92.5', 5.0),
+				('B05', 8.0),
+				('B06', 14.0),
+				('J11', 5.0),
+				('J09', 5.0),
+				('U07.1', 7.0),
+				('B01', 7.0),
+				('B26', 9.0),
+				('A37', 21.0)
+			) v(codigo, dias)
+			WHERE e.codigo_cie10 = v.codigo AND e.periodo_infeccioso_dias IS NULL;
+		`,
+	},
+	{
+		nombre: "distritos de Santa Cruz de la Sierra y sus conexiones",
+		sql: `
+			-- Una arista se guarda una sola vez, de menor a mayor ID
+			DO $$ BEGIN
+				ALTER TABLE conexiones_distrito ADD CONSTRAINT chk_conexion_orden CHECK (id_distrito_a < id_distrito_b);
+			EXCEPTION WHEN duplicate_object THEN NULL;
+			END $$;
+
+			-- Solo con la tabla vacía, para no recrear distritos eliminados desde la API
+			DO $$ BEGIN
+				IF NOT EXISTS (SELECT 1 FROM distritos) THEN
+					INSERT INTO distritos (nombre, habitantes, area_km2, tipo_zona, latitud, longitud, created_at, updated_at)
+					VALUES
+						('Equipetrol', 85000, 12.5, 'Residencial-Comercial', -17.7690416, -63.1956686, NOW(), NOW()),
+						('Norte', 320000, 45.8, 'Residencial-Popular', -17.7987909, -63.210345, NOW(), NOW()),
+						('Plan Tres Mil', 180000, 22.3, 'Popular-Alta Densidad', -17.798792, -63.210345, NOW(), NOW()),
+						('Villa 1ro de Mayo', 95000, 18.7, 'Residencial', -17.7379806, -63.2484834, NOW(), NOW()),
+						('Sur', 125000, 28.4, 'Residencial-Comercial', -17.7441931, -63.1801563, NOW(), NOW()),
+						('Oeste', 75000, 35.2, 'Residencial-Periférico', -17.7439533, -63.1756103, NOW(), NOW()),
+						('Este', 60000, 42.1, 'Periférico-Rural', -17.7728417, -63.2374135, NOW(), NOW()),
+						('Centro', 45000, 8.2, 'Comercial-Histórico', -17.7807346, -63.1890985, NOW(), NOW());
+
+					INSERT INTO conexiones_distrito (id_distrito_a, id_distrito_b, peso)
+					SELECT LEAST(a.id, b.id), GREATEST(a.id, b.id), 1
+					FROM (VALUES
+						('Equipetrol', 'Norte'), ('Equipetrol', 'Centro'), ('Equipetrol', 'Sur'),
+						('Norte', 'Plan Tres Mil'), ('Norte', 'Este'),
+						('Plan Tres Mil', 'Sur'), ('Plan Tres Mil', 'Este'),
+						('Villa 1ro de Mayo', 'Oeste'), ('Villa 1ro de Mayo', 'Centro'),
+						('Sur', 'Centro'), ('Oeste', 'Centro')
+					) v(origen, destino)
+					JOIN distritos a ON a.nombre = v.origen
+					JOIN distritos b ON b.nombre = v.destino;
+				END IF;
+			END $$;
+		`,
+	},
+	{
+		// Columnas geography mantenidas por trigger a partir de latitud/longitud, con índices GiST
+		// para consultas de radio, caja y vecinos más cercanos en la base de datos
+		nombre: "ubicación geográfica PostGIS de hospitales e historiales",
+		sql: `
+			CREATE EXTENSION IF NOT EXISTS postgis;
+
+			CREATE OR REPLACE FUNCTION f_punto_geografico(lat double precision, lng double precision)
+			RETURNS geography AS $$
+				SELECT ST_SetSRID(ST_MakePoint(lng, lat), 4326)::geography
+			$$ LANGUAGE sql IMMUTABLE STRICT PARALLEL SAFE;
+
+			ALTER TABLE hospitales ADD COLUMN IF NOT EXISTS ubicacion geography(Point, 4326);
+			ALTER TABLE historial_clinico ADD COLUMN IF NOT EXISTS ubicacion geography(Point, 4326);
+
+			CREATE OR REPLACE FUNCTION hospitales_ubicacion() RETURNS trigger AS $$
+			BEGIN
+				NEW.ubicacion := f_punto_geografico(NEW.latitud, NEW.longitud);
+				RETURN NEW;
+			END;
+			$$ LANGUAGE plpgsql;
+
+			CREATE OR REPLACE FUNCTION historial_clinico_ubicacion() RETURNS trigger AS $$
+			BEGIN
+				NEW.ubicacion := f_punto_geografico(NEW.patient_latitude, NEW.patient_longitude);
+				RETURN NEW;
+			END;
+			$$ LANGUAGE plpgsql;
+
+			DROP TRIGGER IF EXISTS hospitales_ubicacion ON hospitales;
+			CREATE TRIGGER hospitales_ubicacion
+				BEFORE INSERT OR UPDATE OF latitud, longitud ON hospitales
+				FOR EACH ROW EXECUTE FUNCTION hospitales_ubicacion();
+
+			DROP TRIGGER IF EXISTS historial_clinico_ubicacion ON historial_clinico;
+			CREATE TRIGGER historial_clinico_ubicacion
+				BEFORE INSERT OR UPDATE OF patient_latitude, patient_longitude ON historial_clinico
+				FOR EACH ROW EXECUTE FUNCTION historial_clinico_ubicacion();
+
+			UPDATE hospitales SET ubicacion = f_punto_geografico(latitud, longitud)
+			WHERE ubicacion IS NULL;
+			UPDATE historial_clinico SET ubicacion = f_punto_geografico(patient_latitude, patient_longitude)
+			WHERE ubicacion IS NULL;
+
+			CREATE INDEX IF NOT EXISTS idx_hospitales_ubicacion ON hospitales USING GIST (ubicacion);
+			CREATE INDEX IF NOT EXISTS idx_historial_ubicacion ON historial_clinico USING GIST (ubicacion)
+				WHERE deleted_at IS NULL;
+		`,
+	},
+	{
+		// Los vector tiles filtran por la caja del tile en coordenadas planas (lon/lat), que coincide con
+		// el rectángulo del tile en Web Mercator; la caja como geography tendría lados geodésicos
+		nombre: "índice geométrico de ubicaciones para vector tiles",
+		sql: `
+			CREATE INDEX IF NOT EXISTS idx_historial_ubicacion_geometria ON historial_clinico
+				USING GIST ((ubicacion::geometry)) WHERE deleted_at IS NULL;
+		`,
+	},
+	{
+		nombre: "puntaje de pacientes duplicados",
+		sql: `
+			-- Equivalentes a utils.JaroWinkler y a similitudNombre, similitudFecha del servicio de duplicados,
+			-- para puntuar y paginar los pares en la base de datos
+			CREATE OR REPLACE FUNCTION f_jaro_winkler(a text, b text) RETURNS double precision AS $$
+			DECLARE
+				la int := char_length(a);
+				lb int := char_length(b);
+				ventana int;
+				coincide_a boolean[];
+				coincide_b boolean[];
+				coincidencias int := 0;
+				transposiciones int := 0;
+				prefijo int := 0;
+				j int := 1;
+				jaro double precision;
+			BEGIN
+				IF la = 0 AND lb = 0 THEN
+					RETURN 1;
+				END IF;
+				IF la = 0 OR lb = 0 THEN
+					RETURN 0;
+				END IF;
+
+				ventana := GREATEST(GREATEST(la, lb) / 2 - 1, 0);
+				coincide_a := array_fill(false, ARRAY[la]);
+				coincide_b := array_fill(false, ARRAY[lb]);
+				FOR i IN 1..la LOOP
+					FOR k IN GREATEST(1, i - ventana)..LEAST(lb, i + ventana) LOOP
+						IF NOT coincide_b[k] AND substr(a, i, 1) = substr(b, k, 1) THEN
+							coincide_a[i] := true;
+							coincide_b[k] := true;
+							coincidencias := coincidencias + 1;
+							EXIT;
+						END IF;
+					END LOOP;
+				END LOOP;
+				IF coincidencias = 0 THEN
+					RETURN 0;
+				END IF;
+
+				FOR i IN 1..la LOOP
+					CONTINUE WHEN NOT coincide_a[i];
+					WHILE NOT coincide_b[j] LOOP
+						j := j + 1;
+					END LOOP;
+					IF substr(a, i, 1) <> substr(b, j, 1) THEN
+						transposiciones := transposiciones + 1;
+					END IF;
+					j := j + 1;
+				END LOOP;
+
+				jaro := (coincidencias::float8 / la + coincidencias::float8 / lb
+					+ (coincidencias - transposiciones::float8 / 2) / coincidencias) / 3;
+				WHILE prefijo < LEAST(4, la, lb) AND substr(a, prefijo + 1, 1) = substr(b, prefijo + 1, 1) LOOP
+					prefijo := prefijo + 1;
+				END LOOP;
+				RETURN jaro + prefijo * 0.1 * (1 - jaro);
+			END;
+			$$ LANGUAGE plpgsql IMMUTABLE PARALLEL SAFE STRICT;
+
+			CREATE OR REPLACE FUNCTION f_ordenar_palabras(text) RETURNS text AS $$
+				SELECT coalesce(string_agg(palabra, ' ' ORDER BY palabra COLLATE "C"), '')
+				FROM unnest(string_to_array($1, ' ')) AS palabra
+			$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;
+
+			CREATE OR REPLACE FUNCTION f_similitud_nombre(text, text) RETURNS double precision AS $$
+				SELECT GREATEST(
+					f_jaro_winkler(f_normalizar($1), f_normalizar($2)),
+					f_jaro_winkler(f_ordenar_palabras(f_normalizar($1)), f_ordenar_palabras(f_normalizar($2)))
+				)
+			$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;
+
+			CREATE OR REPLACE FUNCTION f_similitud_fecha(a date, b date) RETURNS double precision AS $$
+				SELECT CASE
+					WHEN a = b THEN 1
+					WHEN date_part('year', a) = date_part('year', b)
+						AND date_part('month', a) = date_part('day', b)
+						AND date_part('day', a) = date_part('month', b) THEN 0.8
+					WHEN (date_part('year', a) = date_part('year', b))::int
+						+ (date_part('month', a) = date_part('month', b))::int
+						+ (date_part('day', a) = date_part('day', b))::int = 2 THEN 0.5
+					ELSE 0
+				END
+			$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;
+
+			-- Bloques de comparación: misma fecha de nacimiento o mismo año y nombre parecido
+			CREATE INDEX IF NOT EXISTS idx_pacientes_fecha_nacimiento ON pacientes (fecha_nacimiento);
+		`,
+	},
+}
+
+// extensionesRequeridas extensiones de PostgreSQL que habilitan las migraciones SQL: unaccent y pg_trgm
+// vienen con el paquete contrib y postgis se instala aparte
+var extensionesRequeridas = []string{"unaccent", "pg_trgm", "postgis"}
+
+// verificarExtensiones comprueba que el servidor tenga instaladas las extensiones requeridas, para fallar
+// con un mensaje claro antes de modificar el esquema en lugar de a mitad de las migraciones
+func verificarExtensiones() error {
+	var disponibles []string
+	err := DB.Raw("SELECT name FROM pg_available_extensions WHERE name IN ?", extensionesRequeridas).
+		Scan(&disponibles).Error
+	if err != nil {
+		return fmt.Errorf("no se pudieron consultar las extensiones disponibles: %w", err)
+	}
+
+	var faltantes []string
+	for _, extension := range extensionesRequeridas {
+		if !slices.Contains(disponibles, extension) {
+			faltantes = append(faltantes, extension)
+		}
+	}
+	if len(faltantes) > 0 {
+		return fmt.Errorf("el servidor PostgreSQL no tiene instaladas las extensiones %s: se requiere PostgreSQL con "+
+			"PostGIS 3 (por ejemplo la imagen postgis/postgis o el paquete postgresql-16-postgis-3) y postgresql-contrib",
+			strings.Join(faltantes, ", "))
+	}
+	return nil
+}
+
+// runSQLMigrations ejecuta las migraciones SQL manuales
+func runSQLMigrations() error {
+	for _, migration := range sqlMigrations {
+		if err := DB.Exec(migration.sql).Error; err != nil {
+			return fmt.Errorf("%s: %w", migration.nombre, err)
+		}
+	}
+	return nil
+}
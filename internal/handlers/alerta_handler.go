@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"hospital-api/internal/models"
+	"hospital-api/internal/services"
+	"hospital-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type AlertaHandler struct {
+	alertaService *services.AlertaService
+	validator     *validator.Validate
+}
+
+// NewAlertaHandler crea una nueva instancia del handler de alertas de brote
+func NewAlertaHandler() *AlertaHandler {
+	return &AlertaHandler{
+		alertaService: services.NewAlertaService(),
+		validator:     validator.New(),
+	}
+}
+
+// GetAlertas obtiene el feed de alertas de brote
+// @Summary Feed de alertas de brote
+// @Description Lista las alertas generadas por el motor de reglas, las detectadas más recientemente primero
+// @Tags alertas
+// @Produce json
+// @Security BearerAuth
+// @Param estado query string false "Estado: activa o resuelta"
+// @Param severidad query string false "Severidad: baja, media, alta o critica"
+// @Param id_regla query int false "ID de la regla"
+// @Param id_enfermedad query int false "ID de la enfermedad en el catálogo"
+// @Param distrito query string false "Distrito"
+// @Param page query int false "Número de página" default(1)
+// @Param limit query int false "Elementos por página" default(10)
+// @Success 200 {object} utils.PaginatedResponse
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 403 {object} utils.APIErrorResponse
+// @Router /alertas [get]
+func (h *AlertaHandler) GetAlertas(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	filtro := services.AlertaFilter{
+		Estado:    c.Query("estado"),
+		Severidad: c.Query("severidad"),
+		Distrito:  c.Query("distrito"),
+	}
+	switch filtro.Estado {
+	case "", models.AlertaActiva, models.AlertaResuelta:
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Estado inválido", "INVALID_FILTER", "Valores permitidos: activa, resuelta")
+		return
+	}
+	switch filtro.Severidad {
+	case "", models.SeveridadBaja, models.SeveridadMedia, models.SeveridadAlta, models.SeveridadCritica:
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Severidad inválida", "INVALID_FILTER", "Valores permitidos: baja, media, alta, critica")
+		return
+	}
+
+	var ok bool
+	if filtro.IDRegla, ok = queryID(c, "id_regla"); !ok {
+		return
+	}
+	if filtro.IDEnfermedad, ok = queryID(c, "id_enfermedad"); !ok {
+		return
+	}
+
+	alertas, total, err := h.alertaService.GetAlertas(filtro, page, limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener alertas", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, alertas, "Alertas obtenidas exitosamente", page, limit, total)
+}
+
+// EvaluarReglas ejecuta el motor de alertas sin esperar al próximo ciclo
+// @Summary Evaluar reglas de alerta
+// @Description Evalúa todas las reglas activas y retorna cuántas alertas se crearon, actualizaron o resolvieron (solo epidemiólogos)
+// @Tags alertas
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} services.ResultadoEvaluacion
+// @Failure 403 {object} utils.APIErrorResponse
+// @Failure 409 {object} utils.APIErrorResponse
+// @Router /alertas/evaluar [post]
+func (h *AlertaHandler) EvaluarReglas(c *gin.Context) {
+	resultado, err := h.alertaService.EvaluarReglas(c.Request.Context())
+	if errors.Is(err, services.ErrEvaluacionEnCurso) {
+		utils.ErrorResponse(c, http.StatusConflict, err.Error(), "EVALUATION_IN_PROGRESS", "Intente nuevamente cuando termine")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al evaluar reglas", "EVALUATION_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, resultado, "Reglas evaluadas exitosamente")
+}
+
+// GetReglas lista las reglas de alerta
+// @Summary Listar reglas de alerta
+// @Description Obtiene una lista paginada de las reglas que evalúa el motor de alertas (solo epidemiólogos)
+// @Tags alertas
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Número de página" default(1)
+// @Param limit query int false "Elementos por página" default(10)
+// @Success 200 {object} utils.PaginatedResponse
+// @Failure 403 {object} utils.APIErrorResponse
+// @Router /alertas/reglas [get]
+func (h *AlertaHandler) GetReglas(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	reglas, total, err := h.alertaService.GetReglas(page, limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener reglas", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, reglas, "Reglas obtenidas exitosamente", page, limit, total)
+}
+
+// GetRegla obtiene una regla de alerta
+// @Summary Obtener regla de alerta
+// @Description Obtiene una regla de alerta por su ID (solo epidemiólogos)
+// @Tags alertas
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la regla"
+// @Success 200 {object} models.ReglaAlerta
+// @Failure 404 {object} utils.APIErrorResponse
+// @Router /alertas/reglas/{id} [get]
+func (h *AlertaHandler) GetRegla(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	regla, err := h.alertaService.GetReglaByID(uint(id))
+	if err != nil {
+		if err.Error() == "regla de alerta no encontrada" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener regla", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, regla, "Regla obtenida exitosamente")
+}
+
+// CreateRegla crea una regla de alerta
+// @Summary Crear regla de alerta
+// @Description Crea una regla de umbral ("más de 10 casos de dengue en Plan Tres Mil en 7 días") o de media móvil ("el doble de la media de las 4 semanas anteriores") (solo epidemiólogos)
+// @Tags alertas
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param regla body services.ReglaAlertaRequest true "Definición de la regla"
+// @Success 201 {object} models.ReglaAlerta
+// @Failure 400 {object} utils.APIErrorResponse
+// @Router /alertas/reglas [post]
+func (h *AlertaHandler) CreateRegla(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	var req services.ReglaAlertaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	regla, err := h.alertaService.CreateRegla(actor, req)
+	if err != nil {
+		if enfermedadErrorResponse(c, err) {
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al crear regla", "CREATE_ERROR", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Regla creada exitosamente",
+		"data":    regla,
+	})
+}
+
+// UpdateRegla reemplaza una regla de alerta
+// @Summary Actualizar regla de alerta
+// @Description Reemplaza la definición de una regla; desactivarla resuelve sus alertas activas (solo epidemiólogos)
+// @Tags alertas
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la regla"
+// @Param regla body services.ReglaAlertaRequest true "Definición de la regla"
+// @Success 200 {object} models.ReglaAlerta
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Router /alertas/reglas/{id} [put]
+func (h *AlertaHandler) UpdateRegla(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	var req services.ReglaAlertaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	regla, err := h.alertaService.UpdateRegla(actor, uint(id), req)
+	if err != nil {
+		if err.Error() == "regla de alerta no encontrada" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+			return
+		}
+		if enfermedadErrorResponse(c, err) {
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al actualizar regla", "UPDATE_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, regla, "Regla actualizada exitosamente")
+}
+
+// DeleteRegla elimina una regla de alerta
+// @Summary Eliminar regla de alerta
+// @Description Elimina una regla y resuelve sus alertas activas; el historial de alertas se conserva (solo epidemiólogos)
+// @Tags alertas
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la regla"
+// @Success 200 {object} utils.APISuccessResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Router /alertas/reglas/{id} [delete]
+func (h *AlertaHandler) DeleteRegla(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	if err := h.alertaService.DeleteRegla(actor, uint(id)); err != nil {
+		if err.Error() == "regla de alerta no encontrada" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al eliminar regla", "DELETE_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Regla eliminada exitosamente")
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"hospital-api/internal/services"
+	"hospital-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	auditService *services.AuditService
+}
+
+// NewAuditHandler crea una nueva instancia del handler de auditoría
+func NewAuditHandler() *AuditHandler {
+	return &AuditHandler{
+		auditService: services.NewAuditService(),
+	}
+}
+
+// GetAuditLogs consulta la bitácora de auditoría del hospital
+// @Summary Consultar auditoría
+// @Description Obtiene las entradas de auditoría del hospital con filtros, de la más reciente a la más antigua, junto con los bloqueos de login por IP o por emails sin cuenta, que no pertenecen a ningún hospital (solo administradores)
+// @Tags auditoria
+// @Produce json
+// @Security BearerAuth
+// @Param id_usuario query int false "ID del usuario que realizó la acción"
+// @Param accion query string false "Acción (crear, leer, actualizar, eliminar, login_bloqueado)"
+// @Param entidad query string false "Entidad (paciente, historial_clinico, email, ip)"
+// @Param entidad_id query string false "ID de la entidad"
+// @Param start_date query string false "Fecha de inicio (YYYY-MM-DD)" format(date)
+// @Param end_date query string false "Fecha de fin inclusive (YYYY-MM-DD)" format(date)
+// @Param page query int false "Número de página" default(1)
+// @Param limit query int false "Elementos por página" default(10)
+// @Success 200 {object} utils.PaginatedResponse
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 403 {object} utils.APIErrorResponse
+// @Router /audit [get]
+func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	filtro := services.AuditFilter{
+		Accion:    c.Query("accion"),
+		Entidad:   c.Query("entidad"),
+		EntidadID: c.Query("entidad_id"),
+	}
+
+	if usuarioStr := c.Query("id_usuario"); usuarioStr != "" {
+		usuarioID, err := strconv.ParseUint(usuarioStr, 10, 32)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "ID de usuario inválido", "INVALID_ID", "")
+			return
+		}
+		id := uint(usuarioID)
+		filtro.IDUsuario = &id
+	}
+
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Fecha de inicio inválida", "INVALID_DATE", "Formato esperado: YYYY-MM-DD")
+			return
+		}
+		filtro.Desde = &parsed
+	}
+
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Fecha de fin inválida", "INVALID_DATE", "Formato esperado: YYYY-MM-DD")
+			return
+		}
+		// Incluir todo el día de fin
+		hasta := parsed.AddDate(0, 0, 1)
+		filtro.Hasta = &hasta
+	}
+
+	entradas, total, err := h.auditService.GetAuditLogs(actor, filtro, page, limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener la auditoría", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, entradas, "Auditoría obtenida exitosamente", page, limit, total)
+}
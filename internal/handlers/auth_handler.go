@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"hospital-api/internal/services"
 	"hospital-api/internal/utils"
@@ -23,9 +26,9 @@ func NewAuthHandler() *AuthHandler {
 	}
 }
 
-// Login maneja el login de hospitales
-// @Summary Login de hospital
-// @Description Autentica un hospital y retorna un token JWT
+// Login maneja el login de usuarios
+// @Summary Login de usuario
+// @Description Autentica un usuario de hospital y retorna un token JWT con su rol
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -33,6 +36,8 @@ func NewAuthHandler() *AuthHandler {
 // @Success 200 {object} services.LoginResponse
 // @Failure 400 {object} utils.ErrorResponse
 // @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 429 {object} utils.ErrorResponse
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req services.LoginRequest
@@ -50,8 +55,18 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	// Intentar login
-	response, err := h.authService.Login(req)
+	response, err := h.authService.Login(req, c.ClientIP())
 	if err != nil {
+		var bloqueado *services.LoginBloqueadoError
+		if errors.As(err, &bloqueado) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(bloqueado.RetryAfter.Seconds()))))
+			utils.ErrorResponse(c, http.StatusTooManyRequests, err.Error(), "TOO_MANY_ATTEMPTS", "")
+			return
+		}
+		if err.Error() == "el email del hospital no ha sido verificado" {
+			utils.ErrorResponse(c, http.StatusForbidden, err.Error(), "EMAIL_NOT_VERIFIED", "")
+			return
+		}
 		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error(), "AUTH_FAILED", "")
 		return
 	}
@@ -59,42 +74,132 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	utils.SuccessResponse(c, response, "Login exitoso")
 }
 
-// GetProfile obtiene el perfil del hospital autenticado
-// @Summary Perfil del hospital
-// @Description Obtiene la información del hospital autenticado
+// Refresh renueva el access token usando un refresh token
+// @Summary Renovar token
+// @Description Canjea un refresh token por un nuevo access token; el refresh token se rota y el anterior queda revocado
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param refresh body services.RefreshRequest true "Refresh token"
+// @Success 200 {object} services.TokenResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req services.RefreshRequest
+
+	// Bind JSON
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
+		return
+	}
+
+	// Validar datos
+	if err := h.validator.Struct(req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	response, err := h.authService.Refresh(req)
+	if err != nil {
+		if err.Error() == "refresh token inválido" || err.Error() == "refresh token expirado" {
+			utils.ErrorResponse(c, http.StatusUnauthorized, err.Error(), "INVALID_REFRESH_TOKEN", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al renovar el token", "REFRESH_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, response, "Token renovado exitosamente")
+}
+
+// Logout cierra la sesión del usuario autenticado
+// @Summary Cerrar sesión
+// @Description Revoca el access token actual y el refresh token indicado (o todos los del usuario con "todas")
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param logout body services.LogoutRequest false "Refresh token a revocar"
+// @Success 200 {object} utils.APISuccessResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	// El cuerpo es opcional: sin él solo se revoca el access token actual
+	var req services.LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
+			return
+		}
+	}
+
+	err := h.authService.Logout(actor.UsuarioID, c.GetString("token_jti"), c.GetTime("token_exp"), req)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al cerrar sesión", "LOGOUT_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Sesión cerrada exitosamente")
+}
+
+// GetProfile obtiene el perfil del usuario autenticado
+// @Summary Perfil del usuario
+// @Description Obtiene la información del usuario autenticado y de su hospital
 // @Tags auth
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {object} models.HospitalResponse
+// @Success 200 {object} services.ProfileResponse
 // @Failure 401 {object} utils.ErrorResponse
 // @Router /auth/profile [get]
 func (h *AuthHandler) GetProfile(c *gin.Context) {
-	hospitalID, exists := c.Get("hospital_id")
-	if !exists {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "Hospital no autenticado", "NOT_AUTHENTICATED", "")
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.authService.GetProfile(actor.UsuarioID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
 		return
 	}
 
-	// Convertir a uint
-	id, ok := hospitalID.(uint)
+	utils.SuccessResponse(c, profile, "Perfil obtenido exitosamente")
+}
+
+// CreateTokenEventos emite un token de corta duración para abrir /eventos/stream desde un EventSource
+// @Summary Token para eventos en tiempo real
+// @Description Emite un token de un minuto que solo sirve para GET /eventos/stream?token=..., porque el EventSource del navegador no puede enviar la cabecera Authorization. El logout también lo revoca.
+// @Tags eventos
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} services.TokenEventosResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Router /eventos/stream/token [post]
+func (h *AuthHandler) CreateTokenEventos(c *gin.Context) {
+	actor, ok := requireActor(c)
 	if !ok {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Error interno", "INTERNAL_ERROR", "Invalid hospital ID type")
 		return
 	}
 
-	// Aquí podrías obtener más información del hospital desde la base de datos
-	// Por ahora, retornamos la información básica disponible en el contexto
-	email, _ := c.Get("hospital_email")
+	token, expiresAt, err := h.authService.GenerarTokenEventos(actor, c.GetString("usuario_email"), c.GetString("token_jti"), c.GetTime("token_exp"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al generar el token", "TOKEN_ERROR", err.Error())
+		return
+	}
 
-	utils.SuccessResponse(c, gin.H{
-		"hospital_id": id,
-		"email":       email,
-	}, "Perfil obtenido exitosamente")
+	utils.SuccessResponse(c, services.TokenEventosResponse{Token: token, ExpiresAt: expiresAt}, "Token de eventos generado exitosamente")
 }
 
 // Register maneja el registro de nuevos hospitales
 // @Summary Registro de hospital
-// @Description Registra un nuevo hospital en el sistema
+// @Description Registra un nuevo hospital en el sistema junto con su usuario administrador
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -137,6 +242,129 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{
 		"success": true,
 		"message": response.Message,
-		"data":    response.Hospital,
+		"data": gin.H{
+			"hospital": response.Hospital,
+			"usuario":  response.Usuario,
+		},
 	})
 }
+
+// RequestEmailVerification reenvía el correo de verificación del hospital
+// @Summary Solicitar verificación de email
+// @Description Envía un nuevo enlace de verificación al email de un hospital no verificado. Responde igual exista o no el email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body services.EmailRequest true "Email del hospital"
+// @Success 200 {object} utils.APISuccessResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /auth/verify-email/request [post]
+func (h *AuthHandler) RequestEmailVerification(c *gin.Context) {
+	var req services.EmailRequest
+	if !h.bindAndValidate(c, &req) {
+		return
+	}
+
+	if err := h.authService.RequestEmailVerification(req); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al enviar el correo de verificación", "EMAIL_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Si el email corresponde a un hospital pendiente de verificación, se envió un nuevo enlace")
+}
+
+// ConfirmEmailVerification confirma el email del hospital
+// @Summary Confirmar verificación de email
+// @Description Consume el token de verificación recibido por correo y habilita el login del hospital
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body services.TokenRequest true "Token de verificación"
+// @Success 200 {object} utils.APISuccessResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /auth/verify-email/confirm [post]
+func (h *AuthHandler) ConfirmEmailVerification(c *gin.Context) {
+	var req services.TokenRequest
+	if !h.bindAndValidate(c, &req) {
+		return
+	}
+
+	if err := h.authService.ConfirmEmailVerification(req); err != nil {
+		h.tokenErrorResponse(c, err, "Error al verificar el email")
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Email verificado exitosamente")
+}
+
+// RequestPasswordReset solicita el restablecimiento de contraseña
+// @Summary Solicitar restablecimiento de contraseña
+// @Description Envía un enlace para restablecer la contraseña. Responde igual exista o no el email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body services.EmailRequest true "Email del usuario"
+// @Success 200 {object} utils.APISuccessResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /auth/password-reset/request [post]
+func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
+	var req services.EmailRequest
+	if !h.bindAndValidate(c, &req) {
+		return
+	}
+
+	if err := h.authService.RequestPasswordReset(req); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al enviar el correo de restablecimiento", "EMAIL_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Si el email está registrado, se envió un enlace para restablecer la contraseña")
+}
+
+// ConfirmPasswordReset establece una nueva contraseña
+// @Summary Confirmar restablecimiento de contraseña
+// @Description Consume el token recibido por correo, cambia la contraseña y cierra las sesiones abiertas del usuario
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body services.ResetPasswordRequest true "Token y nueva contraseña"
+// @Success 200 {object} utils.APISuccessResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /auth/password-reset/confirm [post]
+func (h *AuthHandler) ConfirmPasswordReset(c *gin.Context) {
+	var req services.ResetPasswordRequest
+	if !h.bindAndValidate(c, &req) {
+		return
+	}
+
+	if err := h.authService.ConfirmPasswordReset(req); err != nil {
+		h.tokenErrorResponse(c, err, "Error al restablecer la contraseña")
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Contraseña restablecida exitosamente")
+}
+
+// bindAndValidate lee el cuerpo JSON en req y lo valida; si falla responde 400 y retorna false
+func (h *AuthHandler) bindAndValidate(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
+		return false
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return false
+	}
+
+	return true
+}
+
+// tokenErrorResponse responde los errores de los tokens de un solo uso
+func (h *AuthHandler) tokenErrorResponse(c *gin.Context, err error, message string) {
+	if err.Error() == "token inválido" || err.Error() == "token expirado" {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_TOKEN", "")
+		return
+	}
+	utils.ErrorResponse(c, http.StatusInternalServerError, message, "TOKEN_ERROR", err.Error())
+}
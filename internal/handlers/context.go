@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+
+	"hospital-api/internal/services"
+	"hospital-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requireActor obtiene el actor autenticado del contexto (cargado por AuthMiddleware).
+// Si no existe responde 401 y retorna false.
+func requireActor(c *gin.Context) (services.Actor, bool) {
+	hospitalID, exists := c.Get("hospital_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Hospital no autenticado", "NOT_AUTHENTICATED", "")
+		return services.Actor{}, false
+	}
+
+	id, ok := hospitalID.(uint)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error interno", "INTERNAL_ERROR", "Invalid hospital ID type")
+		return services.Actor{}, false
+	}
+
+	usuarioID, _ := c.Get("usuario_id")
+	uid, _ := usuarioID.(uint)
+
+	return services.Actor{
+		UsuarioID:  uid,
+		HospitalID: id,
+		Rol:        c.GetString("rol"),
+		IP:         c.ClientIP(),
+	}, true
+}
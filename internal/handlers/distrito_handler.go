@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"hospital-api/internal/services"
+	"hospital-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// maxArchivoLimites tamaño máximo de un archivo de límites (50 MB)
+const maxArchivoLimites = 50 << 20
+
+type DistritoHandler struct {
+	distritoService *services.DistritoService
+	validator       *validator.Validate
+}
+
+// NewDistritoHandler crea una nueva instancia del handler de distritos
+func NewDistritoHandler() *DistritoHandler {
+	return &DistritoHandler{
+		distritoService: services.NewDistritoService(),
+		validator:       validator.New(),
+	}
+}
+
+// GetDistritos lista los distritos con sus conexiones
+// @Summary Listar distritos
+// @Description Obtiene los distritos registrados con población, área, densidad, centroide y vecinos ponderados
+// @Tags distritos
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.APISuccessResponse
+// @Router /distritos [get]
+func (h *DistritoHandler) GetDistritos(c *gin.Context) {
+	distritos, err := h.distritoService.GetDistritos()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener distritos", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, distritos, "Distritos obtenidos exitosamente")
+}
+
+// GetDistrito obtiene un distrito
+// @Summary Obtener distrito
+// @Description Obtiene un distrito por su ID con sus conexiones
+// @Tags distritos
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del distrito"
+// @Success 200 {object} models.Distrito
+// @Failure 404 {object} utils.APIErrorResponse
+// @Router /distritos/{id} [get]
+func (h *DistritoHandler) GetDistrito(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	distrito, err := h.distritoService.GetDistritoByID(uint(id))
+	if err != nil {
+		distritoErrorResponse(c, err, "Error al obtener distrito", "FETCH_ERROR")
+		return
+	}
+
+	utils.SuccessResponse(c, distrito, "Distrito obtenido exitosamente")
+}
+
+// CreateDistrito registra un distrito
+// @Summary Crear distrito
+// @Description Registra un distrito con sus datos demográficos y su centroide; las conexiones se asignan aparte (solo administradores)
+// @Tags distritos
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param distrito body services.DistritoRequest true "Datos del distrito"
+// @Success 201 {object} models.Distrito
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 409 {object} utils.APIErrorResponse
+// @Router /distritos [post]
+func (h *DistritoHandler) CreateDistrito(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	var req services.DistritoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	distrito, err := h.distritoService.CreateDistrito(actor, req)
+	if err != nil {
+		distritoErrorResponse(c, err, "Error al crear distrito", "CREATE_ERROR")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Distrito creado exitosamente",
+		"data":    distrito,
+	})
+}
+
+// UpdateDistrito reemplaza los datos de un distrito
+// @Summary Actualizar distrito
+// @Description Reemplaza nombre, población, área, tipo de zona y centroide de un distrito; sus conexiones no cambian (solo administradores)
+// @Tags distritos
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del distrito"
+// @Param distrito body services.DistritoRequest true "Datos del distrito"
+// @Success 200 {object} models.Distrito
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Failure 409 {object} utils.APIErrorResponse
+// @Router /distritos/{id} [put]
+func (h *DistritoHandler) UpdateDistrito(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	var req services.DistritoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	distrito, err := h.distritoService.UpdateDistrito(actor, uint(id), req)
+	if err != nil {
+		distritoErrorResponse(c, err, "Error al actualizar distrito", "UPDATE_ERROR")
+		return
+	}
+
+	utils.SuccessResponse(c, distrito, "Distrito actualizado exitosamente")
+}
+
+// DeleteDistrito elimina un distrito
+// @Summary Eliminar distrito
+// @Description Elimina un distrito y sus conexiones (solo administradores)
+// @Tags distritos
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del distrito"
+// @Success 200 {object} utils.APISuccessResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Router /distritos/{id} [delete]
+func (h *DistritoHandler) DeleteDistrito(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	if err := h.distritoService.DeleteDistrito(actor, uint(id)); err != nil {
+		distritoErrorResponse(c, err, "Error al eliminar distrito", "DELETE_ERROR")
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Distrito eliminado exitosamente")
+}
+
+// UpdateConexiones reemplaza los vecinos de un distrito
+// @Summary Actualizar conexiones de un distrito
+// @Description Reemplaza la lista de distritos conectados y el peso (0, 1] de cada conexión. Las conexiones son simétricas (solo administradores)
+// @Tags distritos
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del distrito"
+// @Param conexiones body services.ConexionesRequest true "Vecinos del distrito"
+// @Success 200 {object} models.Distrito
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Router /distritos/{id}/conexiones [put]
+func (h *DistritoHandler) UpdateConexiones(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	var req services.ConexionesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	distrito, err := h.distritoService.ActualizarConexiones(actor, uint(id), req)
+	if err != nil {
+		if errors.Is(err, services.ErrConexionInvalida) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Cada conexión debe apuntar una sola vez a otro distrito existente", "INVALID_CONNECTION", "")
+			return
+		}
+		distritoErrorResponse(c, err, "Error al actualizar conexiones", "UPDATE_ERROR")
+		return
+	}
+
+	utils.SuccessResponse(c, distrito, "Conexiones actualizadas exitosamente")
+}
+
+// GetLimites obtiene los contornos oficiales importados
+// @Summary Límites de distritos o barrios
+// @Description Obtiene los contornos importados de distritos o barrios como FeatureCollection de GeoJSON
+// @Tags distritos
+// @Produce json
+// @Security BearerAuth
+// @Param nivel query string false "distrito o barrio" default(distrito)
+// @Success 200 {object} utils.APISuccessResponse
+// @Failure 400 {object} utils.APIErrorResponse
+// @Router /distritos/limites [get]
+func (h *DistritoHandler) GetLimites(c *gin.Context) {
+	nivel := c.DefaultQuery("nivel", services.NivelLimiteDistrito)
+	if nivel != services.NivelLimiteDistrito && nivel != services.NivelLimiteBarrio {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Nivel inválido", "INVALID_PARAMETER", "nivel debe ser distrito o barrio")
+		return
+	}
+
+	limites, err := h.distritoService.GetLimites(nivel)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener límites", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, limites, "Límites obtenidos exitosamente")
+}
+
+// ImportarLimites importa contornos oficiales de distritos o barrios
+// @Summary Importar límites
+// @Description Importa los contornos de distritos o barrios desde un FeatureCollection de GeoJSON (.geojson, .json) o un Shapefile en ZIP (.shp, .dbf y opcionalmente .prj y .cpg) en WGS84. Los distritos se enlazan por nombre con el registro; los barrios se crean o actualizan (solo administradores)
+// @Tags distritos
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param archivo formData file true "GeoJSON o Shapefile en ZIP"
+// @Param nivel formData string true "distrito o barrio"
+// @Param campo_nombre formData string false "Propiedad con el nombre (por defecto nombre, name o el nivel)"
+// @Param actualizar_area formData bool false "Reemplazar area_km2 de cada distrito por la del contorno"
+// @Success 200 {object} services.ImportacionLimites
+// @Failure 400 {object} utils.APIErrorResponse
+// @Router /distritos/limites/importar [post]
+func (h *DistritoHandler) ImportarLimites(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	var req services.ImportarLimitesRequest
+	if err := c.ShouldBind(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	archivo, err := c.FormFile("archivo")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "El archivo es requerido", "MISSING_FILE", err.Error())
+		return
+	}
+	if archivo.Size > maxArchivoLimites {
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "El archivo supera los 50 MB", "FILE_TOO_LARGE", "")
+		return
+	}
+
+	contenido, err := archivo.Open()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "No se pudo leer el archivo", "INVALID_FILE", err.Error())
+		return
+	}
+	defer contenido.Close()
+
+	datos, err := io.ReadAll(contenido)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "No se pudo leer el archivo", "INVALID_FILE", err.Error())
+		return
+	}
+
+	resultado, err := h.distritoService.ImportarLimites(actor, req, archivo.Filename, datos)
+	if err != nil {
+		if errors.Is(err, services.ErrArchivoLimites) || errors.Is(err, utils.ErrGeometriaInvalida) || errors.Is(err, utils.ErrShapefileInvalido) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Archivo de límites inválido", "INVALID_FILE", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al importar límites", "IMPORT_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, resultado, "Límites importados exitosamente")
+}
+
+// GetDistritosPublico información básica de los distritos (no requiere JWT)
+func (h *DistritoHandler) GetDistritosPublico(c *gin.Context) {
+	distritos, err := h.distritoService.GetDistritos()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener distritos", "FETCH_ERROR", err.Error())
+		return
+	}
+	resumen, err := h.distritoService.GetResumen()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener distritos", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	datos := make([]map[string]interface{}, 0, len(distritos))
+	for _, distrito := range distritos {
+		datos = append(datos, map[string]interface{}{
+			"nombre":      distrito.Nombre,
+			"habitantes":  distrito.Habitantes,
+			"area_km2":    distrito.AreaKm2,
+			"densidad":    distrito.Densidad,
+			"tipo":        distrito.TipoZona,
+			"coordenadas": map[string]float64{"lat": distrito.Latitud, "lng": distrito.Longitud},
+		})
+	}
+
+	utils.SuccessResponse(c, map[string]interface{}{
+		"ciudad":    services.CiudadDistritos,
+		"distritos": datos,
+		"estadisticas": map[string]interface{}{
+			"poblacion_total":   resumen.PoblacionTotal,
+			"area_total_km2":    resumen.AreaTotalKm2,
+			"densidad_promedio": resumen.DensidadPromedio,
+		},
+	}, "Información de distritos de Santa Cruz obtenida exitosamente")
+}
+
+// GetConectividadPublica matriz de conectividad entre distritos con el peso de cada conexión (no requiere JWT)
+func (h *DistritoHandler) GetConectividadPublica(c *gin.Context) {
+	distritos, err := h.distritoService.GetDistritos()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener distritos", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	matriz := make(map[string][]string, len(distritos))
+	pesos := make(map[string]map[string]float64, len(distritos))
+	for _, distrito := range distritos {
+		matriz[distrito.Nombre] = []string{}
+		pesos[distrito.Nombre] = map[string]float64{}
+		for _, conexion := range distrito.Conexiones {
+			matriz[distrito.Nombre] = append(matriz[distrito.Nombre], conexion.Distrito)
+			pesos[distrito.Nombre][conexion.Distrito] = conexion.Peso
+		}
+	}
+
+	utils.SuccessResponse(c, map[string]interface{}{
+		"matriz_conectividad": matriz,
+		"pesos_conectividad":  pesos,
+		"descripcion":         "Matriz de conectividad entre distritos de " + services.CiudadDistritos,
+		"criterios": []string{
+			"Proximidad geográfica",
+			"Conexiones de transporte público",
+			"Flujo poblacional diario",
+			"Corredores comerciales",
+		},
+	}, "Matriz de conectividad obtenida exitosamente")
+}
+
+func distritoErrorResponse(c *gin.Context, err error, message, code string) {
+	switch err.Error() {
+	case "distrito no encontrado":
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+	case "ya existe un distrito con ese nombre":
+		utils.ErrorResponse(c, http.StatusConflict, err.Error(), "ALREADY_EXISTS", "")
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, code, err.Error())
+	}
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"hospital-api/internal/services"
+	"hospital-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type EnfermedadHandler struct {
+	enfermedadService *services.EnfermedadService
+	validator         *validator.Validate
+}
+
+// NewEnfermedadHandler crea una nueva instancia del handler del catálogo de enfermedades
+func NewEnfermedadHandler() *EnfermedadHandler {
+	return &EnfermedadHandler{
+		enfermedadService: services.NewEnfermedadService(),
+		validator:         validator.New(),
+	}
+}
+
+// BuscarEnfermedades autocompleta enfermedades del catálogo
+// @Summary Buscar enfermedades
+// @Description Sugiere enfermedades del catálogo CIE-10 por nombre, código o sinónimo, ordenadas por coincidencia
+// @Tags enfermedades
+// @Produce json
+// @Security BearerAuth
+// @Param q query string false "Texto a buscar (nombre, código CIE-10 o sinónimo)"
+// @Param limit query int false "Máximo de sugerencias (1-50)" default(10)
+// @Success 200 {array} services.EnfermedadSugerencia
+// @Failure 401 {object} utils.APIErrorResponse
+// @Router /enfermedades [get]
+func (h *EnfermedadHandler) BuscarEnfermedades(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	sugerencias, err := h.enfermedadService.BuscarEnfermedades(c.Query("q"), limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al buscar enfermedades", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, sugerencias, "Enfermedades obtenidas exitosamente")
+}
+
+// GetEnfermedad obtiene una enfermedad del catálogo
+// @Summary Obtener enfermedad
+// @Description Obtiene una enfermedad del catálogo con su código CIE-10, incubación y sinónimos
+// @Tags enfermedades
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la enfermedad"
+// @Success 200 {object} models.Enfermedad
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Router /enfermedades/{id} [get]
+func (h *EnfermedadHandler) GetEnfermedad(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	enfermedad, err := h.enfermedadService.GetEnfermedadByID(uint(id))
+	if err != nil {
+		if err.Error() == "enfermedad no encontrada" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener enfermedad", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, enfermedad, "Enfermedad obtenida exitosamente")
+}
+
+// UpdateIntervaloSerial configura el intervalo serial de una enfermedad
+// @Summary Configurar intervalo serial
+// @Description Define la distribución (gamma o lognormal), media y desviación en días del intervalo serial con que se estima el Rt de la enfermedad (solo epidemiólogos)
+// @Tags enfermedades
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la enfermedad"
+// @Param intervalo body services.IntervaloSerialRequest true "Intervalo serial"
+// @Success 200 {object} models.Enfermedad
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Router /enfermedades/{id}/intervalo-serial [put]
+func (h *EnfermedadHandler) UpdateIntervaloSerial(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	var req services.IntervaloSerialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	enfermedad, err := h.enfermedadService.ActualizarIntervaloSerial(actor, uint(id), req)
+	if err != nil {
+		if err.Error() == "enfermedad no encontrada" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al actualizar intervalo serial", "UPDATE_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, enfermedad, "Intervalo serial actualizado exitosamente")
+}
+
+// CreateEnfermedad agrega una enfermedad al catálogo
+// @Summary Crear enfermedad
+// @Description Agrega una enfermedad al catálogo con su código CIE-10, contagiosidad, vía de transmisión, incubación y notificación obligatoria (solo administradores)
+// @Tags enfermedades
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param enfermedad body services.EnfermedadRequest true "Datos de la enfermedad"
+// @Success 201 {object} models.Enfermedad
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 409 {object} utils.APIErrorResponse
+// @Router /enfermedades [post]
+func (h *EnfermedadHandler) CreateEnfermedad(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	var req services.EnfermedadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	enfermedad, err := h.enfermedadService.CreateEnfermedad(actor, req)
+	if err != nil {
+		catalogoErrorResponse(c, err, "Error al crear enfermedad", "CREATE_ERROR")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Enfermedad creada exitosamente",
+		"data":    enfermedad,
+	})
+}
+
+// UpdateEnfermedad reemplaza los datos de una enfermedad del catálogo
+// @Summary Actualizar enfermedad
+// @Description Reemplaza código CIE-10, nombre, contagiosidad, vía de transmisión, incubación y notificación obligatoria de una enfermedad; sus sinónimos y su intervalo serial no cambian (solo administradores)
+// @Tags enfermedades
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la enfermedad"
+// @Param enfermedad body services.EnfermedadRequest true "Datos de la enfermedad"
+// @Success 200 {object} models.Enfermedad
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Failure 409 {object} utils.APIErrorResponse
+// @Router /enfermedades/{id} [put]
+func (h *EnfermedadHandler) UpdateEnfermedad(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	var req services.EnfermedadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	enfermedad, err := h.enfermedadService.UpdateEnfermedad(actor, uint(id), req)
+	if err != nil {
+		catalogoErrorResponse(c, err, "Error al actualizar enfermedad", "UPDATE_ERROR")
+		return
+	}
+
+	utils.SuccessResponse(c, enfermedad, "Enfermedad actualizada exitosamente")
+}
+
+// UpdateSinonimos reemplaza los sinónimos de una enfermedad
+// @Summary Reemplazar sinónimos
+// @Description Reemplaza los nombres alternativos con que se reconoce una enfermedad al registrar historiales; se guardan sin mayúsculas, acentos ni signos (solo administradores)
+// @Tags enfermedades
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la enfermedad"
+// @Param sinonimos body services.SinonimosRequest true "Sinónimos"
+// @Success 200 {object} models.Enfermedad
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Failure 409 {object} utils.APIErrorResponse
+// @Router /enfermedades/{id}/sinonimos [put]
+func (h *EnfermedadHandler) UpdateSinonimos(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	var req services.SinonimosRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	enfermedad, err := h.enfermedadService.ActualizarSinonimos(actor, uint(id), req)
+	if err != nil {
+		catalogoErrorResponse(c, err, "Error al actualizar sinónimos", "UPDATE_ERROR")
+		return
+	}
+
+	utils.SuccessResponse(c, enfermedad, "Sinónimos actualizados exitosamente")
+}
+
+func catalogoErrorResponse(c *gin.Context, err error, message, code string) {
+	switch {
+	case err.Error() == "enfermedad no encontrada":
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+	case errors.Is(err, services.ErrEnfermedadInvalida):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_INPUT", "")
+	case errors.Is(err, services.ErrTerminoEnfermedadEnUso):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error(), "ALREADY_EXISTS", "")
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, code, err.Error())
+	}
+}
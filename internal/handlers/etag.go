@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"hospital-api/internal/services"
+	"hospital-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setETag agrega el header ETag con la versión del recurso
+func setETag(c *gin.Context, version int) {
+	c.Header("ETag", utils.ETag(version))
+}
+
+// requireIfMatch obtiene las versiones aceptadas del header If-Match (nil para "*").
+// Las modificaciones exigen el header: si falta responde 428 y retorna false.
+func requireIfMatch(c *gin.Context) ([]int, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		utils.ErrorResponse(c, http.StatusPreconditionRequired, "Se requiere el header If-Match con el ETag del recurso", "PRECONDITION_REQUIRED", "Obtenga el recurso con GET y envíe su ETag en If-Match")
+		return nil, false
+	}
+	return utils.ParseIfMatch(header), true
+}
+
+// versionConflictResponse responde 412 con el ETag actual si err es un conflicto de versión
+func versionConflictResponse(c *gin.Context, err error) bool {
+	var conflict *services.VersionConflictError
+	if !errors.As(err, &conflict) {
+		return false
+	}
+
+	setETag(c, conflict.Actual)
+	utils.ErrorResponse(c, http.StatusPreconditionFailed, "El recurso fue modificado por otro usuario", "PRECONDITION_FAILED", "Vuelva a obtener el recurso y aplique sus cambios sobre la versión actual")
+	return true
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"hospital-api/internal/models"
+	"hospital-api/internal/services"
+	"hospital-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// intervaloKeepAlive cada cuánto se envía un comentario SSE para que proxies y navegadores no cierren la conexión
+const intervaloKeepAlive = 25 * time.Second
+
+type EventoHandler struct{}
+
+// NewEventoHandler crea una nueva instancia del handler de eventos en tiempo real
+func NewEventoHandler() *EventoHandler {
+	return &EventoHandler{}
+}
+
+// StreamEventos envía las alertas nuevas y los casos contagiosos nuevos por Server-Sent Events
+// @Summary Eventos en tiempo real (SSE)
+// @Description Mantiene abierta una conexión text/event-stream con los eventos alerta.nueva y caso.contagioso. Reemplaza el polling de /epidemiologia/stats. Desde un EventSource del navegador, que no puede enviar la cabecera Authorization, se autentica con ?token= usando un token de POST /eventos/stream/token.
+// @Tags eventos
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param tipos query string false "Tipos de evento separados por coma (por defecto todos)"
+// @Param token query string false "Token de POST /eventos/stream/token, en lugar de la cabecera Authorization"
+// @Success 200 {string} string "Flujo de eventos"
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 403 {object} utils.APIErrorResponse
+// @Router /eventos/stream [get]
+func (h *EventoHandler) StreamEventos(c *gin.Context) {
+	tipos := models.EventosValidos
+	if tiposStr := c.Query("tipos"); tiposStr != "" {
+		tipos = strings.Split(tiposStr, ",")
+		for _, tipo := range tipos {
+			if !slices.Contains(models.EventosValidos, tipo) {
+				utils.ErrorResponse(c, http.StatusBadRequest, "Tipo de evento inválido", "INVALID_FILTER",
+					"Valores permitidos: "+strings.Join(models.EventosValidos, ", "))
+				return
+			}
+		}
+	}
+
+	eventos, cancelar := services.SuscribirEventos()
+	defer cancelar()
+
+	keepAlive := time.NewTicker(intervaloKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case evento, abierto := <-eventos:
+			if !abierto {
+				return false // El servidor se está deteniendo
+			}
+			if slices.Contains(tipos, evento.Tipo) {
+				c.SSEvent(evento.Tipo, evento)
+			}
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -42,10 +43,9 @@ func NewHistorialHandler() *HistorialHandler {
 func (h *HistorialHandler) CreateHistorial(c *gin.Context) {
 	var request models.HistorialClinicoRequest
 
-	// Obtener ID del hospital del contexto (desde JWT)
-	hospitalID, exists := c.Get("hospital_id")
-	if !exists {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "Hospital no autenticado", "NOT_AUTHENTICATED", "")
+	// Obtener el hospital autenticado del contexto (desde JWT)
+	actor, ok := requireActor(c)
+	if !ok {
 		return
 	}
 
@@ -61,49 +61,25 @@ func (h *HistorialHandler) CreateHistorial(c *gin.Context) {
 		return
 	}
 
-	// Crear servicio de geocodificación
-	geocodingService, err := services.NewGeocodingService()
-	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Error configurando servicio de mapas", "GEOCODING_CONFIG_ERROR", err.Error())
-		return
-	}
-
-	// Obtener información completa de la dirección
-	addressComponents, err := geocodingService.GetAddressComponents(request.PatientAddress)
-	if err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "No se pudo geocodificar la dirección proporcionada", "GEOCODING_ERROR", err.Error())
-		return
-	}
-
-	// Validar que las coordenadas estén en La Paz
-	if !geocodingService.ValidateCoordinates(addressComponents.Coordinates.Latitude, addressComponents.Coordinates.Longitude) {
-		utils.ErrorResponse(c, http.StatusBadRequest, "La dirección debe estar ubicada en La Paz, Bolivia", "INVALID_LOCATION", "")
-		return
-	}
-
 	// Convertir a modelo de base de datos
 	historial := request.ToHistorialClinico()
 
-	// Asignar hospital desde el JWT
-	historial.IDHospital = hospitalID.(uint)
-
-	// Asignar coordenadas y información obtenida
-	historial.PatientLatitude = addressComponents.Coordinates.Latitude
-	historial.PatientLongitude = addressComponents.Coordinates.Longitude
-	historial.PatientAddress = addressComponents.FormattedAddress // Usar dirección formateada
-
-	// Si no se proporcionó distrito, usar el obtenido de Google Maps
-	if historial.PatientDistrict == "" {
-		historial.PatientDistrict = addressComponents.District
-	}
-
-	// Si no se proporcionó barrio, usar el obtenido de Google Maps
-	if historial.PatientNeighborhood == "" {
-		historial.PatientNeighborhood = addressComponents.Neighborhood
+	// Geocodificar la dirección y asignar coordenadas, distrito y barrio
+	addressComponents, err := services.GeocodificarHistorial(historial, request.PatientAddress)
+	if err != nil {
+		geocodingErrorResponse(c, err)
+		return
 	}
 
-	// Crear historial
-	if err := h.historialService.CreateHistorial(historial); err != nil {
+	// Crear historial (el hospital se asigna desde el JWT)
+	if err := h.historialService.CreateHistorial(actor, historial); err != nil {
+		if err.Error() == "paciente no encontrado" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "PACIENTE_NOT_FOUND", "")
+			return
+		}
+		if enfermedadErrorResponse(c, err) {
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al crear historial", "CREATE_ERROR", err.Error())
 		return
 	}
@@ -172,10 +148,16 @@ func (h *HistorialHandler) GeocodeAddress(c *gin.Context) {
 // @Security BearerAuth
 // @Param id path int true "ID del historial clínico"
 // @Success 200 {object} models.HistorialClinico
+// @Header 200 {string} ETag "Versión del historial, requerida en If-Match para modificarlo"
 // @Failure 400 {object} utils.APIErrorResponse
 // @Failure 404 {object} utils.APIErrorResponse
 // @Router /historial/{id} [get]
 func (h *HistorialHandler) GetHistorial(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
@@ -183,15 +165,189 @@ func (h *HistorialHandler) GetHistorial(c *gin.Context) {
 		return
 	}
 
-	historial, err := h.historialService.GetHistorialByID(uint(id))
+	historial, err := h.historialService.GetHistorialByID(actor, uint(id))
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
 		return
 	}
 
+	setETag(c, historial.Version)
 	utils.SuccessResponse(c, historial, "Historial clínico obtenido exitosamente")
 }
 
+// ListHistorial lista historiales clínicos con filtros combinables y paginación por cursor
+// @Summary Listar historiales clínicos
+// @Description Lista los historiales clínicos visibles que cumplen todos los filtros enviados. Se pagina por cursor: la respuesta incluye pagination.next_cursor, que se envía en cursor para obtener la página siguiente con los mismos filtros y orden
+// @Tags historial
+// @Produce json
+// @Security BearerAuth
+// @Param enfermedad query string false "Enfermedad: nombre, código CIE-10 o sinónimo del catálogo"
+// @Param id_enfermedad query int false "ID de la enfermedad en el catálogo"
+// @Param distrito query string false "Distrito del paciente"
+// @Param barrio query string false "Barrio del paciente"
+// @Param id_hospital query int false "ID del hospital"
+// @Param id_paciente query int false "ID del paciente"
+// @Param start_date query string false "Fecha de ingreso desde (YYYY-MM-DD)" format(date)
+// @Param end_date query string false "Fecha de ingreso hasta, inclusive (YYYY-MM-DD)" format(date)
+// @Param is_contagious query bool false "Solo casos contagiosos (true) o no contagiosos (false)"
+// @Param edad_min query int false "Edad mínima del paciente al ingreso"
+// @Param edad_max query int false "Edad máxima del paciente al ingreso"
+// @Param sexo query string false "Sexo del paciente (M, F, O)"
+// @Param bbox query string false "Bounding box: min_lng,min_lat,max_lng,max_lat"
+// @Param lat query number false "Latitud del centro del filtro de radio"
+// @Param lng query number false "Longitud del centro del filtro de radio"
+// @Param radio_km query number false "Radio en km alrededor de lat y lng"
+// @Param sort query string false "Campo de orden, con - para descendente: fecha_ingreso, consultation_date, created_at, enfermedad, patient_district, id" default(-fecha_ingreso)
+// @Param cursor query string false "Cursor de la página siguiente"
+// @Param limit query int false "Elementos por página" default(10)
+// @Success 200 {object} utils.CursorResponse
+// @Failure 400 {object} utils.APIErrorResponse
+// @Router /historial [get]
+func (h *HistorialHandler) ListHistorial(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	filtro, ok := historialFilterDesdeQuery(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	historiales, siguiente, err := h.historialService.ListHistorial(actor, filtro, c.DefaultQuery("sort", "-fecha_ingreso"), c.Query("cursor"), limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrOrdenInvalido):
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_SORT", "Campos permitidos: fecha_ingreso, consultation_date, created_at, enfermedad, patient_district, id")
+		case errors.Is(err, utils.ErrCursorInvalido):
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_CURSOR", "El cursor no corresponde a este orden o está mal formado")
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener historial", "FETCH_ERROR", err.Error())
+		}
+		return
+	}
+
+	utils.CursorSuccessResponse(c, historiales, "Historiales clínicos obtenidos exitosamente", limit, siguiente)
+}
+
+// GetHistorialesCercanos obtiene los historiales más cercanos a un punto
+// @Summary Historiales más cercanos a un punto
+// @Description Obtiene los k historiales clínicos visibles cuya ubicación está más cerca del punto, ordenados por distancia. Acepta los mismos filtros que el listado; con radio_km se descartan los que quedan más lejos
+// @Tags historial
+// @Produce json
+// @Security BearerAuth
+// @Param lat query number true "Latitud"
+// @Param lng query number true "Longitud"
+// @Param k query int false "Cantidad de historiales (máximo 100)" default(10)
+// @Param radio_km query number false "Distancia máxima en km"
+// @Param enfermedad query string false "Enfermedad: nombre, código CIE-10 o sinónimo del catálogo"
+// @Param start_date query string false "Fecha de ingreso desde (YYYY-MM-DD)" format(date)
+// @Param end_date query string false "Fecha de ingreso hasta, inclusive (YYYY-MM-DD)" format(date)
+// @Success 200 {array} services.HistorialCercano
+// @Failure 400 {object} utils.APIErrorResponse
+// @Router /historial/cercanos [get]
+func (h *HistorialHandler) GetHistorialesCercanos(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	lat, lng, ok := queryPunto(c)
+	if !ok {
+		return
+	}
+	k, ok := queryVecinos(c, 10, 100)
+	if !ok {
+		return
+	}
+	filtro, ok := historialFilterDesdeQuery(c)
+	if !ok {
+		return
+	}
+
+	historiales, err := h.historialService.GetHistorialesCercanos(actor, filtro, lat, lng, k)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al buscar historiales cercanos", "SEARCH_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, historiales, "Historiales cercanos obtenidos exitosamente")
+}
+
+// historialFilterDesdeQuery lee los filtros de historial de los parámetros de la consulta; si alguno es
+// inválido responde 400 y retorna false
+func historialFilterDesdeQuery(c *gin.Context) (services.HistorialFilter, bool) {
+	var ok bool
+	filtro := services.HistorialFilter{
+		Enfermedad: c.Query("enfermedad"),
+		Distrito:   c.Query("distrito"),
+		Barrio:     c.Query("barrio"),
+		Sexo:       c.Query("sexo"),
+	}
+
+	if filtro.Sexo != "" && filtro.Sexo != "M" && filtro.Sexo != "F" && filtro.Sexo != "O" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Sexo inválido", "INVALID_FILTER", "Valores permitidos: M, F, O")
+		return filtro, false
+	}
+	if filtro.IDHospital, ok = queryID(c, "id_hospital"); !ok {
+		return filtro, false
+	}
+	if filtro.IDPaciente, ok = queryID(c, "id_paciente"); !ok {
+		return filtro, false
+	}
+	if filtro.IDEnfermedad, ok = queryID(c, "id_enfermedad"); !ok {
+		return filtro, false
+	}
+	if filtro.EdadMin, ok = queryEdad(c, "edad_min"); !ok {
+		return filtro, false
+	}
+	if filtro.EdadMax, ok = queryEdad(c, "edad_max"); !ok {
+		return filtro, false
+	}
+	if filtro.BBox, ok = queryBBox(c, "bbox"); !ok {
+		return filtro, false
+	}
+	if filtro.Radio, ok = queryCirculo(c); !ok {
+		return filtro, false
+	}
+
+	if contagiosoStr := c.Query("is_contagious"); contagiosoStr != "" {
+		contagioso, err := strconv.ParseBool(contagiosoStr)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Filtro is_contagious inválido", "INVALID_FILTER", "Valores permitidos: true, false")
+			return filtro, false
+		}
+		filtro.Contagioso = &contagioso
+	}
+
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Fecha de inicio inválida", "INVALID_DATE", "Formato esperado: YYYY-MM-DD")
+			return filtro, false
+		}
+		filtro.Desde = &parsed
+	}
+
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Fecha de fin inválida", "INVALID_DATE", "Formato esperado: YYYY-MM-DD")
+			return filtro, false
+		}
+		// Incluir todo el día de fin
+		hasta := parsed.AddDate(0, 0, 1)
+		filtro.Hasta = &hasta
+	}
+
+	return filtro, true
+}
+
 // GetHistorialByPaciente obtiene el historial clínico de un paciente específico
 // @Summary Obtener historial por paciente
 // @Description Obtiene todos los registros del historial clínico de un paciente específico
@@ -205,6 +361,11 @@ func (h *HistorialHandler) GetHistorial(c *gin.Context) {
 // @Failure 400 {object} utils.APIErrorResponse
 // @Router /historial/paciente/{paciente_id} [get]
 func (h *HistorialHandler) GetHistorialByPaciente(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
 	pacienteIDParam := c.Param("paciente_id")
 	pacienteID, err := strconv.ParseUint(pacienteIDParam, 10, 32)
 	if err != nil {
@@ -222,7 +383,7 @@ func (h *HistorialHandler) GetHistorialByPaciente(c *gin.Context) {
 		limit = 10
 	}
 
-	historiales, total, err := h.historialService.GetHistorialByPaciente(uint(pacienteID), page, limit)
+	historiales, total, err := h.historialService.GetHistorialByPaciente(actor, uint(pacienteID), page, limit)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener historial", "FETCH_ERROR", err.Error())
 		return
@@ -243,10 +404,9 @@ func (h *HistorialHandler) GetHistorialByPaciente(c *gin.Context) {
 // @Failure 400 {object} utils.APIErrorResponse
 // @Router /historial/hospital [get]
 func (h *HistorialHandler) GetHistorialByHospital(c *gin.Context) {
-	// Obtener ID del hospital del contexto (desde JWT)
-	hospitalID, exists := c.Get("hospital_id")
-	if !exists {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "Hospital no autenticado", "NOT_AUTHENTICATED", "")
+	// Obtener el hospital autenticado del contexto (desde JWT)
+	actor, ok := requireActor(c)
+	if !ok {
 		return
 	}
 
@@ -260,7 +420,7 @@ func (h *HistorialHandler) GetHistorialByHospital(c *gin.Context) {
 		limit = 10
 	}
 
-	historiales, total, err := h.historialService.GetHistorialByHospital(hospitalID.(uint), page, limit)
+	historiales, total, err := h.historialService.GetHistorialByHospital(actor, page, limit)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener historial", "FETCH_ERROR", err.Error())
 		return
@@ -277,12 +437,20 @@ func (h *HistorialHandler) GetHistorialByHospital(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "ID del historial clínico"
+// @Param If-Match header string true "ETag del historial clínico"
 // @Param historial body models.HistorialClinico true "Datos actualizados del historial"
 // @Success 200 {object} utils.APISuccessResponse
 // @Failure 400 {object} utils.APIErrorResponse
 // @Failure 404 {object} utils.APIErrorResponse
+// @Failure 412 {object} utils.APIErrorResponse
+// @Failure 428 {object} utils.APIErrorResponse
 // @Router /historial/{id} [put]
 func (h *HistorialHandler) UpdateHistorial(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
@@ -290,20 +458,100 @@ func (h *HistorialHandler) UpdateHistorial(c *gin.Context) {
 		return
 	}
 
+	ifMatch, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
 	var updates models.HistorialClinico
 	if err := c.ShouldBindJSON(&updates); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
 		return
 	}
 
-	if err := h.historialService.UpdateHistorial(uint(id), &updates); err != nil {
+	historial, err := h.historialService.UpdateHistorial(actor, uint(id), ifMatch, &updates)
+	if err != nil {
+		if versionConflictResponse(c, err) {
+			return
+		}
+		if err.Error() == "historial clínico no encontrado" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+			return
+		}
+		if enfermedadErrorResponse(c, err) {
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al actualizar historial", "UPDATE_ERROR", err.Error())
 		return
 	}
 
+	setETag(c, historial.Version)
 	utils.SuccessResponse(c, nil, "Historial clínico actualizado exitosamente")
 }
 
+// PatchHistorial actualiza parcialmente un historial clínico
+// @Summary Actualizar historial clínico parcialmente
+// @Description Aplica un JSON Merge Patch (RFC 7396): solo se modifican los campos enviados y los enviados como null se vacían. Si cambia patient_address se vuelve a geocodificar
+// @Tags historial
+// @Accept application/merge-patch+json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del historial clínico"
+// @Param If-Match header string true "ETag del historial clínico"
+// @Param patch body object true "Documento JSON Merge Patch"
+// @Success 200 {object} models.HistorialClinico
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Failure 412 {object} utils.APIErrorResponse
+// @Failure 415 {object} utils.APIErrorResponse
+// @Failure 428 {object} utils.APIErrorResponse
+// @Router /historial/{id} [patch]
+func (h *HistorialHandler) PatchHistorial(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	ifMatch, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	patch, ok := readMergePatch(c)
+	if !ok {
+		return
+	}
+
+	historial, addressComponents, err := h.historialService.PatchHistorial(actor, uint(id), ifMatch, patch)
+	if err != nil {
+		patchErrorResponse(c, err, "historial clínico no encontrado", "Error al actualizar historial")
+		return
+	}
+
+	setETag(c, historial.Version)
+
+	if addressComponents != nil {
+		utils.SuccessResponse(c, map[string]interface{}{
+			"historial": historial,
+			"geocoding_info": map[string]interface{}{
+				"formatted_address": addressComponents.FormattedAddress,
+				"coordinates":       addressComponents.Coordinates,
+				"district":          addressComponents.District,
+				"neighborhood":      addressComponents.Neighborhood,
+			},
+		}, "Historial clínico actualizado exitosamente con geocodificación")
+		return
+	}
+
+	utils.SuccessResponse(c, historial, "Historial clínico actualizado exitosamente")
+}
+
 // DeleteHistorial elimina un registro de historial clínico
 // @Summary Eliminar historial clínico
 // @Description Elimina un registro del historial clínico (soft delete)
@@ -311,10 +559,18 @@ func (h *HistorialHandler) UpdateHistorial(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "ID del historial clínico"
+// @Param If-Match header string true "ETag del historial clínico"
 // @Success 200 {object} utils.APISuccessResponse
 // @Failure 400 {object} utils.APIErrorResponse
+// @Failure 412 {object} utils.APIErrorResponse
+// @Failure 428 {object} utils.APIErrorResponse
 // @Router /historial/{id} [delete]
 func (h *HistorialHandler) DeleteHistorial(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
@@ -322,7 +578,19 @@ func (h *HistorialHandler) DeleteHistorial(c *gin.Context) {
 		return
 	}
 
-	if err := h.historialService.DeleteHistorial(uint(id)); err != nil {
+	ifMatch, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	if err := h.historialService.DeleteHistorial(actor, uint(id), ifMatch); err != nil {
+		if versionConflictResponse(c, err) {
+			return
+		}
+		if err.Error() == "historial clínico no encontrado" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al eliminar historial", "DELETE_ERROR", err.Error())
 		return
 	}
@@ -380,6 +648,11 @@ func (h *HistorialHandler) GetEpidemiologicalStats(c *gin.Context) {
 // @Failure 400 {object} utils.APIErrorResponse
 // @Router /epidemiologia/contagious [get]
 func (h *HistorialHandler) GetContagiousHistorial(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 
@@ -390,7 +663,7 @@ func (h *HistorialHandler) GetContagiousHistorial(c *gin.Context) {
 		limit = 10
 	}
 
-	historiales, total, err := h.historialService.GetContagiousHistorial(page, limit)
+	historiales, total, err := h.historialService.GetContagiousHistorial(actor, page, limit)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener casos contagiosos", "FETCH_ERROR", err.Error())
 		return
@@ -458,6 +731,11 @@ func (h *HistorialHandler) EvaluateGeocodePrecision(c *gin.Context) {
 // @Failure 400 {object} utils.APIErrorResponse
 // @Router /historial/enfermedad [get]
 func (h *HistorialHandler) GetHistorialByEnfermedad(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
 	enfermedad := c.Query("enfermedad")
 	if enfermedad == "" {
 		utils.ErrorResponse(c, http.StatusBadRequest, "El parámetro 'enfermedad' es requerido", "MISSING_PARAMETER", "")
@@ -474,7 +752,7 @@ func (h *HistorialHandler) GetHistorialByEnfermedad(c *gin.Context) {
 		limit = 10
 	}
 
-	historiales, total, err := h.historialService.GetHistorialByEnfermedad(enfermedad, page, limit)
+	historiales, total, err := h.historialService.GetHistorialByEnfermedad(actor, enfermedad, page, limit)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener historiales por enfermedad", "FETCH_ERROR", err.Error())
 		return
@@ -495,3 +773,162 @@ func (h *HistorialHandler) GetHistorialByEnfermedad(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// GetRevisiones lista las versiones de un historial clínico
+// @Summary Listar versiones de un historial
+// @Description Obtiene las versiones guardadas de un historial clínico (número de versión, autor y fecha)
+// @Tags historial
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del historial"
+// @Success 200 {array} models.HistorialRevision
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Router /historial/{id}/revisiones [get]
+func (h *HistorialHandler) GetRevisiones(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	revisiones, err := h.historialService.GetRevisiones(actor, uint(id))
+	if err != nil {
+		if err.Error() == "historial clínico no encontrado" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener las versiones", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, revisiones, "Versiones obtenidas exitosamente")
+}
+
+// GetRevision obtiene una versión específica de un historial clínico
+// @Summary Obtener versión de un historial
+// @Description Obtiene la instantánea completa de un historial clínico en una versión
+// @Tags historial
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del historial"
+// @Param version path int true "Número de versión"
+// @Success 200 {object} models.HistorialRevision
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Router /historial/{id}/revisiones/{version} [get]
+func (h *HistorialHandler) GetRevision(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Versión inválida", "INVALID_VERSION", "")
+		return
+	}
+
+	revision, err := h.historialService.GetRevision(actor, uint(id), version)
+	if err != nil {
+		if err.Error() == "historial clínico no encontrado" || err.Error() == "revisión no encontrada" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener la versión", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, revision, "Versión obtenida exitosamente")
+}
+
+// DiffRevisiones compara dos versiones de un historial clínico
+// @Summary Comparar versiones de un historial
+// @Description Obtiene los campos que cambiaron entre dos versiones de un historial clínico
+// @Tags historial
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del historial"
+// @Param desde query int true "Versión inicial"
+// @Param hasta query int true "Versión final"
+// @Success 200 {object} services.RevisionDiff
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Router /historial/{id}/revisiones/diff [get]
+func (h *HistorialHandler) DiffRevisiones(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	desde, errDesde := strconv.Atoi(c.Query("desde"))
+	hasta, errHasta := strconv.Atoi(c.Query("hasta"))
+	if errDesde != nil || errHasta != nil || desde < 1 || hasta < 1 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Los parámetros 'desde' y 'hasta' deben ser números de versión", "INVALID_VERSION", "")
+		return
+	}
+
+	diff, err := h.historialService.DiffRevisiones(actor, uint(id), desde, hasta)
+	if err != nil {
+		if err.Error() == "historial clínico no encontrado" || err.Error() == "revisión no encontrada" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al comparar las versiones", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, diff, "Comparación de versiones obtenida exitosamente")
+}
+
+// geocodingErrorResponse responde un error de geocodificación con su código
+func geocodingErrorResponse(c *gin.Context, err error) {
+	var geocodingErr *services.GeocodingError
+	if !errors.As(err, &geocodingErr) {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error de geocodificación", "GEOCODING_ERROR", err.Error())
+		return
+	}
+
+	statusCode := http.StatusBadRequest
+	if geocodingErr.Code == "GEOCODING_CONFIG_ERROR" {
+		statusCode = http.StatusInternalServerError
+	}
+
+	details := ""
+	if geocodingErr.Err != nil {
+		details = geocodingErr.Err.Error()
+	}
+	utils.ErrorResponse(c, statusCode, geocodingErr.Message, geocodingErr.Code, details)
+}
+
+// enfermedadErrorResponse responde 400 a los errores de validación contra el catálogo de enfermedades.
+// Retorna false si err no es uno de ellos.
+func enfermedadErrorResponse(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrEnfermedadDesconocida):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), "UNKNOWN_DISEASE",
+			"Use el nombre, el código CIE-10 o un sinónimo de GET /enfermedades, o envíe id_enfermedad")
+	case errors.Is(err, services.ErrMotivoContagiosoRequerido):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), "CONTAGION_REASON_REQUIRED", "")
+	default:
+		return false
+	}
+	return true
+}
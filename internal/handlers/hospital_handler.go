@@ -85,7 +85,7 @@ func (h *HospitalHandler) GetHospital(c *gin.Context) {
 
 // GetHospitalesNearby obtiene hospitales cercanos a unas coordenadas
 // @Summary Obtener hospitales cercanos
-// @Description Obtiene hospitales cercanos a unas coordenadas específicas
+// @Description Obtiene los hospitales dentro de un radio de unas coordenadas, del más cercano al más lejano
 // @Tags hospitales
 // @Produce json
 // @Security BearerAuth
@@ -132,6 +132,66 @@ func (h *HospitalHandler) GetHospitalesNearby(c *gin.Context) {
 	utils.SuccessResponse(c, hospitales, "Hospitales cercanos obtenidos exitosamente")
 }
 
+// GetHospitalesMasCercanos obtiene los k hospitales más cercanos a unas coordenadas
+// @Summary Obtener los hospitales más cercanos
+// @Description Obtiene los k hospitales más cercanos a unas coordenadas con su distancia en km, del más cercano al más lejano
+// @Tags hospitales
+// @Produce json
+// @Security BearerAuth
+// @Param lat query number true "Latitud"
+// @Param lng query number true "Longitud"
+// @Param k query int false "Cantidad de hospitales (máximo 50)" default(5)
+// @Success 200 {array} services.HospitalWithDistance
+// @Failure 400 {object} utils.APIErrorResponse
+// @Router /hospitales/nearest [get]
+func (h *HospitalHandler) GetHospitalesMasCercanos(c *gin.Context) {
+	lat, lng, ok := queryPunto(c)
+	if !ok {
+		return
+	}
+	k, ok := queryVecinos(c, 5, 50)
+	if !ok {
+		return
+	}
+
+	hospitales, err := h.hospitalService.GetHospitalesMasCercanos(lat, lng, k)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al buscar hospitales cercanos", "SEARCH_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, hospitales, "Hospitales más cercanos obtenidos exitosamente")
+}
+
+// GetHospitalesEnBBox obtiene los hospitales dentro de un rectángulo geográfico
+// @Summary Obtener hospitales en un área
+// @Description Obtiene los hospitales ubicados dentro de un bounding box, por ejemplo el área visible de un mapa
+// @Tags hospitales
+// @Produce json
+// @Security BearerAuth
+// @Param bbox query string true "Bounding box: min_lng,min_lat,max_lng,max_lat"
+// @Success 200 {array} models.HospitalResponse
+// @Failure 400 {object} utils.APIErrorResponse
+// @Router /hospitales/bbox [get]
+func (h *HospitalHandler) GetHospitalesEnBBox(c *gin.Context) {
+	bbox, ok := queryBBox(c, "bbox")
+	if !ok {
+		return
+	}
+	if bbox == nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Se requiere el parámetro bbox", "MISSING_PARAMETERS", "Formato esperado: min_lng,min_lat,max_lng,max_lat")
+		return
+	}
+
+	hospitales, err := h.hospitalService.GetHospitalesEnBBox(*bbox)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al buscar hospitales", "SEARCH_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, hospitales, "Hospitales del área obtenidos exitosamente")
+}
+
 func (h *HospitalHandler) GetAllHospitalesPublic(c *gin.Context) {
 	hospitales, err := h.hospitalService.GetAllHospitalesSinPaginacion()
 	if err != nil {
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"hospital-api/internal/services"
+	"hospital-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// Tipos de contenido de las capas de mapa
+const (
+	contentTypeGeoJSON = "application/geo+json"
+	contentTypeMVT     = "application/vnd.mapbox-vector-tile"
+)
+
+type MapaHandler struct {
+	mapaService      *services.MapaService
+	mapaCalorService *services.MapaCalorService
+	validator        *validator.Validate
+}
+
+// NewMapaHandler crea una nueva instancia del handler de capas de mapa
+func NewMapaHandler() *MapaHandler {
+	return &MapaHandler{
+		mapaService:      services.NewMapaService(),
+		mapaCalorService: services.NewMapaCalorService(),
+		validator:        validator.New(),
+	}
+}
+
+// GetCasosGeoJSON obtiene los casos como capa GeoJSON
+// @Summary Capa de casos
+// @Description Obtiene los casos visibles como FeatureCollection de GeoJSON (RFC 7946) con un punto por historial, del ingreso más reciente al más antiguo. Acepta los mismos filtros que el listado de historiales. La respuesta es el FeatureCollection sin envoltorio, lista para usar como fuente de un mapa
+// @Tags mapa
+// @Produce json
+// @Security BearerAuth
+// @Param enfermedad query string false "Enfermedad: nombre, código CIE-10 o sinónimo del catálogo"
+// @Param id_enfermedad query int false "ID de la enfermedad en el catálogo"
+// @Param start_date query string false "Fecha de ingreso desde (YYYY-MM-DD)" format(date)
+// @Param end_date query string false "Fecha de ingreso hasta, inclusive (YYYY-MM-DD)" format(date)
+// @Param bbox query string false "Bounding box: min_lng,min_lat,max_lng,max_lat"
+// @Param limit query int false "Cantidad máxima de casos (máximo 50000)" default(10000)
+// @Success 200 {object} utils.FeatureCollection
+// @Failure 400 {object} utils.APIErrorResponse
+// @Router /mapa/casos [get]
+func (h *MapaHandler) GetCasosGeoJSON(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	filtro, ok := historialFilterDesdeQuery(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10000"))
+	if err != nil || limit < 1 || limit > services.LimiteCasosGeoJSON {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Límite inválido", "INVALID_FILTER", "limit debe ser un entero entre 1 y "+strconv.Itoa(services.LimiteCasosGeoJSON))
+		return
+	}
+
+	casos, err := h.mapaService.GetCasosGeoJSON(actor, filtro, limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener casos", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	responderGeoJSON(c, casos)
+}
+
+// GetHospitalesGeoJSON obtiene los hospitales como capa GeoJSON
+// @Summary Capa de hospitales
+// @Description Obtiene los hospitales como FeatureCollection de GeoJSON (RFC 7946), sin envoltorio
+// @Tags mapa
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.FeatureCollection
+// @Router /mapa/hospitales [get]
+func (h *MapaHandler) GetHospitalesGeoJSON(c *gin.Context) {
+	hospitales, err := h.mapaService.GetHospitalesGeoJSON()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener hospitales", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	responderGeoJSON(c, hospitales)
+}
+
+// GetDistritosGeoJSON obtiene los casos agregados por distrito como capa GeoJSON
+// @Summary Capa de casos por distrito
+// @Description Obtiene cada distrito como feature de GeoJSON (RFC 7946) con su contorno oficial, o su centroide si no se importó, y las propiedades total_casos, casos_contagiosos e incidencia_100k de todos los hospitales, sin envoltorio
+// @Tags mapa
+// @Produce json
+// @Security BearerAuth
+// @Param enfermedad query string false "Enfermedad: nombre, código CIE-10 o sinónimo del catálogo"
+// @Param id_enfermedad query int false "ID de la enfermedad en el catálogo"
+// @Param start_date query string false "Fecha de ingreso desde (YYYY-MM-DD)" format(date)
+// @Param end_date query string false "Fecha de ingreso hasta, inclusive (YYYY-MM-DD)" format(date)
+// @Param is_contagious query bool false "Solo casos contagiosos (true) o no contagiosos (false)"
+// @Success 200 {object} utils.FeatureCollection
+// @Failure 400 {object} utils.APIErrorResponse
+// @Router /mapa/distritos [get]
+func (h *MapaHandler) GetDistritosGeoJSON(c *gin.Context) {
+	filtro, ok := historialFilterDesdeQuery(c)
+	if !ok {
+		return
+	}
+
+	distritos, err := h.mapaService.GetDistritosGeoJSON(filtro)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener distritos", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	responderGeoJSON(c, distritos)
+}
+
+// GetMapaCalor estima la densidad de casos de una enfermedad
+// @Summary Mapa de calor por densidad de núcleo
+// @Description Estima la densidad de casos de una enfermedad (KDE) en una grilla regular para la ventana indicada, con todos los hospitales. Por defecto usa los últimos 30 días, celdas de 250 m, ancho de banda de 1000 m y núcleo cuártico. Con incidencia=true cada celda se expresa en casos por 100 mil habitantes según la población de su distrito. Con formato=contornos devuelve curvas de nivel como FeatureCollection de GeoJSON en lugar de la grilla
+// @Tags mapa
+// @Produce json
+// @Security BearerAuth
+// @Param enfermedad query string true "Enfermedad: nombre, código CIE-10 o sinónimo del catálogo"
+// @Param start_date query string false "Fecha de ingreso desde (YYYY-MM-DD)" format(date)
+// @Param end_date query string false "Fecha de ingreso hasta, inclusive (YYYY-MM-DD)" format(date)
+// @Param celda_m query number false "Lado de la celda en metros (25 a 5000)" default(250)
+// @Param ancho_banda_m query number false "Ancho de banda en metros, entre 1 y 20 celdas" default(1000)
+// @Param kernel query string false "Núcleo: cuartico, epanechnikov, gaussiano o uniforme" default(cuartico)
+// @Param incidencia query bool false "Normalizar a casos por 100 mil habitantes del distrito"
+// @Param formato query string false "grilla o contornos" default(grilla)
+// @Param niveles query int false "Cantidad de curvas de nivel en formato contornos (1 a 20)" default(5)
+// @Param bbox query string false "Área de la grilla: min_lng,min_lat,max_lng,max_lat; por defecto la de los distritos registrados"
+// @Success 200 {object} services.MapaCalor
+// @Failure 400 {object} utils.APIErrorResponse
+// @Router /mapa/calor [get]
+func (h *MapaHandler) GetMapaCalor(c *gin.Context) {
+	var req services.MapaCalorRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Parámetros inválidos", "INVALID_INPUT", err.Error())
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	var ok bool
+	if req.BBox, ok = queryBBox(c, "bbox"); !ok {
+		return
+	}
+
+	mapa, err := h.mapaCalorService.GenerarMapaCalor(req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrParametrosMapaCalor):
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_PARAMETER", "")
+		case errors.Is(err, services.ErrGrillaMapaCalor):
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), "GRID_TOO_LARGE", "Reduzca el área con bbox o aumente celda_m")
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Error al generar el mapa de calor", "ANALYSIS_ERROR", err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, mapa, "Mapa de calor generado exitosamente")
+}
+
+// GetTileCasos obtiene un vector tile de casos
+// @Summary Vector tile de casos
+// @Description Obtiene el tile z/x/y (esquema XYZ de Web Mercator) en formato Mapbox Vector Tile con la capa "casos". Desde el zoom 14 cada caso es un punto con id_enfermedad, enfermedad, is_contagious y fecha_ingreso; con menos zoom los casos cercanos se agrupan en puntos con las propiedades casos y contagiosos. Acepta los mismos filtros que el listado de historiales. Un tile sin casos responde 204
+// @Tags mapa
+// @Produce application/vnd.mapbox-vector-tile
+// @Security BearerAuth
+// @Param z path int true "Zoom (0 a 22)"
+// @Param x path int true "Columna del tile"
+// @Param y path string true "Fila del tile con extensión .mvt"
+// @Param enfermedad query string false "Enfermedad: nombre, código CIE-10 o sinónimo del catálogo"
+// @Param start_date query string false "Fecha de ingreso desde (YYYY-MM-DD)" format(date)
+// @Param end_date query string false "Fecha de ingreso hasta, inclusive (YYYY-MM-DD)" format(date)
+// @Success 200 {file} binary
+// @Success 204
+// @Failure 400 {object} utils.APIErrorResponse
+// @Router /tiles/{z}/{x}/{y}.mvt [get]
+func (h *MapaHandler) GetTileCasos(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	z, x, y, ok := tileDesdeRuta(c)
+	if !ok {
+		return
+	}
+	filtro, ok := historialFilterDesdeQuery(c)
+	if !ok {
+		return
+	}
+
+	tile, err := h.mapaService.GetTileCasos(actor, filtro, z, x, y)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al generar el tile", "TILE_ERROR", err.Error())
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=60")
+	if len(tile) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.Data(http.StatusOK, contentTypeMVT, tile)
+}
+
+// tileDesdeRuta lee z, x e y de la ruta /tiles/:z/:x/:y, donde y lleva la extensión .mvt; si el tile no
+// existe en el esquema XYZ responde 400 y retorna false
+func tileDesdeRuta(c *gin.Context) (z, x, y int, ok bool) {
+	yStr, esMVT := strings.CutSuffix(c.Param("y"), ".mvt")
+
+	z, errZ := strconv.Atoi(c.Param("z"))
+	x, errX := strconv.Atoi(c.Param("x"))
+	y, errY := strconv.Atoi(yStr)
+	if !esMVT || errZ != nil || errX != nil || errY != nil || z < 0 || z > services.MaxZoomTiles ||
+		x < 0 || x >= 1<<z || y < 0 || y >= 1<<z {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Tile inválido", "INVALID_TILE",
+			"Formato esperado: /tiles/{z}/{x}/{y}.mvt con z entre 0 y "+strconv.Itoa(services.MaxZoomTiles)+", y x e y entre 0 y 2^z - 1")
+		return 0, 0, 0, false
+	}
+	return z, x, y, true
+}
+
+// responderGeoJSON escribe un FeatureCollection como application/geo+json
+func responderGeoJSON(c *gin.Context, coleccion *utils.FeatureCollection) {
+	c.Header("Content-Type", contentTypeGeoJSON)
+	c.JSON(http.StatusOK, coleccion)
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"hospital-api/internal/models"
+	"hospital-api/internal/services"
+	"hospital-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificacionHandler struct {
+	notificacionService *services.NotificacionService
+}
+
+// NewNotificacionHandler crea una nueva instancia del handler de notificaciones epidemiológicas
+func NewNotificacionHandler() *NotificacionHandler {
+	return &NotificacionHandler{
+		notificacionService: services.NewNotificacionService(),
+	}
+}
+
+// GetNotificaciones lista la bandeja de notificaciones epidemiológicas
+// @Summary Bandeja de notificaciones obligatorias
+// @Description Lista las notificaciones de enfermedades de notificación obligatoria. Los epidemiólogos ven las de todos los hospitales; el personal clínico, las de su hospital.
+// @Tags notificaciones
+// @Produce json
+// @Security BearerAuth
+// @Param estado query string false "Estado: pendiente, enviada o recibida"
+// @Param id_enfermedad query int false "ID de la enfermedad en el catálogo"
+// @Param id_hospital query int false "ID del hospital que notifica"
+// @Param start_date query string false "Generadas desde (YYYY-MM-DD)" format(date)
+// @Param end_date query string false "Generadas hasta, inclusive (YYYY-MM-DD)" format(date)
+// @Param page query int false "Número de página" default(1)
+// @Param limit query int false "Elementos por página" default(10)
+// @Success 200 {object} utils.PaginatedResponse
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 403 {object} utils.APIErrorResponse
+// @Router /notificaciones [get]
+func (h *NotificacionHandler) GetNotificaciones(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	filtro := services.NotificacionFilter{Estado: c.Query("estado")}
+	switch filtro.Estado {
+	case "", models.NotificacionPendiente, models.NotificacionEnviada, models.NotificacionRecibida:
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Estado inválido", "INVALID_FILTER", "Valores permitidos: pendiente, enviada, recibida")
+		return
+	}
+
+	if filtro.IDEnfermedad, ok = queryID(c, "id_enfermedad"); !ok {
+		return
+	}
+	if filtro.IDHospital, ok = queryID(c, "id_hospital"); !ok {
+		return
+	}
+
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Fecha de inicio inválida", "INVALID_DATE", "Formato esperado: YYYY-MM-DD")
+			return
+		}
+		filtro.Desde = &parsed
+	}
+
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Fecha de fin inválida", "INVALID_DATE", "Formato esperado: YYYY-MM-DD")
+			return
+		}
+		// Incluir todo el día de fin
+		hasta := parsed.AddDate(0, 0, 1)
+		filtro.Hasta = &hasta
+	}
+
+	notificaciones, total, err := h.notificacionService.GetNotificaciones(actor, filtro, page, limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener notificaciones", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, notificaciones, "Notificaciones obtenidas exitosamente", page, limit, total)
+}
+
+// GetNotificacion obtiene una notificación epidemiológica
+// @Summary Obtener notificación
+// @Description Obtiene una notificación con su historial clínico, enfermedad y hospital
+// @Tags notificaciones
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la notificación"
+// @Success 200 {object} models.NotificacionEpidemiologica
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Router /notificaciones/{id} [get]
+func (h *NotificacionHandler) GetNotificacion(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	notificacion, err := h.notificacionService.GetNotificacionByID(actor, uint(id))
+	if err != nil {
+		if err.Error() == "notificación no encontrada" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener notificación", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, notificacion, "Notificación obtenida exitosamente")
+}
+
+// EnviarNotificacion envía una notificación pendiente al equipo de vigilancia
+// @Summary Enviar notificación
+// @Description Marca como enviada una notificación pendiente del hospital (solo médicos)
+// @Tags notificaciones
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la notificación"
+// @Param body body services.CambioEstadoNotificacionRequest false "Observaciones"
+// @Success 200 {object} models.NotificacionEpidemiologica
+// @Failure 404 {object} utils.APIErrorResponse
+// @Failure 409 {object} utils.APIErrorResponse
+// @Router /notificaciones/{id}/enviar [post]
+func (h *NotificacionHandler) EnviarNotificacion(c *gin.Context) {
+	h.cambiarEstado(c, h.notificacionService.EnviarNotificacion, "Notificación enviada exitosamente")
+}
+
+// AcusarRecibo registra el acuse de recibo de una notificación enviada
+// @Summary Acusar recibo de notificación
+// @Description Marca como recibida una notificación enviada (solo epidemiólogos)
+// @Tags notificaciones
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la notificación"
+// @Param body body services.CambioEstadoNotificacionRequest false "Observaciones"
+// @Success 200 {object} models.NotificacionEpidemiologica
+// @Failure 404 {object} utils.APIErrorResponse
+// @Failure 409 {object} utils.APIErrorResponse
+// @Router /notificaciones/{id}/acuse [post]
+func (h *NotificacionHandler) AcusarRecibo(c *gin.Context) {
+	h.cambiarEstado(c, h.notificacionService.AcusarRecibo, "Acuse de recibo registrado exitosamente")
+}
+
+// cambiarEstado resuelve una transición de estado de notificación con el cuerpo opcional de observaciones
+func (h *NotificacionHandler) cambiarEstado(c *gin.Context, transicion func(services.Actor, uint, string) (*models.NotificacionEpidemiologica, error), message string) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	var request services.CambioEstadoNotificacionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
+			return
+		}
+	}
+
+	notificacion, err := transicion(actor, uint(id), request.Observaciones)
+	if err != nil {
+		if err.Error() == "notificación no encontrada" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+			return
+		}
+		if errors.Is(err, services.ErrTransicionNotificacion) {
+			utils.ErrorResponse(c, http.StatusConflict, err.Error(), "INVALID_STATE", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al actualizar notificación", "UPDATE_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, notificacion, message)
+}
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"hospital-api/internal/models"
 	"hospital-api/internal/services"
@@ -13,15 +15,17 @@ import (
 )
 
 type PacienteHandler struct {
-	pacienteService *services.PacienteService
-	validator       *validator.Validate
+	pacienteService  *services.PacienteService
+	duplicadoService *services.DuplicadoService
+	validator        *validator.Validate
 }
 
 // NewPacienteHandler crea una nueva instancia del handler de pacientes
 func NewPacienteHandler() *PacienteHandler {
 	return &PacienteHandler{
-		pacienteService: services.NewPacienteService(),
-		validator:       validator.New(),
+		pacienteService:  services.NewPacienteService(),
+		duplicadoService: services.NewDuplicadoService(),
+		validator:        validator.New(),
 	}
 }
 
@@ -38,6 +42,11 @@ func NewPacienteHandler() *PacienteHandler {
 // @Failure 401 {object} utils.APIErrorResponse
 // @Router /pacientes [post]
 func (h *PacienteHandler) CreatePaciente(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
 	var paciente models.Paciente
 
 	// Bind JSON
@@ -53,7 +62,7 @@ func (h *PacienteHandler) CreatePaciente(c *gin.Context) {
 	}
 
 	// Crear paciente
-	if err := h.pacienteService.CreatePaciente(&paciente); err != nil {
+	if err := h.pacienteService.CreatePaciente(actor, &paciente); err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al crear paciente", "CREATE_ERROR", err.Error())
 		return
 	}
@@ -69,10 +78,16 @@ func (h *PacienteHandler) CreatePaciente(c *gin.Context) {
 // @Security BearerAuth
 // @Param id path int true "ID del paciente"
 // @Success 200 {object} models.Paciente
+// @Header 200 {string} ETag "Versión del paciente, requerida en If-Match para modificarlo"
 // @Failure 400 {object} utils.APIErrorResponse
 // @Failure 404 {object} utils.APIErrorResponse
 // @Router /pacientes/{id} [get]
 func (h *PacienteHandler) GetPaciente(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
@@ -80,12 +95,13 @@ func (h *PacienteHandler) GetPaciente(c *gin.Context) {
 		return
 	}
 
-	paciente, err := h.pacienteService.GetPacienteByID(uint(id))
+	paciente, err := h.pacienteService.GetPacienteByID(actor, uint(id))
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
 		return
 	}
 
+	setETag(c, paciente.Version)
 	utils.SuccessResponse(c, paciente, "Paciente obtenido exitosamente")
 }
 
@@ -101,6 +117,11 @@ func (h *PacienteHandler) GetPaciente(c *gin.Context) {
 // @Failure 400 {object} utils.APIErrorResponse
 // @Router /pacientes [get]
 func (h *PacienteHandler) GetAllPacientes(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 
@@ -111,7 +132,7 @@ func (h *PacienteHandler) GetAllPacientes(c *gin.Context) {
 		limit = 10
 	}
 
-	pacientes, total, err := h.pacienteService.GetAllPacientes(page, limit)
+	pacientes, total, err := h.pacienteService.GetAllPacientes(actor, page, limit)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener pacientes", "FETCH_ERROR", err.Error())
 		return
@@ -122,18 +143,26 @@ func (h *PacienteHandler) GetAllPacientes(c *gin.Context) {
 
 // UpdatePaciente actualiza un paciente
 // @Summary Actualizar paciente
-// @Description Actualiza los datos de un paciente existente
+// @Description Actualiza los datos de un paciente registrado por el hospital del usuario
 // @Tags pacientes
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "ID del paciente"
+// @Param If-Match header string true "ETag del paciente"
 // @Param paciente body models.Paciente true "Datos actualizados del paciente"
 // @Success 200 {object} utils.APISuccessResponse
 // @Failure 400 {object} utils.APIErrorResponse
 // @Failure 404 {object} utils.APIErrorResponse
+// @Failure 412 {object} utils.APIErrorResponse
+// @Failure 428 {object} utils.APIErrorResponse
 // @Router /pacientes/{id} [put]
 func (h *PacienteHandler) UpdatePaciente(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
@@ -141,6 +170,11 @@ func (h *PacienteHandler) UpdatePaciente(c *gin.Context) {
 		return
 	}
 
+	ifMatch, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
 	var updates models.Paciente
 	if err := c.ShouldBindJSON(&updates); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
@@ -150,26 +184,92 @@ func (h *PacienteHandler) UpdatePaciente(c *gin.Context) {
 	// Validar datos (omitir validaciones required para updates parciales)
 	// Aquí podrías implementar validaciones específicas para updates
 
-	if err := h.pacienteService.UpdatePaciente(uint(id), &updates); err != nil {
+	paciente, err := h.pacienteService.UpdatePaciente(actor, uint(id), ifMatch, &updates)
+	if err != nil {
+		if versionConflictResponse(c, err) {
+			return
+		}
+		if err.Error() == "paciente no encontrado" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al actualizar paciente", "UPDATE_ERROR", err.Error())
 		return
 	}
 
+	setETag(c, paciente.Version)
 	utils.SuccessResponse(c, nil, "Paciente actualizado exitosamente")
 }
 
+// PatchPaciente actualiza parcialmente un paciente
+// @Summary Actualizar paciente parcialmente
+// @Description Aplica un JSON Merge Patch (RFC 7396) a un paciente registrado por el hospital del usuario: solo se modifican los campos enviados y los enviados como null se vacían. El resultado se valida completo
+// @Tags pacientes
+// @Accept application/merge-patch+json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del paciente"
+// @Param If-Match header string true "ETag del paciente"
+// @Param patch body object true "Documento JSON Merge Patch"
+// @Success 200 {object} models.Paciente
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Failure 412 {object} utils.APIErrorResponse
+// @Failure 415 {object} utils.APIErrorResponse
+// @Failure 428 {object} utils.APIErrorResponse
+// @Router /pacientes/{id} [patch]
+func (h *PacienteHandler) PatchPaciente(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	ifMatch, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	patch, ok := readMergePatch(c)
+	if !ok {
+		return
+	}
+
+	paciente, err := h.pacienteService.PatchPaciente(actor, uint(id), ifMatch, patch)
+	if err != nil {
+		patchErrorResponse(c, err, "paciente no encontrado", "Error al actualizar paciente")
+		return
+	}
+
+	setETag(c, paciente.Version)
+	utils.SuccessResponse(c, paciente, "Paciente actualizado exitosamente")
+}
+
 // DeletePaciente elimina un paciente
 // @Summary Eliminar paciente
-// @Description Elimina un paciente del sistema (soft delete)
+// @Description Elimina un paciente registrado por el hospital del usuario (soft delete)
 // @Tags pacientes
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "ID del paciente"
+// @Param If-Match header string true "ETag del paciente"
 // @Success 200 {object} utils.APISuccessResponse
 // @Failure 400 {object} utils.APIErrorResponse
 // @Failure 404 {object} utils.APIErrorResponse
+// @Failure 412 {object} utils.APIErrorResponse
+// @Failure 428 {object} utils.APIErrorResponse
 // @Router /pacientes/{id} [delete]
 func (h *PacienteHandler) DeletePaciente(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
@@ -177,7 +277,19 @@ func (h *PacienteHandler) DeletePaciente(c *gin.Context) {
 		return
 	}
 
-	if err := h.pacienteService.DeletePaciente(uint(id)); err != nil {
+	ifMatch, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	if err := h.pacienteService.DeletePaciente(actor, uint(id), ifMatch); err != nil {
+		if versionConflictResponse(c, err) {
+			return
+		}
+		if err.Error() == "paciente no encontrado" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al eliminar paciente", "DELETE_ERROR", err.Error())
 		return
 	}
@@ -185,22 +297,63 @@ func (h *PacienteHandler) DeletePaciente(c *gin.Context) {
 	utils.SuccessResponse(c, nil, "Paciente eliminado exitosamente")
 }
 
-// SearchPacientes busca pacientes por nombre
+// SearchPacientes busca pacientes por nombre o CI con filtros
 // @Summary Buscar pacientes
-// @Description Busca pacientes por nombre con paginación
+// @Description Busca pacientes por nombre (sin distinguir mayúsculas ni acentos, tolerando errores de escritura) o por CI, ordenados por relevancia. Cada resultado incluye el hospital de su última atención y las coincidencias resaltadas con <mark>. Se requiere q o al menos un filtro
 // @Tags pacientes
 // @Produce json
 // @Security BearerAuth
-// @Param q query string true "Término de búsqueda"
+// @Param q query string false "Nombre o CI"
+// @Param sexo query string false "Sexo (M, F, O)"
+// @Param tipo_sangre query string false "Tipo de sangre"
+// @Param edad_min query int false "Edad mínima en años"
+// @Param edad_max query int false "Edad máxima en años"
+// @Param ultimo_hospital query int false "ID del hospital de la última atención"
 // @Param page query int false "Número de página" default(1)
 // @Param limit query int false "Elementos por página" default(10)
 // @Success 200 {object} utils.PaginatedResponse
 // @Failure 400 {object} utils.APIErrorResponse
 // @Router /pacientes/search [get]
 func (h *PacienteHandler) SearchPacientes(c *gin.Context) {
-	query := c.Query("q")
-	if query == "" {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Término de búsqueda requerido", "MISSING_QUERY", "")
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	filtro := services.PacienteSearchFilter{
+		Q:          strings.TrimSpace(c.Query("q")),
+		Sexo:       c.Query("sexo"),
+		TipoSangre: c.Query("tipo_sangre"),
+	}
+
+	if filtro.Sexo != "" && filtro.Sexo != "M" && filtro.Sexo != "F" && filtro.Sexo != "O" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Sexo inválido", "INVALID_FILTER", "Valores permitidos: M, F, O")
+		return
+	}
+
+	if filtro.EdadMin, ok = queryEdad(c, "edad_min"); !ok {
+		return
+	}
+	if filtro.EdadMax, ok = queryEdad(c, "edad_max"); !ok {
+		return
+	}
+	if filtro.EdadMin != nil && filtro.EdadMax != nil && *filtro.EdadMin > *filtro.EdadMax {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Rango de edad inválido", "INVALID_FILTER", "edad_min no puede ser mayor que edad_max")
+		return
+	}
+
+	if hospitalStr := c.Query("ultimo_hospital"); hospitalStr != "" {
+		hospitalID, err := strconv.ParseUint(hospitalStr, 10, 32)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "ID de hospital inválido", "INVALID_ID", "")
+			return
+		}
+		id := uint(hospitalID)
+		filtro.UltimoHospital = &id
+	}
+
+	if filtro.Q == "" && filtro.Sexo == "" && filtro.TipoSangre == "" && filtro.EdadMin == nil && filtro.EdadMax == nil && filtro.UltimoHospital == nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Término de búsqueda o filtro requerido", "MISSING_QUERY", "")
 		return
 	}
 
@@ -214,7 +367,7 @@ func (h *PacienteHandler) SearchPacientes(c *gin.Context) {
 		limit = 10
 	}
 
-	pacientes, total, err := h.pacienteService.SearchPacientes(query, page, limit)
+	pacientes, total, err := h.pacienteService.SearchPacientes(actor, filtro, page, limit)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error en la búsqueda", "SEARCH_ERROR", err.Error())
 		return
@@ -222,3 +375,179 @@ func (h *PacienteHandler) SearchPacientes(c *gin.Context) {
 
 	utils.PaginatedSuccessResponse(c, pacientes, "Búsqueda completada exitosamente", page, limit, total)
 }
+
+// GetPacientesSinHospital lista los pacientes sin hospital de registro
+// @Summary Pacientes sin hospital
+// @Description Lista los pacientes sin hospital de registro ni historiales clínicos, creados antes de que se registrara el hospital de cada paciente. Ningún hospital puede verlos hasta que un administrador los reclama
+// @Tags pacientes
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Número de página" default(1)
+// @Param limit query int false "Elementos por página" default(10)
+// @Success 200 {object} utils.PaginatedResponse
+// @Router /pacientes/unassigned [get]
+func (h *PacienteHandler) GetPacientesSinHospital(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	pacientes, total, err := h.pacienteService.GetPacientesSinHospital(actor, page, limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener pacientes", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, pacientes, "Pacientes sin hospital obtenidos exitosamente", page, limit, total)
+}
+
+// ReclamarPaciente asigna un paciente sin hospital al hospital del administrador
+// @Summary Reclamar paciente
+// @Description Registra en el hospital del administrador un paciente sin hospital de registro, que desde entonces es visible para su personal
+// @Tags pacientes
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del paciente"
+// @Success 200 {object} models.Paciente
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Failure 409 {object} utils.APIErrorResponse
+// @Router /pacientes/{id}/claim [post]
+func (h *PacienteHandler) ReclamarPaciente(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	paciente, err := h.pacienteService.ReclamarPaciente(actor, uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrPacienteConHospital) {
+			utils.ErrorResponse(c, http.StatusConflict, err.Error(), "ALREADY_ASSIGNED", "")
+			return
+		}
+		if err.Error() == "paciente no encontrado" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al reclamar paciente", "UPDATE_ERROR", err.Error())
+		return
+	}
+
+	setETag(c, paciente.Version)
+	utils.SuccessResponse(c, paciente, "Paciente reclamado exitosamente")
+}
+
+// GetDuplicados lista pares de pacientes que probablemente son la misma persona
+// @Summary Reporte de pacientes duplicados
+// @Description Compara los pacientes visibles para el hospital con los de toda la red que comparten fecha de nacimiento o CI, y lista los pares cuyo puntaje (nombre aproximado, fecha de nacimiento y CI) alcanza el umbral, ordenados de mayor a menor. Si el otro paciente del par no es visible para el hospital se devuelve enmascarado en duplicado_enmascarado (iniciales, final del CI, año de nacimiento y sexo)
+// @Tags pacientes
+// @Produce json
+// @Security BearerAuth
+// @Param umbral query number false "Puntaje mínimo entre 0.5 y 1" default(0.85)
+// @Param page query int false "Número de página" default(1)
+// @Param limit query int false "Elementos por página" default(10)
+// @Success 200 {object} utils.PaginatedResponse
+// @Failure 400 {object} utils.APIErrorResponse
+// @Router /pacientes/duplicates [get]
+func (h *PacienteHandler) GetDuplicados(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	umbral := services.UmbralDuplicado
+	if umbralParam := c.Query("umbral"); umbralParam != "" {
+		parsed, err := strconv.ParseFloat(umbralParam, 64)
+		if err != nil || parsed < 0.5 || parsed > 1 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Umbral inválido", "INVALID_THRESHOLD", "El umbral debe ser un número entre 0.5 y 1")
+			return
+		}
+		umbral = parsed
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	candidatos, total, err := h.duplicadoService.FindDuplicados(actor, umbral, page, limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al buscar duplicados", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, candidatos, "Posibles duplicados obtenidos exitosamente", page, limit, total)
+}
+
+// FusionarPacientes fusiona un paciente duplicado en otro
+// @Summary Fusionar pacientes
+// @Description Mueve los historiales clínicos del duplicado al superviviente, completa los datos de identidad vacíos del superviviente y elimina el duplicado. Un médico solo puede fusionar pacientes visibles para su hospital; administradores y epidemiólogos pueden fusionar pacientes de otros hospitales cuando su puntaje alcanza el umbral de duplicados
+// @Tags pacientes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param fusion body services.FusionRequest true "Pacientes a fusionar"
+// @Success 200 {object} services.FusionResultado
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 403 {object} utils.APIErrorResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Failure 409 {object} utils.APIErrorResponse
+// @Router /pacientes/merge [post]
+func (h *PacienteHandler) FusionarPacientes(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	var req services.FusionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	resultado, err := h.duplicadoService.FusionarPacientes(actor, req)
+	if err != nil {
+		if errors.Is(err, services.ErrPacientesNoDuplicados) {
+			utils.ErrorResponse(c, http.StatusConflict, err.Error(), "NOT_DUPLICATE", "")
+			return
+		}
+		if errors.Is(err, services.ErrFusionNoAutorizada) {
+			utils.ErrorResponse(c, http.StatusForbidden, err.Error(), "FORBIDDEN", "")
+			return
+		}
+		if err.Error() == "paciente no encontrado" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al fusionar pacientes", "MERGE_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, resultado, "Pacientes fusionados exitosamente")
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+
+	"hospital-api/internal/services"
+	"hospital-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// readMergePatch lee el cuerpo de un PATCH. Acepta application/merge-patch+json (RFC 7396) y application/json.
+// Si el cuerpo no es válido responde el error y retorna false.
+func readMergePatch(c *gin.Context) ([]byte, bool) {
+	mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if err != nil || (mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+		utils.ErrorResponse(c, http.StatusUnsupportedMediaType, "Content-Type no soportado", "UNSUPPORTED_MEDIA_TYPE", "Use application/merge-patch+json")
+		return nil, false
+	}
+
+	patch, err := c.GetRawData()
+	if err != nil || len(patch) == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", "El cuerpo del PATCH está vacío")
+		return nil, false
+	}
+
+	return patch, true
+}
+
+// patchErrorResponse responde los errores comunes al aplicar un merge patch
+func patchErrorResponse(c *gin.Context, err error, notFoundMessage, message string) {
+	var validationErrors validator.ValidationErrors
+	var geocodingErr *services.GeocodingError
+
+	if versionConflictResponse(c, err) || enfermedadErrorResponse(c, err) {
+		return
+	}
+
+	switch {
+	case err.Error() == notFoundMessage:
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+	case errors.Is(err, services.ErrPatchInvalido):
+		utils.ErrorResponse(c, http.StatusBadRequest, "Merge patch inválido", "INVALID_PATCH", err.Error())
+	case errors.As(err, &validationErrors):
+		utils.ValidationErrorResponse(c, validationErrors)
+	case errors.As(err, &geocodingErr):
+		geocodingErrorResponse(c, err)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, "UPDATE_ERROR", err.Error())
+	}
+}
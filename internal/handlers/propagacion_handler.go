@@ -1,13 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 		"fmt"
 	"math"
+	"sort"
 	"strings"
 	"time"
 
+	"hospital-api/internal/models"
 	"hospital-api/internal/services"
 	"hospital-api/internal/utils"
 
@@ -18,6 +21,7 @@ import (
 
 type PropagacionHandler struct {
 	propagacionService *services.PropagacionService
+	distritoService    *services.DistritoService
 	validator          *validator.Validate
 }
 
@@ -25,6 +29,7 @@ type PropagacionHandler struct {
 func NewPropagacionHandler() *PropagacionHandler {
 	return &PropagacionHandler{
 		propagacionService: services.NewPropagacionService(),
+		distritoService:    services.NewDistritoService(),
 		validator:          validator.New(),
 	}
 }
@@ -37,6 +42,9 @@ func NewPropagacionHandler() *PropagacionHandler {
 // @Security BearerAuth
 // @Param enfermedad query string true "Nombre de la enfermedad"
 // @Param dias query int false "Días de análisis histórico" default(30)
+// @Param horizonte query int false "Días a pronosticar con el modelo SEIR" default(7)
+// @Param periodo_latencia query number false "Días promedio de latencia del modelo SEIR (por defecto, el punto medio de la incubación del catálogo)"
+// @Param periodo_infeccioso query number false "Días promedio del periodo infeccioso del modelo SEIR (por defecto, el del catálogo)"
 // @Success 200 {object} services.VelocidadPropagacion
 // @Failure 400 {object} utils.APIErrorResponse
 // @Failure 500 {object} utils.APIErrorResponse
@@ -55,7 +63,17 @@ func (h *PropagacionHandler) AnalyzeSpreadVelocity(c *gin.Context) {
 		return
 	}
 
-	analisis, err := h.propagacionService.AnalyzeSpreadVelocity(enfermedad, dias)
+	horizonte, ok := parseHorizonte(c)
+	if !ok {
+		return
+	}
+
+	periodos, ok := parsePeriodosSEIR(c)
+	if !ok {
+		return
+	}
+
+	analisis, err := h.propagacionService.AnalyzeSpreadVelocity(enfermedad, dias, horizonte, periodos)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al analizar velocidad de propagación", "ANALYSIS_ERROR", err.Error())
 		return
@@ -73,6 +91,9 @@ func (h *PropagacionHandler) AnalyzeSpreadVelocity(c *gin.Context) {
 // @Param distrito path string true "Nombre del distrito"
 // @Param enfermedad query string true "Nombre de la enfermedad"
 // @Param dias query int false "Días de análisis histórico" default(30)
+// @Param horizonte query int false "Días a pronosticar con el modelo SEIR" default(7)
+// @Param periodo_latencia query number false "Días promedio de latencia del modelo SEIR (por defecto, el punto medio de la incubación del catálogo)"
+// @Param periodo_infeccioso query number false "Días promedio del periodo infeccioso del modelo SEIR (por defecto, el del catálogo)"
 // @Success 200 {object} services.PrediccionPropagacion
 // @Failure 400 {object} utils.APIErrorResponse
 // @Failure 404 {object} utils.APIErrorResponse
@@ -97,7 +118,17 @@ func (h *PropagacionHandler) GetDistrictPrediction(c *gin.Context) {
 		return
 	}
 
-	prediccion, err := h.propagacionService.GetSpreadPredictionsByDistrict(distrito, enfermedad, dias)
+	horizonte, ok := parseHorizonte(c)
+	if !ok {
+		return
+	}
+
+	periodos, ok := parsePeriodosSEIR(c)
+	if !ok {
+		return
+	}
+
+	prediccion, err := h.propagacionService.GetSpreadPredictionsByDistrict(distrito, enfermedad, dias, horizonte, periodos)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "No se encontraron predicciones para el distrito especificado", "NOT_FOUND", err.Error())
 		return
@@ -145,7 +176,7 @@ func (h *PropagacionHandler) GetSpreadComparison(c *gin.Context) {
 	for _, enfermedad := range enfermedades {
 		enfermedad = strings.TrimSpace(enfermedad)
 		if enfermedad != "" {
-			analisis, err := h.propagacionService.AnalyzeSpreadVelocity(enfermedad, dias)
+			analisis, err := h.propagacionService.AnalyzeSpreadVelocity(enfermedad, dias, services.HorizontePrediccionDefecto, services.PeriodosSEIR{})
 			if err != nil {
 				comparacion[enfermedad] = map[string]string{
 					"error": err.Error(),
@@ -173,13 +204,25 @@ func (h *PropagacionHandler) GetSpreadComparison(c *gin.Context) {
 // @Success 200 {object} map[string]interface{}
 // @Router /propagacion/densidad [get]
 func (h *PropagacionHandler) GetDensityAnalysis(c *gin.Context) {
+	distritos, err := h.distritoService.GetDistritos()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener distritos", "DATABASE_ERROR", err.Error())
+		return
+	}
+
+	// Ordenar por densidad descendente
+	sort.Slice(distritos, func(i, j int) bool {
+		return distritos[i].Densidad > distritos[j].Densidad
+	})
+
 	// Obtener datos de densidad poblacional
+	estadisticas := h.calcularEstadisticasGenerales(distritos)
 	densityData := map[string]interface{}{
-		"ciudad": "Santa Cruz de la Sierra",
+		"ciudad": services.CiudadDistritos,
 		"fecha_analisis": time.Now().Format("2006-01-02"),
-		"distritos": h.obtenerDatosDensidad(),
-		"estadisticas_generales": h.calcularEstadisticasGenerales(),
-		"recomendaciones_vigilancia": h.generarRecomendacionesVigilancia(),
+		"distritos": h.obtenerDatosDensidad(distritos, estadisticas["densidad_promedio"].(int)),
+		"estadisticas_generales": estadisticas,
+		"recomendaciones_vigilancia": h.generarRecomendacionesVigilancia(distritos),
 	}
 
 	utils.SuccessResponse(c, densityData, "Análisis de densidad poblacional obtenido exitosamente")
@@ -206,7 +249,13 @@ func (h *PropagacionHandler) GetSpreadRoutes(c *gin.Context) {
 	origen := c.Query("origen")
 	dias := 30
 
-	analisis, err := h.propagacionService.AnalyzeSpreadVelocity(enfermedad, dias)
+	matriz, err := h.distritoService.GetMatrizConectividad()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener distritos", "DATABASE_ERROR", err.Error())
+		return
+	}
+
+	analisis, err := h.propagacionService.AnalyzeSpreadVelocity(enfermedad, dias, services.HorizontePrediccionDefecto, services.PeriodosSEIR{})
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al analizar rutas de propagación", "ANALYSIS_ERROR", err.Error())
 		return
@@ -230,15 +279,159 @@ func (h *PropagacionHandler) GetSpreadRoutes(c *gin.Context) {
 		"origen_filtro": origen,
 		"total_rutas": len(rutas),
 		"rutas_propagacion": rutas,
-		"matriz_conectividad": h.generarMatrizConectividad(),
+		"matriz_conectividad": matriz,
 		"recomendaciones": h.generarRecomendacionesRutas(rutas),
 	}
 
 	utils.SuccessResponse(c, response, "Rutas de propagación obtenidas exitosamente")
 }
 
+// DetectAberrations detecta días con más casos de lo esperado mediante algoritmos de vigilancia estándar
+// @Summary Detección estadística de brotes
+// @Description Aplica EARS C1/C2/C3, CUSUM y una línea base de tipo Farrington a los casos diarios de una enfermedad por distrito y retorna los días señalados con su umbral y p-valor
+// @Tags propagacion
+// @Produce json
+// @Security BearerAuth
+// @Param enfermedad query string true "Nombre o código CIE-10 de la enfermedad"
+// @Param distrito query string false "Distrito, sin distinguir mayúsculas ni acentos (por defecto todos los distritos con casos)"
+// @Param dias query int false "Días analizados" default(90)
+// @Param metodos query string false "Métodos separados por coma: ears_c1, ears_c2, ears_c3, cusum, farrington (por defecto todos)"
+// @Success 200 {object} services.AnalisisAberraciones
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 500 {object} utils.APIErrorResponse
+// @Router /propagacion/aberraciones [get]
+func (h *PropagacionHandler) DetectAberrations(c *gin.Context) {
+	enfermedad := c.Query("enfermedad")
+	if enfermedad == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "El parámetro 'enfermedad' es requerido", "MISSING_PARAMETER", "")
+		return
+	}
+
+	dias, err := strconv.Atoi(c.DefaultQuery("dias", "90"))
+	if err != nil || dias < 14 || dias > 365 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "El parámetro 'dias' debe ser un número entre 14 y 365", "INVALID_PARAMETER", "")
+		return
+	}
+
+	metodos := services.MetodosAberracion
+	if metodosStr := c.Query("metodos"); metodosStr != "" {
+		metodos = strings.Split(metodosStr, ",")
+		if err := services.ValidarMetodosAberracion(metodos); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Método de detección inválido", "INVALID_PARAMETER", err.Error())
+			return
+		}
+	}
+
+	analisis, err := h.propagacionService.DetectarAberraciones(enfermedad, c.Query("distrito"), dias, metodos)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al detectar aberraciones", "ANALYSIS_ERROR", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, analisis, "Detección de aberraciones completada exitosamente")
+}
+
+// EstimateRt estima el número reproductivo efectivo de una enfermedad
+// @Summary Número reproductivo efectivo (Rt)
+// @Description Estima el Rt diario por el método de Cori et al. con ventana deslizante, usando el inicio de síntomas de cada caso (o la fecha de consulta si no se registró) y el intervalo serial de la enfermedad. Retorna Rt con intervalo creíble del 95% y la probabilidad de que supere 1.
+// @Tags propagacion
+// @Produce json
+// @Security BearerAuth
+// @Param enfermedad query string true "Nombre o código CIE-10 de la enfermedad"
+// @Param distrito query string false "Distrito, sin distinguir mayúsculas ni acentos (por defecto toda la ciudad)"
+// @Param dias query int false "Días analizados" default(90)
+// @Param ventana query int false "Días de la ventana deslizante" default(7)
+// @Param si_distribucion query string false "Distribución del intervalo serial: gamma o lognormal (reemplaza la del catálogo)"
+// @Param si_media query number false "Media del intervalo serial en días"
+// @Param si_desviacion query number false "Desviación estándar del intervalo serial en días"
+// @Success 200 {object} services.EstimacionRt
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 500 {object} utils.APIErrorResponse
+// @Router /propagacion/rt [get]
+func (h *PropagacionHandler) EstimateRt(c *gin.Context) {
+	enfermedad := c.Query("enfermedad")
+	if enfermedad == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "El parámetro 'enfermedad' es requerido", "MISSING_PARAMETER", "")
+		return
+	}
+
+	dias, err := strconv.Atoi(c.DefaultQuery("dias", "90"))
+	if err != nil || dias < 14 || dias > 365 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "El parámetro 'dias' debe ser un número entre 14 y 365", "INVALID_PARAMETER", "")
+		return
+	}
+
+	ventana, err := strconv.Atoi(c.DefaultQuery("ventana", strconv.Itoa(services.RtVentanaDefecto)))
+	if err != nil || ventana < 2 || ventana > 28 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "El parámetro 'ventana' debe ser un número entre 2 y 28", "INVALID_PARAMETER", "")
+		return
+	}
+
+	var intervalo *services.IntervaloSerial
+	if c.Query("si_media") != "" || c.Query("si_desviacion") != "" || c.Query("si_distribucion") != "" {
+		media, errMedia := strconv.ParseFloat(c.Query("si_media"), 64)
+		desviacion, errDesviacion := strconv.ParseFloat(c.Query("si_desviacion"), 64)
+		distribucion := c.DefaultQuery("si_distribucion", models.IntervaloSerialGamma)
+		if errMedia != nil || errDesviacion != nil || media <= 0 || media > 120 || desviacion <= 0 || desviacion > 60 ||
+			(distribucion != models.IntervaloSerialGamma && distribucion != models.IntervaloSerialLognormal) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Intervalo serial inválido", "INVALID_PARAMETER",
+				"si_media (0-120] y si_desviacion (0-60] son requeridos; si_distribucion debe ser gamma o lognormal")
+			return
+		}
+		intervalo = &services.IntervaloSerial{Distribucion: distribucion, Media: media, Desviacion: desviacion}
+	}
+
+	estimacion, err := h.propagacionService.EstimarRt(enfermedad, c.Query("distrito"), dias, ventana, intervalo)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrEnfermedadDesconocida):
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), "UNKNOWN_DISEASE", "Indique si_media y si_desviacion para enfermedades fuera del catálogo")
+		case errors.Is(err, services.ErrIntervaloSerialNoConfigurado):
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), "SERIAL_INTERVAL_REQUIRED", "Indique si_media y si_desviacion o configure el intervalo en /enfermedades/{id}/intervalo-serial")
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Error al estimar Rt", "ANALYSIS_ERROR", err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, estimacion, "Estimación de Rt completada exitosamente")
+}
+
 // Métodos auxiliares
 
+// parseHorizonte lee el parámetro 'horizonte' (1 a 60 días); responde 400 y retorna false si es inválido
+func parseHorizonte(c *gin.Context) (int, bool) {
+	horizonte, err := strconv.Atoi(c.DefaultQuery("horizonte", strconv.Itoa(services.HorizontePrediccionDefecto)))
+	if err != nil || horizonte < 1 || horizonte > 60 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "El parámetro 'horizonte' debe ser un número entre 1 y 60", "INVALID_PARAMETER", "")
+		return 0, false
+	}
+	return horizonte, true
+}
+
+// parsePeriodosSEIR lee los parámetros opcionales 'periodo_latencia' y 'periodo_infeccioso' (más de 0 y hasta
+// 60 días); los que se omiten quedan en 0 para tomarlos del catálogo. Responde 400 y retorna false si son inválidos.
+func parsePeriodosSEIR(c *gin.Context) (services.PeriodosSEIR, bool) {
+	var periodos services.PeriodosSEIR
+	for _, p := range []struct {
+		nombre  string
+		destino *float64
+	}{{"periodo_latencia", &periodos.Latencia}, {"periodo_infeccioso", &periodos.Infeccioso}} {
+		parametro, destino := p.nombre, p.destino
+		valor := c.Query(parametro)
+		if valor == "" {
+			continue
+		}
+		dias, err := strconv.ParseFloat(valor, 64)
+		if err != nil || dias <= 0 || dias > 60 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "El parámetro '"+parametro+"' debe ser un número de días mayor que 0 y hasta 60", "INVALID_PARAMETER", "")
+			return services.PeriodosSEIR{}, false
+		}
+		*destino = dias
+	}
+	return periodos, true
+}
+
 func (h *PropagacionHandler) generarResumenComparativo(analisis []services.VelocidadPropagacion) map[string]interface{} {
 	if len(analisis) == 0 {
 		return map[string]interface{}{"error": "No hay datos suficientes para comparar"}
@@ -288,73 +481,84 @@ func (h *PropagacionHandler) generarResumenComparativo(analisis []services.Veloc
 	}
 }
 
-func (h *PropagacionHandler) obtenerDatosDensidad() []map[string]interface{} {
-	// Esta información vendría del servicio, aquí la simulamos
-	distritos := []map[string]interface{}{
-		{
-			"distrito":      "Plan Tres Mil",
-			"habitantes":    180000,
-			"area_km2":      22.3,
-			"densidad":      8072,
-			"tipo_zona":     "Popular-Alta Densidad",
-			"riesgo_base":   "ALTO",
-			"conectividad":  []string{"Norte", "Sur", "Este"},
-		},
-		{
-			"distrito":      "Norte",
-			"habitantes":    320000,
-			"area_km2":      45.8,
-			"densidad":      6986,
-			"tipo_zona":     "Residencial-Popular",
-			"riesgo_base":   "ALTO",
-			"conectividad":  []string{"Equipetrol", "Plan Tres Mil", "Este"},
-		},
-		{
-			"distrito":      "Equipetrol",
-			"habitantes":    85000,
-			"area_km2":      12.5,
-			"densidad":      6800,
-			"tipo_zona":     "Residencial-Comercial",
-			"riesgo_base":   "MEDIO",
-			"conectividad":  []string{"Norte", "Centro", "Sur"},
-		},
-	}
-
-	return distritos
+// obtenerDatosDensidad datos de cada distrito con su riesgo base respecto de la densidad promedio de la ciudad
+func (h *PropagacionHandler) obtenerDatosDensidad(distritos []models.Distrito, densidadPromedio int) []map[string]interface{} {
+	datos := make([]map[string]interface{}, 0, len(distritos))
+	for _, distrito := range distritos {
+		riesgoBase := "BAJO"
+		switch {
+		case float64(distrito.Densidad) >= 1.3*float64(densidadPromedio):
+			riesgoBase = "ALTO"
+		case float64(distrito.Densidad) >= 0.7*float64(densidadPromedio):
+			riesgoBase = "MEDIO"
+		}
+
+		conectividad := make([]string, len(distrito.Conexiones))
+		for i, conexion := range distrito.Conexiones {
+			conectividad[i] = conexion.Distrito
+		}
+
+		datos = append(datos, map[string]interface{}{
+			"distrito":     distrito.Nombre,
+			"habitantes":   distrito.Habitantes,
+			"area_km2":     distrito.AreaKm2,
+			"densidad":     distrito.Densidad,
+			"tipo_zona":    distrito.TipoZona,
+			"riesgo_base":  riesgoBase,
+			"conectividad": conectividad,
+		})
+	}
+
+	return datos
 }
 
-func (h *PropagacionHandler) calcularEstadisticasGenerales() map[string]interface{} {
+// calcularEstadisticasGenerales totales de la ciudad; los distritos vienen ordenados por densidad descendente
+func (h *PropagacionHandler) calcularEstadisticasGenerales(distritos []models.Distrito) map[string]interface{} {
+	poblacionTotal := 0
+	areaTotal := 0.0
+	for _, distrito := range distritos {
+		poblacionTotal += distrito.Habitantes
+		areaTotal += distrito.AreaKm2
+	}
+
+	densidadPromedio := 0
+	if areaTotal > 0 {
+		densidadPromedio = int(math.Round(float64(poblacionTotal) / areaTotal))
+	}
+
+	mayor, menor := "", ""
+	if len(distritos) > 0 {
+		mayor = distritos[0].Nombre
+		menor = distritos[len(distritos)-1].Nombre
+	}
+
 	return map[string]interface{}{
-		"poblacion_total_santa_cruz": 1970000,
-		"area_total_km2":            187.2,
-		"densidad_promedio":         5245,
-		"distrito_mayor_densidad":   "Plan Tres Mil",
-		"distrito_menor_densidad":   "Este",
-		"total_distritos":           8,
+		"poblacion_total_santa_cruz": poblacionTotal,
+		"area_total_km2":            math.Round(areaTotal*10) / 10,
+		"densidad_promedio":         densidadPromedio,
+		"distrito_mayor_densidad":   mayor,
+		"distrito_menor_densidad":   menor,
+		"total_distritos":           len(distritos),
 	}
 }
 
-func (h *PropagacionHandler) generarRecomendacionesVigilancia() []string {
-	return []string{
-		"🏙️ Priorizar vigilancia epidemiológica en Plan Tres Mil y Norte por alta densidad poblacional",
+func (h *PropagacionHandler) generarRecomendacionesVigilancia(distritos []models.Distrito) []string {
+	var recomendaciones []string
+	if len(distritos) > 0 {
+		prioritarios := []string{distritos[0].Nombre}
+		if len(distritos) > 1 {
+			prioritarios = append(prioritarios, distritos[1].Nombre)
+		}
+		recomendaciones = append(recomendaciones,
+			fmt.Sprintf("🏙️ Priorizar vigilancia epidemiológica en %s por alta densidad poblacional", strings.Join(prioritarios, " y ")))
+	}
+
+	return append(recomendaciones,
 		"🚌 Monitorear estaciones de transporte público como puntos de dispersión",
 		"🏥 Distribuir recursos médicos proporcionalmente a la densidad poblacional",
 		"📊 Implementar sistema de alerta temprana en distritos de alta conectividad",
 		"🎯 Establecer centros de testeo móviles en zonas de alta densidad",
-	}
-}
-
-func (h *PropagacionHandler) generarMatrizConectividad() map[string][]string {
-	return map[string][]string{
-		"Equipetrol":       {"Norte", "Centro", "Sur"},
-		"Norte":            {"Equipetrol", "Plan Tres Mil", "Este"},
-		"Plan Tres Mil":    {"Norte", "Sur", "Este"},
-		"Villa 1ro de Mayo": {"Oeste", "Centro"},
-		"Sur":              {"Equipetrol", "Plan Tres Mil", "Centro"},
-		"Oeste":            {"Villa 1ro de Mayo", "Centro"},
-		"Este":             {"Norte", "Plan Tres Mil"},
-		"Centro":           {"Equipetrol", "Sur", "Oeste", "Villa 1ro de Mayo"},
-	}
+	)
 }
 
 func (h *PropagacionHandler) generarRecomendacionesRutas(rutas []services.RutaPropagacion) []string {
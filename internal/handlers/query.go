@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"hospital-api/internal/services"
+	"hospital-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// queryEdad lee un parámetro de edad opcional; si no es un entero no negativo responde 400 y retorna false
+func queryEdad(c *gin.Context, param string) (*int, bool) {
+	valor := c.Query(param)
+	if valor == "" {
+		return nil, true
+	}
+
+	edad, err := strconv.Atoi(valor)
+	if err != nil || edad < 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Edad inválida", "INVALID_FILTER", param+" debe ser un entero no negativo")
+		return nil, false
+	}
+	return &edad, true
+}
+
+// queryID lee un parámetro de ID opcional; si no es un entero positivo responde 400 y retorna false
+func queryID(c *gin.Context, param string) (*uint, bool) {
+	valor := c.Query(param)
+	if valor == "" {
+		return nil, true
+	}
+
+	parsed, err := strconv.ParseUint(valor, 10, 32)
+	if err != nil || parsed == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", param+" debe ser un entero positivo")
+		return nil, false
+	}
+	id := uint(parsed)
+	return &id, true
+}
+
+// queryBBox lee un parámetro bbox opcional con formato "min_lng,min_lat,max_lng,max_lat";
+// si es inválido responde 400 y retorna false
+func queryBBox(c *gin.Context, param string) (*services.BoundingBox, bool) {
+	valor := c.Query(param)
+	if valor == "" {
+		return nil, true
+	}
+
+	partes := strings.Split(valor, ",")
+	coords := make([]float64, len(partes))
+	for i, parte := range partes {
+		coord, err := strconv.ParseFloat(strings.TrimSpace(parte), 64)
+		if err != nil {
+			coords = nil
+			break
+		}
+		coords[i] = coord
+	}
+
+	if len(coords) != 4 || coords[0] >= coords[2] || coords[1] >= coords[3] ||
+		coords[0] < -180 || coords[2] > 180 || coords[1] < -90 || coords[3] > 90 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Bounding box inválido", "INVALID_FILTER", "Formato esperado: min_lng,min_lat,max_lng,max_lat")
+		return nil, false
+	}
+
+	return &services.BoundingBox{MinLng: coords[0], MinLat: coords[1], MaxLng: coords[2], MaxLat: coords[3]}, true
+}
+
+// queryPunto lee los parámetros obligatorios lat y lng; si faltan o están fuera de rango responde 400 y retorna false
+func queryPunto(c *gin.Context) (lat, lng float64, ok bool) {
+	latStr, lngStr := c.Query("lat"), c.Query("lng")
+	if latStr == "" || lngStr == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Se requieren parámetros lat y lng", "MISSING_PARAMETERS", "")
+		return 0, 0, false
+	}
+
+	lat, err := strconv.ParseFloat(latStr, 64)
+	if err != nil || lat < -90 || lat > 90 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Latitud inválida", "INVALID_LATITUDE", "")
+		return 0, 0, false
+	}
+	lng, err = strconv.ParseFloat(lngStr, 64)
+	if err != nil || lng < -180 || lng > 180 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Longitud inválida", "INVALID_LONGITUDE", "")
+		return 0, 0, false
+	}
+	return lat, lng, true
+}
+
+// queryCirculo lee un filtro de radio opcional formado por lat, lng y radio_km (los tres o ninguno);
+// si es inválido responde 400 y retorna false
+func queryCirculo(c *gin.Context) (*services.Circulo, bool) {
+	radioStr := c.Query("radio_km")
+	if radioStr == "" && c.Query("lat") == "" && c.Query("lng") == "" {
+		return nil, true
+	}
+	if radioStr == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Se requiere radio_km junto con lat y lng", "MISSING_PARAMETERS", "")
+		return nil, false
+	}
+
+	lat, lng, ok := queryPunto(c)
+	if !ok {
+		return nil, false
+	}
+	radio, err := strconv.ParseFloat(radioStr, 64)
+	if err != nil || radio <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Radio inválido", "INVALID_RADIUS", "radio_km debe ser un número positivo")
+		return nil, false
+	}
+	return &services.Circulo{Lat: lat, Lng: lng, RadioKm: radio}, true
+}
+
+// queryVecinos lee la cantidad k de vecinos más cercanos, entre 1 y maximo; si es inválida responde 400 y retorna false
+func queryVecinos(c *gin.Context, porDefecto, maximo int) (int, bool) {
+	valor := c.Query("k")
+	if valor == "" {
+		return porDefecto, true
+	}
+
+	k, err := strconv.Atoi(valor)
+	if err != nil || k < 1 || k > maximo {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Cantidad de vecinos inválida", "INVALID_FILTER", "k debe ser un entero entre 1 y "+strconv.Itoa(maximo))
+		return 0, false
+	}
+	return k, true
+}
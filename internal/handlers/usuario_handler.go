@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"hospital-api/internal/services"
+	"hospital-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type UsuarioHandler struct {
+	usuarioService *services.UsuarioService
+	validator      *validator.Validate
+}
+
+// NewUsuarioHandler crea una nueva instancia del handler de usuarios
+func NewUsuarioHandler() *UsuarioHandler {
+	return &UsuarioHandler{
+		usuarioService: services.NewUsuarioService(),
+		validator:      validator.New(),
+	}
+}
+
+// GetUsuarios lista los usuarios del hospital autenticado
+// @Summary Listar usuarios
+// @Description Obtiene una lista paginada de los usuarios del hospital (solo administradores)
+// @Tags usuarios
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Número de página" default(1)
+// @Param limit query int false "Elementos por página" default(10)
+// @Success 200 {object} utils.PaginatedResponse
+// @Failure 403 {object} utils.APIErrorResponse
+// @Router /usuarios [get]
+func (h *UsuarioHandler) GetUsuarios(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	usuarios, total, err := h.usuarioService.GetUsuarios(actor, page, limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener usuarios", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	response := make([]interface{}, len(usuarios))
+	for i := range usuarios {
+		response[i] = usuarios[i].ToResponse()
+	}
+
+	utils.PaginatedSuccessResponse(c, response, "Usuarios obtenidos exitosamente", page, limit, total)
+}
+
+// CreateUsuario crea un usuario en el hospital autenticado
+// @Summary Crear usuario
+// @Description Crea un usuario con un rol dentro del hospital (solo administradores)
+// @Tags usuarios
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param usuario body services.CreateUsuarioRequest true "Datos del usuario"
+// @Success 201 {object} models.UsuarioResponse
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 409 {object} utils.APIErrorResponse
+// @Router /usuarios [post]
+func (h *UsuarioHandler) CreateUsuario(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	var req services.CreateUsuarioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	usuario, err := h.usuarioService.CreateUsuario(actor, req)
+	if err != nil {
+		if err.Error() == "el email ya está registrado" {
+			utils.ErrorResponse(c, http.StatusConflict, err.Error(), "ALREADY_EXISTS", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al crear usuario", "CREATE_ERROR", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Usuario creado exitosamente",
+		"data":    usuario.ToResponse(),
+	})
+}
+
+// UpdateUsuario actualiza un usuario del hospital autenticado
+// @Summary Actualizar usuario
+// @Description Actualiza nombre, contraseña, rol o estado de un usuario (solo administradores)
+// @Tags usuarios
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del usuario"
+// @Param usuario body services.UpdateUsuarioRequest true "Campos a actualizar"
+// @Success 200 {object} models.UsuarioResponse
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Router /usuarios/{id} [put]
+func (h *UsuarioHandler) UpdateUsuario(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	var req services.UpdateUsuarioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	usuario, err := h.usuarioService.UpdateUsuario(actor, uint(id), req)
+	if err != nil {
+		if err.Error() == "usuario no encontrado" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), "UPDATE_ERROR", "")
+		return
+	}
+
+	utils.SuccessResponse(c, usuario.ToResponse(), "Usuario actualizado exitosamente")
+}
+
+// DeleteUsuario elimina un usuario del hospital autenticado
+// @Summary Eliminar usuario
+// @Description Elimina un usuario del hospital (soft delete, solo administradores)
+// @Tags usuarios
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del usuario"
+// @Success 200 {object} utils.APISuccessResponse
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Router /usuarios/{id} [delete]
+func (h *UsuarioHandler) DeleteUsuario(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	if err := h.usuarioService.DeleteUsuario(actor, uint(id)); err != nil {
+		if err.Error() == "usuario no encontrado" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), "DELETE_ERROR", "")
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Usuario eliminado exitosamente")
+}
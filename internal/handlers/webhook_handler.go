@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"hospital-api/internal/models"
+	"hospital-api/internal/services"
+	"hospital-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+	validator      *validator.Validate
+}
+
+// NewWebhookHandler crea una nueva instancia del handler de webhooks
+func NewWebhookHandler() *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: services.NewWebhookService(),
+		validator:      validator.New(),
+	}
+}
+
+// GetWebhooks lista las suscripciones de webhook
+// @Summary Listar webhooks
+// @Description Obtiene una lista paginada de las suscripciones de webhook de sistemas externos (solo epidemiólogos)
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Número de página" default(1)
+// @Param limit query int false "Elementos por página" default(10)
+// @Success 200 {object} utils.PaginatedResponse
+// @Failure 403 {object} utils.APIErrorResponse
+// @Router /webhooks [get]
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	suscripciones, total, err := h.webhookService.GetWebhooks(page, limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error al obtener webhooks", "FETCH_ERROR", err.Error())
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, suscripciones, "Webhooks obtenidos exitosamente", page, limit, total)
+}
+
+// GetWebhook obtiene una suscripción de webhook
+// @Summary Obtener webhook
+// @Description Obtiene una suscripción de webhook por su ID (solo epidemiólogos)
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del webhook"
+// @Success 200 {object} models.SuscripcionWebhook
+// @Failure 404 {object} utils.APIErrorResponse
+// @Router /webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	suscripcion, err := h.webhookService.GetWebhookByID(uint(id))
+	if err != nil {
+		webhookErrorResponse(c, err, "Error al obtener webhook", "FETCH_ERROR")
+		return
+	}
+
+	utils.SuccessResponse(c, suscripcion, "Webhook obtenido exitosamente")
+}
+
+// CreateWebhook crea una suscripción de webhook
+// @Summary Crear webhook
+// @Description Suscribe una URL a eventos (alerta.nueva, caso.contagioso). La respuesta incluye el secreto HMAC, que no se vuelve a mostrar. La URL debe resolver a direcciones públicas (solo epidemiólogos)
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param webhook body services.WebhookRequest true "Datos de la suscripción"
+// @Success 201 {object} services.WebhookCreado
+// @Failure 400 {object} utils.APIErrorResponse
+// @Router /webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	var req services.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	suscripcion, err := h.webhookService.CreateWebhook(actor, req)
+	if err != nil {
+		webhookErrorResponse(c, err, "Error al crear webhook", "CREATE_ERROR")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Webhook creado exitosamente",
+		"data":    suscripcion,
+	})
+}
+
+// UpdateWebhook reemplaza una suscripción de webhook
+// @Summary Actualizar webhook
+// @Description Reemplaza nombre, URL, eventos y estado de una suscripción; el secreto no cambia. La URL debe resolver a direcciones públicas (solo epidemiólogos)
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del webhook"
+// @Param webhook body services.WebhookRequest true "Datos de la suscripción"
+// @Success 200 {object} models.SuscripcionWebhook
+// @Failure 400 {object} utils.APIErrorResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Router /webhooks/{id} [put]
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	var req services.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Datos inválidos", "INVALID_INPUT", err.Error())
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	suscripcion, err := h.webhookService.UpdateWebhook(actor, uint(id), req)
+	if err != nil {
+		webhookErrorResponse(c, err, "Error al actualizar webhook", "UPDATE_ERROR")
+		return
+	}
+
+	utils.SuccessResponse(c, suscripcion, "Webhook actualizado exitosamente")
+}
+
+// DeleteWebhook elimina una suscripción de webhook
+// @Summary Eliminar webhook
+// @Description Elimina una suscripción; sus entregas pendientes pasan a fallidas al procesarse (solo epidemiólogos)
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del webhook"
+// @Success 200 {object} utils.APISuccessResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(actor, uint(id)); err != nil {
+		webhookErrorResponse(c, err, "Error al eliminar webhook", "DELETE_ERROR")
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Webhook eliminado exitosamente")
+}
+
+// GetEntregas lista las entregas de un webhook con sus intentos
+// @Summary Entregas de un webhook
+// @Description Lista los envíos de eventos a la suscripción con cada intento (código HTTP, error y duración) (solo epidemiólogos)
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del webhook"
+// @Param estado query string false "Estado: pendiente, entregada o fallida"
+// @Param page query int false "Número de página" default(1)
+// @Param limit query int false "Elementos por página" default(10)
+// @Success 200 {object} utils.PaginatedResponse
+// @Failure 404 {object} utils.APIErrorResponse
+// @Router /webhooks/{id}/entregas [get]
+func (h *WebhookHandler) GetEntregas(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	estado := c.Query("estado")
+	switch estado {
+	case "", models.EntregaPendiente, models.EntregaEntregada, models.EntregaFallida:
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Estado inválido", "INVALID_FILTER", "Valores permitidos: pendiente, entregada, fallida")
+		return
+	}
+
+	entregas, total, err := h.webhookService.GetEntregas(uint(id), estado, page, limit)
+	if err != nil {
+		webhookErrorResponse(c, err, "Error al obtener entregas", "FETCH_ERROR")
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, entregas, "Entregas obtenidas exitosamente", page, limit, total)
+}
+
+// ReintentarEntrega vuelve a encolar una entrega fallida
+// @Summary Reintentar entrega
+// @Description Vuelve a poner en cola una entrega fallida con un nuevo ciclo de reintentos (solo epidemiólogos)
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del webhook"
+// @Param entrega_id path int true "ID de la entrega"
+// @Success 200 {object} models.EntregaWebhook
+// @Failure 404 {object} utils.APIErrorResponse
+// @Failure 409 {object} utils.APIErrorResponse
+// @Router /webhooks/{id}/entregas/{entrega_id}/reintentar [post]
+func (h *WebhookHandler) ReintentarEntrega(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID inválido", "INVALID_ID", "")
+		return
+	}
+	entregaID, err := strconv.ParseUint(c.Param("entrega_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID de entrega inválido", "INVALID_ID", "")
+		return
+	}
+
+	entrega, err := h.webhookService.ReintentarEntrega(uint(id), uint(entregaID))
+	if err != nil {
+		if errors.Is(err, services.ErrEntregaNoReintentable) {
+			utils.ErrorResponse(c, http.StatusConflict, err.Error(), "INVALID_STATE", "")
+			return
+		}
+		webhookErrorResponse(c, err, "Error al reintentar entrega", "UPDATE_ERROR")
+		return
+	}
+
+	utils.SuccessResponse(c, entrega, "Entrega encolada nuevamente")
+}
+
+// webhookErrorResponse responde 404 si el webhook o la entrega no existen y 500 en otro caso
+func webhookErrorResponse(c *gin.Context, err error, message, code string) {
+	if errors.Is(err, services.ErrURLWebhookNoPermitida) {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_URL", "")
+		return
+	}
+	if err.Error() == "webhook no encontrado" || err.Error() == "entrega no encontrada" {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND", "")
+		return
+	}
+	utils.ErrorResponse(c, http.StatusInternalServerError, message, code, err.Error())
+}
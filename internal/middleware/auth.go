@@ -3,24 +3,53 @@ package middleware
 import (
 	"net/http"
 	"os"
+	"slices"
 	"strings"
 
+	"hospital-api/internal/database"
+	"hospital-api/internal/models"
+
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 // JWTClaims define los claims del JWT
 type JWTClaims struct {
+	UsuarioID  uint   `json:"usuario_id"`
 	HospitalID uint   `json:"hospital_id"`
 	Email      string `json:"email"`
+	Rol        string `json:"rol"`
 	jwt.RegisteredClaims
 }
 
+// AudienciaEventos audiencia de los tokens de corta duración que solo sirven para abrir /eventos/stream
+const AudienciaEventos = "eventos.stream"
+
 // AuthMiddleware middleware para verificar JWT
 func AuthMiddleware() gin.HandlerFunc {
+	return autenticar(false)
+}
+
+// EventStreamAuthMiddleware verifica el JWT de /eventos/stream. Además de la cabecera Authorization acepta
+// un token de eventos en el parámetro token, porque el EventSource del navegador no puede enviar cabeceras.
+func EventStreamAuthMiddleware() gin.HandlerFunc {
+	return autenticar(true)
+}
+
+// autenticar verifica el access token de la cabecera Authorization o, si tokenEnQuery y no hay cabecera,
+// el token de eventos del parámetro token
+func autenticar(tokenEnQuery bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		opciones := []jwt.ParserOption{jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired()}
+
+		var tokenString string
 		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
+		switch {
+		case authHeader == "" && tokenEnQuery && c.Query("token") != "":
+			// En la URL solo se aceptan tokens de eventos: un access token quedaría en los logs de proxies
+			tokenString = c.Query("token")
+			opciones = append(opciones, jwt.WithAudience(AudienciaEventos))
+		case authHeader == "":
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error":   "Token de autorización requerido",
 				"code":    "AUTH_TOKEN_REQUIRED",
@@ -28,27 +57,30 @@ func AuthMiddleware() gin.HandlerFunc {
 			})
 			c.Abort()
 			return
+		default:
+			// Verificar formato "Bearer token"
+			tokenParts := strings.Split(authHeader, " ")
+			if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error":   "Formato de token inválido",
+					"code":    "INVALID_TOKEN_FORMAT",
+					"success": false,
+				})
+				c.Abort()
+				return
+			}
+			tokenString = tokenParts[1]
 		}
 
-		// Verificar formato "Bearer token"
-		tokenParts := strings.Split(authHeader, " ")
-		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "Formato de token inválido",
-				"code":    "INVALID_TOKEN_FORMAT",
-				"success": false,
-			})
-			c.Abort()
-			return
-		}
-
-		tokenString := tokenParts[1]
-
 		// Verificar y parsear el token
 		token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
 			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
+		}, opciones...)
 
+		if err == nil && authHeader != "" && slices.Contains(token.Claims.(*JWTClaims).Audience, AudienciaEventos) {
+			// Un token de eventos no reemplaza al access token en la cabecera
+			err = jwt.ErrTokenInvalidAudience
+		}
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error":   "Token inválido",
@@ -59,10 +91,35 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
-			// Agregar información del hospital al contexto
+		if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid && claims.ID != "" {
+			// Rechazar tokens revocados por logout
+			var revocados int64
+			if err := database.GetDB().Model(&models.TokenRevocado{}).Where("jti = ?", claims.ID).Count(&revocados).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Error verificando el token",
+					"code":    "INTERNAL_ERROR",
+					"success": false,
+				})
+				c.Abort()
+				return
+			}
+			if revocados > 0 {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error":   "Token revocado",
+					"code":    "TOKEN_REVOKED",
+					"success": false,
+				})
+				c.Abort()
+				return
+			}
+
+			// Agregar información del usuario y su hospital al contexto
+			c.Set("usuario_id", claims.UsuarioID)
 			c.Set("hospital_id", claims.HospitalID)
-			c.Set("hospital_email", claims.Email)
+			c.Set("usuario_email", claims.Email)
+			c.Set("rol", claims.Rol)
+			c.Set("token_jti", claims.ID)
+			c.Set("token_exp", claims.ExpiresAt.Time)
 			c.Next()
 		} else {
 			c.JSON(http.StatusUnauthorized, gin.H{
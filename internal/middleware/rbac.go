@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRoles middleware que permite el acceso solo a usuarios con alguno de los roles indicados.
+// Debe usarse después de AuthMiddleware.
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	permitidos := make(map[string]bool, len(roles))
+	for _, rol := range roles {
+		permitidos[rol] = true
+	}
+
+	return func(c *gin.Context) {
+		rol := c.GetString("rol")
+		if rol == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "Usuario no autenticado",
+				"code":    "NOT_AUTHENTICATED",
+				"success": false,
+			})
+			c.Abort()
+			return
+		}
+
+		if !permitidos[rol] {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "No tiene permisos para acceder a este recurso",
+				"code":    "FORBIDDEN",
+				"success": false,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
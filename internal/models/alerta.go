@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tipos de regla de alerta de brote
+const (
+	ReglaUmbral     = "umbral"      // Casos en la ventana por encima de un número fijo
+	ReglaMediaMovil = "media_movil" // Casos en la ventana por encima de un múltiplo de la media de las ventanas anteriores
+)
+
+// Severidades de las alertas de brote
+const (
+	SeveridadBaja    = "baja"
+	SeveridadMedia   = "media"
+	SeveridadAlta    = "alta"
+	SeveridadCritica = "critica"
+)
+
+// Estados de una alerta de brote
+const (
+	AlertaActiva   = "activa"   // La regla se sigue cumpliendo en la última evaluación
+	AlertaResuelta = "resuelta" // La regla dejó de cumplirse
+)
+
+// ReglaAlerta condición que el motor de alertas evalúa periódicamente sobre historial_clinico.
+// Sin distrito la regla se evalúa por separado en cada distrito.
+type ReglaAlerta struct {
+	ID                 uint           `json:"id" gorm:"primaryKey;autoIncrement"`
+	Nombre             string         `json:"nombre" gorm:"type:varchar(150);not null"`
+	Tipo               string         `json:"tipo" gorm:"type:varchar(20);not null;check:tipo IN ('umbral','media_movil')"`
+	IDEnfermedad       *uint          `json:"id_enfermedad" gorm:"index"` // Nil evalúa todas las enfermedades juntas
+	Distrito           string         `json:"distrito" gorm:"type:varchar(100)"`
+	Umbral             float64        `json:"umbral" gorm:"not null"` // Casos (umbral) o multiplicador de la media (media_movil)
+	VentanaDias        int            `json:"ventana_dias" gorm:"not null;default:7"`
+	VentanasReferencia int            `json:"ventanas_referencia" gorm:"not null;default:4"` // Ventanas anteriores promediadas en media_movil
+	Severidad          string         `json:"severidad" gorm:"type:varchar(10);not null;check:severidad IN ('baja','media','alta','critica')"`
+	Activa             bool           `json:"activa" gorm:"not null;default:true"`
+	IDUsuario          *uint          `json:"id_usuario"` // Usuario que creó la regla
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relaciones
+	Enfermedad *Enfermedad `json:"enfermedad,omitempty" gorm:"foreignKey:IDEnfermedad"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (ReglaAlerta) TableName() string {
+	return "reglas_alerta"
+}
+
+// AlertaBrote alerta generada por una regla. Mientras la regla se siga cumpliendo en el mismo distrito
+// se actualiza la misma alerta activa en lugar de crear una nueva.
+type AlertaBrote struct {
+	ID               uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	IDRegla          uint       `json:"id_regla" gorm:"not null;index"`
+	IDEnfermedad     *uint      `json:"id_enfermedad" gorm:"index"`
+	Distrito         string     `json:"distrito" gorm:"type:varchar(100);not null;default:''"`
+	Severidad        string     `json:"severidad" gorm:"type:varchar(10);not null;index"`
+	Estado           string     `json:"estado" gorm:"type:varchar(10);not null;default:'activa';index"`
+	Casos            int        `json:"casos" gorm:"not null"`
+	Referencia       float64    `json:"referencia" gorm:"not null"` // Umbral o media de referencia contra la que se comparó
+	Mensaje          string     `json:"mensaje" gorm:"type:text;not null"`
+	Detecciones      int        `json:"detecciones" gorm:"not null;default:1"` // Evaluaciones consecutivas en que se cumplió
+	PrimeraDeteccion time.Time  `json:"primera_deteccion" gorm:"not null"`
+	UltimaDeteccion  time.Time  `json:"ultima_deteccion" gorm:"not null"`
+	ResueltaAt       *time.Time `json:"resuelta_at"`
+	CreatedAt        time.Time  `json:"created_at" gorm:"index"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	// Relaciones
+	Regla      *ReglaAlerta `json:"regla,omitempty" gorm:"foreignKey:IDRegla"`
+	Enfermedad *Enfermedad  `json:"enfermedad,omitempty" gorm:"foreignKey:IDEnfermedad"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (AlertaBrote) TableName() string {
+	return "alertas_brote"
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Acciones registradas en la bitácora de auditoría
+const (
+	AccionLoginBloqueado = "login_bloqueado"
+	AccionCrear          = "crear"
+	AccionLeer           = "leer"
+	AccionActualizar     = "actualizar"
+	AccionEliminar       = "eliminar"
+	AccionFusionar       = "fusionar"
+)
+
+// Entidades auditadas
+const (
+	EntidadPaciente     = "paciente"
+	EntidadHistorial    = "historial_clinico"
+	EntidadNotificacion = "notificacion_epidemiologica"
+	EntidadReglaAlerta  = "regla_alerta"
+	EntidadWebhook      = "suscripcion_webhook"
+	EntidadEnfermedad   = "enfermedad"
+	EntidadDistrito     = "distrito"
+	EntidadBarrio       = "barrio"
+)
+
+// RolSistema rol de las entradas de auditoría que no realiza un usuario, como las migraciones de datos
+const RolSistema = "sistema"
+
+// AuditLog representa una entrada de la bitácora de auditoría.
+// La tabla es de solo inserción: un trigger rechaza UPDATE, DELETE y TRUNCATE.
+type AuditLog struct {
+	ID         uint            `json:"id" gorm:"primaryKey;autoIncrement"`
+	IDUsuario  *uint           `json:"id_usuario" gorm:"index"`
+	IDHospital *uint           `json:"id_hospital" gorm:"index"`
+	Rol        string          `json:"rol" gorm:"type:varchar(20)"`
+	Accion     string          `json:"accion" gorm:"type:varchar(50);not null;index"`
+	Entidad    string          `json:"entidad" gorm:"type:varchar(50);not null;index:idx_audit_entidad"`
+	EntidadID  string          `json:"entidad_id" gorm:"type:varchar(100);index:idx_audit_entidad"`
+	Cambios    json.RawMessage `json:"cambios,omitempty" gorm:"type:jsonb"` // Diff antes/después por campo
+	Detalle    json.RawMessage `json:"detalle,omitempty" gorm:"type:jsonb"`
+	IP         string          `json:"ip" gorm:"type:varchar(45)"`
+	CreatedAt  time.Time       `json:"created_at" gorm:"index"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (AuditLog) TableName() string {
+	return "audit_log"
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// LimiteGeografico contorno oficial importado de GeoJSON o Shapefile, guardado como MultiPolygon de GeoJSON,
+// con su caja para descartar candidatos antes de la prueba de punto en polígono. Nulo si no se importó.
+type LimiteGeografico struct {
+	Limite       json.RawMessage `json:"-" gorm:"type:jsonb"`
+	LimiteMinLat *float64        `json:"-"`
+	LimiteMinLng *float64        `json:"-"`
+	LimiteMaxLat *float64        `json:"-"`
+	LimiteMaxLng *float64        `json:"-"`
+}
+
+// Distrito distrito de la ciudad con sus datos demográficos y su centroide. Es la fuente de los cálculos de
+// propagación (densidad, rutas, modelo SEIR) y de los endpoints públicos de distritos.
+type Distrito struct {
+	ID         uint    `json:"id" gorm:"primaryKey;autoIncrement"`
+	Nombre     string  `json:"nombre" gorm:"type:varchar(100);uniqueIndex;not null"`
+	Habitantes int     `json:"habitantes" gorm:"not null"`
+	AreaKm2    float64 `json:"area_km2" gorm:"not null"`
+	Densidad   int     `json:"densidad_hab_km2" gorm:"-"` // Habitantes / AreaKm2, calculada al leer
+	TipoZona   string  `json:"tipo_zona" gorm:"type:varchar(50);not null;default:''"`
+	Latitud    float64 `json:"latitud" gorm:"not null"`  // Centroide
+	Longitud   float64 `json:"longitud" gorm:"not null"` // Centroide
+	LimiteGeografico
+	TieneLimite bool      `json:"tiene_limite" gorm:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Relaciones
+	Conexiones []ConexionDistrito `json:"conexiones,omitempty" gorm:"-"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (Distrito) TableName() string {
+	return "distritos"
+}
+
+// ConexionDistrito arista no dirigida del grafo de distritos. Se guarda una sola vez con IDDistritoA < IDDistritoB.
+type ConexionDistrito struct {
+	ID          uint    `json:"-" gorm:"primaryKey;autoIncrement"`
+	IDDistritoA uint    `json:"-" gorm:"not null;uniqueIndex:idx_conexion_distritos"`
+	IDDistritoB uint    `json:"-" gorm:"not null;uniqueIndex:idx_conexion_distritos;index"`
+	Peso        float64 `json:"peso" gorm:"not null;default:1"` // Intensidad relativa (0, 1]: transporte y flujo diario entre ambos
+
+	// Vecino visto desde el distrito consultado; no se persiste
+	IDDistrito uint   `json:"id_distrito" gorm:"-"`
+	Distrito   string `json:"distrito" gorm:"-"`
+
+	// Relaciones
+	DistritoA *Distrito `json:"-" gorm:"foreignKey:IDDistritoA;constraint:OnDelete:CASCADE"`
+	DistritoB *Distrito `json:"-" gorm:"foreignKey:IDDistritoB;constraint:OnDelete:CASCADE"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (ConexionDistrito) TableName() string {
+	return "conexiones_distrito"
+}
+
+// Barrio barrio con su contorno oficial. IDDistrito es el distrito que contiene su punto interior al importarlo.
+type Barrio struct {
+	ID         uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	Nombre     string `json:"nombre" gorm:"type:varchar(150);not null;index"`
+	IDDistrito *uint  `json:"id_distrito" gorm:"index"`
+	LimiteGeografico
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relaciones
+	Distrito *Distrito `json:"-" gorm:"foreignKey:IDDistrito;constraint:OnDelete:SET NULL"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (Barrio) TableName() string {
+	return "barrios"
+}
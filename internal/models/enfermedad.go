@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Vías de transmisión de las enfermedades del catálogo
+const (
+	ViaVectorial      = "vectorial"      // Picadura de mosquito, vinchuca u otro vector
+	ViaAerea          = "aerea"          // Gotitas o aerosoles respiratorios
+	ViaContacto       = "contacto"       // Contacto directo con personas, animales o fluidos
+	ViaAlimentosAgua  = "alimentos_agua" // Alimentos o agua contaminados
+	ViaNoTransmisible = "no_transmisible"
+)
+
+// Distribuciones del intervalo serial (días entre el inicio de síntomas de un caso y el de los casos que contagia)
+const (
+	IntervaloSerialGamma     = "gamma"
+	IntervaloSerialLognormal = "lognormal"
+)
+
+// Enfermedad representa una entrada del catálogo de enfermedades codificado con CIE-10
+type Enfermedad struct {
+	ID                          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CodigoCIE10                 string    `json:"codigo_cie10" gorm:"type:varchar(10);uniqueIndex;not null"`
+	Nombre                      string    `json:"nombre" gorm:"type:varchar(150);uniqueIndex;not null"` // Nombre canónico
+	Contagiosa                  bool      `json:"contagiosa" gorm:"not null;default:false"`
+	ViaTransmision              string    `json:"via_transmision" gorm:"type:varchar(20);not null;default:''"`
+	IncubacionMinDias           *int      `json:"incubacion_min_dias"`
+	IncubacionMaxDias           *int      `json:"incubacion_max_dias"`
+	PeriodoInfecciosoDias       *float64  `json:"periodo_infeccioso_dias"` // Días promedio en que un caso contagia; para el modelo SEIR
+	NotificacionObligatoria     bool      `json:"notificacion_obligatoria" gorm:"not null;default:false"`
+	IntervaloSerialDistribucion string    `json:"intervalo_serial_distribucion" gorm:"type:varchar(10);not null;default:''"` // Para estimar Rt; vacío si no está configurado
+	IntervaloSerialMedia        *float64  `json:"intervalo_serial_media"`                                                    // Días
+	IntervaloSerialDesviacion   *float64  `json:"intervalo_serial_desviacion"`                                               // Días
+	CreatedAt                   time.Time `json:"created_at"`
+	UpdatedAt                   time.Time `json:"updated_at"`
+
+	// Relaciones
+	Sinonimos []EnfermedadSinonimo `json:"sinonimos,omitempty" gorm:"foreignKey:IDEnfermedad"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (Enfermedad) TableName() string {
+	return "enfermedades"
+}
+
+// EnfermedadSinonimo nombre alternativo con el que se registra una enfermedad ("dengue clásico", "DENV").
+// Se guarda normalizado (minúsculas, sin acentos ni signos) y cada sinónimo pertenece a una sola enfermedad.
+type EnfermedadSinonimo struct {
+	ID           uint   `json:"-" gorm:"primaryKey;autoIncrement"`
+	IDEnfermedad uint   `json:"-" gorm:"not null;index"`
+	Sinonimo     string `json:"sinonimo" gorm:"type:varchar(150);uniqueIndex;not null"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (EnfermedadSinonimo) TableName() string {
+	return "enfermedad_sinonimos"
+}
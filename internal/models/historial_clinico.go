@@ -13,7 +13,8 @@ type HistorialClinico struct {
 	IDHospital     uint      `json:"id_hospital" gorm:"not null" validate:"required"`
 	FechaIngreso   time.Time `json:"fecha_ingreso" gorm:"type:timestamp;not null" validate:"required"`
 	MotivoConsulta string    `json:"motivo_consulta" gorm:"type:varchar(200);not null" validate:"required,min=3,max=200"`
-	Enfermedad     string    `json:"enfermedad" gorm:"type:varchar(150);not null" validate:"required,min=2,max=150"`
+	Enfermedad     string    `json:"enfermedad" gorm:"type:varchar(150);not null" validate:"required,min=2,max=150"` // Texto registrado; la enfermedad codificada es IDEnfermedad
+	IDEnfermedad   *uint     `json:"id_enfermedad" gorm:"index"`                                                     // Entrada del catálogo; nula en registros antiguos sin equivalente
 	Diagnostico    string    `json:"diagnostico" gorm:"type:text"`
 	Tratamiento    string    `json:"tratamiento" gorm:"type:text"`
 	Medicamentos   string    `json:"medicamentos" gorm:"type:text"`
@@ -25,21 +26,33 @@ type HistorialClinico struct {
 	PatientAddress      string  `json:"patient_address" gorm:"type:varchar(500);not null" validate:"required,min=5,max=500"`
 	PatientDistrict     string  `json:"patient_district" gorm:"type:varchar(100);not null" validate:"required,min=2,max=100"`
 	PatientNeighborhood string  `json:"patient_neighborhood" gorm:"type:varchar(100)"`
+	IDDistrito          *uint   `json:"id_distrito" gorm:"index"` // Distrito oficial que contiene las coordenadas; nulo fuera de los límites importados
+	IDBarrio            *uint   `json:"id_barrio" gorm:"index"`   // Barrio oficial que contiene las coordenadas
 
 	// Datos temporales
 	ConsultationDate  time.Time  `json:"consultation_date" gorm:"type:date;not null;default:CURRENT_DATE"`
 	SymptomsStartDate *time.Time `json:"symptoms_start_date" gorm:"type:date"`
 
-	// Contexto epidemiológico
-	IsContagious bool `json:"is_contagious" gorm:"default:false"`
+	// Contexto epidemiológico: se deriva del catálogo de enfermedades salvo corrección manual justificada
+	IsContagious            bool   `json:"is_contagious" gorm:"default:false"`
+	ContagiosoManual        bool   `json:"contagioso_manual" gorm:"not null;default:false"` // is_contagious difiere del catálogo por decisión clínica
+	MotivoContagioso        string `json:"motivo_contagioso" gorm:"type:varchar(300)" validate:"max=300"`
+	ViaTransmision          string `json:"via_transmision" gorm:"type:varchar(20);not null;default:''"`
+	NotificacionObligatoria bool   `json:"notificacion_obligatoria" gorm:"not null;default:false"`
+
+	// Control de concurrencia: se incrementa en cada modificación (ETag)
+	Version int `json:"version" gorm:"not null;default:1"`
 
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relaciones
-	Paciente Paciente `json:"paciente,omitempty" gorm:"foreignKey:IDPaciente"`
-	Hospital Hospital `json:"hospital,omitempty" gorm:"foreignKey:IDHospital"`
+	Paciente Paciente    `json:"paciente,omitempty" gorm:"foreignKey:IDPaciente"`
+	Hospital Hospital    `json:"hospital,omitempty" gorm:"foreignKey:IDHospital"`
+	Catalogo *Enfermedad `json:"enfermedad_catalogo,omitempty" gorm:"foreignKey:IDEnfermedad"`
+	Distrito *Distrito   `json:"-" gorm:"foreignKey:IDDistrito;constraint:OnDelete:SET NULL"`
+	Barrio   *Barrio     `json:"-" gorm:"foreignKey:IDBarrio;constraint:OnDelete:SET NULL"`
 }
 
 // TableName especifica el nombre de la tabla en la base de datos
@@ -49,24 +62,26 @@ func (HistorialClinico) TableName() string {
 
 // HistorialClinicoResponse estructura para respuestas con información relacionada
 type HistorialClinicoResponse struct {
-	ID                  uint       `json:"id"`
-	FechaIngreso        time.Time  `json:"fecha_ingreso"`
-	MotivoConsulta      string     `json:"motivo_consulta"`
-	Enfermedad          string     `json:"enfermedad"`
-	Diagnostico         string     `json:"diagnostico"`
-	Tratamiento         string     `json:"tratamiento"`
-	Medicamentos        string     `json:"medicamentos"`
-	Observaciones       string     `json:"observaciones"`
-	PatientLatitude     float64    `json:"patient_latitude"`
-	PatientLongitude    float64    `json:"patient_longitude"`
-	PatientAddress      string     `json:"patient_address"`
-	PatientDistrict     string     `json:"patient_district"`
-	PatientNeighborhood string     `json:"patient_neighborhood"`
-	ConsultationDate    time.Time  `json:"consultation_date"`
-	SymptomsStartDate   *time.Time `json:"symptoms_start_date"`
-	IsContagious        bool       `json:"is_contagious"`
-	CreatedAt           time.Time  `json:"created_at"`
-	UpdatedAt           time.Time  `json:"updated_at"`
+	ID                      uint       `json:"id"`
+	FechaIngreso            time.Time  `json:"fecha_ingreso"`
+	MotivoConsulta          string     `json:"motivo_consulta"`
+	Enfermedad              string     `json:"enfermedad"`
+	Diagnostico             string     `json:"diagnostico"`
+	Tratamiento             string     `json:"tratamiento"`
+	Medicamentos            string     `json:"medicamentos"`
+	Observaciones           string     `json:"observaciones"`
+	PatientLatitude         float64    `json:"patient_latitude"`
+	PatientLongitude        float64    `json:"patient_longitude"`
+	PatientAddress          string     `json:"patient_address"`
+	PatientDistrict         string     `json:"patient_district"`
+	PatientNeighborhood     string     `json:"patient_neighborhood"`
+	ConsultationDate        time.Time  `json:"consultation_date"`
+	SymptomsStartDate       *time.Time `json:"symptoms_start_date"`
+	IsContagious            bool       `json:"is_contagious"`
+	ViaTransmision          string     `json:"via_transmision"`
+	NotificacionObligatoria bool       `json:"notificacion_obligatoria"`
+	CreatedAt               time.Time  `json:"created_at"`
+	UpdatedAt               time.Time  `json:"updated_at"`
 
 	// Información relacionada
 	Paciente *Paciente `json:"paciente,omitempty"`
@@ -76,23 +91,25 @@ type HistorialClinicoResponse struct {
 // ToResponse convierte HistorialClinico a HistorialClinicoResponse
 func (h *HistorialClinico) ToResponse() HistorialClinicoResponse {
 	return HistorialClinicoResponse{
-		ID:                  h.ID,
-		FechaIngreso:        h.FechaIngreso,
-		MotivoConsulta:      h.MotivoConsulta,
-		Enfermedad:          h.Enfermedad,
-		Diagnostico:         h.Diagnostico,
-		Tratamiento:         h.Tratamiento,
-		Medicamentos:        h.Medicamentos,
-		Observaciones:       h.Observaciones,
-		PatientLatitude:     h.PatientLatitude,
-		PatientLongitude:    h.PatientLongitude,
-		PatientAddress:      h.PatientAddress,
-		PatientDistrict:     h.PatientDistrict,
-		PatientNeighborhood: h.PatientNeighborhood,
-		ConsultationDate:    h.ConsultationDate,
-		SymptomsStartDate:   h.SymptomsStartDate,
-		IsContagious:        h.IsContagious,
-		CreatedAt:           h.CreatedAt,
-		UpdatedAt:           h.UpdatedAt,
+		ID:                      h.ID,
+		FechaIngreso:            h.FechaIngreso,
+		MotivoConsulta:          h.MotivoConsulta,
+		Enfermedad:              h.Enfermedad,
+		Diagnostico:             h.Diagnostico,
+		Tratamiento:             h.Tratamiento,
+		Medicamentos:            h.Medicamentos,
+		Observaciones:           h.Observaciones,
+		PatientLatitude:         h.PatientLatitude,
+		PatientLongitude:        h.PatientLongitude,
+		PatientAddress:          h.PatientAddress,
+		PatientDistrict:         h.PatientDistrict,
+		PatientNeighborhood:     h.PatientNeighborhood,
+		ConsultationDate:        h.ConsultationDate,
+		SymptomsStartDate:       h.SymptomsStartDate,
+		IsContagious:            h.IsContagious,
+		ViaTransmision:          h.ViaTransmision,
+		NotificacionObligatoria: h.NotificacionObligatoria,
+		CreatedAt:               h.CreatedAt,
+		UpdatedAt:               h.UpdatedAt,
 	}
 }
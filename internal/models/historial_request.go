@@ -7,7 +7,8 @@ type HistorialClinicoRequest struct {
 	IDPaciente     uint      `json:"id_paciente" validate:"required"`
 	FechaIngreso   time.Time `json:"fecha_ingreso" validate:"required"`
 	MotivoConsulta string    `json:"motivo_consulta" validate:"required,min=3,max=200"`
-	Enfermedad     string    `json:"enfermedad" validate:"required,min=2,max=150"`
+	Enfermedad     string    `json:"enfermedad" validate:"required_without=IDEnfermedad,omitempty,min=2,max=150"` // Nombre, código CIE-10 o sinónimo del catálogo
+	IDEnfermedad   *uint     `json:"id_enfermedad,omitempty"`
 	Diagnostico    string    `json:"diagnostico"`
 	Tratamiento    string    `json:"tratamiento"`
 	Medicamentos   string    `json:"medicamentos"`
@@ -22,16 +23,19 @@ type HistorialClinicoRequest struct {
 
 	ConsultationDate  time.Time  `json:"consultation_date"`
 	SymptomsStartDate *time.Time `json:"symptoms_start_date,omitempty"`
-	IsContagious      bool       `json:"is_contagious"`
+	IsContagious      *bool      `json:"is_contagious,omitempty"` // Por defecto según el catálogo; si difiere requiere motivo_contagioso
+	MotivoContagioso  string     `json:"motivo_contagioso,omitempty" validate:"max=300"`
 }
 
-// ToHistorialClinico convierte el request a modelo de base de datos
+// ToHistorialClinico convierte el request a modelo de base de datos.
+// Un is_contagious enviado queda como indicación manual que el servicio contrasta con el catálogo.
 func (r *HistorialClinicoRequest) ToHistorialClinico() *HistorialClinico {
-	return &HistorialClinico{
+	historial := &HistorialClinico{
 		IDPaciente:          r.IDPaciente,
 		FechaIngreso:        r.FechaIngreso,
 		MotivoConsulta:      r.MotivoConsulta,
 		Enfermedad:          r.Enfermedad,
+		IDEnfermedad:        r.IDEnfermedad,
 		Diagnostico:         r.Diagnostico,
 		Tratamiento:         r.Tratamiento,
 		Medicamentos:        r.Medicamentos,
@@ -41,6 +45,11 @@ func (r *HistorialClinicoRequest) ToHistorialClinico() *HistorialClinico {
 		PatientNeighborhood: r.PatientNeighborhood,
 		ConsultationDate:    r.ConsultationDate,
 		SymptomsStartDate:   r.SymptomsStartDate,
-		IsContagious:        r.IsContagious,
+		MotivoContagioso:    r.MotivoContagioso,
 	}
+	if r.IsContagious != nil {
+		historial.IsContagious = *r.IsContagious
+		historial.ContagiosoManual = true
+	}
+	return historial
 }
@@ -4,24 +4,26 @@ import "time"
 
 // HistorialEnfermedadResponse estructura específica para la respuesta del endpoint de búsqueda por enfermedad
 type HistorialEnfermedadResponse struct {
-	ID                  uint                   `json:"id"`
-	FechaIngreso        time.Time              `json:"fecha_ingreso"`
-	MotivoConsulta      string                 `json:"motivo_consulta"`
-	Diagnostico         string                 `json:"diagnostico"`
-	Tratamiento         string                 `json:"tratamiento"`
-	Medicamentos        string                 `json:"medicamentos"`
-	Observaciones       string                 `json:"observaciones"`
-	PatientLatitude     float64                `json:"patient_latitude"`
-	PatientLongitude    float64                `json:"patient_longitude"`
-	PatientAddress      string                 `json:"patient_address"`
-	PatientDistrict     string                 `json:"patient_district"`
-	PatientNeighborhood string                 `json:"patient_neighborhood"`
-	ConsultationDate    time.Time              `json:"consultation_date"`
-	SymptomsStartDate   *time.Time             `json:"symptoms_start_date"`
-	IsContagious        bool                   `json:"is_contagious"`
-	CreatedAt           time.Time              `json:"created_at"`
-	Paciente            PacienteEnfermedadInfo `json:"paciente"`
-	Hospital            HospitalEnfermedadInfo `json:"hospital"`
+	ID                      uint                   `json:"id"`
+	FechaIngreso            time.Time              `json:"fecha_ingreso"`
+	MotivoConsulta          string                 `json:"motivo_consulta"`
+	Diagnostico             string                 `json:"diagnostico"`
+	Tratamiento             string                 `json:"tratamiento"`
+	Medicamentos            string                 `json:"medicamentos"`
+	Observaciones           string                 `json:"observaciones"`
+	PatientLatitude         float64                `json:"patient_latitude"`
+	PatientLongitude        float64                `json:"patient_longitude"`
+	PatientAddress          string                 `json:"patient_address"`
+	PatientDistrict         string                 `json:"patient_district"`
+	PatientNeighborhood     string                 `json:"patient_neighborhood"`
+	ConsultationDate        time.Time              `json:"consultation_date"`
+	SymptomsStartDate       *time.Time             `json:"symptoms_start_date"`
+	IsContagious            bool                   `json:"is_contagious"`
+	ViaTransmision          string                 `json:"via_transmision"`
+	NotificacionObligatoria bool                   `json:"notificacion_obligatoria"`
+	CreatedAt               time.Time              `json:"created_at"`
+	Paciente                PacienteEnfermedadInfo `json:"paciente"`
+	Hospital                HospitalEnfermedadInfo `json:"hospital"`
 }
 
 // PacienteEnfermedadInfo información simplificada del paciente
@@ -73,22 +75,24 @@ func (h *HistorialClinico) ToEnfermedadResponse() HistorialEnfermedadResponse {
 	}
 
 	return HistorialEnfermedadResponse{
-		ID:                  h.ID,
-		FechaIngreso:        h.FechaIngreso,
-		MotivoConsulta:      h.MotivoConsulta,
-		Diagnostico:         h.Diagnostico,
-		Tratamiento:         h.Tratamiento,
-		Medicamentos:        h.Medicamentos,
-		Observaciones:       h.Observaciones,
-		PatientLatitude:     h.PatientLatitude,
-		PatientLongitude:    h.PatientLongitude,
-		PatientAddress:      h.PatientAddress,
-		PatientDistrict:     h.PatientDistrict,
-		PatientNeighborhood: h.PatientNeighborhood,
-		ConsultationDate:    h.ConsultationDate,
-		SymptomsStartDate:   h.SymptomsStartDate,
-		IsContagious:        h.IsContagious,
-		CreatedAt:           h.CreatedAt,
+		ID:                      h.ID,
+		FechaIngreso:            h.FechaIngreso,
+		MotivoConsulta:          h.MotivoConsulta,
+		Diagnostico:             h.Diagnostico,
+		Tratamiento:             h.Tratamiento,
+		Medicamentos:            h.Medicamentos,
+		Observaciones:           h.Observaciones,
+		PatientLatitude:         h.PatientLatitude,
+		PatientLongitude:        h.PatientLongitude,
+		PatientAddress:          h.PatientAddress,
+		PatientDistrict:         h.PatientDistrict,
+		PatientNeighborhood:     h.PatientNeighborhood,
+		ConsultationDate:        h.ConsultationDate,
+		SymptomsStartDate:       h.SymptomsStartDate,
+		IsContagious:            h.IsContagious,
+		ViaTransmision:          h.ViaTransmision,
+		NotificacionObligatoria: h.NotificacionObligatoria,
+		CreatedAt:               h.CreatedAt,
 		Paciente: PacienteEnfermedadInfo{
 			ID:       h.Paciente.ID,
 			Nombre:   nombre,
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// HistorialRevision representa una versión de un historial clínico.
+// Cada creación, modificación o eliminación guarda una instantánea completa con un número de versión correlativo.
+// La tabla es de solo inserción: un trigger rechaza UPDATE, DELETE y TRUNCATE.
+type HistorialRevision struct {
+	ID          uint            `json:"id" gorm:"primaryKey;autoIncrement"`
+	IDHistorial uint            `json:"id_historial" gorm:"not null;uniqueIndex:idx_historial_revision_version"`
+	Version     int             `json:"version" gorm:"not null;uniqueIndex:idx_historial_revision_version"`
+	Datos       json.RawMessage `json:"datos,omitempty" gorm:"type:jsonb;not null"`
+	IDUsuario   *uint           `json:"id_usuario"`                              // Usuario que generó la versión; nil para datos previos al versionado
+	Eliminada   bool            `json:"eliminada" gorm:"not null;default:false"` // La versión registra la eliminación del historial
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (HistorialRevision) TableName() string {
+	return "historial_revisiones"
+}
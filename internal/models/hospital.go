@@ -8,21 +8,22 @@ import (
 
 // Hospital representa la tabla de hospitales
 type Hospital struct {
-	ID        uint           `json:"id" gorm:"primaryKey;autoIncrement"`
-	Nombre    string         `json:"nombre" gorm:"type:varchar(100);not null" validate:"required,min=2,max=100"`
-	Direccion string         `json:"direccion" gorm:"type:varchar(200);not null" validate:"required,min=5,max=200"`
-	Latitud   float64        `json:"latitud" gorm:"type:decimal(10,8);not null" validate:"required,latitude"`
-	Longitud  float64        `json:"longitud" gorm:"type:decimal(11,8);not null" validate:"required,longitude"`
-	Ciudad    string         `json:"ciudad" gorm:"type:varchar(50);not null" validate:"required,min=2,max=50"`
-	Telefono  string         `json:"telefono" gorm:"type:varchar(20);unique"`
-	Email     string         `json:"email" gorm:"type:varchar(100);unique;not null" validate:"required,email"`
-	Password  string         `json:"-" gorm:"type:varchar(255);not null"` // No se incluye en JSON
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID              uint           `json:"id" gorm:"primaryKey;autoIncrement"`
+	Nombre          string         `json:"nombre" gorm:"type:varchar(100);not null" validate:"required,min=2,max=100"`
+	Direccion       string         `json:"direccion" gorm:"type:varchar(200);not null" validate:"required,min=5,max=200"`
+	Latitud         float64        `json:"latitud" gorm:"type:decimal(10,8);not null" validate:"required,latitude"`
+	Longitud        float64        `json:"longitud" gorm:"type:decimal(11,8);not null" validate:"required,longitude"`
+	Ciudad          string         `json:"ciudad" gorm:"type:varchar(50);not null" validate:"required,min=2,max=50"`
+	Telefono        string         `json:"telefono" gorm:"type:varchar(20);unique"`
+	Email           string         `json:"email" gorm:"type:varchar(100);unique;not null" validate:"required,email"` // Email de contacto; las credenciales viven en usuarios
+	EmailVerificado bool           `json:"email_verificado" gorm:"not null;default:false"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relaciones
 	HistorialesClinico []HistorialClinico `json:"historiales_clinico,omitempty" gorm:"foreignKey:IDHospital"`
+	Usuarios           []Usuario          `json:"usuarios,omitempty" gorm:"foreignKey:IDHospital"`
 }
 
 // TableName especifica el nombre de la tabla en la base de datos
@@ -32,30 +33,32 @@ func (Hospital) TableName() string {
 
 // HospitalResponse es la estructura para respuestas sin información sensible
 type HospitalResponse struct {
-	ID        uint      `json:"id"`
-	Nombre    string    `json:"nombre"`
-	Direccion string    `json:"direccion"`
-	Latitud   float64   `json:"latitud"`
-	Longitud  float64   `json:"longitud"`
-	Ciudad    string    `json:"ciudad"`
-	Telefono  string    `json:"telefono"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uint      `json:"id"`
+	Nombre          string    `json:"nombre"`
+	Direccion       string    `json:"direccion"`
+	Latitud         float64   `json:"latitud"`
+	Longitud        float64   `json:"longitud"`
+	Ciudad          string    `json:"ciudad"`
+	Telefono        string    `json:"telefono"`
+	Email           string    `json:"email"`
+	EmailVerificado bool      `json:"email_verificado"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
 }
 
 // ToResponse convierte Hospital a HospitalResponse
 func (h *Hospital) ToResponse() HospitalResponse {
 	return HospitalResponse{
-		ID:        h.ID,
-		Nombre:    h.Nombre,
-		Direccion: h.Direccion,
-		Latitud:   h.Latitud,
-		Longitud:  h.Longitud,
-		Ciudad:    h.Ciudad,
-		Telefono:  h.Telefono,
-		Email:     h.Email,
-		CreatedAt: h.CreatedAt,
-		UpdatedAt: h.UpdatedAt,
+		ID:              h.ID,
+		Nombre:          h.Nombre,
+		Direccion:       h.Direccion,
+		Latitud:         h.Latitud,
+		Longitud:        h.Longitud,
+		Ciudad:          h.Ciudad,
+		Telefono:        h.Telefono,
+		Email:           h.Email,
+		EmailVerificado: h.EmailVerificado,
+		CreatedAt:       h.CreatedAt,
+		UpdatedAt:       h.UpdatedAt,
 	}
 }
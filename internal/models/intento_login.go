@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// IntentoLogin acumula los intentos de login fallidos de un email o una IP
+type IntentoLogin struct {
+	ID             uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Tipo           string     `json:"tipo" gorm:"type:varchar(10);not null;uniqueIndex:idx_intento_login_clave"` // "email" o "ip"
+	Valor          string     `json:"valor" gorm:"type:varchar(100);not null;uniqueIndex:idx_intento_login_clave"`
+	Fallos         int        `json:"fallos" gorm:"not null;default:0"`
+	PrimerFallo    time.Time  `json:"primer_fallo" gorm:"not null"`
+	BloqueadoHasta *time.Time `json:"bloqueado_hasta"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (IntentoLogin) TableName() string {
+	return "intentos_login"
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Estados de una notificación epidemiológica: pendiente → enviada → recibida
+const (
+	NotificacionPendiente = "pendiente" // Generada al registrar el caso, falta que el hospital la envíe
+	NotificacionEnviada   = "enviada"   // Enviada por el hospital al equipo de vigilancia
+	NotificacionRecibida  = "recibida"  // Con acuse de recibo del equipo de vigilancia
+)
+
+// NotificacionEpidemiologica notificación obligatoria de un caso de una enfermedad de notificación inmediata.
+// Se genera una por historial y enfermedad cuando el catálogo marca la enfermedad como notificable.
+type NotificacionEpidemiologica struct {
+	ID                 uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	IDHistorial        uint       `json:"id_historial" gorm:"not null;uniqueIndex:idx_notificacion_historial_enfermedad"`
+	IDEnfermedad       uint       `json:"id_enfermedad" gorm:"not null;uniqueIndex:idx_notificacion_historial_enfermedad"`
+	IDHospital         uint       `json:"id_hospital" gorm:"not null;index"`
+	Estado             string     `json:"estado" gorm:"type:varchar(20);not null;default:'pendiente';index;check:estado IN ('pendiente','enviada','recibida')"`
+	EnviadaAt          *time.Time `json:"enviada_at"`
+	IDUsuarioEnvio     *uint      `json:"id_usuario_envio"`
+	RecibidaAt         *time.Time `json:"recibida_at"`
+	IDUsuarioRecepcion *uint      `json:"id_usuario_recepcion"`
+	Observaciones      string     `json:"observaciones" gorm:"type:text"`
+	CreatedAt          time.Time  `json:"created_at" gorm:"index"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+
+	// Relaciones
+	Historial  *HistorialClinico `json:"historial,omitempty" gorm:"foreignKey:IDHistorial"`
+	Enfermedad *Enfermedad       `json:"enfermedad,omitempty" gorm:"foreignKey:IDEnfermedad"`
+	Hospital   *Hospital         `json:"hospital,omitempty" gorm:"foreignKey:IDHospital"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (NotificacionEpidemiologica) TableName() string {
+	return "notificacion_epidemiologica"
+}
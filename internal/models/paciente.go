@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -9,12 +10,20 @@ import (
 // Paciente representa la tabla de pacientes
 type Paciente struct {
 	ID              uint           `json:"id" gorm:"primaryKey;autoIncrement"`
-	Nombre          string         `json:"nombre" gorm:"type:varchar(100);not null" validate:"required,min=2,max=100"`
+	CI              string         `json:"ci" gorm:"type:varchar(20);index" validate:"omitempty,max=20"` // Cédula de identidad
+	Nombres         string         `json:"nombres" gorm:"type:varchar(100)" validate:"omitempty,min=2,max=100"`
+	Apellidos       string         `json:"apellidos" gorm:"type:varchar(100)" validate:"omitempty,min=2,max=100"`
+	Nombre          string         `json:"nombre" gorm:"type:varchar(100);not null" validate:"required_without=Nombres,omitempty,min=2,max=100"` // Nombre completo; se deriva de nombres y apellidos si se envían
 	FechaNacimiento time.Time      `json:"fecha_nacimiento" gorm:"type:date;not null" validate:"required"`
 	Sexo            string         `json:"sexo" gorm:"type:varchar(1);not null;check:sexo IN ('M','F','O')" validate:"required,oneof=M F O"`
 	TipoSangre      string         `json:"tipo_sangre" gorm:"type:varchar(4)" validate:"omitempty,max=4"`
 	PesoKg          float64        `json:"peso_kg" gorm:"type:decimal(5,2);check:peso_kg > 0" validate:"omitempty,gt=0"`
 	AlturaCm        int            `json:"altura_cm" gorm:"type:int;check:altura_cm > 0" validate:"omitempty,gt=0"`
+	Telefono        string         `json:"telefono" gorm:"type:varchar(20)" validate:"omitempty,max=20"`
+	Direccion       string         `json:"direccion" gorm:"type:varchar(255)" validate:"omitempty,max=255"`
+	IDHospital      *uint          `json:"id_hospital" gorm:"index"`               // Hospital que registró al paciente
+	IDFusionadoEn   *uint          `json:"id_fusionado_en,omitempty" gorm:"index"` // Paciente que absorbió este registro duplicado
+	Version         int            `json:"version" gorm:"not null;default:1"`      // Se incrementa en cada modificación (ETag)
 	CreatedAt       time.Time      `json:"created_at"`
 	UpdatedAt       time.Time      `json:"updated_at"`
 	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
@@ -28,6 +37,15 @@ func (Paciente) TableName() string {
 	return "pacientes"
 }
 
+// NombreCompleto retorna "nombres apellidos" si hay nombre estructurado, o el nombre libre en caso contrario
+func (p *Paciente) NombreCompleto() string {
+	completo := strings.Join(strings.Fields(p.Nombres+" "+p.Apellidos), " ")
+	if completo == "" {
+		return p.Nombre
+	}
+	return completo
+}
+
 // GetAge calcula la edad del paciente
 func (p *Paciente) GetAge() int {
 	now := time.Now()
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// RefreshToken representa un refresh token emitido a un usuario.
+// Solo se almacena el hash SHA-256 del token; cada uso lo rota por uno nuevo de la misma familia.
+type RefreshToken struct {
+	ID             uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	IDUsuario      uint       `json:"id_usuario" gorm:"not null;index"`
+	TokenHash      string     `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	Familia        string     `json:"familia" gorm:"type:varchar(64);not null;index"` // Cadena de rotaciones originada en un login
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	ReemplazadoPor *uint      `json:"reemplazado_por"`
+	CreatedAt      time.Time  `json:"created_at"`
+
+	// Relaciones
+	Usuario Usuario `json:"-" gorm:"foreignKey:IDUsuario"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// TokenRevocado representa un access token (jti) revocado antes de su expiración
+type TokenRevocado struct {
+	JTI       string    `json:"jti" gorm:"primaryKey;type:varchar(64)"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (TokenRevocado) TableName() string {
+	return "tokens_revocados"
+}
+
+// Tipos de token de verificación
+const (
+	TokenVerificacionEmail = "verificacion_email"
+	TokenResetPassword     = "reset_password"
+)
+
+// TokenVerificacion representa un token de un solo uso enviado por email
+// para verificar la cuenta de un hospital o restablecer la contraseña de un usuario
+type TokenVerificacion struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	IDUsuario uint       `json:"id_usuario" gorm:"not null;index"`
+	Tipo      string     `json:"tipo" gorm:"type:varchar(30);not null;check:tipo IN ('verificacion_email','reset_password')"`
+	TokenHash string     `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// Relaciones
+	Usuario Usuario `json:"-" gorm:"foreignKey:IDUsuario"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (TokenVerificacion) TableName() string {
+	return "tokens_verificacion"
+}
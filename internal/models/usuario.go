@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Roles disponibles para los usuarios del sistema
+const (
+	RolAdmin        = "admin"
+	RolMedico       = "medico"
+	RolEnfermeria   = "enfermeria"
+	RolEpidemiologo = "epidemiologo"
+	RolAnalista     = "analista"
+)
+
+// RolesValidos lista todos los roles aceptados
+var RolesValidos = []string{RolAdmin, RolMedico, RolEnfermeria, RolEpidemiologo, RolAnalista}
+
+// Usuario representa la tabla de usuarios; cada usuario pertenece a un hospital
+type Usuario struct {
+	ID           uint           `json:"id" gorm:"primaryKey;autoIncrement"`
+	IDHospital   uint           `json:"id_hospital" gorm:"not null;index"`
+	Nombre       string         `json:"nombre" gorm:"type:varchar(100);not null" validate:"required,min=2,max=100"`
+	Email        string         `json:"email" gorm:"type:varchar(100);unique;not null" validate:"required,email"`
+	Password     string         `json:"-" gorm:"type:varchar(255);not null"` // No se incluye en JSON
+	Rol          string         `json:"rol" gorm:"type:varchar(20);not null;check:rol IN ('admin','medico','enfermeria','epidemiologo','analista')" validate:"required,oneof=admin medico enfermeria epidemiologo analista"`
+	Activo       bool           `json:"activo" gorm:"not null;default:true"`
+	UltimoAcceso *time.Time     `json:"ultimo_acceso"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relaciones
+	Hospital Hospital `json:"hospital,omitempty" gorm:"foreignKey:IDHospital"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (Usuario) TableName() string {
+	return "usuarios"
+}
+
+// UsuarioResponse es la estructura para respuestas sin información sensible
+type UsuarioResponse struct {
+	ID           uint       `json:"id"`
+	IDHospital   uint       `json:"id_hospital"`
+	Nombre       string     `json:"nombre"`
+	Email        string     `json:"email"`
+	Rol          string     `json:"rol"`
+	Activo       bool       `json:"activo"`
+	UltimoAcceso *time.Time `json:"ultimo_acceso"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// ToResponse convierte Usuario a UsuarioResponse
+func (u *Usuario) ToResponse() UsuarioResponse {
+	return UsuarioResponse{
+		ID:           u.ID,
+		IDHospital:   u.IDHospital,
+		Nombre:       u.Nombre,
+		Email:        u.Email,
+		Rol:          u.Rol,
+		Activo:       u.Activo,
+		UltimoAcceso: u.UltimoAcceso,
+		CreatedAt:    u.CreatedAt,
+		UpdatedAt:    u.UpdatedAt,
+	}
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tipos de evento publicados en tiempo real (SSE y webhooks)
+const (
+	EventoAlertaNueva    = "alerta.nueva"    // El motor de alertas generó una alerta de brote
+	EventoCasoContagioso = "caso.contagioso" // Se registró un historial clínico contagioso
+)
+
+// EventosValidos lista los tipos de evento a los que se puede suscribir un webhook
+var EventosValidos = []string{EventoAlertaNueva, EventoCasoContagioso}
+
+// Estados de una entrega de webhook
+const (
+	EntregaPendiente = "pendiente" // En cola o esperando el próximo reintento
+	EntregaEntregada = "entregada" // El destino respondió 2xx
+	EntregaFallida   = "fallida"   // Se agotaron los reintentos
+)
+
+// SuscripcionWebhook sistema externo que recibe los eventos por HTTP POST firmados con HMAC-SHA256
+type SuscripcionWebhook struct {
+	ID        uint           `json:"id" gorm:"primaryKey;autoIncrement"`
+	Nombre    string         `json:"nombre" gorm:"type:varchar(150);not null"`
+	URL       string         `json:"url" gorm:"type:varchar(500);not null"`
+	Secreto   string         `json:"-" gorm:"type:varchar(128);not null"`       // Clave HMAC; solo se muestra al crear la suscripción
+	Eventos   string         `json:"eventos" gorm:"type:varchar(200);not null"` // Tipos de evento separados por coma
+	Activa    bool           `json:"activa" gorm:"not null;default:true"`
+	IDUsuario *uint          `json:"id_usuario"` // Usuario que creó la suscripción
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (SuscripcionWebhook) TableName() string {
+	return "suscripciones_webhook"
+}
+
+// EntregaWebhook envío de un evento a una suscripción, con sus reintentos
+type EntregaWebhook struct {
+	ID             uint            `json:"id" gorm:"primaryKey;autoIncrement"`
+	IDSuscripcion  uint            `json:"id_suscripcion" gorm:"not null;index"`
+	Evento         string          `json:"evento" gorm:"type:varchar(50);not null"`
+	Payload        json.RawMessage `json:"payload" gorm:"type:jsonb;not null"`
+	Estado         string          `json:"estado" gorm:"type:varchar(10);not null;default:'pendiente';index:idx_entregas_webhook_cola"`
+	Intentos       int             `json:"intentos" gorm:"not null;default:0"`
+	ProximoIntento time.Time       `json:"proximo_intento" gorm:"not null;index:idx_entregas_webhook_cola"`
+	EntregadaAt    *time.Time      `json:"entregada_at"`
+	CreatedAt      time.Time       `json:"created_at" gorm:"index"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+
+	// Relaciones
+	IntentosEntrega []IntentoWebhook `json:"intentos_entrega,omitempty" gorm:"foreignKey:IDEntrega"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (EntregaWebhook) TableName() string {
+	return "entregas_webhook"
+}
+
+// IntentoWebhook resultado de cada intento de envío de una entrega
+type IntentoWebhook struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	IDEntrega  uint      `json:"id_entrega" gorm:"not null;index"`
+	Numero     int       `json:"numero" gorm:"not null"`
+	CodigoHTTP int       `json:"codigo_http"` // 0 si no hubo respuesta
+	Error      string    `json:"error,omitempty" gorm:"type:text"`
+	DuracionMs int64     `json:"duracion_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (IntentoWebhook) TableName() string {
+	return "intentos_webhook"
+}
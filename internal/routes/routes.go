@@ -1,8 +1,12 @@
 package routes
 
 import (
+	"log"
+
+	"hospital-api/internal/config"
 	"hospital-api/internal/handlers"
 	"hospital-api/internal/middleware"
+	"hospital-api/internal/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,6 +16,11 @@ func SetupRoutes() *gin.Engine {
 	// Crear router de Gin
 	router := gin.New()
 
+	// Solo se confía en el X-Forwarded-For de los proxies configurados (la IP se usa para limitar el login)
+	if err := router.SetTrustedProxies(config.GetConfig().Server.TrustedProxies); err != nil {
+		log.Printf("TRUSTED_PROXIES inválido: %v", err)
+	}
+
 	// Middleware globales
 	router.Use(middleware.JSONLoggerMiddleware())
 	router.Use(gin.Recovery())
@@ -26,10 +35,31 @@ func SetupRoutes() *gin.Engine {
 	propagacionHandler := handlers.NewPropagacionHandler()
 	// AGREGADO: Crear instancia del chatbot handler
 	chatbotHandler := handlers.NewChatbotHandler()
+	usuarioHandler := handlers.NewUsuarioHandler()
+	auditHandler := handlers.NewAuditHandler()
+	enfermedadHandler := handlers.NewEnfermedadHandler()
+	notificacionHandler := handlers.NewNotificacionHandler()
+	alertaHandler := handlers.NewAlertaHandler()
+	eventoHandler := handlers.NewEventoHandler()
+	webhookHandler := handlers.NewWebhookHandler()
+	distritoHandler := handlers.NewDistritoHandler()
+	mapaHandler := handlers.NewMapaHandler()
+
+	// Permisos por rol
+	soloAdmin := middleware.RequireRoles(models.RolAdmin)
+	soloMedicos := middleware.RequireRoles(models.RolMedico)
+	personalClinico := middleware.RequireRoles(models.RolMedico, models.RolEnfermeria)
+	lecturaHistorial := middleware.RequireRoles(models.RolMedico, models.RolEnfermeria, models.RolEpidemiologo)
+	vigilancia := middleware.RequireRoles(models.RolEpidemiologo, models.RolAnalista)
+	soloEpidemiologos := middleware.RequireRoles(models.RolEpidemiologo)
+	fusionPacientes := middleware.RequireRoles(models.RolMedico, models.RolAdmin, models.RolEpidemiologo)
 
-	// Todas las rutas son públicas ahora
 	api := router.Group("/api/v1")
 	{
+		// ==========================
+		// Rutas públicas
+		// ==========================
+
 		// Health check
 		api.GET("/health", func(c *gin.Context) {
 			c.JSON(200, gin.H{
@@ -44,56 +74,168 @@ func SetupRoutes() *gin.Engine {
 		{
 			auth.POST("/login", authHandler.Login)
 			auth.POST("/register", authHandler.Register)
-			auth.GET("/profile", authHandler.GetProfile)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/verify-email/request", authHandler.RequestEmailVerification)
+			auth.POST("/verify-email/confirm", authHandler.ConfirmEmailVerification)
+			auth.POST("/password-reset/request", authHandler.RequestPasswordReset)
+			auth.POST("/password-reset/confirm", authHandler.ConfirmPasswordReset)
+		}
+
+		// ==========================
+		// Rutas protegidas (requieren JWT)
+		// ==========================
+		protected := api.Group("")
+		protected.Use(middleware.AuthMiddleware())
+
+		protected.GET("/auth/profile", authHandler.GetProfile)
+		protected.POST("/auth/logout", authHandler.Logout)
+
+		// Gestión de usuarios del hospital
+		usuarios := protected.Group("/usuarios", soloAdmin)
+		{
+			usuarios.GET("/", usuarioHandler.GetUsuarios)
+			usuarios.POST("/", usuarioHandler.CreateUsuario)
+			usuarios.PUT("/:id", usuarioHandler.UpdateUsuario)
+			usuarios.DELETE("/:id", usuarioHandler.DeleteUsuario)
 		}
 
+		// Rutas de auditoría (solo administradores)
+		protected.GET("/audit", soloAdmin, auditHandler.GetAuditLogs)
+
+		// Pacientes sin hospital de registro (solo administradores)
+		protected.GET("/pacientes/unassigned", soloAdmin, pacienteHandler.GetPacientesSinHospital)
+		protected.POST("/pacientes/:id/claim", soloAdmin, pacienteHandler.ReclamarPaciente)
+
+		// Fusión de pacientes duplicados: entre hospitales solo administradores y epidemiólogos
+		protected.POST("/pacientes/merge", fusionPacientes, pacienteHandler.FusionarPacientes)
+
 		// Gestión de pacientes
-		pacientes := api.Group("/pacientes")
+		pacientes := protected.Group("/pacientes", personalClinico)
 		{
 			pacientes.POST("/", pacienteHandler.CreatePaciente)
 			pacientes.GET("/search", pacienteHandler.SearchPacientes)
+			pacientes.GET("/duplicates", pacienteHandler.GetDuplicados)
 			pacientes.GET("/", pacienteHandler.GetAllPacientes)
 			pacientes.GET("/:id", pacienteHandler.GetPaciente)
 			pacientes.PUT("/:id", pacienteHandler.UpdatePaciente)
+			pacientes.PATCH("/:id", pacienteHandler.PatchPaciente)
 			pacientes.DELETE("/:id", pacienteHandler.DeletePaciente)
 		}
 
 		// Gestión de hospitales
-		hospitales := api.Group("/hospitales")
+		hospitales := protected.Group("/hospitales")
 		{
 			hospitales.GET("/", hospitalHandler.GetAllHospitales)
 			hospitales.GET("/nearby", hospitalHandler.GetHospitalesNearby)
+			hospitales.GET("/nearest", hospitalHandler.GetHospitalesMasCercanos)
+			hospitales.GET("/bbox", hospitalHandler.GetHospitalesEnBBox)
 			hospitales.GET("/:id", hospitalHandler.GetHospital)
 			hospitales.GET("/with-patients-count", hospitalHandler.GetAllHospitalesWithPatientsCount)
 			hospitales.GET("/:id/with-patients-count", hospitalHandler.GetHospitalWithPatientsCount)
 			hospitales.GET("/stats-overview", hospitalHandler.GetHospitalesStatsOverview)
 		}
 
+		// Registro de distritos: población, área, centroide y conexiones
+		distritos := protected.Group("/distritos")
+		{
+			distritos.GET("/", distritoHandler.GetDistritos)
+			distritos.GET("/limites", distritoHandler.GetLimites)
+			distritos.POST("/limites/importar", soloAdmin, distritoHandler.ImportarLimites)
+			distritos.GET("/:id", distritoHandler.GetDistrito)
+			distritos.POST("/", soloAdmin, distritoHandler.CreateDistrito)
+			distritos.PUT("/:id", soloAdmin, distritoHandler.UpdateDistrito)
+			distritos.DELETE("/:id", soloAdmin, distritoHandler.DeleteDistrito)
+			distritos.PUT("/:id/conexiones", soloAdmin, distritoHandler.UpdateConexiones)
+		}
+
+		// Catálogo de enfermedades (CIE-10)
+		enfermedades := protected.Group("/enfermedades")
+		{
+			enfermedades.GET("/", enfermedadHandler.BuscarEnfermedades)
+			enfermedades.GET("/:id", enfermedadHandler.GetEnfermedad)
+			enfermedades.PUT("/:id/intervalo-serial", soloEpidemiologos, enfermedadHandler.UpdateIntervaloSerial)
+			enfermedades.POST("/", soloAdmin, enfermedadHandler.CreateEnfermedad)
+			enfermedades.PUT("/:id", soloAdmin, enfermedadHandler.UpdateEnfermedad)
+			enfermedades.PUT("/:id/sinonimos", soloAdmin, enfermedadHandler.UpdateSinonimos)
+		}
+
 		// Gestión de historial clínico
-		historial := api.Group("/historial")
+		historial := protected.Group("/historial")
 		{
-			historial.POST("/", historialHandler.CreateHistorial)
-			historial.GET("/", hospitalHandler.GetAllHospitales)
-			historial.GET("/:id", historialHandler.GetHistorial)
-			historial.PUT("/:id", historialHandler.UpdateHistorial)
-			historial.DELETE("/:id", historialHandler.DeleteHistorial)
-			historial.GET("/paciente/:paciente_id", historialHandler.GetHistorialByPaciente)
-			historial.GET("/enfermedad", historialHandler.GetHistorialByEnfermedad)
+			historial.POST("/", soloMedicos, historialHandler.CreateHistorial)
+			historial.GET("/", lecturaHistorial, historialHandler.ListHistorial)
+			historial.GET("/hospital", personalClinico, historialHandler.GetHistorialByHospital)
+			historial.GET("/cercanos", lecturaHistorial, historialHandler.GetHistorialesCercanos)
+			historial.GET("/:id", lecturaHistorial, historialHandler.GetHistorial)
+			historial.PUT("/:id", soloMedicos, historialHandler.UpdateHistorial)
+			historial.PATCH("/:id", soloMedicos, historialHandler.PatchHistorial)
+			historial.DELETE("/:id", soloMedicos, historialHandler.DeleteHistorial)
+			historial.GET("/:id/revisiones", lecturaHistorial, historialHandler.GetRevisiones)
+			historial.GET("/:id/revisiones/diff", lecturaHistorial, historialHandler.DiffRevisiones)
+			historial.GET("/:id/revisiones/:version", lecturaHistorial, historialHandler.GetRevision)
+			historial.GET("/paciente/:paciente_id", lecturaHistorial, historialHandler.GetHistorialByPaciente)
+			historial.GET("/enfermedad", lecturaHistorial, historialHandler.GetHistorialByEnfermedad)
+		}
+
+		// Notificación obligatoria de enfermedades
+		notificaciones := protected.Group("/notificaciones", lecturaHistorial)
+		{
+			notificaciones.GET("/", notificacionHandler.GetNotificaciones)
+			notificaciones.GET("/:id", notificacionHandler.GetNotificacion)
+			notificaciones.POST("/:id/enviar", soloMedicos, notificacionHandler.EnviarNotificacion)
+			notificaciones.POST("/:id/acuse", soloEpidemiologos, notificacionHandler.AcusarRecibo)
+		}
+
+		// Alertas de brote y reglas del motor de alertas
+		alertas := protected.Group("/alertas")
+		{
+			alertas.GET("/", vigilancia, alertaHandler.GetAlertas)
+			alertas.POST("/evaluar", soloEpidemiologos, alertaHandler.EvaluarReglas)
+			alertas.GET("/reglas", soloEpidemiologos, alertaHandler.GetReglas)
+			alertas.POST("/reglas", soloEpidemiologos, alertaHandler.CreateRegla)
+			alertas.GET("/reglas/:id", soloEpidemiologos, alertaHandler.GetRegla)
+			alertas.PUT("/reglas/:id", soloEpidemiologos, alertaHandler.UpdateRegla)
+			alertas.DELETE("/reglas/:id", soloEpidemiologos, alertaHandler.DeleteRegla)
+		}
+
+		// Eventos en tiempo real: SSE para tableros y webhooks para sistemas externos
+		// El stream se registra fuera de protected porque acepta el token de eventos en la URL
+		api.GET("/eventos/stream", middleware.EventStreamAuthMiddleware(), vigilancia, eventoHandler.StreamEventos)
+		protected.POST("/eventos/stream/token", vigilancia, authHandler.CreateTokenEventos)
+		webhooks := protected.Group("/webhooks", soloEpidemiologos)
+		{
+			webhooks.GET("/", webhookHandler.GetWebhooks)
+			webhooks.POST("/", webhookHandler.CreateWebhook)
+			webhooks.GET("/:id", webhookHandler.GetWebhook)
+			webhooks.PUT("/:id", webhookHandler.UpdateWebhook)
+			webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+			webhooks.GET("/:id/entregas", webhookHandler.GetEntregas)
+			webhooks.POST("/:id/entregas/:entrega_id/reintentar", webhookHandler.ReintentarEntrega)
 		}
 
 		// Endpoints para geocodificación
-		api.POST("/geocode", historialHandler.GeocodeAddress)
-		api.POST("/geocode/evaluate", historialHandler.EvaluateGeocodePrecision)
+		protected.POST("/geocode", personalClinico, historialHandler.GeocodeAddress)
+		protected.POST("/geocode/evaluate", personalClinico, historialHandler.EvaluateGeocodePrecision)
 
 		// Epidemiología y mapas de calor
-		epidemiologia := api.Group("/epidemiologia")
+		epidemiologia := protected.Group("/epidemiologia")
+		{
+			epidemiologia.GET("/stats", vigilancia, historialHandler.GetEpidemiologicalStats)
+			epidemiologia.GET("/contagious", lecturaHistorial, historialHandler.GetContagiousHistorial)
+		}
+
+		// Capas de mapa en GeoJSON y vector tiles de casos
+		mapa := protected.Group("/mapa")
 		{
-			epidemiologia.GET("/stats", historialHandler.GetEpidemiologicalStats)
-			epidemiologia.GET("/contagious", historialHandler.GetContagiousHistorial)
+			mapa.GET("/casos", lecturaHistorial, mapaHandler.GetCasosGeoJSON)
+			mapa.GET("/hospitales", mapaHandler.GetHospitalesGeoJSON)
+			mapa.GET("/distritos", vigilancia, mapaHandler.GetDistritosGeoJSON)
+			mapa.GET("/calor", vigilancia, mapaHandler.GetMapaCalor)
 		}
+		protected.GET("/tiles/:z/:x/:y", lecturaHistorial, mapaHandler.GetTileCasos)
 
 		// Propagación
-		propagacionGroup := api.Group("/propagacion")
+		propagacionGroup := protected.Group("/propagacion", soloEpidemiologos)
 		{
 			// Análisis principal de velocidad de propagación
 			propagacionGroup.GET("/analizar", propagacionHandler.AnalyzeSpreadVelocity)
@@ -109,10 +251,16 @@ func SetupRoutes() *gin.Engine {
 			
 			// Rutas de propagación
 			propagacionGroup.GET("/rutas", propagacionHandler.GetSpreadRoutes)
+
+			// Detección estadística de brotes (EARS, CUSUM, Farrington)
+			propagacionGroup.GET("/aberraciones", propagacionHandler.DetectAberrations)
+
+			// Número reproductivo efectivo (Rt, método de Cori)
+			propagacionGroup.GET("/rt", propagacionHandler.EstimateRt)
 		}
 
 		// CORREGIDO: Chatbot endpoints
-		chatbot := api.Group("/chatbot")
+		chatbot := protected.Group("/chatbot")
 		{
 			// Endpoint principal para conversación
 			chatbot.POST("/chat", chatbotHandler.Chat)
@@ -121,121 +269,14 @@ func SetupRoutes() *gin.Engine {
 			chatbot.GET("/health", chatbotHandler.HealthCheck)
 		}
 
-		// Grupo público para datos de referencia
+		// Grupo público para datos de referencia (no requiere JWT)
 		publicGroup := api.Group("/public/propagacion")
 		{
 			// Información básica de distritos de Santa Cruz
-			publicGroup.GET("/distritos", func(c *gin.Context) {
-				distritos := map[string]interface{}{
-					"ciudad": "Santa Cruz de la Sierra",
-					"distritos": []map[string]interface{}{
-						{
-							"nombre":       "Equipetrol",
-							"habitantes":   85000,
-							"area_km2":     12.5,
-							"densidad":     6800,
-							"tipo":         "Residencial-Comercial",
-							"coordenadas":  map[string]float64{"lat": -17.7690416, "lng": -63.1956686},
-						},
-						{
-							"nombre":       "Norte",
-							"habitantes":   320000,
-							"area_km2":     45.8,
-							"densidad":     6986,
-							"tipo":         "Residencial-Popular",
-							"coordenadas":  map[string]float64{"lat": -17.7987909, "lng": -63.210345},
-						},
-						{
-							"nombre":       "Plan Tres Mil",
-							"habitantes":   180000,
-							"area_km2":     22.3,
-							"densidad":     8072,
-							"tipo":         "Popular-Alta Densidad",
-							"coordenadas":  map[string]float64{"lat": -17.798792, "lng": -63.210345},
-						},
-						{
-							"nombre":       "Villa 1ro de Mayo",
-							"habitantes":   95000,
-							"area_km2":     18.7,
-							"densidad":     5080,
-							"tipo":         "Residencial",
-							"coordenadas":  map[string]float64{"lat": -17.7379806, "lng": -63.2484834},
-						},
-						{
-							"nombre":       "Sur",
-							"habitantes":   125000,
-							"area_km2":     28.4,
-							"densidad":     4401,
-							"tipo":         "Residencial-Comercial",
-							"coordenadas":  map[string]float64{"lat": -17.7441931, "lng": -63.1801563},
-						},
-						{
-							"nombre":       "Oeste",
-							"habitantes":   75000,
-							"area_km2":     35.2,
-							"densidad":     2131,
-							"tipo":         "Residencial-Periférico",
-							"coordenadas":  map[string]float64{"lat": -17.7439533, "lng": -63.1756103},
-						},
-						{
-							"nombre":       "Este",
-							"habitantes":   60000,
-							"area_km2":     42.1,
-							"densidad":     1425,
-							"tipo":         "Periférico-Rural",
-							"coordenadas":  map[string]float64{"lat": -17.7728417, "lng": -63.2374135},
-						},
-						{
-							"nombre":       "Centro",
-							"habitantes":   45000,
-							"area_km2":     8.2,
-							"densidad":     5488,
-							"tipo":         "Comercial-Histórico",
-							"coordenadas":  map[string]float64{"lat": -17.7807346, "lng": -63.1890985},
-						},
-					},
-					"estadisticas": map[string]interface{}{
-						"poblacion_total": 1970000,
-						"area_total_km2":  187.2,
-						"densidad_promedio": 5245,
-					},
-				}
-				
-				c.JSON(200, map[string]interface{}{
-					"success": true,
-					"message": "Información de distritos de Santa Cruz obtenida exitosamente",
-					"data":    distritos,
-				})
-			})
+			publicGroup.GET("/distritos", distritoHandler.GetDistritosPublico)
 			
 			// Matriz de conectividad entre distritos
-			publicGroup.GET("/conectividad", func(c *gin.Context) {
-				conectividad := map[string]interface{}{
-					"matriz_conectividad": map[string][]string{
-						"Equipetrol":       {"Norte", "Centro", "Sur"},
-						"Norte":            {"Equipetrol", "Plan Tres Mil", "Este"},
-						"Plan Tres Mil":    {"Norte", "Sur", "Este"},
-						"Villa 1ro de Mayo": {"Oeste", "Centro"},
-						"Sur":              {"Equipetrol", "Plan Tres Mil", "Centro"},
-						"Oeste":            {"Villa 1ro de Mayo", "Centro"},
-						"Este":             {"Norte", "Plan Tres Mil"},
-						"Centro":           {"Equipetrol", "Sur", "Oeste", "Villa 1ro de Mayo"},
-					},
-					"descripcion": "Matriz de conectividad entre distritos de Santa Cruz de la Sierra",
-					"criterios": []string{
-						"Proximidad geográfica",
-						"Conexiones de transporte público",
-						"Flujo poblacional diario",
-						"Corredores comerciales",
-					},
-				}
-				
-				c.JSON(200, map[string]interface{}{
-					"success": true,
-					"message": "Matriz de conectividad obtenida exitosamente",
-					"data":    conectividad,
-				})
-			})
+			publicGroup.GET("/conectividad", distritoHandler.GetConectividadPublica)
 		}
 	}
 
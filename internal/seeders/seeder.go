@@ -36,8 +36,9 @@ func (s *Seeder) CleanDatabase() error {
 		}
 	}
 
-	// Limpiar tablas en orden para evitar problemas de claves foráneas
-	tables := []string{"historial_clinicos", "pacientes", "hospitals"}
+	// Limpiar tablas en orden para evitar problemas de claves foráneas.
+	// audit_log e historial_revisiones no se limpian: son de solo inserción
+	tables := []string{"historial_clinicos", "pacientes", "intentos_login", "tokens_verificacion", "tokens_revocados", "refresh_tokens", "usuarios", "hospitals"}
 
 	for _, table := range tables {
 		if err := s.db.Exec(fmt.Sprintf("DELETE FROM %s", table)).Error; err != nil {
@@ -191,7 +192,6 @@ type EnfermedadInfo struct {
 	Diagnosticos   []string
 	Tratamientos   []string
 	Medicamentos   []string
-	EsContagiosa   bool
 	Observaciones  []string
 }
 
@@ -220,7 +220,6 @@ var enfermedadesEspecificas = []EnfermedadInfo{
 			"Suero oral abundante",
 			"Paracetamol 1g cada 8 horas (adultos)",
 		},
-		EsContagiosa: true,
 		Observaciones: []string{
 			"Paciente en vigilancia epidemiológica",
 			"Control de plaquetas cada 24 horas",
@@ -251,7 +250,6 @@ var enfermedadesEspecificas = []EnfermedadInfo{
 			"Vitamina A 200,000 UI dosis única",
 			"Suero fisiológico para hidratación ocular",
 		},
-		EsContagiosa: true,
 		Observaciones: []string{
 			"Caso notificado inmediatamente a epidemiología",
 			"Aislamiento respiratorio estricto",
@@ -282,7 +280,6 @@ var enfermedadesEspecificas = []EnfermedadInfo{
 			"Loratadina 10mg para picazón",
 			"Abundantes líquidos",
 		},
-		EsContagiosa: true,
 		Observaciones: []string{
 			"Orientación sobre prevención de vectores",
 			"Caso notificado a vigilancia epidemiológica",
@@ -313,7 +310,6 @@ var enfermedadesEspecificas = []EnfermedadInfo{
 			"Paracetamol 1g cada 8 horas",
 			"Ibuprofeno 400mg cada 8 horas",
 		},
-		EsContagiosa: true,
 		Observaciones: []string{
 			"Aislamiento respiratorio por 7 días",
 			"Vigilancia de complicaciones respiratorias",
@@ -344,7 +340,6 @@ var enfermedadesEspecificas = []EnfermedadInfo{
 			"Paracetamol para control de fiebre",
 			"Broncodilatadores si hay broncoespasmo",
 		},
-		EsContagiosa: true,
 		Observaciones: []string{
 			"Notificación inmediata obligatoria",
 			"Aislamiento estricto por 7-10 días",
@@ -375,7 +370,6 @@ var enfermedadesEspecificas = []EnfermedadInfo{
 			"Ambroxol 30mg cada 8 horas",
 			"Amoxicilina 500mg cada 8 horas si bacteriana",
 		},
-		EsContagiosa: false,
 		Observaciones: []string{
 			"Evitar irritantes respiratorios",
 			"Hidratación abundante",
@@ -404,7 +398,6 @@ func (s *Seeder) SeedHospitalesSantaCruz() error {
 			Ciudad:    "Santa Cruz de la Sierra",
 			Telefono:  "+591-3-3460101",
 			Email:     "admin@huj.org.bo",
-			Password:  string(hashedPassword),
 		},
 		{
 			Nombre:    "Hospital de Niños Dr. Mario Ortiz Suárez",
@@ -414,7 +407,6 @@ func (s *Seeder) SeedHospitalesSantaCruz() error {
 			Ciudad:    "Santa Cruz de la Sierra",
 			Telefono:  "+591-3-3346969",
 			Email:     "admin@hospitalninos.gob.bo",
-			Password:  string(hashedPassword),
 		},
 		{
 			Nombre:    "Hospital San Juan de Dios",
@@ -424,7 +416,6 @@ func (s *Seeder) SeedHospitalesSantaCruz() error {
 			Ciudad:    "Santa Cruz de la Sierra",
 			Telefono:  "+591-3-3342777",
 			Email:     "admin@hospitalsanjuan.org.bo",
-			Password:  string(hashedPassword),
 		},
 		{
 			Nombre:    "Hospital Percy Boland",
@@ -434,7 +425,6 @@ func (s *Seeder) SeedHospitalesSantaCruz() error {
 			Ciudad:    "Santa Cruz de la Sierra",
 			Telefono:  "+591-3-3462031",
 			Email:     "admin@percyboland.com",
-			Password:  string(hashedPassword),
 		},
 
 		{
@@ -445,7 +435,6 @@ func (s *Seeder) SeedHospitalesSantaCruz() error {
 			Ciudad:    "Santa Cruz de la Sierra",
 			Telefono:  "+591-3-3480200",
 			Email:     "admin@hospitalfrances.gob.bo",
-			Password:  string(hashedPassword),
 		},
 		{
 			Nombre:    "Hospital del Norte",
@@ -455,7 +444,6 @@ func (s *Seeder) SeedHospitalesSantaCruz() error {
 			Ciudad:    "Santa Cruz de la Sierra",
 			Telefono:  "+591-3-3555100",
 			Email:     "admin@hospitalnorte.com.bo",
-			Password:  string(hashedPassword),
 		},
 		{
 			Nombre:    "Clínica Foianini",
@@ -465,7 +453,6 @@ func (s *Seeder) SeedHospitalesSantaCruz() error {
 			Ciudad:    "Santa Cruz de la Sierra",
 			Telefono:  "+591-3-3462100",
 			Email:     "admin@foianini.org",
-			Password:  string(hashedPassword),
 		},
 		{
 			Nombre:    "Hospital La Católica",
@@ -475,7 +462,6 @@ func (s *Seeder) SeedHospitalesSantaCruz() error {
 			Ciudad:    "Santa Cruz de la Sierra",
 			Telefono:  "+591-3-3336633",
 			Email:     "admin@lacatolica.com.bo",
-			Password:  string(hashedPassword),
 		},
 
 		{
@@ -486,7 +472,6 @@ func (s *Seeder) SeedHospitalesSantaCruz() error {
 			Ciudad:    "Santa Cruz de la Sierra",
 			Telefono:  "+591-3-3462800",
 			Email:     "admin@hospitaldelamujer.gob.bo",
-			Password:  string(hashedPassword),
 		},
 		{
 			Nombre:    "Hospital General San Juan de Dios",
@@ -496,7 +481,6 @@ func (s *Seeder) SeedHospitalesSantaCruz() error {
 			Ciudad:    "Santa Cruz de la Sierra",
 			Telefono:  "+591-3-3480300",
 			Email:     "admin@hospitalgeneralsanjuan.gob.bo",
-			Password:  string(hashedPassword),
 		},
 
 		{
@@ -507,7 +491,6 @@ func (s *Seeder) SeedHospitalesSantaCruz() error {
 			Ciudad:    "Santa Cruz de la Sierra",
 			Telefono:  "+591-3-3337700",
 			Email:     "admin@corazondejesus.org.bo",
-			Password:  string(hashedPassword),
 		},
 	}
 
@@ -517,14 +500,79 @@ func (s *Seeder) SeedHospitalesSantaCruz() error {
 		result := s.db.Where("email = ?", hospital.Email).First(&existingHospital)
 
 		if result.Error != nil && result.Error == gorm.ErrRecordNotFound {
-			// No existe, crear nuevo
+			// No existe, crear nuevo (los hospitales de prueba se crean con el email verificado)
+			hospital.EmailVerificado = true
 			if err := s.db.Create(&hospital).Error; err != nil {
 				return err
 			}
 			log.Printf("✅ Hospital creado: %s", hospital.Nombre)
 		} else {
 			log.Printf("⚠  Hospital ya existe: %s", hospital.Nombre)
+			hospital = existingHospital
 		}
+
+		// Usuario administrador del hospital con el email del hospital
+		if err := s.seedUsuario(hospital.ID, "Administrador "+hospital.Nombre, hospital.Email, models.RolAdmin, string(hashedPassword)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// usuariosDemo cuentas de prueba para cada rol, asignadas al primer hospital
+var usuariosDemo = []struct {
+	Nombre string
+	Email  string
+	Rol    string
+}{
+	{"Dra. Carla Suárez", "medico@huj.org.bo", models.RolMedico},
+	{"Lic. Rosa Mendoza", "enfermeria@huj.org.bo", models.RolEnfermeria},
+	{"Dr. Luis Antelo", "epidemiologia@huj.org.bo", models.RolEpidemiologo},
+	{"Ing. Paola Rivero", "analista@huj.org.bo", models.RolAnalista},
+}
+
+// SeedUsuariosDemo crea usuarios de prueba para cada rol (contraseña: admin123)
+func (s *Seeder) SeedUsuariosDemo() error {
+	log.Println("👤 Seeding usuarios de prueba por rol...")
+
+	var hospital models.Hospital
+	if err := s.db.Order("id").First(&hospital).Error; err != nil {
+		return fmt.Errorf("no hay hospitales para asignar usuarios: %w", err)
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("admin123"), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	for _, u := range usuariosDemo {
+		if err := s.seedUsuario(hospital.ID, u.Nombre, u.Email, u.Rol, string(hashedPassword)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// seedUsuario crea un usuario si su email no existe
+func (s *Seeder) seedUsuario(hospitalID uint, nombre, email, rol, hashedPassword string) error {
+	var existingUsuario models.Usuario
+	result := s.db.Where("email = ?", email).First(&existingUsuario)
+
+	if result.Error != nil && result.Error == gorm.ErrRecordNotFound {
+		usuario := models.Usuario{
+			IDHospital: hospitalID,
+			Nombre:     nombre,
+			Email:      email,
+			Password:   hashedPassword,
+			Rol:        rol,
+			Activo:     true,
+		}
+		if err := s.db.Create(&usuario).Error; err != nil {
+			return err
+		}
+		log.Printf("✅ Usuario creado: %s (%s)", email, rol)
 	}
 
 	return nil
@@ -610,6 +658,12 @@ func (s *Seeder) SeedRandomHistoriales() error {
 		return fmt.Errorf("no hay hospitales o pacientes suficientes para crear historiales")
 	}
 
+	// La contagiosidad, la vía de transmisión y la notificación salen del catálogo de enfermedades
+	catalogo, err := s.catalogoEnfermedades()
+	if err != nil {
+		return err
+	}
+
 	// Inicializar generador
 	rand.Seed(time.Now().UnixNano())
 
@@ -640,9 +694,6 @@ func (s *Seeder) SeedRandomHistoriales() error {
 		medicamento := enfermedadInfo.Medicamentos[rand.Intn(len(enfermedadInfo.Medicamentos))]
 		observacion := enfermedadInfo.Observaciones[rand.Intn(len(enfermedadInfo.Observaciones))]
 
-		// La contagiosidad depende de la enfermedad
-		esContagioso := enfermedadInfo.EsContagiosa
-
 		historial := models.HistorialClinico{
 			IDPaciente:          paciente.ID,
 			IDHospital:          hospital.ID,
@@ -660,7 +711,12 @@ func (s *Seeder) SeedRandomHistoriales() error {
 			PatientNeighborhood: direccion.Barrio,
 			ConsultationDate:    fechaConsulta,
 			SymptomsStartDate:   &fechaSintomas,
-			IsContagious:        esContagioso,
+		}
+		if enfermedad, ok := catalogo[enfermedadInfo.Nombre]; ok {
+			historial.IDEnfermedad = &enfermedad.ID
+			historial.IsContagious = enfermedad.Contagiosa
+			historial.ViaTransmision = enfermedad.ViaTransmision
+			historial.NotificacionObligatoria = enfermedad.NotificacionObligatoria
 		}
 
 		if err := s.db.Create(&historial).Error; err != nil {
@@ -676,6 +732,20 @@ func (s *Seeder) SeedRandomHistoriales() error {
 	return nil
 }
 
+// catalogoEnfermedades retorna las enfermedades del catálogo indexadas por nombre canónico
+func (s *Seeder) catalogoEnfermedades() (map[string]models.Enfermedad, error) {
+	var enfermedades []models.Enfermedad
+	if err := s.db.Find(&enfermedades).Error; err != nil {
+		return nil, err
+	}
+
+	catalogo := make(map[string]models.Enfermedad, len(enfermedades))
+	for _, enfermedad := range enfermedades {
+		catalogo[enfermedad.Nombre] = enfermedad
+	}
+	return catalogo, nil
+}
+
 // SeedAllRandom ejecuta la generación de datos aleatorios
 func (s *Seeder) SeedAllRandom() error {
 	log.Println("🌱 Iniciando generación de datos aleatorios para Santa Cruz...")
@@ -685,6 +755,11 @@ func (s *Seeder) SeedAllRandom() error {
 		return err
 	}
 
+	// Usuarios de prueba para cada rol
+	if err := s.SeedUsuariosDemo(); err != nil {
+		return err
+	}
+
 	// Generar pacientes aleatorios
 	if err := s.SeedRandomPacientes(); err != nil {
 		return err
@@ -703,6 +778,11 @@ func (s *Seeder) SeedAllRandom() error {
 func (s *Seeder) ShowEnfermedadesStats() error {
 	log.Println("📊 Estadísticas de enfermedades generadas:")
 
+	catalogo, err := s.catalogoEnfermedades()
+	if err != nil {
+		return err
+	}
+
 	for _, enfermedad := range enfermedadesEspecificas {
 		var count int64
 		if err := s.db.Model(&models.HistorialClinico{}).Where("enfermedad = ?", enfermedad.Nombre).Count(&count).Error; err != nil {
@@ -710,7 +790,7 @@ func (s *Seeder) ShowEnfermedadesStats() error {
 		}
 
 		contagiosaStr := "No contagiosa"
-		if enfermedad.EsContagiosa {
+		if catalogo[enfermedad.Nombre].Contagiosa {
 			contagiosaStr = "Contagiosa"
 		}
 
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"hospital-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Actor identifica a quien realiza una operación y delimita los registros que puede ver
+type Actor struct {
+	UsuarioID  uint
+	HospitalID uint
+	Rol        string
+	IP         string // IP del cliente, registrada en la auditoría
+}
+
+// actorSistema actor de los procesos sin usuario, como los comandos de mantenimiento. Sus operaciones se
+// auditan con rol sistema en el hospital del registro afectado para que las vean sus administradores.
+func actorSistema(hospitalID uint) Actor {
+	return Actor{HospitalID: hospitalID, Rol: models.RolSistema}
+}
+
+// historialScope limita las lecturas de historial clínico a los registros del hospital del actor.
+// Los epidemiólogos realizan vigilancia sobre toda la red, por lo que leen historiales de todos los hospitales.
+func (a Actor) historialScope(db *gorm.DB) *gorm.DB {
+	if a.Rol == models.RolEpidemiologo {
+		return db
+	}
+	return a.historialWriteScope(db)
+}
+
+// historialWriteScope limita las modificaciones de historial clínico a los registros del hospital del actor
+func (a Actor) historialWriteScope(db *gorm.DB) *gorm.DB {
+	return db.Where("historial_clinico.id_hospital = ?", a.HospitalID)
+}
+
+// pacienteScope limita las consultas de pacientes a los registrados por el hospital
+// del actor o a los que tienen al menos un historial clínico en él
+func (a Actor) pacienteScope(db *gorm.DB) *gorm.DB {
+	return db.Where(`(pacientes.id_hospital = ? OR EXISTS (
+		SELECT 1 FROM historial_clinico hc
+		WHERE hc.id_paciente = pacientes.id AND hc.id_hospital = ? AND hc.deleted_at IS NULL
+	))`, a.HospitalID, a.HospitalID)
+}
+
+// pacienteWriteScope limita las modificaciones de pacientes a los registrados por el hospital del actor.
+// Un hospital que solo atendió al paciente lo ve, pero no puede modificarlo ni eliminarlo.
+func (a Actor) pacienteWriteScope(db *gorm.DB) *gorm.DB {
+	return db.Where("pacientes.id_hospital = ?", a.HospitalID)
+}
+
+// notificacionScope limita las notificaciones a las del hospital del actor.
+// El equipo de vigilancia (epidemiólogos) recibe las de todos los hospitales.
+func (a Actor) notificacionScope(db *gorm.DB) *gorm.DB {
+	if a.Rol == models.RolEpidemiologo {
+		return db
+	}
+	return db.Where("notificacion_epidemiologica.id_hospital = ?", a.HospitalID)
+}
@@ -0,0 +1,467 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"hospital-api/internal/database"
+	"hospital-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrEvaluacionEnCurso indica que otra evaluación de las reglas, en este u otro servidor, no terminó
+var ErrEvaluacionEnCurso = errors.New("ya hay una evaluación de reglas de alerta en curso")
+
+// claveEvaluacionAlertas clave del advisory lock de Postgres que serializa las evaluaciones de reglas
+// entre todas las instancias de la API que comparten la base de datos
+const claveEvaluacionAlertas int64 = 0x616c6572746173 // "alertas"
+
+type AlertaService struct {
+	db *gorm.DB
+}
+
+// ReglaAlertaRequest datos para crear o reemplazar una regla de alerta.
+// La enfermedad se indica por id_enfermedad o por nombre, código CIE-10 o sinónimo; sin ninguna se cuentan todas.
+type ReglaAlertaRequest struct {
+	Nombre             string  `json:"nombre" validate:"required,min=3,max=150"`
+	Tipo               string  `json:"tipo" validate:"required,oneof=umbral media_movil"`
+	IDEnfermedad       *uint   `json:"id_enfermedad"`
+	Enfermedad         string  `json:"enfermedad" validate:"omitempty,max=150"`
+	Distrito           string  `json:"distrito" validate:"omitempty,max=100"`
+	Umbral             float64 `json:"umbral" validate:"required,gt=0"`
+	VentanaDias        int     `json:"ventana_dias" validate:"omitempty,min=1,max=90"`
+	VentanasReferencia int     `json:"ventanas_referencia" validate:"omitempty,min=1,max=52"`
+	Severidad          string  `json:"severidad" validate:"required,oneof=baja media alta critica"`
+	Activa             *bool   `json:"activa"`
+}
+
+// AlertaFilter filtros del feed de alertas
+type AlertaFilter struct {
+	Estado       string
+	Severidad    string
+	IDRegla      *uint
+	IDEnfermedad *uint
+	Distrito     string
+}
+
+// ResultadoEvaluacion resumen de una evaluación de las reglas activas
+type ResultadoEvaluacion struct {
+	ReglasEvaluadas     int `json:"reglas_evaluadas"`
+	AlertasNuevas       int `json:"alertas_nuevas"`
+	AlertasActualizadas int `json:"alertas_actualizadas"`
+	AlertasResueltas    int `json:"alertas_resueltas"`
+
+	nuevas []models.AlertaBrote // Alertas creadas en la regla en evaluación, cuyos eventos se registran en su transacción
+}
+
+// medicionRegla casos de un distrito en la ventana actual y su valor de referencia
+type medicionRegla struct {
+	Distrito   string
+	Casos      int
+	Referencia float64 // Media de las ventanas anteriores (media_movil)
+}
+
+// NewAlertaService crea una nueva instancia del servicio de alertas de brote
+func NewAlertaService() *AlertaService {
+	return &AlertaService{
+		db: database.GetDB(),
+	}
+}
+
+// GetReglas obtiene las reglas de alerta con paginación
+func (s *AlertaService) GetReglas(page, limit int) ([]models.ReglaAlerta, int64, error) {
+	var reglas []models.ReglaAlerta
+	var total int64
+
+	s.db.Model(&models.ReglaAlerta{}).Count(&total)
+
+	offset := (page - 1) * limit
+	err := s.db.Preload("Enfermedad").Offset(offset).Limit(limit).Order("id").Find(&reglas).Error
+
+	return reglas, total, err
+}
+
+// GetReglaByID obtiene una regla de alerta
+func (s *AlertaService) GetReglaByID(id uint) (*models.ReglaAlerta, error) {
+	var regla models.ReglaAlerta
+	if err := s.db.Preload("Enfermedad").First(&regla, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("regla de alerta no encontrada")
+		}
+		return nil, err
+	}
+	return &regla, nil
+}
+
+// CreateRegla crea una regla de alerta; se evalúa a partir de la siguiente ejecución del motor
+func (s *AlertaService) CreateRegla(actor Actor, req ReglaAlertaRequest) (*models.ReglaAlerta, error) {
+	regla := models.ReglaAlerta{IDUsuario: &actor.UsuarioID}
+	if err := s.aplicarRequest(&regla, req); err != nil {
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&regla).Error; err != nil {
+			return err
+		}
+		return auditar(tx, actor, models.AccionCrear, models.EntidadReglaAlerta, regla.ID, nil, regla, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetReglaByID(regla.ID)
+}
+
+// UpdateRegla reemplaza la definición de una regla. Sus alertas activas se revisan en la siguiente evaluación.
+func (s *AlertaService) UpdateRegla(actor Actor, id uint, req ReglaAlertaRequest) (*models.ReglaAlerta, error) {
+	antes, err := s.GetReglaByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	despues := *antes
+	despues.Enfermedad = nil
+	if err := s.aplicarRequest(&despues, req); err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&despues).Error; err != nil {
+			return err
+		}
+		// Una regla desactivada deja de evaluarse, así que sus alertas no se resolverían solas
+		if !despues.Activa {
+			if err := resolverAlertasRegla(tx, id); err != nil {
+				return err
+			}
+		}
+		return auditar(tx, actor, models.AccionActualizar, models.EntidadReglaAlerta, id, antes, despues, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetReglaByID(id)
+}
+
+// DeleteRegla elimina (soft delete) una regla y resuelve sus alertas activas
+func (s *AlertaService) DeleteRegla(actor Actor, id uint) error {
+	regla, err := s.GetReglaByID(id)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(regla).Error; err != nil {
+			return err
+		}
+		if err := resolverAlertasRegla(tx, id); err != nil {
+			return err
+		}
+		return auditar(tx, actor, models.AccionEliminar, models.EntidadReglaAlerta, id, regla, nil, nil)
+	})
+}
+
+// GetAlertas obtiene el feed de alertas de brote, las detectadas más recientemente primero
+func (s *AlertaService) GetAlertas(filtro AlertaFilter, page, limit int) ([]models.AlertaBrote, int64, error) {
+	var alertas []models.AlertaBrote
+	var total int64
+
+	query := s.db.Model(&models.AlertaBrote{})
+
+	if filtro.Estado != "" {
+		query = query.Where("estado = ?", filtro.Estado)
+	}
+	if filtro.Severidad != "" {
+		query = query.Where("severidad = ?", filtro.Severidad)
+	}
+	if filtro.IDRegla != nil {
+		query = query.Where("id_regla = ?", *filtro.IDRegla)
+	}
+	if filtro.IDEnfermedad != nil {
+		query = query.Where("id_enfermedad = ?", *filtro.IDEnfermedad)
+	}
+	if filtro.Distrito != "" {
+		query = query.Where("LOWER(distrito) = LOWER(?)", filtro.Distrito)
+	}
+
+	// Contar total
+	query.Count(&total)
+
+	offset := (page - 1) * limit
+	err := query.Preload("Regla", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).Preload("Enfermedad").
+		Offset(offset).Limit(limit).
+		Order("ultima_deteccion DESC, id DESC").
+		Find(&alertas).Error
+
+	return alertas, total, err
+}
+
+// IniciarMotor evalúa las reglas activas en segundo plano al arrancar y luego cada intervalo, hasta que
+// se cancela ctx. Con intervalo 0 no se programa ninguna evaluación.
+func (s *AlertaService) IniciarMotor(ctx context.Context, intervalo time.Duration) {
+	if intervalo <= 0 {
+		log.Println("Motor de alertas desactivado (ALERTAS_INTERVALO=0)")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(intervalo)
+		defer ticker.Stop()
+
+		for {
+			resultado, err := s.EvaluarReglas(ctx)
+			switch {
+			case errors.Is(err, ErrEvaluacionEnCurso):
+				log.Println("Evaluación de reglas de alerta omitida: otra instancia la está ejecutando")
+			case err != nil && ctx.Err() == nil:
+				log.Printf("Error al evaluar reglas de alerta: %v", err)
+			case err == nil && resultado.AlertasNuevas > 0:
+				log.Printf("🚨 %d alertas de brote nuevas", resultado.AlertasNuevas)
+			}
+
+			select {
+			case <-ctx.Done():
+				log.Println("Motor de alertas detenido")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// EvaluarReglas evalúa todas las reglas activas contra los historiales de toda la red.
+// Cada regla crea o actualiza una alerta por distrito en que se cumple y resuelve las que dejaron de cumplirse.
+// Una sola evaluación corre a la vez en toda la red: si otra instancia tiene el advisory lock retorna
+// ErrEvaluacionEnCurso sin esperar.
+func (s *AlertaService) EvaluarReglas(ctx context.Context) (*ResultadoEvaluacion, error) {
+	var resultado *ResultadoEvaluacion
+	// El advisory lock es de la sesión, así que se toma y se libera en la misma conexión
+	err := s.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		var bloqueado bool
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?)", claveEvaluacionAlertas).Scan(&bloqueado).Error; err != nil {
+			return err
+		}
+		if !bloqueado {
+			return ErrEvaluacionEnCurso
+		}
+		// Se libera aunque ctx se haya cancelado, para no dejar la conexión del pool con el lock tomado
+		defer conn.WithContext(context.Background()).Exec("SELECT pg_advisory_unlock(?)", claveEvaluacionAlertas)
+
+		var err error
+		resultado, err = s.evaluarReglasActivas(conn)
+		return err
+	})
+	return resultado, err
+}
+
+// evaluarReglasActivas evalúa cada regla activa en su propia transacción y difunde los eventos de las
+// alertas nuevas cuando se confirma
+func (s *AlertaService) evaluarReglasActivas(db *gorm.DB) (*ResultadoEvaluacion, error) {
+	var reglas []models.ReglaAlerta
+	if err := db.Preload("Enfermedad").Where("activa = ?", true).Find(&reglas).Error; err != nil {
+		return nil, err
+	}
+
+	resultado := &ResultadoEvaluacion{}
+	ahora := time.Now()
+	for i := range reglas {
+		resultado.nuevas = nil
+		var eventos []*Evento
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := evaluarRegla(tx, &reglas[i], ahora, resultado); err != nil {
+				return err
+			}
+			for _, alerta := range resultado.nuevas {
+				alerta.Enfermedad = reglas[i].Enfermedad
+				evento, err := registrarEvento(tx, models.EventoAlertaNueva, alerta)
+				if err != nil {
+					return err
+				}
+				eventos = append(eventos, evento)
+			}
+			return nil
+		})
+		if err != nil {
+			return resultado, fmt.Errorf("regla %d: %w", reglas[i].ID, err)
+		}
+		resultado.ReglasEvaluadas++
+
+		for _, evento := range eventos {
+			difundirEvento(evento)
+		}
+	}
+
+	return resultado, nil
+}
+
+// aplicarRequest copia la definición del request en la regla resolviendo la enfermedad contra el catálogo
+func (s *AlertaService) aplicarRequest(regla *models.ReglaAlerta, req ReglaAlertaRequest) error {
+	regla.Nombre = req.Nombre
+	regla.Tipo = req.Tipo
+	regla.Distrito = req.Distrito
+	regla.Umbral = req.Umbral
+	regla.Severidad = req.Severidad
+	regla.VentanaDias = req.VentanaDias
+	if regla.VentanaDias == 0 {
+		regla.VentanaDias = 7
+	}
+	regla.VentanasReferencia = req.VentanasReferencia
+	if regla.VentanasReferencia == 0 {
+		regla.VentanasReferencia = 4
+	}
+	regla.Activa = req.Activa == nil || *req.Activa
+
+	regla.IDEnfermedad = nil
+	if req.IDEnfermedad != nil || req.Enfermedad != "" {
+		enfermedad, err := resolverEnfermedad(s.db, req.IDEnfermedad, req.Enfermedad)
+		if err != nil {
+			return err
+		}
+		regla.IDEnfermedad = &enfermedad.ID
+	}
+	return nil
+}
+
+// resolverAlertasRegla marca como resueltas las alertas activas de una regla
+func resolverAlertasRegla(tx *gorm.DB, idRegla uint) error {
+	return tx.Model(&models.AlertaBrote{}).
+		Where("id_regla = ? AND estado = ?", idRegla, models.AlertaActiva).
+		Updates(map[string]interface{}{"estado": models.AlertaResuelta, "resuelta_at": time.Now()}).Error
+}
+
+// evaluarRegla mide la regla en la ventana que termina hoy y sincroniza sus alertas activas
+func evaluarRegla(tx *gorm.DB, regla *models.ReglaAlerta, ahora time.Time, resultado *ResultadoEvaluacion) error {
+	mediciones, err := medirRegla(tx, regla, ahora)
+	if err != nil {
+		return err
+	}
+
+	var activas []models.AlertaBrote
+	if err := tx.Where("id_regla = ? AND estado = ?", regla.ID, models.AlertaActiva).Find(&activas).Error; err != nil {
+		return err
+	}
+	pendientes := make(map[string]*models.AlertaBrote, len(activas))
+	for i := range activas {
+		pendientes[activas[i].Distrito] = &activas[i]
+	}
+
+	for _, medicion := range mediciones {
+		referencia, cumple := evaluarMedicion(regla, medicion)
+		if !cumple {
+			continue
+		}
+		mensaje := mensajeAlerta(regla, medicion, referencia)
+
+		if alerta, ok := pendientes[medicion.Distrito]; ok {
+			// La misma situación sigue vigente: se actualiza la alerta en lugar de repetirla
+			delete(pendientes, medicion.Distrito)
+			err := tx.Model(alerta).Updates(map[string]interface{}{
+				"severidad":        regla.Severidad,
+				"casos":            medicion.Casos,
+				"referencia":       referencia,
+				"mensaje":          mensaje,
+				"detecciones":      gorm.Expr("detecciones + 1"),
+				"ultima_deteccion": ahora,
+			}).Error
+			if err != nil {
+				return err
+			}
+			resultado.AlertasActualizadas++
+			continue
+		}
+
+		alerta := models.AlertaBrote{
+			IDRegla:          regla.ID,
+			IDEnfermedad:     regla.IDEnfermedad,
+			Distrito:         medicion.Distrito,
+			Severidad:        regla.Severidad,
+			Estado:           models.AlertaActiva,
+			Casos:            medicion.Casos,
+			Referencia:       referencia,
+			Mensaje:          mensaje,
+			Detecciones:      1,
+			PrimeraDeteccion: ahora,
+			UltimaDeteccion:  ahora,
+		}
+		if err := tx.Create(&alerta).Error; err != nil {
+			return err
+		}
+		resultado.AlertasNuevas++
+		resultado.nuevas = append(resultado.nuevas, alerta)
+	}
+
+	for _, alerta := range pendientes {
+		err := tx.Model(alerta).Updates(map[string]interface{}{
+			"estado":      models.AlertaResuelta,
+			"resuelta_at": ahora,
+		}).Error
+		if err != nil {
+			return err
+		}
+		resultado.AlertasResueltas++
+	}
+
+	return nil
+}
+
+// medirRegla cuenta por distrito los casos de la ventana actual (los últimos VentanaDias días, incluido hoy)
+// y, para media_movil, la media de casos de las VentanasReferencia ventanas inmediatamente anteriores
+func medirRegla(tx *gorm.DB, regla *models.ReglaAlerta, ahora time.Time) ([]medicionRegla, error) {
+	hoy := time.Date(ahora.Year(), ahora.Month(), ahora.Day(), 0, 0, 0, 0, ahora.Location())
+	inicio := hoy.AddDate(0, 0, 1-regla.VentanaDias)
+	inicioConsulta := inicio
+
+	query := tx.Model(&models.HistorialClinico{})
+	if regla.Tipo == models.ReglaMediaMovil {
+		inicioConsulta = inicio.AddDate(0, 0, -regla.VentanaDias*regla.VentanasReferencia)
+		query = query.Select(`patient_district AS distrito,
+			COUNT(*) FILTER (WHERE consultation_date >= ?) AS casos,
+			COUNT(*) FILTER (WHERE consultation_date < ?)::float / ? AS referencia`,
+			inicio, inicio, float64(regla.VentanasReferencia))
+	} else {
+		query = query.Select("patient_district AS distrito, COUNT(*) AS casos")
+	}
+
+	query = query.Where("consultation_date >= ? AND consultation_date <= ?", inicioConsulta, hoy)
+	if regla.IDEnfermedad != nil {
+		query = query.Where("historial_clinico.id_enfermedad = ?", *regla.IDEnfermedad)
+	}
+	if regla.Distrito != "" {
+		query = query.Where("LOWER(patient_district) = LOWER(?)", regla.Distrito)
+	}
+
+	var mediciones []medicionRegla
+	err := query.Group("patient_district").Scan(&mediciones).Error
+	return mediciones, err
+}
+
+// evaluarMedicion retorna el valor de referencia de la medición y si la regla se cumple.
+// En media_movil una media menor a 1 se toma como 1 para no disparar con los primeros casos aislados.
+func evaluarMedicion(regla *models.ReglaAlerta, medicion medicionRegla) (float64, bool) {
+	if regla.Tipo == models.ReglaMediaMovil {
+		return medicion.Referencia, float64(medicion.Casos) > regla.Umbral*math.Max(medicion.Referencia, 1)
+	}
+	return regla.Umbral, float64(medicion.Casos) > regla.Umbral
+}
+
+// mensajeAlerta describe la situación detectada
+func mensajeAlerta(regla *models.ReglaAlerta, medicion medicionRegla, referencia float64) string {
+	enfermedad := "todas las enfermedades"
+	if regla.Enfermedad != nil {
+		enfermedad = regla.Enfermedad.Nombre
+	}
+
+	if regla.Tipo == models.ReglaMediaMovil {
+		return fmt.Sprintf("%d casos de %s en %s en los últimos %d días: supera %.1f veces la media de las %d ventanas anteriores (%.1f)",
+			medicion.Casos, enfermedad, medicion.Distrito, regla.VentanaDias, regla.Umbral, regla.VentanasReferencia, referencia)
+	}
+	return fmt.Sprintf("%d casos de %s en %s en los últimos %d días: supera el umbral de %.0f",
+		medicion.Casos, enfermedad, medicion.Distrito, regla.VentanaDias, referencia)
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"hospital-api/internal/database"
+	"hospital-api/internal/models"
+	"hospital-api/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+type AuditService struct {
+	db *gorm.DB
+}
+
+// AuditFilter filtros para consultar la bitácora de auditoría
+type AuditFilter struct {
+	IDUsuario *uint
+	Accion    string
+	Entidad   string
+	EntidadID string
+	Desde     *time.Time // Inclusivo
+	Hasta     *time.Time // Exclusivo
+}
+
+// camposNoAuditados se excluyen del diff: los gestiona GORM o son relaciones precargadas
+var camposNoAuditados = []string{"created_at", "updated_at", "paciente", "hospital", "historiales_clinico", "enfermedad_catalogo"}
+
+// NewAuditService crea una nueva instancia del servicio de auditoría
+func NewAuditService() *AuditService {
+	return &AuditService{
+		db: database.GetDB(),
+	}
+}
+
+// Registrar agrega una entrada a la bitácora de auditoría; detalle se guarda como JSON
+func (s *AuditService) Registrar(entrada models.AuditLog, detalle interface{}) error {
+	if detalle != nil {
+		data, err := json.Marshal(detalle)
+		if err != nil {
+			return err
+		}
+		entrada.Detalle = data
+	}
+
+	return s.db.Create(&entrada).Error
+}
+
+// GetAuditLogs obtiene las entradas de auditoría del hospital del actor con filtros y paginación
+func (s *AuditService) GetAuditLogs(actor Actor, filtro AuditFilter, page, limit int) ([]models.AuditLog, int64, error) {
+	var entradas []models.AuditLog
+	var total int64
+
+	// Los bloqueos de login por IP o por un email sin cuenta no son de ningún hospital y los ven todos los administradores
+	query := s.db.Model(&models.AuditLog{}).
+		Where("id_hospital = ? OR (id_hospital IS NULL AND accion = ?)", actor.HospitalID, models.AccionLoginBloqueado)
+
+	if filtro.IDUsuario != nil {
+		query = query.Where("id_usuario = ?", *filtro.IDUsuario)
+	}
+	if filtro.Accion != "" {
+		query = query.Where("accion = ?", filtro.Accion)
+	}
+	if filtro.Entidad != "" {
+		query = query.Where("entidad = ?", filtro.Entidad)
+	}
+	if filtro.EntidadID != "" {
+		query = query.Where("entidad_id = ?", filtro.EntidadID)
+	}
+	if filtro.Desde != nil {
+		query = query.Where("created_at >= ?", *filtro.Desde)
+	}
+	if filtro.Hasta != nil {
+		query = query.Where("created_at < ?", *filtro.Hasta)
+	}
+
+	// Contar total
+	query.Count(&total)
+
+	// Obtener registros con paginación, los más recientes primero
+	offset := (page - 1) * limit
+	err := query.Offset(offset).Limit(limit).Order("created_at DESC, id DESC").Find(&entradas).Error
+
+	return entradas, total, err
+}
+
+// auditar registra una operación del actor sobre una entidad usando db, que puede ser la
+// transacción de la propia operación para que ambas se confirmen o descarten juntas.
+// antes y despues se comparan para guardar el diff; en lecturas ambos son nil.
+func auditar(db *gorm.DB, actor Actor, accion, entidad string, entidadID uint, antes, despues interface{}, detalle interface{}) error {
+	entrada := models.AuditLog{
+		Rol:     actor.Rol,
+		Accion:  accion,
+		Entidad: entidad,
+		IP:      actor.IP,
+	}
+	// Los procesos del sistema (actorSistema) no tienen usuario
+	if actor.UsuarioID != 0 {
+		entrada.IDUsuario = &actor.UsuarioID
+	}
+	if actor.HospitalID != 0 {
+		entrada.IDHospital = &actor.HospitalID
+	}
+	if entidadID != 0 {
+		entrada.EntidadID = strconv.FormatUint(uint64(entidadID), 10)
+	}
+
+	if antes != nil || despues != nil {
+		cambios, err := utils.Diff(antes, despues, camposNoAuditados...)
+		if err != nil {
+			return err
+		}
+		if entrada.Cambios, err = json.Marshal(cambios); err != nil {
+			return err
+		}
+	}
+
+	if detalle != nil {
+		data, err := json.Marshal(detalle)
+		if err != nil {
+			return err
+		}
+		entrada.Detalle = data
+	}
+
+	return db.Create(&entrada).Error
+}
+
+// auditarLectura registra la lectura de varios registros de una entidad guardando sus IDs
+func auditarLectura(db *gorm.DB, actor Actor, entidad string, ids []uint, detalle map[string]interface{}) error {
+	if detalle == nil {
+		detalle = map[string]interface{}{}
+	}
+	detalle["ids"] = ids
+	return auditar(db, actor, models.AccionLeer, entidad, 0, nil, nil, detalle)
+}
@@ -1,10 +1,16 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"log"
 	"os"
 	"time"
 
+	"hospital-api/internal/config"
 	"hospital-api/internal/database"
 	"hospital-api/internal/middleware"
 	"hospital-api/internal/models"
@@ -12,12 +18,21 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AuthService struct {
-	db *gorm.DB
+	db          *gorm.DB
+	emailSender EmailSender
+	loginGuard  *LoginGuardService
 }
 
+const (
+	verificacionEmailTTL = 48 * time.Hour
+	resetPasswordTTL     = time.Hour
+	tokenEventosTTL      = time.Minute
+)
+
 type LoginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=6"`
@@ -34,60 +49,249 @@ type RegisterRequest struct {
 	Password  string  `json:"password" validate:"required,min=6"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	Todas        bool   `json:"todas"` // Cerrar todas las sesiones del usuario
+}
+
+type EmailRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type TokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6"`
+}
+
 type LoginResponse struct {
-	Token    string                  `json:"token"`
-	Hospital models.HospitalResponse `json:"hospital"`
-	Success  bool                    `json:"success"`
-	Message  string                  `json:"message"`
+	Token        string                  `json:"token"`
+	RefreshToken string                  `json:"refresh_token"`
+	ExpiresAt    time.Time               `json:"expires_at"`
+	Usuario      models.UsuarioResponse  `json:"usuario"`
+	Hospital     models.HospitalResponse `json:"hospital"`
+	Success      bool                    `json:"success"`
+	Message      string                  `json:"message"`
+}
+
+type TokenResponse struct {
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// TokenEventosResponse token de corta duración para /eventos/stream
+type TokenEventosResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 type RegisterResponse struct {
 	Hospital models.HospitalResponse `json:"hospital"`
+	Usuario  models.UsuarioResponse  `json:"usuario"`
 	Success  bool                    `json:"success"`
 	Message  string                  `json:"message"`
 }
 
+type ProfileResponse struct {
+	Usuario  models.UsuarioResponse  `json:"usuario"`
+	Hospital models.HospitalResponse `json:"hospital"`
+}
+
 // NewAuthService crea una nueva instancia del servicio de autenticación
 func NewAuthService() *AuthService {
 	return &AuthService{
-		db: database.GetDB(),
+		db:          database.GetDB(),
+		emailSender: NewEmailSender(),
+		loginGuard:  NewLoginGuardService(),
 	}
 }
 
-// Login autentica un hospital y retorna un JWT
-func (s *AuthService) Login(req LoginRequest) (*LoginResponse, error) {
-	var hospital models.Hospital
+// Login autentica un usuario y retorna un JWT de corta duración con su rol y hospital junto a un refresh token
+func (s *AuthService) Login(req LoginRequest, ip string) (*LoginResponse, error) {
+	// Rechazar emails o IPs bloqueados y aplicar el retraso por fallos previos
+	if err := s.loginGuard.Verificar(req.Email, ip); err != nil {
+		return nil, err
+	}
+
+	var usuario models.Usuario
 
-	// Buscar hospital por email
-	err := s.db.Where("email = ?", req.Email).First(&hospital).Error
+	// Buscar usuario activo por email
+	err := s.db.Preload("Hospital").Where("email = ? AND activo = ?", req.Email, true).First(&usuario).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.loginGuard.RegistrarFallo(req.Email, ip)
 			return nil, errors.New("credenciales inválidas")
 		}
 		return nil, err
 	}
 
 	// Verificar contraseña
-	err = bcrypt.CompareHashAndPassword([]byte(hospital.Password), []byte(req.Password))
+	err = bcrypt.CompareHashAndPassword([]byte(usuario.Password), []byte(req.Password))
 	if err != nil {
+		s.loginGuard.RegistrarFallo(req.Email, ip)
 		return nil, errors.New("credenciales inválidas")
 	}
+	s.loginGuard.RegistrarExito(req.Email)
+
+	// El hospital debe haber confirmado su email antes de operar
+	if !usuario.Hospital.EmailVerificado {
+		return nil, errors.New("el email del hospital no ha sido verificado")
+	}
 
 	// Generar token JWT
-	token, err := s.generateJWT(hospital.ID, hospital.Email)
+	token, expiresAt, err := s.generateJWT(usuario)
 	if err != nil {
 		return nil, err
 	}
 
+	// Cada login inicia una nueva familia de refresh tokens
+	familia, err := generateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, _, err := createRefreshToken(s.db, usuario.ID, familia)
+	if err != nil {
+		return nil, err
+	}
+
+	// Registrar último acceso
+	now := time.Now()
+	s.db.Model(&usuario).Update("ultimo_acceso", now)
+	usuario.UltimoAcceso = &now
+
 	return &LoginResponse{
-		Token:    token,
-		Hospital: hospital.ToResponse(),
-		Success:  true,
-		Message:  "Login exitoso",
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+		Usuario:      usuario.ToResponse(),
+		Hospital:     usuario.Hospital.ToResponse(),
+		Success:      true,
+		Message:      "Login exitoso",
 	}, nil
 }
 
-// Register registra un nuevo hospital en el sistema
+// Refresh canjea un refresh token por un nuevo access token y rota el refresh token.
+// Si se presenta un refresh token ya rotado se asume que fue robado y se revoca toda su familia.
+func (s *AuthService) Refresh(req RefreshRequest) (*TokenResponse, error) {
+	var stored models.RefreshToken
+	err := s.db.Where("token_hash = ?", hashToken(req.RefreshToken)).First(&stored).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("refresh token inválido")
+		}
+		return nil, err
+	}
+
+	if stored.RevokedAt != nil {
+		revokeFamilia(s.db, stored.Familia)
+		return nil, errors.New("refresh token inválido")
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, errors.New("refresh token expirado")
+	}
+
+	var usuario models.Usuario
+	err = s.db.Where("activo = ?", true).First(&usuario, stored.IDUsuario).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			revokeFamilia(s.db, stored.Familia)
+			return nil, errors.New("refresh token inválido")
+		}
+		return nil, err
+	}
+
+	var response TokenResponse
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Revocar el token actual solo si nadie lo usó antes (evita rotaciones concurrentes)
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", stored.ID).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("refresh token reutilizado")
+		}
+
+		refreshToken, nuevoID, err := createRefreshToken(tx, usuario.ID, stored.Familia)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.RefreshToken{}).Where("id = ?", stored.ID).Update("reemplazado_por", nuevoID).Error; err != nil {
+			return err
+		}
+
+		token, expiresAt, err := s.generateJWT(usuario)
+		if err != nil {
+			return err
+		}
+
+		response = TokenResponse{
+			Token:        token,
+			RefreshToken: refreshToken,
+			ExpiresAt:    expiresAt,
+		}
+		return nil
+	})
+	if err != nil {
+		if err.Error() == "refresh token reutilizado" {
+			if err := revokeFamilia(s.db, stored.Familia); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("refresh token inválido")
+		}
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// Logout revoca el access token actual (por su jti) y el refresh token indicado.
+// Con Todas se revocan todos los refresh tokens del usuario.
+func (s *AuthService) Logout(usuarioID uint, jti string, expiresAt time.Time, req LogoutRequest) error {
+	if jti != "" {
+		err := s.db.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.TokenRevocado{JTI: jti, ExpiresAt: expiresAt}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	if req.Todas {
+		if err := revokeRefreshTokensUsuario(s.db, usuarioID); err != nil {
+			return err
+		}
+	} else if req.RefreshToken != "" {
+		var stored models.RefreshToken
+		err := s.db.Where("token_hash = ? AND id_usuario = ?", hashToken(req.RefreshToken), usuarioID).First(&stored).Error
+		if err == nil {
+			if err := revokeFamilia(s.db, stored.Familia); err != nil {
+				return err
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+
+	// Los jti ya expirados no necesitan seguir en la lista de revocados. La sesión ya quedó cerrada, por lo
+	// que un error de esta limpieza solo se registra; se reintenta en el próximo logout.
+	if err := s.db.Where("expires_at < ?", time.Now()).Delete(&models.TokenRevocado{}).Error; err != nil {
+		log.Printf("Error al depurar tokens revocados expirados: %v", err)
+	}
+
+	return nil
+}
+
+// Register registra un nuevo hospital en el sistema junto con su usuario administrador
 func (s *AuthService) Register(req RegisterRequest) (*RegisterResponse, error) {
 	// Verificar si el email ya existe
 	var existingHospital models.Hospital
@@ -99,6 +303,15 @@ func (s *AuthService) Register(req RegisterRequest) (*RegisterResponse, error) {
 		return nil, err
 	}
 
+	var existingUsuario models.Usuario
+	err = s.db.Where("email = ?", req.Email).First(&existingUsuario).Error
+	if err == nil {
+		return nil, errors.New("el email ya está registrado")
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
 	// Verificar si el teléfono ya existe (si se proporciona)
 	if req.Telefono != "" {
 		err = s.db.Where("telefono = ?", req.Telefono).First(&existingHospital).Error
@@ -125,30 +338,221 @@ func (s *AuthService) Register(req RegisterRequest) (*RegisterResponse, error) {
 		Ciudad:    req.Ciudad,
 		Telefono:  req.Telefono,
 		Email:     req.Email,
-		Password:  hashedPassword,
 	}
 
-	// Guardar en la base de datos
-	err = s.db.Create(&hospital).Error
+	// El email y la contraseña de registro pasan a ser las credenciales del administrador
+	usuario := models.Usuario{
+		Nombre:   "Administrador " + req.Nombre,
+		Email:    req.Email,
+		Password: hashedPassword,
+		Rol:      models.RolAdmin,
+		Activo:   true,
+	}
+
+	// Guardar ambos registros y el token de verificación en una transacción
+	var token string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&hospital).Error; err != nil {
+			return err
+		}
+		usuario.IDHospital = hospital.ID
+		if err := tx.Create(&usuario).Error; err != nil {
+			return err
+		}
+		token, err = createTokenVerificacion(tx, usuario.ID, models.TokenVerificacionEmail, verificacionEmailTTL)
+		return err
+	})
 	if err != nil {
 		return nil, errors.New("error al crear el hospital")
 	}
 
+	// Un fallo al enviar no deshace el registro: el hospital puede solicitar otro correo
+	if err := s.sendVerificacionEmail(usuario.Email, token); err != nil {
+		log.Printf("Error enviando correo de verificación a %s: %v", usuario.Email, err)
+	}
+
 	return &RegisterResponse{
 		Hospital: hospital.ToResponse(),
+		Usuario:  usuario.ToResponse(),
 		Success:  true,
-		Message:  "Hospital registrado exitosamente",
+		Message:  "Hospital registrado exitosamente. Revise su correo para verificar la cuenta",
+	}, nil
+}
+
+// RequestEmailVerification envía un nuevo correo de verificación al email de un hospital no verificado.
+// No informa si el email existe para no permitir enumerar cuentas.
+func (s *AuthService) RequestEmailVerification(req EmailRequest) error {
+	var usuario models.Usuario
+	err := s.db.Preload("Hospital").Where("email = ? AND activo = ?", req.Email, true).First(&usuario).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// Solo se verifica el email de contacto del hospital, que es el de su administrador
+	if usuario.Hospital.EmailVerificado || usuario.Email != usuario.Hospital.Email {
+		return nil
+	}
+
+	token, err := createTokenVerificacion(s.db, usuario.ID, models.TokenVerificacionEmail, verificacionEmailTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.sendVerificacionEmail(usuario.Email, token)
+}
+
+// ConfirmEmailVerification consume un token de verificación y marca el email del hospital como verificado
+func (s *AuthService) ConfirmEmailVerification(req TokenRequest) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		tokenVerificacion, err := consumeTokenVerificacion(tx, req.Token, models.TokenVerificacionEmail)
+		if err != nil {
+			return err
+		}
+
+		var usuario models.Usuario
+		if err := tx.First(&usuario, tokenVerificacion.IDUsuario).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("token inválido")
+			}
+			return err
+		}
+
+		return tx.Model(&models.Hospital{}).Where("id = ?", usuario.IDHospital).Update("email_verificado", true).Error
+	})
+}
+
+// RequestPasswordReset envía un correo para restablecer la contraseña de un usuario activo.
+// No informa si el email existe para no permitir enumerar cuentas.
+func (s *AuthService) RequestPasswordReset(req EmailRequest) error {
+	var usuario models.Usuario
+	err := s.db.Where("email = ? AND activo = ?", req.Email, true).First(&usuario).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := createTokenVerificacion(s.db, usuario.ID, models.TokenResetPassword, resetPasswordTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/restablecer-password?token=%s", config.GetConfig().Email.AppURL, token)
+	body := fmt.Sprintf("Hola %s,\n\nRecibimos una solicitud para restablecer su contraseña. Use el siguiente enlace (válido por %.0f h):\n\n%s\n\nSi no realizó esta solicitud, ignore este correo.",
+		usuario.Nombre, resetPasswordTTL.Hours(), link)
+
+	return s.emailSender.Send(usuario.Email, "Restablecer contraseña", body)
+}
+
+// ConfirmPasswordReset consume un token de restablecimiento, cambia la contraseña y cierra las sesiones abiertas
+func (s *AuthService) ConfirmPasswordReset(req ResetPasswordRequest) error {
+	hashedPassword, err := HashPassword(req.Password)
+	if err != nil {
+		return errors.New("error al procesar la contraseña")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		tokenVerificacion, err := consumeTokenVerificacion(tx, req.Token, models.TokenResetPassword)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.Usuario{}).
+			Where("id = ? AND activo = ?", tokenVerificacion.IDUsuario, true).
+			Update("password", hashedPassword)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("token inválido")
+		}
+
+		return revokeRefreshTokensUsuario(tx, tokenVerificacion.IDUsuario)
+	})
+}
+
+// sendVerificacionEmail envía el enlace de verificación de email
+func (s *AuthService) sendVerificacionEmail(email, token string) error {
+	link := fmt.Sprintf("%s/verificar-email?token=%s", config.GetConfig().Email.AppURL, token)
+	body := fmt.Sprintf("Bienvenido,\n\nPara activar la cuenta del hospital confirme su email con el siguiente enlace (válido por %.0f h):\n\n%s",
+		verificacionEmailTTL.Hours(), link)
+
+	return s.emailSender.Send(email, "Verifique el email de su hospital", body)
+}
+
+// GetProfile obtiene el perfil del usuario autenticado y de su hospital
+func (s *AuthService) GetProfile(usuarioID uint) (*ProfileResponse, error) {
+	var usuario models.Usuario
+	err := s.db.Preload("Hospital").First(&usuario, usuarioID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("usuario no encontrado")
+		}
+		return nil, err
+	}
+
+	return &ProfileResponse{
+		Usuario:  usuario.ToResponse(),
+		Hospital: usuario.Hospital.ToResponse(),
 	}, nil
 }
 
-// generateJWT genera un token JWT para el hospital
-func (s *AuthService) generateJWT(hospitalID uint, email string) (string, error) {
+// generateJWT genera un access token JWT de corta duración para el usuario
+func (s *AuthService) generateJWT(usuario models.Usuario) (string, time.Time, error) {
+	jti, err := generateRandomToken(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(config.GetConfig().JWT.AccessTokenTTL)
+
 	claims := &middleware.JWTClaims{
-		HospitalID: hospitalID,
+		UsuarioID:  usuario.ID,
+		HospitalID: usuario.IDHospital,
+		Email:      usuario.Email,
+		Rol:        usuario.Rol,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Subject:   usuario.Email,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenString, expiresAt, nil
+}
+
+// GenerarTokenEventos genera un token de un minuto que solo sirve para abrir /eventos/stream con ?token=,
+// porque el EventSource del navegador no puede enviar la cabecera Authorization. Comparte el jti del access
+// token con el que se pidió, de modo que el logout también lo revoca, y no dura más que él.
+func (s *AuthService) GenerarTokenEventos(actor Actor, email, jti string, accessExp time.Time) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(tokenEventosTTL)
+	if accessExp.Before(expiresAt) {
+		expiresAt = accessExp
+	}
+
+	claims := &middleware.JWTClaims{
+		UsuarioID:  actor.UsuarioID,
+		HospitalID: actor.HospitalID,
 		Email:      email,
+		Rol:        actor.Rol,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        jti,
+			Audience:  jwt.ClaimStrings{middleware.AudienciaEventos},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Subject:   email,
 		},
 	}
@@ -156,10 +560,119 @@ func (s *AuthService) generateJWT(hospitalID uint, email string) (string, error)
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
 	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenString, expiresAt, nil
+}
+
+// createRefreshToken genera un refresh token, guarda su hash y retorna el token en claro junto a su ID
+func createRefreshToken(db *gorm.DB, usuarioID uint, familia string) (string, uint, error) {
+	token, err := generateRandomToken(32)
+	if err != nil {
+		return "", 0, err
+	}
+
+	refreshToken := models.RefreshToken{
+		IDUsuario: usuarioID,
+		TokenHash: hashToken(token),
+		Familia:   familia,
+		ExpiresAt: time.Now().Add(config.GetConfig().JWT.RefreshTokenTTL),
+	}
+	if err := db.Create(&refreshToken).Error; err != nil {
+		return "", 0, err
+	}
+
+	return token, refreshToken.ID, nil
+}
+
+// createTokenVerificacion genera un token de un solo uso del tipo indicado e invalida los anteriores sin usar
+func createTokenVerificacion(db *gorm.DB, usuarioID uint, tipo string, ttl time.Duration) (string, error) {
+	token, err := generateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	err = db.Model(&models.TokenVerificacion{}).
+		Where("id_usuario = ? AND tipo = ? AND used_at IS NULL", usuarioID, tipo).
+		Update("used_at", now).Error
+	if err != nil {
+		return "", err
+	}
+
+	tokenVerificacion := models.TokenVerificacion{
+		IDUsuario: usuarioID,
+		Tipo:      tipo,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := db.Create(&tokenVerificacion).Error; err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// consumeTokenVerificacion valida un token del tipo indicado y lo marca como usado
+func consumeTokenVerificacion(db *gorm.DB, token, tipo string) (*models.TokenVerificacion, error) {
+	var tokenVerificacion models.TokenVerificacion
+	err := db.Where("token_hash = ? AND tipo = ?", hashToken(token), tipo).First(&tokenVerificacion).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("token inválido")
+		}
+		return nil, err
+	}
+
+	if tokenVerificacion.UsedAt != nil {
+		return nil, errors.New("token inválido")
+	}
+	if time.Now().After(tokenVerificacion.ExpiresAt) {
+		return nil, errors.New("token expirado")
+	}
+
+	// Marcar como usado solo si nadie lo consumió antes
+	result := db.Model(&models.TokenVerificacion{}).
+		Where("id = ? AND used_at IS NULL", tokenVerificacion.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("token inválido")
+	}
+
+	return &tokenVerificacion, nil
+}
+
+// revokeFamilia revoca todos los refresh tokens activos de una familia
+func revokeFamilia(db *gorm.DB, familia string) error {
+	return db.Model(&models.RefreshToken{}).
+		Where("familia = ? AND revoked_at IS NULL", familia).
+		Update("revoked_at", time.Now()).Error
+}
+
+// revokeRefreshTokensUsuario revoca todos los refresh tokens activos de un usuario
+func revokeRefreshTokensUsuario(db *gorm.DB, usuarioID uint) error {
+	return db.Model(&models.RefreshToken{}).
+		Where("id_usuario = ? AND revoked_at IS NULL", usuarioID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// generateRandomToken genera un valor aleatorio de n bytes codificado en hexadecimal
+func generateRandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
 		return "", err
 	}
+	return hex.EncodeToString(b), nil
+}
 
-	return tokenString, nil
+// hashToken retorna el hash SHA-256 en hexadecimal de un token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 // HashPassword hashea una contraseña usando bcrypt
@@ -0,0 +1,521 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"strings"
+	"time"
+
+	"hospital-api/internal/models"
+	"hospital-api/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Niveles de límites geográficos que se pueden importar
+const (
+	NivelLimiteDistrito = "distrito"
+	NivelLimiteBarrio   = "barrio"
+)
+
+// loteAsignacionDistritos historiales que se reasignan por transacción en el backfill
+const loteAsignacionDistritos = 500
+
+// ErrArchivoLimites indica un archivo de límites que no se puede interpretar
+var ErrArchivoLimites = errors.New("archivo de límites inválido")
+
+// ImportarLimitesRequest opciones de importación de un archivo de límites
+type ImportarLimitesRequest struct {
+	Nivel          string `form:"nivel" validate:"required,oneof=distrito barrio"`
+	CampoNombre    string `form:"campo_nombre" validate:"max=50"` // Propiedad con el nombre; por defecto nombre, name o el nivel
+	ActualizarArea bool   `form:"actualizar_area"`                // Reemplaza area_km2 de cada distrito por la del polígono
+}
+
+// ImportacionLimites resultado de importar un archivo de límites
+type ImportacionLimites struct {
+	Nivel           string   `json:"nivel"`
+	Features        int      `json:"features"`
+	Creados         int      `json:"creados"`
+	Actualizados    int      `json:"actualizados"`
+	SinCoincidencia []string `json:"sin_coincidencia"` // Distritos del archivo que no están en el registro
+	SinDistrito     []string `json:"sin_distrito"`     // Barrios cuyo punto interior no cae en ningún distrito
+}
+
+// AsignacionDistritos resultado del backfill de distritos y barrios de los historiales
+type AsignacionDistritos struct {
+	Revisados    int `json:"revisados"`
+	Actualizados int `json:"actualizados"`
+	SinDistrito  int `json:"sin_distrito"` // Historiales cuyas coordenadas no caen en ningún distrito importado
+}
+
+// featureLimite contorno de un archivo con el nombre leído de sus propiedades
+type featureLimite struct {
+	nombre    string
+	geometria utils.MultiPoligono
+}
+
+// filaLimite fila de distritos o barrios con su contorno
+type filaLimite struct {
+	ID         uint
+	Nombre     string
+	IDDistrito *uint
+	Limite     json.RawMessage
+}
+
+// limiteCargado contorno interpretado de un distrito o barrio
+type limiteCargado struct {
+	id         uint
+	nombre     string
+	idDistrito *uint
+	caja       utils.CajaLimite
+	geometria  utils.MultiPoligono
+}
+
+// localizadorLimites distritos y barrios con contorno para ubicar coordenadas
+type localizadorLimites struct {
+	distritos []limiteCargado
+	barrios   []limiteCargado
+}
+
+// ImportarLimites importa los contornos oficiales de distritos o barrios desde un GeoJSON (FeatureCollection,
+// .geojson o .json) o un Shapefile comprimido en ZIP. Los distritos se enlazan por nombre con el registro
+// existente; los barrios se crean o actualizan y quedan en el distrito que contiene su punto interior.
+// Las features con el mismo nombre se unen en un solo contorno.
+func (s *DistritoService) ImportarLimites(actor Actor, req ImportarLimitesRequest, nombreArchivo string, datos []byte) (*ImportacionLimites, error) {
+	features, err := leerFeaturesLimite(nombreArchivo, datos, camposNombreLimite(req))
+	if err != nil {
+		return nil, err
+	}
+
+	resultado := &ImportacionLimites{Nivel: req.Nivel, Features: len(features), SinCoincidencia: []string{}, SinDistrito: []string{}}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if req.Nivel == NivelLimiteDistrito {
+			return importarLimitesDistritos(tx, actor, features, req.ActualizarArea, nombreArchivo, resultado)
+		}
+		return importarLimitesBarrios(tx, actor, features, nombreArchivo, resultado)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resultado, nil
+}
+
+func importarLimitesDistritos(tx *gorm.DB, actor Actor, features []featureLimite, actualizarArea bool, archivo string, resultado *ImportacionLimites) error {
+	var distritos []models.Distrito
+	if err := tx.Omit("limite").Clauses(clause.Locking{Strength: "UPDATE"}).Find(&distritos).Error; err != nil {
+		return err
+	}
+	porNombre := make(map[string]*models.Distrito, len(distritos))
+	for i := range distritos {
+		porNombre[utils.NormalizarTexto(distritos[i].Nombre)] = &distritos[i]
+	}
+
+	for _, feature := range features {
+		distrito, ok := porNombre[utils.NormalizarTexto(feature.nombre)]
+		if !ok {
+			resultado.SinCoincidencia = append(resultado.SinCoincidencia, feature.nombre)
+			continue
+		}
+
+		cambios, err := columnasLimite(feature.geometria)
+		if err != nil {
+			return err
+		}
+		area := math.Round(feature.geometria.AreaKm2()*10) / 10
+		if actualizarArea && area > 0 {
+			cambios["area_km2"] = area
+		}
+		if err := tx.Model(&models.Distrito{}).Where("id = ?", distrito.ID).Updates(cambios).Error; err != nil {
+			return err
+		}
+
+		detalle := map[string]interface{}{"archivo": archivo, "poligonos": len(feature.geometria), "area_limite_km2": area}
+		var antes, despues interface{}
+		if actualizarArea && area > 0 {
+			antes, despues = map[string]interface{}{"area_km2": distrito.AreaKm2}, map[string]interface{}{"area_km2": area}
+		}
+		if err := auditar(tx, actor, models.AccionActualizar, models.EntidadDistrito, distrito.ID, antes, despues, detalle); err != nil {
+			return err
+		}
+		resultado.Actualizados++
+	}
+	return nil
+}
+
+func importarLimitesBarrios(tx *gorm.DB, actor Actor, features []featureLimite, archivo string, resultado *ImportacionLimites) error {
+	localizador, err := cargarLocalizador(tx, false)
+	if err != nil {
+		return err
+	}
+
+	var existentes []models.Barrio
+	if err := tx.Omit("limite").Clauses(clause.Locking{Strength: "UPDATE"}).Find(&existentes).Error; err != nil {
+		return err
+	}
+	claveBarrio := func(nombre string, idDistrito *uint) string {
+		if idDistrito == nil {
+			return "0|" + utils.NormalizarTexto(nombre)
+		}
+		return fmt.Sprintf("%d|%s", *idDistrito, utils.NormalizarTexto(nombre))
+	}
+	porClave := make(map[string]*models.Barrio, len(existentes))
+	for i := range existentes {
+		porClave[claveBarrio(existentes[i].Nombre, existentes[i].IDDistrito)] = &existentes[i]
+	}
+
+	for _, feature := range features {
+		var idDistrito *uint
+		if distrito, _ := localizador.ubicar(feature.geometria.PuntoInterior()); distrito != nil {
+			id := distrito.id
+			idDistrito = &id
+		} else {
+			resultado.SinDistrito = append(resultado.SinDistrito, feature.nombre)
+		}
+
+		cambios, err := columnasLimite(feature.geometria)
+		if err != nil {
+			return err
+		}
+		detalle := map[string]interface{}{"archivo": archivo, "poligonos": len(feature.geometria)}
+
+		if barrio, ok := porClave[claveBarrio(feature.nombre, idDistrito)]; ok {
+			if err := tx.Model(&models.Barrio{}).Where("id = ?", barrio.ID).Updates(cambios).Error; err != nil {
+				return err
+			}
+			if err := auditar(tx, actor, models.AccionActualizar, models.EntidadBarrio, barrio.ID, nil, nil, detalle); err != nil {
+				return err
+			}
+			resultado.Actualizados++
+			continue
+		}
+
+		barrio := models.Barrio{Nombre: feature.nombre, IDDistrito: idDistrito}
+		if err := tx.Create(&barrio).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Barrio{}).Where("id = ?", barrio.ID).Updates(cambios).Error; err != nil {
+			return err
+		}
+		if err := auditar(tx, actor, models.AccionCrear, models.EntidadBarrio, barrio.ID, nil, barrio, detalle); err != nil {
+			return err
+		}
+		porClave[claveBarrio(barrio.Nombre, barrio.IDDistrito)] = &barrio
+		resultado.Creados++
+	}
+	return nil
+}
+
+// columnasLimite contorno y caja de una geometría listos para Updates
+func columnasLimite(geometria utils.MultiPoligono) (map[string]interface{}, error) {
+	limite, err := geometria.GeoJSON()
+	if err != nil {
+		return nil, err
+	}
+	caja := geometria.Caja()
+	return map[string]interface{}{
+		"limite":         limite,
+		"limite_min_lat": caja.MinLat,
+		"limite_min_lng": caja.MinLng,
+		"limite_max_lat": caja.MaxLat,
+		"limite_max_lng": caja.MaxLng,
+	}, nil
+}
+
+// camposNombreLimite propiedades donde se busca el nombre de cada feature, en orden
+func camposNombreLimite(req ImportarLimitesRequest) []string {
+	if req.CampoNombre != "" {
+		return []string{req.CampoNombre}
+	}
+	return []string{"nombre", "name", req.Nivel}
+}
+
+// leerFeaturesLimite lee las features de un GeoJSON o de un Shapefile en ZIP según la extensión del archivo
+func leerFeaturesLimite(nombreArchivo string, datos []byte, camposNombre []string) ([]featureLimite, error) {
+	type featureLeida struct {
+		propiedades map[string]interface{}
+		geometria   utils.MultiPoligono
+	}
+	var leidas []featureLeida
+
+	switch strings.ToLower(path.Ext(nombreArchivo)) {
+	case ".zip":
+		features, err := utils.LeerShapefileZip(datos)
+		if err != nil {
+			return nil, err
+		}
+		for _, feature := range features {
+			propiedades := make(map[string]interface{}, len(feature.Atributos))
+			for clave, valor := range feature.Atributos {
+				propiedades[clave] = valor
+			}
+			leidas = append(leidas, featureLeida{propiedades: propiedades, geometria: feature.Geometria})
+		}
+	case ".geojson", ".json":
+		var coleccion struct {
+			Type     string `json:"type"`
+			Features []struct {
+				Geometry   json.RawMessage        `json:"geometry"`
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"features"`
+		}
+		if err := json.Unmarshal(datos, &coleccion); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrArchivoLimites, err)
+		}
+		if coleccion.Type != "FeatureCollection" {
+			return nil, fmt.Errorf("%w: se esperaba un FeatureCollection de GeoJSON", ErrArchivoLimites)
+		}
+		for i, feature := range coleccion.Features {
+			geometria, err := utils.ParseGeometriaGeoJSON(feature.Geometry)
+			if err != nil {
+				return nil, fmt.Errorf("feature %d: %w", i+1, err)
+			}
+			leidas = append(leidas, featureLeida{propiedades: feature.Properties, geometria: geometria})
+		}
+	default:
+		return nil, fmt.Errorf("%w: formato no admitido, se espera .geojson, .json o un Shapefile en .zip", ErrArchivoLimites)
+	}
+
+	if len(leidas) == 0 {
+		return nil, fmt.Errorf("%w: el archivo no contiene polígonos", ErrArchivoLimites)
+	}
+
+	// Las features con el mismo nombre (distritos en varias partes) se unen
+	var features []featureLimite
+	indice := make(map[string]int)
+	for i, leida := range leidas {
+		nombre := propiedadNombre(leida.propiedades, camposNombre)
+		if nombre == "" {
+			return nil, fmt.Errorf("%w: la feature %d no tiene la propiedad %s", ErrArchivoLimites, i+1, strings.Join(camposNombre, " ni "))
+		}
+		clave := utils.NormalizarTexto(nombre)
+		if j, ok := indice[clave]; ok {
+			features[j].geometria = append(features[j].geometria, leida.geometria...)
+			continue
+		}
+		indice[clave] = len(features)
+		features = append(features, featureLimite{nombre: nombre, geometria: leida.geometria})
+	}
+	return features, nil
+}
+
+// propiedadNombre primer campo no vacío de los indicados, sin distinguir mayúsculas en la clave
+func propiedadNombre(propiedades map[string]interface{}, campos []string) string {
+	for _, campo := range campos {
+		for clave, valor := range propiedades {
+			if !strings.EqualFold(clave, campo) || valor == nil {
+				continue
+			}
+			if texto := strings.TrimSpace(fmt.Sprint(valor)); texto != "" {
+				return texto
+			}
+		}
+	}
+	return ""
+}
+
+// GetLimites contornos de distritos o barrios como FeatureCollection de GeoJSON
+func (s *DistritoService) GetLimites(nivel string) (*utils.FeatureCollection, error) {
+	tabla := "distritos"
+	if nivel == NivelLimiteBarrio {
+		tabla = "barrios"
+	}
+
+	var filas []filaLimite
+	if err := consultaLimites(s.db, tabla).Scan(&filas).Error; err != nil {
+		return nil, err
+	}
+
+	coleccion := utils.NuevaFeatureCollection()
+	for _, fila := range filas {
+		propiedades := map[string]interface{}{"id": fila.ID, "nombre": fila.Nombre}
+		if nivel == NivelLimiteBarrio {
+			propiedades["id_distrito"] = fila.IDDistrito
+		}
+		coleccion.Agregar(fila.ID, fila.Limite, propiedades)
+	}
+	return coleccion, nil
+}
+
+// AsignarDistritosHistoriales reasigna por punto en polígono el distrito y el barrio de los historiales
+// existentes. Con soloSinAsignar se omiten los que ya tienen distrito. Cada historial que cambia sube
+// de versión, guarda su revisión y queda en la auditoría de su hospital, sin usuario asociado.
+func (s *DistritoService) AsignarDistritosHistoriales(soloSinAsignar bool) (*AsignacionDistritos, error) {
+	localizador, err := cargarLocalizador(s.db, true)
+	if err != nil {
+		return nil, err
+	}
+
+	resultado := &AsignacionDistritos{}
+	var ultimoID uint
+	for {
+		var lote []models.HistorialClinico
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id > ?", ultimoID)
+			if soloSinAsignar {
+				query = query.Where("id_distrito IS NULL")
+			}
+			if err := query.Order("id").Limit(loteAsignacionDistritos).Find(&lote).Error; err != nil {
+				return err
+			}
+
+			for i := range lote {
+				antes := lote[i]
+				despues := lote[i]
+				if !localizador.aplicar(&despues) {
+					if despues.IDDistrito == nil {
+						resultado.SinDistrito++
+					}
+					continue
+				}
+				if despues.IDDistrito == nil {
+					resultado.SinDistrito++
+				}
+				despues.Version = antes.Version + 1
+				despues.UpdatedAt = time.Now()
+
+				err := tx.Model(&models.HistorialClinico{}).
+					Where("id = ?", despues.ID).
+					Select("id_distrito", "id_barrio", "patient_district", "patient_neighborhood", "version", "updated_at").
+					Updates(&despues).Error
+				if err != nil {
+					return err
+				}
+				if err := registrarRevisionActualizacion(tx, &antes, &despues, nil); err != nil {
+					return err
+				}
+				err = auditar(tx, actorSistema(despues.IDHospital), models.AccionActualizar, models.EntidadHistorial, despues.ID, antes, despues, map[string]interface{}{
+					"asignacion_distritos": true,
+				})
+				if err != nil {
+					return err
+				}
+				resultado.Actualizados++
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		resultado.Revisados += len(lote)
+		if len(lote) < loteAsignacionDistritos {
+			return resultado, nil
+		}
+		ultimoID = lote[len(lote)-1].ID
+	}
+}
+
+// asignarDistrito ubica las coordenadas del historial en los contornos importados y le asigna el distrito
+// y el barrio oficiales, cuyos nombres reemplazan a patient_district y patient_neighborhood
+func asignarDistrito(db *gorm.DB, historial *models.HistorialClinico) error {
+	lat, lng := historial.PatientLatitude, historial.PatientLongitude
+	enCaja := func(db *gorm.DB) *gorm.DB {
+		return db.Where("limite_min_lat <= ? AND limite_max_lat >= ? AND limite_min_lng <= ? AND limite_max_lng >= ?", lat, lat, lng, lng)
+	}
+
+	localizador, err := cargarLocalizador(db.Scopes(enCaja), true)
+	if err != nil {
+		return err
+	}
+	localizador.aplicar(historial)
+	return nil
+}
+
+// cargarLocalizador lee los contornos de los distritos y, si se indica, de los barrios
+func cargarLocalizador(db *gorm.DB, conBarrios bool) (*localizadorLimites, error) {
+	localizador := &localizadorLimites{}
+	var err error
+	if localizador.distritos, err = leerLimites(consultaLimites(db, "distritos")); err != nil {
+		return nil, err
+	}
+	if conBarrios {
+		if localizador.barrios, err = leerLimites(consultaLimites(db, "barrios")); err != nil {
+			return nil, err
+		}
+	}
+	return localizador, nil
+}
+
+// consultaLimites filas de la tabla con contorno importado
+func consultaLimites(db *gorm.DB, tabla string) *gorm.DB {
+	columnas := "id, nombre, limite"
+	if tabla == "barrios" {
+		columnas += ", id_distrito"
+	}
+	return db.Table(tabla).Select(columnas).Where("limite IS NOT NULL").Order("id")
+}
+
+func leerLimites(query *gorm.DB) ([]limiteCargado, error) {
+	var filas []filaLimite
+	if err := query.Scan(&filas).Error; err != nil {
+		return nil, err
+	}
+
+	limites := make([]limiteCargado, 0, len(filas))
+	for _, fila := range filas {
+		geometria, err := utils.ParseGeometriaGeoJSON(fila.Limite)
+		if err != nil {
+			return nil, fmt.Errorf("contorno de %s: %w", fila.Nombre, err)
+		}
+		limites = append(limites, limiteCargado{
+			id:         fila.ID,
+			nombre:     fila.Nombre,
+			idDistrito: fila.IDDistrito,
+			caja:       geometria.Caja(),
+			geometria:  geometria,
+		})
+	}
+	return limites, nil
+}
+
+// ubicar distrito y barrio cuyos contornos contienen el punto; nil si no cae en ninguno
+func (l *localizadorLimites) ubicar(lat, lng float64) (distrito, barrio *limiteCargado) {
+	return primerLimite(l.distritos, lat, lng), primerLimite(l.barrios, lat, lng)
+}
+
+func primerLimite(limites []limiteCargado, lat, lng float64) *limiteCargado {
+	for i := range limites {
+		caja := limites[i].caja
+		if lat < caja.MinLat || lat > caja.MaxLat || lng < caja.MinLng || lng > caja.MaxLng {
+			continue
+		}
+		if limites[i].geometria.Contiene(lat, lng) {
+			return &limites[i]
+		}
+	}
+	return nil
+}
+
+// aplicar asigna al historial el distrito y el barrio que contienen sus coordenadas. Los nombres oficiales
+// reemplazan al texto registrado; fuera de los contornos se conserva el texto. Retorna si algo cambió.
+func (l *localizadorLimites) aplicar(historial *models.HistorialClinico) bool {
+	distrito, barrio := l.ubicar(historial.PatientLatitude, historial.PatientLongitude)
+	antes := *historial
+
+	historial.IDDistrito = nil
+	if distrito != nil {
+		id := distrito.id
+		historial.IDDistrito = &id
+		historial.PatientDistrict = distrito.nombre
+	}
+	historial.IDBarrio = nil
+	if barrio != nil {
+		id := barrio.id
+		historial.IDBarrio = &id
+		historial.PatientNeighborhood = barrio.nombre
+	}
+
+	return !mismoID(antes.IDDistrito, historial.IDDistrito) || !mismoID(antes.IDBarrio, historial.IDBarrio) ||
+		antes.PatientDistrict != historial.PatientDistrict || antes.PatientNeighborhood != historial.PatientNeighborhood
+}
+
+func mismoID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
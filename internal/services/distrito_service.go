@@ -0,0 +1,371 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"hospital-api/internal/database"
+	"hospital-api/internal/models"
+	"hospital-api/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CiudadDistritos ciudad a la que pertenece el registro de distritos
+const CiudadDistritos = "Santa Cruz de la Sierra"
+
+// ErrConexionInvalida indica una conexión consigo mismo, repetida o hacia un distrito inexistente
+var ErrConexionInvalida = errors.New("conexión de distritos inválida")
+
+type DistritoService struct {
+	db *gorm.DB
+}
+
+// DistritoRequest datos demográficos y centroide de un distrito
+type DistritoRequest struct {
+	Nombre     string  `json:"nombre" validate:"required,min=2,max=100"`
+	Habitantes int     `json:"habitantes" validate:"required,gt=0"`
+	AreaKm2    float64 `json:"area_km2" validate:"required,gt=0"`
+	TipoZona   string  `json:"tipo_zona" validate:"max=50"`
+	Latitud    float64 `json:"latitud" validate:"required,gte=-90,lte=90"`
+	Longitud   float64 `json:"longitud" validate:"required,gte=-180,lte=180"`
+}
+
+// ConexionRequest vecino de un distrito con el peso de la conexión
+type ConexionRequest struct {
+	IDDistrito uint    `json:"id_distrito" validate:"required"`
+	Peso       float64 `json:"peso" validate:"required,gt=0,lte=1"`
+}
+
+// ConexionesRequest lista completa de vecinos de un distrito; reemplaza la anterior
+type ConexionesRequest struct {
+	Conexiones []ConexionRequest `json:"conexiones" validate:"dive"`
+}
+
+// ResumenDistritos totales de la ciudad
+type ResumenDistritos struct {
+	PoblacionTotal   int     `json:"poblacion_total"`
+	AreaTotalKm2     float64 `json:"area_total_km2"`
+	DensidadPromedio int     `json:"densidad_promedio"`
+	TotalDistritos   int     `json:"total_distritos"`
+}
+
+// NewDistritoService crea una nueva instancia del servicio de distritos
+func NewDistritoService() *DistritoService {
+	return &DistritoService{
+		db: database.GetDB(),
+	}
+}
+
+// GetDistritos obtiene todos los distritos con sus conexiones, ordenados por nombre
+func (s *DistritoService) GetDistritos() ([]models.Distrito, error) {
+	red, err := cargarRedDistritos(s.db)
+	if err != nil {
+		return nil, err
+	}
+	return red.distritos, nil
+}
+
+// GetDistritoByID obtiene un distrito con sus conexiones
+func (s *DistritoService) GetDistritoByID(id uint) (*models.Distrito, error) {
+	red, err := cargarRedDistritos(s.db)
+	if err != nil {
+		return nil, err
+	}
+	for i := range red.distritos {
+		if red.distritos[i].ID == id {
+			return &red.distritos[i], nil
+		}
+	}
+	return nil, errors.New("distrito no encontrado")
+}
+
+// CreateDistrito registra un distrito sin conexiones
+func (s *DistritoService) CreateDistrito(actor Actor, req DistritoRequest) (*models.Distrito, error) {
+	distrito := models.Distrito{}
+	aplicarDistritoRequest(&distrito, req)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := validarNombreDistrito(tx, req.Nombre, 0); err != nil {
+			return err
+		}
+		if err := tx.Create(&distrito).Error; err != nil {
+			return err
+		}
+		return auditar(tx, actor, models.AccionCrear, models.EntidadDistrito, distrito.ID, nil, distrito, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	completarDensidad(&distrito)
+	return &distrito, nil
+}
+
+// UpdateDistrito reemplaza los datos de un distrito; sus conexiones no cambian
+func (s *DistritoService) UpdateDistrito(actor Actor, id uint, req DistritoRequest) (*models.Distrito, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var antes models.Distrito
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&antes, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("distrito no encontrado")
+			}
+			return err
+		}
+		if err := validarNombreDistrito(tx, req.Nombre, id); err != nil {
+			return err
+		}
+
+		despues := antes
+		aplicarDistritoRequest(&despues, req)
+		if err := tx.Save(&despues).Error; err != nil {
+			return err
+		}
+		return auditar(tx, actor, models.AccionActualizar, models.EntidadDistrito, id, antes, despues, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetDistritoByID(id)
+}
+
+// DeleteDistrito elimina un distrito y sus conexiones
+func (s *DistritoService) DeleteDistrito(actor Actor, id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var distrito models.Distrito
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&distrito, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("distrito no encontrado")
+			}
+			return err
+		}
+		if err := tx.Delete(&distrito).Error; err != nil {
+			return err
+		}
+		return auditar(tx, actor, models.AccionEliminar, models.EntidadDistrito, id, distrito, nil, nil)
+	})
+}
+
+// ActualizarConexiones reemplaza los vecinos de un distrito. Las conexiones son simétricas: también
+// cambian en la lista de cada vecino.
+func (s *DistritoService) ActualizarConexiones(actor Actor, id uint, req ConexionesRequest) (*models.Distrito, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var distrito models.Distrito
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&distrito, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("distrito no encontrado")
+			}
+			return err
+		}
+
+		vistos := make(map[uint]bool, len(req.Conexiones))
+		conexiones := make([]models.ConexionDistrito, 0, len(req.Conexiones))
+		for _, c := range req.Conexiones {
+			if c.IDDistrito == id || vistos[c.IDDistrito] {
+				return ErrConexionInvalida
+			}
+			vistos[c.IDDistrito] = true
+			conexiones = append(conexiones, models.ConexionDistrito{
+				IDDistritoA: min(id, c.IDDistrito),
+				IDDistritoB: max(id, c.IDDistrito),
+				Peso:        c.Peso,
+			})
+		}
+
+		if len(vistos) > 0 {
+			ids := make([]uint, 0, len(vistos))
+			for vecino := range vistos {
+				ids = append(ids, vecino)
+			}
+			var existentes int64
+			if err := tx.Model(&models.Distrito{}).Where("id IN ?", ids).Count(&existentes).Error; err != nil {
+				return err
+			}
+			if int(existentes) != len(ids) {
+				return ErrConexionInvalida
+			}
+		}
+
+		var antes []models.ConexionDistrito
+		err := tx.Where("id_distrito_a = ? OR id_distrito_b = ?", id, id).Order("id_distrito_a, id_distrito_b").Find(&antes).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("id_distrito_a = ? OR id_distrito_b = ?", id, id).Delete(&models.ConexionDistrito{}).Error; err != nil {
+			return err
+		}
+		if len(conexiones) > 0 {
+			if err := tx.Create(&conexiones).Error; err != nil {
+				return err
+			}
+		}
+
+		return auditar(tx, actor, models.AccionActualizar, models.EntidadDistrito, id,
+			map[string]interface{}{"conexiones": antes}, map[string]interface{}{"conexiones": conexiones}, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetDistritoByID(id)
+}
+
+// GetMatrizConectividad nombres de los vecinos de cada distrito
+func (s *DistritoService) GetMatrizConectividad() (map[string][]string, error) {
+	red, err := cargarRedDistritos(s.db)
+	if err != nil {
+		return nil, err
+	}
+	return red.matrizConectividad(), nil
+}
+
+// GetResumen totales de población y área de todos los distritos
+func (s *DistritoService) GetResumen() (*ResumenDistritos, error) {
+	red, err := cargarRedDistritos(s.db)
+	if err != nil {
+		return nil, err
+	}
+	resumen := red.resumen()
+	return &resumen, nil
+}
+
+func aplicarDistritoRequest(distrito *models.Distrito, req DistritoRequest) {
+	distrito.Nombre = req.Nombre
+	distrito.Habitantes = req.Habitantes
+	distrito.AreaKm2 = req.AreaKm2
+	distrito.TipoZona = req.TipoZona
+	distrito.Latitud = req.Latitud
+	distrito.Longitud = req.Longitud
+}
+
+// validarNombreDistrito rechaza un nombre que ya usa otro distrito, sin distinguir mayúsculas ni acentos
+func validarNombreDistrito(tx *gorm.DB, nombre string, id uint) error {
+	var count int64
+	err := tx.Model(&models.Distrito{}).
+		Where("f_normalizar(nombre) = f_normalizar(?) AND id <> ?", nombre, id).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("ya existe un distrito con ese nombre")
+	}
+	return nil
+}
+
+// completarDensidad calcula los campos derivados que no se guardan
+func completarDensidad(distrito *models.Distrito) {
+	distrito.TieneLimite = distrito.LimiteMinLat != nil
+	if distrito.AreaKm2 > 0 {
+		distrito.Densidad = int(math.Round(float64(distrito.Habitantes) / distrito.AreaKm2))
+	}
+}
+
+// vecinoDistrito vecino de un distrito en el grafo, con el peso de la conexión
+type vecinoDistrito struct {
+	Nombre string
+	Peso   float64
+}
+
+// redDistritos registro de distritos leído de la base de datos para un cálculo
+type redDistritos struct {
+	distritos []models.Distrito // Ordenados por nombre, con densidad y conexiones
+	indice    map[string]int    // Nombre normalizado → posición en distritos
+}
+
+// cargarRedDistritos lee los distritos y sus conexiones
+func cargarRedDistritos(db *gorm.DB) (*redDistritos, error) {
+	var distritos []models.Distrito
+	if err := db.Omit("limite").Order("nombre").Find(&distritos).Error; err != nil {
+		return nil, err
+	}
+	var conexiones []models.ConexionDistrito
+	if err := db.Find(&conexiones).Error; err != nil {
+		return nil, err
+	}
+
+	red := &redDistritos{distritos: distritos, indice: make(map[string]int, len(distritos))}
+	porID := make(map[uint]int, len(distritos))
+	for i := range red.distritos {
+		completarDensidad(&red.distritos[i])
+		red.distritos[i].Conexiones = []models.ConexionDistrito{}
+		red.indice[utils.NormalizarTexto(red.distritos[i].Nombre)] = i
+		porID[red.distritos[i].ID] = i
+	}
+
+	for _, conexion := range conexiones {
+		a, okA := porID[conexion.IDDistritoA]
+		b, okB := porID[conexion.IDDistritoB]
+		if !okA || !okB {
+			continue
+		}
+		red.agregarVecino(a, b, conexion.Peso)
+		red.agregarVecino(b, a, conexion.Peso)
+	}
+	for i := range red.distritos {
+		sort.Slice(red.distritos[i].Conexiones, func(x, y int) bool {
+			return red.distritos[i].Conexiones[x].Distrito < red.distritos[i].Conexiones[y].Distrito
+		})
+	}
+
+	return red, nil
+}
+
+func (r *redDistritos) agregarVecino(desde, hacia int, peso float64) {
+	r.distritos[desde].Conexiones = append(r.distritos[desde].Conexiones, models.ConexionDistrito{
+		IDDistrito: r.distritos[hacia].ID,
+		Distrito:   r.distritos[hacia].Nombre,
+		Peso:       peso,
+	})
+}
+
+// buscar obtiene un distrito por nombre, sin distinguir mayúsculas, acentos ni signos
+func (r *redDistritos) buscar(nombre string) (*models.Distrito, bool) {
+	i, ok := r.indice[utils.NormalizarTexto(nombre)]
+	if !ok {
+		return nil, false
+	}
+	return &r.distritos[i], true
+}
+
+// vecinos distritos conectados con el indicado
+func (r *redDistritos) vecinos(nombre string) []vecinoDistrito {
+	distrito, ok := r.buscar(nombre)
+	if !ok {
+		return nil
+	}
+	vecinos := make([]vecinoDistrito, len(distrito.Conexiones))
+	for i, conexion := range distrito.Conexiones {
+		vecinos[i] = vecinoDistrito{Nombre: conexion.Distrito, Peso: conexion.Peso}
+	}
+	return vecinos
+}
+
+// matrizConectividad nombres de los vecinos de cada distrito
+func (r *redDistritos) matrizConectividad() map[string][]string {
+	matriz := make(map[string][]string, len(r.distritos))
+	for _, distrito := range r.distritos {
+		nombres := make([]string, len(distrito.Conexiones))
+		for i, conexion := range distrito.Conexiones {
+			nombres[i] = conexion.Distrito
+		}
+		matriz[distrito.Nombre] = nombres
+	}
+	return matriz
+}
+
+func (r *redDistritos) resumen() ResumenDistritos {
+	resumen := ResumenDistritos{TotalDistritos: len(r.distritos)}
+	for _, distrito := range r.distritos {
+		resumen.PoblacionTotal += distrito.Habitantes
+		resumen.AreaTotalKm2 += distrito.AreaKm2
+	}
+	resumen.AreaTotalKm2 = math.Round(resumen.AreaTotalKm2*10) / 10
+	if resumen.AreaTotalKm2 > 0 {
+		resumen.DensidadPromedio = int(math.Round(float64(resumen.PoblacionTotal) / resumen.AreaTotalKm2))
+	}
+	return resumen
+}
@@ -0,0 +1,428 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"hospital-api/internal/database"
+	"hospital-api/internal/models"
+	"hospital-api/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UmbralDuplicado puntaje mínimo para considerar que dos pacientes son la misma persona
+const UmbralDuplicado = 0.85
+
+// ciNormalizadoSQL expresión SQL equivalente a utils.NormalizarCI
+const ciNormalizadoSQL = "regexp_replace(upper(ci), '[^0-9A-Z]', '', 'g')"
+
+// bloqueDuplicadoSQL condición que deben cumplir los pacientes a y b para compararse: misma fecha de
+// nacimiento, mismo CI, o mismo año de nacimiento y nombres parecidos según pg_trgm
+const bloqueDuplicadoSQL = `a.fecha_nacimiento = b.fecha_nacimiento
+	OR (regexp_replace(upper(a.ci), '[^0-9A-Z]', '', 'g') <> ''
+		AND regexp_replace(upper(a.ci), '[^0-9A-Z]', '', 'g') = regexp_replace(upper(b.ci), '[^0-9A-Z]', '', 'g'))
+	OR (date_part('year', a.fecha_nacimiento) = date_part('year', b.fecha_nacimiento)
+		AND f_unaccent(lower(a.nombre)) % f_unaccent(lower(b.nombre)))`
+
+// puntajeDuplicadoSQL expresión SQL equivalente a puntuarDuplicado(a, b).Total
+const puntajeDuplicadoSQL = `CASE
+	WHEN regexp_replace(upper(a.ci), '[^0-9A-Z]', '', 'g') = '' OR regexp_replace(upper(b.ci), '[^0-9A-Z]', '', 'g') = ''
+		THEN 0.65 * f_similitud_nombre(a.nombre, b.nombre) + 0.35 * f_similitud_fecha(a.fecha_nacimiento, b.fecha_nacimiento)
+	ELSE 0.4 * f_similitud_nombre(a.nombre, b.nombre) + 0.2 * f_similitud_fecha(a.fecha_nacimiento, b.fecha_nacimiento)
+		+ CASE WHEN regexp_replace(upper(a.ci), '[^0-9A-Z]', '', 'g') = regexp_replace(upper(b.ci), '[^0-9A-Z]', '', 'g') THEN 0.4 ELSE 0 END
+END`
+
+// ErrPacientesNoDuplicados indica que se intentó fusionar pacientes que no se parecen lo suficiente
+var ErrPacientesNoDuplicados = errors.New("los pacientes no coinciden lo suficiente para fusionarlos")
+
+// ErrFusionNoAutorizada indica que un médico intentó fusionar un paciente de otro hospital
+var ErrFusionNoAutorizada = errors.New("fusionar pacientes de otro hospital requiere un administrador o epidemiólogo")
+
+type DuplicadoService struct {
+	db *gorm.DB
+}
+
+// PuntajeDuplicado desglose de la similitud entre dos pacientes (0 a 1)
+type PuntajeDuplicado struct {
+	Total           float64  `json:"total"`
+	Nombre          float64  `json:"nombre"`
+	FechaNacimiento float64  `json:"fecha_nacimiento"`
+	CI              *float64 `json:"ci"` // nil si alguno de los dos no tiene CI
+}
+
+// CandidatoDuplicado par de pacientes que probablemente son la misma persona. Paciente es siempre visible
+// para el actor; el otro lado se incluye completo en Duplicado solo si también lo es y, si no,
+// enmascarado en DuplicadoEnmascarado.
+type CandidatoDuplicado struct {
+	Paciente             models.Paciente      `json:"paciente"`
+	Duplicado            *models.Paciente     `json:"duplicado,omitempty"`
+	DuplicadoEnmascarado *PacienteEnmascarado `json:"duplicado_enmascarado,omitempty"`
+	Puntaje              PuntajeDuplicado     `json:"puntaje"`
+}
+
+// PacienteEnmascarado identificadores parciales de un paciente de otro hospital, suficientes para
+// reconocer el par sin exponer sus datos personales
+type PacienteEnmascarado struct {
+	ID             uint   `json:"id"`
+	IDHospital     *uint  `json:"id_hospital"`
+	Iniciales      string `json:"iniciales"`
+	CI             string `json:"ci"` // Solo los últimos 3 caracteres
+	AnioNacimiento int    `json:"anio_nacimiento"`
+	Sexo           string `json:"sexo"`
+}
+
+// FusionRequest pacientes a fusionar: el duplicado se elimina y sus historiales pasan al superviviente
+type FusionRequest struct {
+	IDSuperviviente uint `json:"id_superviviente" validate:"required"`
+	IDDuplicado     uint `json:"id_duplicado" validate:"required,nefield=IDSuperviviente"`
+}
+
+// FusionResultado paciente resultante de una fusión
+type FusionResultado struct {
+	Paciente           models.Paciente `json:"paciente"`
+	HistorialesMovidos int             `json:"historiales_movidos"`
+}
+
+// NewDuplicadoService crea una nueva instancia del servicio de detección de duplicados
+func NewDuplicadoService() *DuplicadoService {
+	return &DuplicadoService{
+		db: database.GetDB(),
+	}
+}
+
+// FindDuplicados busca pacientes de toda la red que probablemente sean la misma persona que un paciente
+// visible para el actor. Solo se comparan pacientes con la misma fecha de nacimiento, el mismo CI, o el
+// mismo año de nacimiento y un nombre parecido (similitud de trigramas de pg_trgm), de modo que un error de
+// digitación en la fecha no impide detectar el par. Los pares se puntúan y paginan en la base de datos.
+func (s *DuplicadoService) FindDuplicados(actor Actor, umbral float64, page, limit int) ([]CandidatoDuplicado, int64, error) {
+	visibles := s.db.Model(&models.Paciente{}).Scopes(actor.pacienteScope).Select("pacientes.id")
+
+	// a es siempre el paciente visible; si b también lo es, el par se cuenta una sola vez
+	pares := s.db.Table("pacientes AS a").
+		Joins("JOIN pacientes AS b ON b.id <> a.id AND b.deleted_at IS NULL AND ("+bloqueDuplicadoSQL+
+			") AND (a.id < b.id OR b.id NOT IN (?))", visibles).
+		Where("a.deleted_at IS NULL AND a.id IN (?)", visibles).
+		Select("a.id AS id_paciente, b.id AS id_duplicado, " + puntajeDuplicadoSQL + " AS puntaje")
+	query := s.db.Table("(?) AS pares", pares).Where("puntaje >= ?", umbral).Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var filas []struct {
+		IDPaciente  uint
+		IDDuplicado uint
+	}
+	err := query.Select("id_paciente, id_duplicado").
+		Order("puntaje DESC, id_paciente, id_duplicado").
+		Offset((page - 1) * limit).Limit(limit).
+		Scan(&filas).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]uint, 0, len(filas)*2)
+	for _, fila := range filas {
+		ids = append(ids, fila.IDPaciente, fila.IDDuplicado)
+	}
+
+	var pacientes []models.Paciente
+	if err := s.db.Where("id IN ?", ids).Find(&pacientes).Error; err != nil {
+		return nil, 0, err
+	}
+	porID := make(map[uint]*models.Paciente, len(pacientes))
+	for i := range pacientes {
+		porID[pacientes[i].ID] = &pacientes[i]
+	}
+
+	var visiblesPagina []uint
+	err = s.db.Model(&models.Paciente{}).Scopes(actor.pacienteScope).
+		Where("pacientes.id IN ?", ids).
+		Pluck("pacientes.id", &visiblesPagina).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	candidatos := make([]CandidatoDuplicado, 0, len(filas))
+	for _, fila := range filas {
+		a, b := porID[fila.IDPaciente], porID[fila.IDDuplicado]
+		if a == nil || b == nil {
+			continue // Eliminado entre las dos consultas
+		}
+		candidato := CandidatoDuplicado{Paciente: *a, Puntaje: puntuarDuplicado(a, b)}
+		if slices.Contains(visiblesPagina, b.ID) {
+			candidato.Duplicado = b
+		} else {
+			candidato.DuplicadoEnmascarado = enmascararPaciente(b)
+		}
+		candidatos = append(candidatos, candidato)
+	}
+
+	err = auditarLectura(s.db, actor, models.EntidadPaciente, ids, map[string]interface{}{
+		"reporte": "duplicados",
+		"umbral":  umbral,
+		"page":    page,
+		"limit":   limit,
+	})
+
+	return candidatos, total, err
+}
+
+// enmascararPaciente reduce un paciente a sus iniciales, el final del CI, el año de nacimiento y el sexo
+func enmascararPaciente(p *models.Paciente) *PacienteEnmascarado {
+	var iniciales strings.Builder
+	for _, palabra := range strings.Fields(p.NombreCompleto()) {
+		inicial, _ := utf8.DecodeRuneInString(palabra)
+		iniciales.WriteRune(unicode.ToUpper(inicial))
+		iniciales.WriteByte('.')
+	}
+
+	ci := utils.NormalizarCI(p.CI)
+	if len(ci) > 3 {
+		ci = strings.Repeat("*", len(ci)-3) + ci[len(ci)-3:]
+	}
+
+	return &PacienteEnmascarado{
+		ID:             p.ID,
+		IDHospital:     p.IDHospital,
+		Iniciales:      iniciales.String(),
+		CI:             ci,
+		AnioNacimiento: p.FechaNacimiento.Year(),
+		Sexo:           p.Sexo,
+	}
+}
+
+// fusionaEntreHospitales indica si el rol del actor puede fusionar pacientes que no son de su hospital
+func fusionaEntreHospitales(actor Actor) bool {
+	return actor.Rol == models.RolAdmin || actor.Rol == models.RolEpidemiologo
+}
+
+// FusionarPacientes mueve los historiales del duplicado al superviviente, completa los datos de identidad
+// vacíos del superviviente con los del duplicado y elimina el duplicado (soft delete).
+// Un médico solo fusiona pacientes visibles para su hospital. Administradores y epidemiólogos pueden
+// fusionar pacientes de otros hospitales si su puntaje alcanza UmbralDuplicado.
+func (s *DuplicadoService) FusionarPacientes(actor Actor, req FusionRequest) (*FusionResultado, error) {
+	if req.IDSuperviviente == req.IDDuplicado {
+		return nil, errors.New("no se puede fusionar un paciente consigo mismo")
+	}
+
+	resultado := &FusionResultado{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var pacientes []models.Paciente
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []uint{req.IDSuperviviente, req.IDDuplicado}).
+			Find(&pacientes).Error
+		if err != nil {
+			return err
+		}
+		if len(pacientes) != 2 {
+			return errors.New("paciente no encontrado")
+		}
+		superviviente, duplicado := pacientes[0], pacientes[1]
+		if superviviente.ID != req.IDSuperviviente {
+			superviviente, duplicado = duplicado, superviviente
+		}
+
+		var visibles int64
+		err = tx.Model(&models.Paciente{}).Scopes(actor.pacienteScope).
+			Where("pacientes.id IN ?", []uint{superviviente.ID, duplicado.ID}).
+			Count(&visibles).Error
+		if err != nil {
+			return err
+		}
+		puntaje := puntuarDuplicado(&superviviente, &duplicado)
+		if visibles < 2 {
+			if !fusionaEntreHospitales(actor) {
+				if visibles == 0 {
+					return errors.New("paciente no encontrado")
+				}
+				return ErrFusionNoAutorizada
+			}
+			if puntaje.Total < UmbralDuplicado {
+				return ErrPacientesNoDuplicados
+			}
+		}
+
+		// Completar los datos de identidad que le falten al superviviente
+		fusionado := superviviente
+		completarIdentidad(&fusionado, &duplicado)
+		fusionado.Version = superviviente.Version + 1
+		err = tx.Model(&models.Paciente{}).
+			Where("id = ?", superviviente.ID).
+			Select("ci", "nombres", "apellidos", "nombre", "telefono", "direccion", "tipo_sangre", "version").
+			Updates(&fusionado).Error
+		if err != nil {
+			return err
+		}
+
+		movidos, err := moverHistoriales(tx, actor, duplicado.ID, superviviente.ID)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.Paciente{}).Where("id = ?", duplicado.ID).Updates(map[string]interface{}{
+			"id_fusionado_en": superviviente.ID,
+			"version":         duplicado.Version + 1,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Paciente{}, duplicado.ID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.First(&resultado.Paciente, superviviente.ID).Error; err != nil {
+			return err
+		}
+		resultado.HistorialesMovidos = movidos
+
+		err = auditar(tx, actor, models.AccionFusionar, models.EntidadPaciente, superviviente.ID, superviviente, resultado.Paciente, map[string]interface{}{
+			"id_duplicado":        duplicado.ID,
+			"historiales_movidos": movidos,
+			"puntaje":             puntaje,
+		})
+		if err != nil {
+			return err
+		}
+		return auditar(tx, actor, models.AccionEliminar, models.EntidadPaciente, duplicado.ID, duplicado, nil, map[string]interface{}{
+			"id_fusionado_en": superviviente.ID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resultado, nil
+}
+
+// moverHistoriales reasigna los historiales (incluidos los eliminados) de un paciente a otro,
+// registrando una nueva versión y una entrada de auditoría por cada historial
+func moverHistoriales(tx *gorm.DB, actor Actor, desde, hacia uint) (int, error) {
+	var historiales []models.HistorialClinico
+	err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Where("id_paciente = ?", desde).Find(&historiales).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range historiales {
+		antes := historiales[i]
+		err := tx.Unscoped().Model(&models.HistorialClinico{}).Where("id = ?", antes.ID).Updates(map[string]interface{}{
+			"id_paciente": hacia,
+			"version":     antes.Version + 1,
+		}).Error
+		if err != nil {
+			return 0, err
+		}
+
+		var despues models.HistorialClinico
+		if err := tx.Unscoped().First(&despues, antes.ID).Error; err != nil {
+			return 0, err
+		}
+
+		if err := registrarRevisionActualizacion(tx, &antes, &despues, &actor.UsuarioID); err != nil {
+			return 0, err
+		}
+		err = auditar(tx, actor, models.AccionActualizar, models.EntidadHistorial, antes.ID, antes, despues, map[string]interface{}{
+			"fusion_paciente": desde,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(historiales), nil
+}
+
+// completarIdentidad copia en destino los datos de identidad que tiene origen y a destino le faltan
+func completarIdentidad(destino, origen *models.Paciente) {
+	if destino.CI == "" {
+		destino.CI = origen.CI
+	}
+	if destino.Nombres == "" && destino.Apellidos == "" && (origen.Nombres != "" || origen.Apellidos != "") {
+		destino.Nombres = origen.Nombres
+		destino.Apellidos = origen.Apellidos
+		destino.Nombre = destino.NombreCompleto()
+	}
+	if destino.Telefono == "" {
+		destino.Telefono = origen.Telefono
+	}
+	if destino.Direccion == "" {
+		destino.Direccion = origen.Direccion
+	}
+	if destino.TipoSangre == "" {
+		destino.TipoSangre = origen.TipoSangre
+	}
+}
+
+// puntuarDuplicado combina la similitud de nombre, fecha de nacimiento y CI de dos pacientes.
+// Cuando ambos tienen CI, su coincidencia pesa tanto como el nombre; un CI distinto baja el puntaje.
+func puntuarDuplicado(a, b *models.Paciente) PuntajeDuplicado {
+	puntaje := PuntajeDuplicado{
+		Nombre:          similitudNombre(a.NombreCompleto(), b.NombreCompleto()),
+		FechaNacimiento: similitudFecha(a.FechaNacimiento, b.FechaNacimiento),
+	}
+
+	ciA, ciB := utils.NormalizarCI(a.CI), utils.NormalizarCI(b.CI)
+	if ciA == "" || ciB == "" {
+		puntaje.Total = 0.65*puntaje.Nombre + 0.35*puntaje.FechaNacimiento
+	} else {
+		ci := 0.0
+		if ciA == ciB {
+			ci = 1
+		}
+		puntaje.CI = &ci
+		puntaje.Total = 0.4*puntaje.Nombre + 0.2*puntaje.FechaNacimiento + 0.4*ci
+	}
+
+	return puntaje
+}
+
+// similitudNombre compara dos nombres sin acentos ni mayúsculas, tolerando que las palabras estén en otro orden
+func similitudNombre(a, b string) float64 {
+	a, b = utils.NormalizarTexto(a), utils.NormalizarTexto(b)
+	return max(utils.JaroWinkler(a, b), utils.JaroWinkler(ordenarPalabras(a), ordenarPalabras(b)))
+}
+
+// ordenarPalabras ordena alfabéticamente las palabras de un texto normalizado
+func ordenarPalabras(s string) string {
+	palabras := strings.Fields(s)
+	sort.Strings(palabras)
+	return strings.Join(palabras, " ")
+}
+
+// similitudFecha compara dos fechas de nacimiento tolerando errores de digitación:
+// día y mes invertidos, o un solo componente distinto
+func similitudFecha(a, b time.Time) float64 {
+	ya, ma, da := a.Date()
+	yb, mb, db := b.Date()
+
+	switch {
+	case ya == yb && ma == mb && da == db:
+		return 1
+	case ya == yb && int(ma) == db && da == int(mb):
+		return 0.8
+	}
+
+	iguales := 0
+	if ya == yb {
+		iguales++
+	}
+	if ma == mb {
+		iguales++
+	}
+	if da == db {
+		iguales++
+	}
+	if iguales == 2 {
+		return 0.5
+	}
+	return 0
+}
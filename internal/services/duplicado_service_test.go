@@ -0,0 +1,131 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"hospital-api/internal/models"
+)
+
+func fecha(anio int, mes time.Month, dia int) time.Time {
+	return time.Date(anio, mes, dia, 0, 0, 0, 0, time.UTC)
+}
+
+func TestSimilitudFecha(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b time.Time
+		want float64
+	}{
+		{"iguales", fecha(1990, 3, 7), fecha(1990, 3, 7), 1},
+		{"día y mes invertidos", fecha(1990, 3, 7), fecha(1990, 7, 3), 0.8},
+		{"solo el día distinto", fecha(1990, 3, 7), fecha(1990, 3, 8), 0.5},
+		{"solo el año distinto", fecha(1990, 3, 7), fecha(1991, 3, 7), 0.5},
+		{"invertidos en otro año", fecha(1990, 3, 7), fecha(1991, 7, 3), 0},
+		{"distintas", fecha(1990, 3, 7), fecha(1985, 11, 20), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := similitudFecha(tt.a, tt.b); got != tt.want {
+				t.Errorf("similitudFecha() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimilitudNombre(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"José Pérez", "JOSE PEREZ", 1},
+		{"Juan Pérez", "Pérez Juan", 1},
+		{"MARTHA", "MARHTA", 0.961111},
+		{"DWAYNE", "DUANE", 0.840000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := similitudNombre(tt.a, tt.b); math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("similitudNombre(%q, %q) = %.6f, want %.6f", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPuntuarDuplicado(t *testing.T) {
+	base := models.Paciente{Nombres: "Juan", Apellidos: "Pérez", CI: "1234567", FechaNacimiento: fecha(1990, 3, 7)}
+
+	tests := []struct {
+		name      string
+		otro      models.Paciente
+		wantTotal float64
+		wantCI    *float64
+	}{
+		{
+			name:      "misma persona con CI",
+			otro:      models.Paciente{Nombres: "JUAN", Apellidos: "PEREZ", CI: "1.234.567", FechaNacimiento: fecha(1990, 3, 7)},
+			wantTotal: 1,
+			wantCI:    ptrFloat(1),
+		},
+		{
+			name:      "CI distinto",
+			otro:      models.Paciente{Nombres: "Juan", Apellidos: "Pérez", CI: "7654321", FechaNacimiento: fecha(1990, 3, 7)},
+			wantTotal: 0.4 + 0.2,
+			wantCI:    ptrFloat(0),
+		},
+		{
+			name:      "sin CI y con día y mes invertidos",
+			otro:      models.Paciente{Nombres: "Pérez", Apellidos: "Juan", FechaNacimiento: fecha(1990, 7, 3)},
+			wantTotal: 0.65 + 0.35*0.8,
+		},
+		{
+			name:      "sin CI y con el nombre del registro anterior",
+			otro:      models.Paciente{Nombre: "juan perez", FechaNacimiento: fecha(1991, 3, 7)},
+			wantTotal: 0.65 + 0.35*0.5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := puntuarDuplicado(&base, &tt.otro)
+			if math.Abs(got.Total-tt.wantTotal) > 1e-9 {
+				t.Errorf("Total = %v, want %v", got.Total, tt.wantTotal)
+			}
+			switch {
+			case (got.CI == nil) != (tt.wantCI == nil):
+				t.Errorf("CI = %v, want %v", got.CI, tt.wantCI)
+			case got.CI != nil && *got.CI != *tt.wantCI:
+				t.Errorf("CI = %v, want %v", *got.CI, *tt.wantCI)
+			}
+		})
+	}
+}
+
+func TestEnmascararPaciente(t *testing.T) {
+	tests := []struct {
+		name          string
+		paciente      models.Paciente
+		wantIniciales string
+		wantCI        string
+	}{
+		{"nombres y apellidos", models.Paciente{Nombres: "juan carlos", Apellidos: "Pérez Ávalos", CI: "1234567"}, "J.C.P.Á.", "****567"},
+		{"nombre del registro anterior", models.Paciente{Nombre: "Ana Benítez", CI: "12-3"}, "A.B.", "123"},
+		{"sin CI", models.Paciente{Nombres: "Ana"}, "A.", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := enmascararPaciente(&tt.paciente)
+			if got.Iniciales != tt.wantIniciales || got.CI != tt.wantCI {
+				t.Errorf("enmascararPaciente() = %q, %q; want %q, %q", got.Iniciales, got.CI, tt.wantIniciales, tt.wantCI)
+			}
+		})
+	}
+}
+
+func ptrFloat(v float64) *float64 {
+	return &v
+}
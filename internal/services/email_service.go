@@ -0,0 +1,91 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"hospital-api/internal/config"
+)
+
+// EmailSender envía correos electrónicos
+type EmailSender interface {
+	Send(to, subject, body string) error
+}
+
+// SMTPEmailSender envía correos a través de un servidor SMTP
+type SMTPEmailSender struct {
+	host     string
+	port     string
+	user     string
+	password string
+	from     string
+}
+
+// LogEmailSender escribe los correos en un archivo o en el log en lugar de enviarlos (desarrollo local)
+type LogEmailSender struct {
+	path string
+}
+
+// NewEmailSender crea el EmailSender indicado por la configuración (EMAIL_DRIVER)
+func NewEmailSender() EmailSender {
+	cfg := config.GetConfig().Email
+
+	if cfg.Driver == "smtp" {
+		return &SMTPEmailSender{
+			host:     cfg.SMTPHost,
+			port:     cfg.SMTPPort,
+			user:     cfg.SMTPUser,
+			password: cfg.SMTPPassword,
+			from:     cfg.From,
+		}
+	}
+
+	return &LogEmailSender{path: cfg.LogFile}
+}
+
+// Send envía un correo de texto plano por SMTP
+func (s *SMTPEmailSender) Send(to, subject, body string) error {
+	if s.host == "" {
+		return fmt.Errorf("SMTP_HOST no configurado")
+	}
+
+	msg := strings.Join([]string{
+		"From: " + s.from,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	var auth smtp.Auth
+	if s.user != "" {
+		auth = smtp.PlainAuth("", s.user, s.password, s.host)
+	}
+
+	return smtp.SendMail(s.host+":"+s.port, auth, s.from, []string{to}, []byte(msg))
+}
+
+// Send registra el correo en el archivo configurado o en el log estándar
+func (s *LogEmailSender) Send(to, subject, body string) error {
+	entry := fmt.Sprintf("[%s] Para: %s\nAsunto: %s\n\n%s\n---\n", time.Now().Format(time.RFC3339), to, subject, body)
+
+	if s.path == "" {
+		log.Printf("📧 Correo (no enviado):\n%s", entry)
+		return nil
+	}
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(entry)
+	return err
+}
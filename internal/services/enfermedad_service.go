@@ -0,0 +1,383 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"hospital-api/internal/database"
+	"hospital-api/internal/models"
+	"hospital-api/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrEnfermedadDesconocida indica que la enfermedad indicada no existe en el catálogo
+var ErrEnfermedadDesconocida = errors.New("enfermedad no registrada en el catálogo")
+
+// ErrEnfermedadInvalida indica datos del catálogo inconsistentes, como una incubación mínima mayor que la máxima
+var ErrEnfermedadInvalida = errors.New("datos de la enfermedad inválidos")
+
+// ErrTerminoEnfermedadEnUso indica un nombre, código CIE-10 o sinónimo que ya identifica a otra enfermedad
+var ErrTerminoEnfermedadEnUso = errors.New("el término ya identifica a otra enfermedad del catálogo")
+
+// ErrMotivoContagiosoRequerido indica un is_contagious distinto al del catálogo sin motivo_contagioso
+var ErrMotivoContagiosoRequerido = errors.New("se requiere motivo_contagioso para contradecir la contagiosidad del catálogo")
+
+// columnasEpidemiologicas columnas de historial_clinico derivadas del catálogo de enfermedades
+var columnasEpidemiologicas = []string{"is_contagious", "contagioso_manual", "motivo_contagioso", "via_transmision", "notificacion_obligatoria"}
+
+// coincideEnfermedadSQL condición sobre historial_clinico para la enfermedad @enfermedad, indicada por nombre,
+// código CIE-10 o sinónimo. Los historiales antiguos sin enlazar al catálogo se comparan por su texto.
+const coincideEnfermedadSQL = `(historial_clinico.id_enfermedad IN (
+		SELECT e.id FROM enfermedades e
+		WHERE f_normalizar(e.nombre) = f_normalizar(@enfermedad) OR f_normalizar(e.codigo_cie10) = f_normalizar(@enfermedad)
+		UNION SELECT s.id_enfermedad FROM enfermedad_sinonimos s WHERE s.sinonimo = f_normalizar(@enfermedad)
+	) OR (historial_clinico.id_enfermedad IS NULL AND LOWER(historial_clinico.enfermedad) = LOWER(@enfermedad)))`
+
+type EnfermedadService struct {
+	db *gorm.DB
+}
+
+// EnfermedadSugerencia resultado del autocompletado: la enfermedad, el término que coincidió y su puntaje (0 a 1)
+type EnfermedadSugerencia struct {
+	models.Enfermedad
+	Coincidencia string  `json:"coincidencia"`
+	Puntaje      float64 `json:"puntaje"`
+}
+
+// IntervaloSerialRequest distribución del intervalo serial de una enfermedad, en días
+type IntervaloSerialRequest struct {
+	Distribucion string  `json:"distribucion" validate:"required,oneof=gamma lognormal"`
+	Media        float64 `json:"media" validate:"required,gt=0,lte=120"`
+	Desviacion   float64 `json:"desviacion" validate:"required,gt=0,lte=60"`
+}
+
+// EnfermedadRequest datos de una entrada del catálogo de enfermedades
+type EnfermedadRequest struct {
+	CodigoCIE10             string   `json:"codigo_cie10" validate:"required,max=10"`
+	Nombre                  string   `json:"nombre" validate:"required,min=2,max=150"`
+	Contagiosa              bool     `json:"contagiosa"`
+	ViaTransmision          string   `json:"via_transmision" validate:"required,oneof=vectorial aerea contacto alimentos_agua no_transmisible"`
+	IncubacionMinDias       *int     `json:"incubacion_min_dias" validate:"omitempty,gte=0,lte=365"`
+	IncubacionMaxDias       *int     `json:"incubacion_max_dias" validate:"omitempty,gte=0,lte=365"`
+	PeriodoInfecciosoDias   *float64 `json:"periodo_infeccioso_dias" validate:"omitempty,gt=0,lte=60"`
+	NotificacionObligatoria bool     `json:"notificacion_obligatoria"`
+}
+
+// SinonimosRequest lista completa de sinónimos de una enfermedad; reemplaza la anterior
+type SinonimosRequest struct {
+	Sinonimos []string `json:"sinonimos" validate:"dive,required,max=150"`
+}
+
+// NewEnfermedadService crea una nueva instancia del servicio del catálogo de enfermedades
+func NewEnfermedadService() *EnfermedadService {
+	return &EnfermedadService{
+		db: database.GetDB(),
+	}
+}
+
+// BuscarEnfermedades sugiere enfermedades del catálogo cuyo nombre, código CIE-10 o sinónimo coincide con q
+// (exacto, por prefijo o aproximado). Sin q retorna el catálogo ordenado por nombre.
+func (s *EnfermedadService) BuscarEnfermedades(q string, limit int) ([]EnfermedadSugerencia, error) {
+	var enfermedades []models.Enfermedad
+	if err := s.db.Preload("Sinonimos").Order("nombre").Find(&enfermedades).Error; err != nil {
+		return nil, err
+	}
+
+	q = utils.NormalizarTexto(q)
+	sugerencias := []EnfermedadSugerencia{}
+	for _, enfermedad := range enfermedades {
+		if q == "" {
+			sugerencias = append(sugerencias, EnfermedadSugerencia{Enfermedad: enfermedad})
+			continue
+		}
+
+		terminos := []string{enfermedad.Nombre, enfermedad.CodigoCIE10}
+		for _, sinonimo := range enfermedad.Sinonimos {
+			terminos = append(terminos, sinonimo.Sinonimo)
+		}
+
+		mejor := EnfermedadSugerencia{Enfermedad: enfermedad}
+		for _, termino := range terminos {
+			if puntaje := puntuarTermino(q, utils.NormalizarTexto(termino)); puntaje > mejor.Puntaje {
+				mejor.Puntaje = puntaje
+				mejor.Coincidencia = termino
+			}
+		}
+		if mejor.Puntaje > 0 {
+			sugerencias = append(sugerencias, mejor)
+		}
+	}
+
+	sort.SliceStable(sugerencias, func(i, j int) bool {
+		return sugerencias[i].Puntaje > sugerencias[j].Puntaje
+	})
+	if len(sugerencias) > limit {
+		sugerencias = sugerencias[:limit]
+	}
+
+	return sugerencias, nil
+}
+
+// GetEnfermedadByID obtiene una enfermedad del catálogo con sus sinónimos
+func (s *EnfermedadService) GetEnfermedadByID(id uint) (*models.Enfermedad, error) {
+	var enfermedad models.Enfermedad
+	if err := s.db.Preload("Sinonimos").First(&enfermedad, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("enfermedad no encontrada")
+		}
+		return nil, err
+	}
+	return &enfermedad, nil
+}
+
+// ActualizarIntervaloSerial configura el intervalo serial con que se estima el Rt de una enfermedad
+func (s *EnfermedadService) ActualizarIntervaloSerial(actor Actor, id uint, req IntervaloSerialRequest) (*models.Enfermedad, error) {
+	var despues models.Enfermedad
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var antes models.Enfermedad
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&antes, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("enfermedad no encontrada")
+			}
+			return err
+		}
+
+		despues = antes
+		despues.IntervaloSerialDistribucion = req.Distribucion
+		despues.IntervaloSerialMedia = &req.Media
+		despues.IntervaloSerialDesviacion = &req.Desviacion
+		if err := tx.Save(&despues).Error; err != nil {
+			return err
+		}
+
+		return auditar(tx, actor, models.AccionActualizar, models.EntidadEnfermedad, id, antes, despues, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &despues, nil
+}
+
+// CreateEnfermedad agrega una enfermedad al catálogo
+func (s *EnfermedadService) CreateEnfermedad(actor Actor, req EnfermedadRequest) (*models.Enfermedad, error) {
+	if err := validarIncubacion(req); err != nil {
+		return nil, err
+	}
+
+	enfermedad := models.Enfermedad{}
+	aplicarEnfermedadRequest(&enfermedad, req)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := validarTerminosEnfermedad(tx, 0, req.Nombre, req.CodigoCIE10); err != nil {
+			return err
+		}
+		if err := tx.Create(&enfermedad).Error; err != nil {
+			return err
+		}
+		return auditar(tx, actor, models.AccionCrear, models.EntidadEnfermedad, enfermedad.ID, nil, enfermedad, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetEnfermedadByID(enfermedad.ID)
+}
+
+// UpdateEnfermedad reemplaza los datos de una enfermedad del catálogo; sus sinónimos y su intervalo serial no
+// cambian. Los historiales ya registrados conservan la contagiosidad, vía y notificación derivadas al registrarse.
+func (s *EnfermedadService) UpdateEnfermedad(actor Actor, id uint, req EnfermedadRequest) (*models.Enfermedad, error) {
+	if err := validarIncubacion(req); err != nil {
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var antes models.Enfermedad
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&antes, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("enfermedad no encontrada")
+			}
+			return err
+		}
+		if err := validarTerminosEnfermedad(tx, id, req.Nombre, req.CodigoCIE10); err != nil {
+			return err
+		}
+
+		despues := antes
+		aplicarEnfermedadRequest(&despues, req)
+		if err := tx.Save(&despues).Error; err != nil {
+			return err
+		}
+		return auditar(tx, actor, models.AccionActualizar, models.EntidadEnfermedad, id, antes, despues, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetEnfermedadByID(id)
+}
+
+// ActualizarSinonimos reemplaza los sinónimos de una enfermedad, que se guardan normalizados
+func (s *EnfermedadService) ActualizarSinonimos(actor Actor, id uint, req SinonimosRequest) (*models.Enfermedad, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var antes models.Enfermedad
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Sinonimos").First(&antes, id).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("enfermedad no encontrada")
+			}
+			return err
+		}
+		if err := validarTerminosEnfermedad(tx, id, req.Sinonimos...); err != nil {
+			return err
+		}
+
+		if err := tx.Where("id_enfermedad = ?", id).Delete(&models.EnfermedadSinonimo{}).Error; err != nil {
+			return err
+		}
+		for _, sinonimo := range req.Sinonimos {
+			err := tx.Exec(`INSERT INTO enfermedad_sinonimos (id_enfermedad, sinonimo) VALUES (?, f_normalizar(?))
+				ON CONFLICT (sinonimo) DO NOTHING`, id, sinonimo).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		var despues models.Enfermedad
+		if err := tx.Preload("Sinonimos").First(&despues, id).Error; err != nil {
+			return err
+		}
+		return auditar(tx, actor, models.AccionActualizar, models.EntidadEnfermedad, id,
+			map[string]interface{}{"sinonimos": antes.Sinonimos},
+			map[string]interface{}{"sinonimos": despues.Sinonimos}, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetEnfermedadByID(id)
+}
+
+func aplicarEnfermedadRequest(enfermedad *models.Enfermedad, req EnfermedadRequest) {
+	enfermedad.CodigoCIE10 = strings.ToUpper(strings.TrimSpace(req.CodigoCIE10))
+	enfermedad.Nombre = strings.TrimSpace(req.Nombre)
+	enfermedad.Contagiosa = req.Contagiosa
+	enfermedad.ViaTransmision = req.ViaTransmision
+	enfermedad.IncubacionMinDias = req.IncubacionMinDias
+	enfermedad.IncubacionMaxDias = req.IncubacionMaxDias
+	enfermedad.PeriodoInfecciosoDias = req.PeriodoInfecciosoDias
+	enfermedad.NotificacionObligatoria = req.NotificacionObligatoria
+}
+
+// validarIncubacion rechaza una incubación mínima mayor que la máxima
+func validarIncubacion(req EnfermedadRequest) error {
+	if req.IncubacionMinDias != nil && req.IncubacionMaxDias != nil && *req.IncubacionMinDias > *req.IncubacionMaxDias {
+		return fmt.Errorf("%w: incubacion_min_dias no puede superar incubacion_max_dias", ErrEnfermedadInvalida)
+	}
+	return nil
+}
+
+// validarTerminosEnfermedad rechaza términos que ya son el nombre, el código CIE-10 o un sinónimo de otra
+// enfermedad, sin distinguir mayúsculas, acentos ni signos, para que cada término resuelva a una sola entrada
+func validarTerminosEnfermedad(tx *gorm.DB, id uint, terminos ...string) error {
+	for _, termino := range terminos {
+		var enUso []string
+		err := tx.Raw(`SELECT e.nombre FROM enfermedades e
+			WHERE e.id <> @id AND (f_normalizar(e.nombre) = f_normalizar(@termino) OR f_normalizar(e.codigo_cie10) = f_normalizar(@termino)
+				OR e.id IN (SELECT id_enfermedad FROM enfermedad_sinonimos WHERE sinonimo = f_normalizar(@termino)))`,
+			sql.Named("id", id), sql.Named("termino", termino)).Scan(&enUso).Error
+		if err != nil {
+			return err
+		}
+		if len(enUso) > 0 {
+			return fmt.Errorf("%w: \"%s\" corresponde a %s", ErrTerminoEnfermedadEnUso, termino, enUso[0])
+		}
+	}
+	return nil
+}
+
+// puntuarTermino compara una búsqueda con un término del catálogo, ambos normalizados
+func puntuarTermino(q, termino string) float64 {
+	switch {
+	case termino == q:
+		return 1
+	case strings.HasPrefix(termino, q):
+		return 0.9
+	case strings.Contains(" "+termino, " "+q):
+		return 0.8
+	}
+
+	if similitud := utils.JaroWinkler(q, termino); similitud >= 0.85 {
+		return 0.7 * similitud
+	}
+	return 0
+}
+
+// resolverEnfermedad busca la entrada del catálogo indicada por id o, si id es nil, por nombre,
+// código CIE-10 o sinónimo exacto (sin distinguir mayúsculas, acentos ni signos)
+func resolverEnfermedad(db *gorm.DB, id *uint, texto string) (*models.Enfermedad, error) {
+	var enfermedad models.Enfermedad
+	var err error
+	if id != nil {
+		err = db.First(&enfermedad, *id).Error
+	} else {
+		err = db.Where(`f_normalizar(nombre) = f_normalizar(@texto) OR f_normalizar(codigo_cie10) = f_normalizar(@texto)
+			OR id IN (SELECT id_enfermedad FROM enfermedad_sinonimos WHERE sinonimo = f_normalizar(@texto))`,
+			sql.Named("texto", texto)).First(&enfermedad).Error
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEnfermedadDesconocida
+		}
+		return nil, err
+	}
+	return &enfermedad, nil
+}
+
+// asignarEnfermedad enlaza el historial con el catálogo a partir de su id_enfermedad (si usarID) o de su texto.
+// Si el historial no trae texto se completa con el nombre canónico.
+func asignarEnfermedad(db *gorm.DB, historial *models.HistorialClinico, usarID bool) error {
+	var id *uint
+	if usarID {
+		id = historial.IDEnfermedad
+	}
+
+	enfermedad, err := resolverEnfermedad(db, id, historial.Enfermedad)
+	if err != nil {
+		return err
+	}
+
+	historial.IDEnfermedad = &enfermedad.ID
+	if historial.Enfermedad == "" {
+		historial.Enfermedad = enfermedad.Nombre
+	}
+	return derivarEpidemiologia(historial, enfermedad)
+}
+
+// derivarEpidemiologia completa la vía de transmisión, la notificación obligatoria y la contagiosidad del
+// historial a partir del catálogo. ContagiosoManual (o un motivo) marca un is_contagious indicado por el médico:
+// si coincide con el catálogo no es una corrección; si difiere se conserva, siempre que venga justificado.
+func derivarEpidemiologia(historial *models.HistorialClinico, enfermedad *models.Enfermedad) error {
+	historial.ViaTransmision = enfermedad.ViaTransmision
+	historial.NotificacionObligatoria = enfermedad.NotificacionObligatoria
+	historial.MotivoContagioso = strings.TrimSpace(historial.MotivoContagioso)
+
+	indicado := historial.ContagiosoManual || historial.MotivoContagioso != ""
+	if !indicado || historial.IsContagious == enfermedad.Contagiosa {
+		historial.IsContagious = enfermedad.Contagiosa
+		historial.ContagiosoManual = false
+		historial.MotivoContagioso = ""
+		return nil
+	}
+
+	if historial.MotivoContagioso == "" {
+		return ErrMotivoContagiosoRequerido
+	}
+	historial.ContagiosoManual = true
+	return nil
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"hospital-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Evento notificación en tiempo real para los tableros (SSE) y los sistemas externos (webhooks)
+type Evento struct {
+	Tipo  string      `json:"tipo"`
+	Fecha time.Time   `json:"fecha"`
+	Datos interface{} `json:"datos"`
+}
+
+// CasoContagiosoEvento datos de un caso contagioso nuevo. No incluye datos del paciente ni coordenadas.
+type CasoContagiosoEvento struct {
+	IDHistorial         uint      `json:"id_historial"`
+	IDHospital          uint      `json:"id_hospital"`
+	IDEnfermedad        *uint     `json:"id_enfermedad"`
+	Enfermedad          string    `json:"enfermedad"`
+	PatientDistrict     string    `json:"patient_district"`
+	PatientNeighborhood string    `json:"patient_neighborhood"`
+	ConsultationDate    time.Time `json:"consultation_date"`
+}
+
+// bufferSuscriptor eventos que un suscriptor SSE puede tener sin leer antes de empezar a perderlos
+const bufferSuscriptor = 64
+
+// suscriptoresEventos canales de las conexiones SSE abiertas en esta instancia
+var suscriptoresEventos = struct {
+	sync.Mutex
+	canales  map[chan Evento]struct{}
+	cerrados bool
+}{canales: map[chan Evento]struct{}{}}
+
+// SuscribirEventos registra un suscriptor y retorna su canal y la función para darlo de baja.
+// Un suscriptor lento pierde eventos en lugar de bloquear a quien los publica.
+// El canal se cierra cuando el servidor se detiene (CerrarSuscripciones).
+func SuscribirEventos() (<-chan Evento, func()) {
+	canal := make(chan Evento, bufferSuscriptor)
+
+	suscriptoresEventos.Lock()
+	if suscriptoresEventos.cerrados {
+		close(canal)
+	} else {
+		suscriptoresEventos.canales[canal] = struct{}{}
+	}
+	suscriptoresEventos.Unlock()
+
+	return canal, func() {
+		suscriptoresEventos.Lock()
+		delete(suscriptoresEventos.canales, canal)
+		suscriptoresEventos.Unlock()
+	}
+}
+
+// CerrarSuscripciones cierra los canales de las conexiones SSE abiertas y rechaza las nuevas, para que el
+// apagado del servidor no espere a que los clientes se desconecten
+func CerrarSuscripciones() {
+	suscriptoresEventos.Lock()
+	defer suscriptoresEventos.Unlock()
+
+	suscriptoresEventos.cerrados = true
+	for canal := range suscriptoresEventos.canales {
+		close(canal)
+		delete(suscriptoresEventos.canales, canal)
+	}
+}
+
+// registrarEvento arma el evento y encola sus entregas de webhook en la transacción que lo origina (outbox):
+// si la transacción se descarta no se entrega nada y, si se confirma, ninguna entrega se pierde.
+// El evento retornado se difunde con difundirEvento después de confirmar la transacción.
+func registrarEvento(tx *gorm.DB, tipo string, datos interface{}) (*Evento, error) {
+	evento := &Evento{Tipo: tipo, Fecha: time.Now(), Datos: datos}
+	if err := encolarWebhooks(tx, evento); err != nil {
+		return nil, fmt.Errorf("encolar webhooks del evento %s: %w", tipo, err)
+	}
+	return evento, nil
+}
+
+// difundirEvento envía un evento ya confirmado a los suscriptores SSE y despierta al despachador de webhooks.
+// Un evento nil (cambio que no generó evento) se ignora.
+func difundirEvento(evento *Evento) {
+	if evento == nil {
+		return
+	}
+
+	suscriptoresEventos.Lock()
+	for canal := range suscriptoresEventos.canales {
+		select {
+		case canal <- *evento:
+		default:
+		}
+	}
+	suscriptoresEventos.Unlock()
+
+	avisarDespachador()
+}
+
+// registrarCasoContagioso registra el evento de un historial contagioso; retorna nil si no lo es
+func registrarCasoContagioso(tx *gorm.DB, historial *models.HistorialClinico) (*Evento, error) {
+	if !historial.IsContagious {
+		return nil, nil
+	}
+
+	return registrarEvento(tx, models.EventoCasoContagioso, CasoContagiosoEvento{
+		IDHistorial:         historial.ID,
+		IDHospital:          historial.IDHospital,
+		IDEnfermedad:        historial.IDEnfermedad,
+		Enfermedad:          historial.Enfermedad,
+		PatientDistrict:     historial.PatientDistrict,
+		PatientNeighborhood: historial.PatientNeighborhood,
+		ConsultationDate:    historial.ConsultationDate,
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strings"
 
+	"hospital-api/internal/models"
 	"hospital-api/internal/utils"
 
 	"googlemaps.github.io/maps"
@@ -152,6 +153,57 @@ func (g *GeocodingService) GetAddressComponents(address string) (*AddressCompone
 	return components, nil
 }
 
+// GeocodingError error al geocodificar la dirección de un historial, con el código de error de la API
+type GeocodingError struct {
+	Code    string // GEOCODING_CONFIG_ERROR, GEOCODING_ERROR o INVALID_LOCATION
+	Message string
+	Err     error
+}
+
+func (e *GeocodingError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *GeocodingError) Unwrap() error {
+	return e.Err
+}
+
+// GeocodificarHistorial geocodifica la dirección del paciente y asigna al historial las coordenadas,
+// la dirección formateada y, si están vacíos, el distrito y el barrio obtenidos
+func GeocodificarHistorial(historial *models.HistorialClinico, direccion string) (*AddressComponents, error) {
+	geocodingService, err := NewGeocodingService()
+	if err != nil {
+		return nil, &GeocodingError{Code: "GEOCODING_CONFIG_ERROR", Message: "Error configurando servicio de mapas", Err: err}
+	}
+
+	// Obtener información completa de la dirección
+	addressComponents, err := geocodingService.GetAddressComponents(direccion)
+	if err != nil {
+		return nil, &GeocodingError{Code: "GEOCODING_ERROR", Message: "No se pudo geocodificar la dirección proporcionada", Err: err}
+	}
+
+	// Validar que las coordenadas estén en el área de cobertura
+	if !geocodingService.ValidateCoordinates(addressComponents.Coordinates.Latitude, addressComponents.Coordinates.Longitude) {
+		return nil, &GeocodingError{Code: "INVALID_LOCATION", Message: "La dirección debe estar ubicada en La Paz, Bolivia"}
+	}
+
+	historial.PatientLatitude = addressComponents.Coordinates.Latitude
+	historial.PatientLongitude = addressComponents.Coordinates.Longitude
+	historial.PatientAddress = addressComponents.FormattedAddress // Usar dirección formateada
+
+	if historial.PatientDistrict == "" {
+		historial.PatientDistrict = addressComponents.District
+	}
+	if historial.PatientNeighborhood == "" {
+		historial.PatientNeighborhood = addressComponents.Neighborhood
+	}
+
+	return addressComponents, nil
+}
+
 // ValidateCoordinates valida que las coordenadas estén dentro de los límites de Santa Cruz
 func (g *GeocodingService) ValidateCoordinates(lat, lng float64) bool {
 	// Límites aproximados de Santa Cruz de la Sierra, Bolivia
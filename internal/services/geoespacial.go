@@ -0,0 +1,56 @@
+package services
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Consultas espaciales sobre las columnas geography "ubicacion" de hospitales e historial_clinico. Un
+// trigger las mantiene a partir de latitud y longitud, y sus índices GiST permiten resolver en la base
+// de datos las búsquedas por radio, por caja y de vecinos más cercanos.
+
+// Columnas de ubicación indexadas
+const (
+	ubicacionHospital  = "hospitales.ubicacion"
+	ubicacionHistorial = "historial_clinico.ubicacion"
+)
+
+// Circulo área de búsqueda alrededor de un punto
+type Circulo struct {
+	Lat     float64 `json:"lat"`
+	Lng     float64 `json:"lng"`
+	RadioKm float64 `json:"radio_km"`
+}
+
+// dentroDeRadio filtra las filas cuya ubicación está a lo sumo a RadioKm del centro (distancia geodésica)
+func dentroDeRadio(columna string, c Circulo) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("ST_DWithin("+columna+", f_punto_geografico(?, ?), ?)", c.Lat, c.Lng, c.RadioKm*1000)
+	}
+}
+
+// dentroDeCaja filtra las filas cuya ubicación cae dentro del rectángulo. Los lados de la caja se toman
+// como geodésicas, lo que a escala de ciudad difiere de los paralelos en menos de un metro.
+func dentroDeCaja(columna string, b BoundingBox) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("ST_Intersects("+columna+", ST_MakeEnvelope(?, ?, ?, ?, 4326)::geography)",
+			b.MinLng, b.MinLat, b.MaxLng, b.MaxLat)
+	}
+}
+
+// ordenPorCercania ordena de la ubicación más cercana a la más lejana con el operador KNN <->, que
+// recorre el índice GiST en lugar de calcular la distancia a todas las filas
+func ordenPorCercania(columna string, lat, lng float64) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:                columna + " <-> f_punto_geografico(?, ?)",
+			Vars:               []interface{}{lat, lng},
+			WithoutParentheses: true,
+		}})
+	}
+}
+
+// distanciaKmSQL expresión de la distancia geodésica en km entre la columna y el punto (lat, lng)
+func distanciaKmSQL(columna string) string {
+	return "ST_Distance(" + columna + ", f_punto_geografico(?, ?)) / 1000"
+}
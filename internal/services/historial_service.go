@@ -1,13 +1,19 @@
 package services
 
 import (
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"hospital-api/internal/database"
 	"hospital-api/internal/models"
+	"hospital-api/internal/utils"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type HistorialService struct {
@@ -35,12 +41,155 @@ type DistrictStats struct {
 	ContagiousCases int64  `json:"contagious_cases"`
 }
 
+type RevisionDiff struct {
+	IDHistorial uint                         `json:"id_historial"`
+	Desde       int                          `json:"desde"`
+	Hasta       int                          `json:"hasta"`
+	Cambios     map[string]utils.CambioCampo `json:"cambios"`
+}
+
 type DateStats struct {
 	Date            string `json:"date"`
 	TotalCases      int64  `json:"total_cases"`
 	ContagiousCases int64  `json:"contagious_cases"`
 }
 
+// celdaMapaCalorGrados lado de la celda con que se agrupan los casos del mapa de calor (unos 11 m)
+const celdaMapaCalorGrados = 0.0001
+
+// ErrOrdenInvalido indica un campo de ordenamiento no permitido
+var ErrOrdenInvalido = errors.New("campo de ordenamiento no permitido")
+
+// HistorialFilter filtros combinables para listar historiales clínicos; los campos vacíos no filtran
+type HistorialFilter struct {
+	Enfermedad   string       `json:"enfermedad,omitempty"` // Nombre, código CIE-10 o sinónimo del catálogo
+	IDEnfermedad *uint        `json:"id_enfermedad,omitempty"`
+	Distrito     string       `json:"distrito,omitempty"`
+	Barrio       string       `json:"barrio,omitempty"`
+	IDHospital   *uint        `json:"id_hospital,omitempty"`
+	IDPaciente   *uint        `json:"id_paciente,omitempty"`
+	Desde        *time.Time   `json:"desde,omitempty"` // fecha_ingreso >= Desde
+	Hasta        *time.Time   `json:"hasta,omitempty"` // fecha_ingreso < Hasta
+	Contagioso   *bool        `json:"contagioso,omitempty"`
+	EdadMin      *int         `json:"edad_min,omitempty"` // Edad del paciente al ingreso
+	EdadMax      *int         `json:"edad_max,omitempty"`
+	Sexo         string       `json:"sexo,omitempty"`
+	BBox         *BoundingBox `json:"bbox,omitempty"`
+	Radio        *Circulo     `json:"radio,omitempty"` // Ubicación del paciente a lo sumo a RadioKm del punto
+}
+
+// BoundingBox rectángulo geográfico en grados
+type BoundingBox struct {
+	MinLng float64 `json:"min_lng"`
+	MinLat float64 `json:"min_lat"`
+	MaxLng float64 `json:"max_lng"`
+	MaxLat float64 `json:"max_lat"`
+}
+
+// scope aplica los filtros a una consulta sobre historial_clinico
+func (f HistorialFilter) scope(db *gorm.DB) *gorm.DB {
+	if f.Enfermedad != "" {
+		db = db.Where(coincideEnfermedadSQL, sql.Named("enfermedad", f.Enfermedad))
+	}
+	if f.IDEnfermedad != nil {
+		db = db.Where("historial_clinico.id_enfermedad = ?", *f.IDEnfermedad)
+	}
+	if f.Distrito != "" {
+		db = db.Where("LOWER(historial_clinico.patient_district) = LOWER(?)", f.Distrito)
+	}
+	if f.Barrio != "" {
+		db = db.Where("LOWER(historial_clinico.patient_neighborhood) = LOWER(?)", f.Barrio)
+	}
+	if f.IDHospital != nil {
+		db = db.Where("historial_clinico.id_hospital = ?", *f.IDHospital)
+	}
+	if f.IDPaciente != nil {
+		db = db.Where("historial_clinico.id_paciente = ?", *f.IDPaciente)
+	}
+	if f.Desde != nil {
+		db = db.Where("historial_clinico.fecha_ingreso >= ?", *f.Desde)
+	}
+	if f.Hasta != nil {
+		db = db.Where("historial_clinico.fecha_ingreso < ?", *f.Hasta)
+	}
+	if f.Contagioso != nil {
+		db = db.Where("historial_clinico.is_contagious = ?", *f.Contagioso)
+	}
+	if f.Sexo != "" || f.EdadMin != nil || f.EdadMax != nil {
+		condiciones := []string{"p.id = historial_clinico.id_paciente"}
+		var args []interface{}
+		if f.Sexo != "" {
+			condiciones = append(condiciones, "p.sexo = ?")
+			args = append(args, f.Sexo)
+		}
+		if f.EdadMin != nil {
+			condiciones = append(condiciones, "p.fecha_nacimiento <= historial_clinico.fecha_ingreso - make_interval(years => ?)")
+			args = append(args, *f.EdadMin)
+		}
+		if f.EdadMax != nil {
+			condiciones = append(condiciones, "p.fecha_nacimiento > historial_clinico.fecha_ingreso - make_interval(years => ?)")
+			args = append(args, *f.EdadMax+1)
+		}
+		db = db.Where("EXISTS (SELECT 1 FROM pacientes p WHERE "+strings.Join(condiciones, " AND ")+")", args...)
+	}
+	if f.BBox != nil {
+		db = db.Scopes(dentroDeCaja(ubicacionHistorial, *f.BBox))
+	}
+	if f.Radio != nil {
+		db = db.Scopes(dentroDeRadio(ubicacionHistorial, *f.Radio))
+	}
+	return db
+}
+
+// campoOrden columna por la que se puede ordenar un listado, con su conversión desde y hacia el cursor
+type campoOrden struct {
+	columna string
+	valor   func(h *models.HistorialClinico) string
+	parse   func(valor string) (interface{}, error)
+}
+
+// camposOrdenHistorial campos indexados (junto con id) por los que se pueden ordenar los historiales
+var camposOrdenHistorial = map[string]campoOrden{
+	"fecha_ingreso": {
+		columna: "historial_clinico.fecha_ingreso",
+		valor:   func(h *models.HistorialClinico) string { return h.FechaIngreso.Format(time.RFC3339Nano) },
+		parse:   parseCursorFecha,
+	},
+	"consultation_date": {
+		columna: "historial_clinico.consultation_date",
+		valor:   func(h *models.HistorialClinico) string { return h.ConsultationDate.Format(time.RFC3339Nano) },
+		parse:   parseCursorFecha,
+	},
+	"created_at": {
+		columna: "historial_clinico.created_at",
+		valor:   func(h *models.HistorialClinico) string { return h.CreatedAt.Format(time.RFC3339Nano) },
+		parse:   parseCursorFecha,
+	},
+	"enfermedad": {
+		columna: "historial_clinico.enfermedad",
+		valor:   func(h *models.HistorialClinico) string { return h.Enfermedad },
+		parse:   parseCursorTexto,
+	},
+	"patient_district": {
+		columna: "historial_clinico.patient_district",
+		valor:   func(h *models.HistorialClinico) string { return h.PatientDistrict },
+		parse:   parseCursorTexto,
+	},
+	"id": {
+		columna: "historial_clinico.id",
+		valor:   func(h *models.HistorialClinico) string { return "" },
+		parse:   parseCursorTexto,
+	},
+}
+
+func parseCursorFecha(valor string) (interface{}, error) {
+	return time.Parse(time.RFC3339Nano, valor)
+}
+
+func parseCursorTexto(valor string) (interface{}, error) {
+	return valor, nil
+}
+
 // NewHistorialService crea una nueva instancia del servicio de historial clínico
 func NewHistorialService() *HistorialService {
 	return &HistorialService{
@@ -48,30 +197,76 @@ func NewHistorialService() *HistorialService {
 	}
 }
 
-// CreateHistorial crea un nuevo registro de historial clínico
-func (s *HistorialService) CreateHistorial(historial *models.HistorialClinico) error {
-	return s.db.Create(historial).Error
+// CreateHistorial crea un nuevo registro de historial clínico en el hospital del actor
+func (s *HistorialService) CreateHistorial(actor Actor, historial *models.HistorialClinico) error {
+	// El paciente debe ser visible para el hospital que registra el historial
+	var count int64
+	if err := s.db.Model(&models.Paciente{}).Scopes(actor.pacienteScope).Where("id = ?", historial.IDPaciente).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("paciente no encontrado")
+	}
+
+	historial.IDHospital = actor.HospitalID
+	historial.Version = 1
+
+	if err := asignarEnfermedad(s.db, historial, historial.IDEnfermedad != nil); err != nil {
+		return err
+	}
+	if err := asignarDistrito(s.db, historial); err != nil {
+		return err
+	}
+
+	var evento *Evento
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(historial).Error; err != nil {
+			return err
+		}
+		if err := registrarRevision(tx, historial, &actor.UsuarioID); err != nil {
+			return err
+		}
+		if err := auditar(tx, actor, models.AccionCrear, models.EntidadHistorial, historial.ID, nil, historial, nil); err != nil {
+			return err
+		}
+		if err := registrarNotificacion(tx, actor, historial); err != nil {
+			return err
+		}
+		var err error
+		evento, err = registrarCasoContagioso(tx, historial)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	difundirEvento(evento)
+	return nil
 }
 
 // GetHistorialByID obtiene un historial por ID con información relacionada
-func (s *HistorialService) GetHistorialByID(id uint) (*models.HistorialClinico, error) {
+func (s *HistorialService) GetHistorialByID(actor Actor, id uint) (*models.HistorialClinico, error) {
 	var historial models.HistorialClinico
-	err := s.db.Preload("Paciente").Preload("Hospital").First(&historial, id).Error
+	err := s.db.Scopes(actor.historialScope).Preload("Paciente").Preload("Hospital").Preload("Catalogo").First(&historial, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("historial clínico no encontrado")
 		}
 		return nil, err
 	}
+
+	if err := auditar(s.db, actor, models.AccionLeer, models.EntidadHistorial, historial.ID, nil, nil, nil); err != nil {
+		return nil, err
+	}
 	return &historial, nil
 }
 
 // GetHistorialByPaciente obtiene el historial clínico de un paciente
-func (s *HistorialService) GetHistorialByPaciente(pacienteID uint, page, limit int) ([]models.HistorialClinico, int64, error) {
+func (s *HistorialService) GetHistorialByPaciente(actor Actor, pacienteID uint, page, limit int) ([]models.HistorialClinico, int64, error) {
 	var historiales []models.HistorialClinico
 	var total int64
 
-	query := s.db.Where("id_paciente = ?", pacienteID)
+	query := s.db.Scopes(actor.historialScope).Where("id_paciente = ?", pacienteID)
 
 	// Contar total
 	query.Model(&models.HistorialClinico{}).Count(&total)
@@ -83,16 +278,25 @@ func (s *HistorialService) GetHistorialByPaciente(pacienteID uint, page, limit i
 		Limit(limit).
 		Order("fecha_ingreso DESC").
 		Find(&historiales).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = auditarLectura(s.db, actor, models.EntidadHistorial, historialIDs(historiales), map[string]interface{}{
+		"id_paciente": pacienteID,
+		"page":        page,
+		"limit":       limit,
+	})
 
 	return historiales, total, err
 }
 
-// GetHistorialByHospital obtiene el historial clínico de un hospital
-func (s *HistorialService) GetHistorialByHospital(hospitalID uint, page, limit int) ([]models.HistorialClinico, int64, error) {
+// GetHistorialByHospital obtiene el historial clínico del hospital del actor
+func (s *HistorialService) GetHistorialByHospital(actor Actor, page, limit int) ([]models.HistorialClinico, int64, error) {
 	var historiales []models.HistorialClinico
 	var total int64
 
-	query := s.db.Where("id_hospital = ?", hospitalID)
+	query := s.db.Scopes(actor.historialWriteScope)
 
 	// Contar total
 	query.Model(&models.HistorialClinico{}).Count(&total)
@@ -104,18 +308,324 @@ func (s *HistorialService) GetHistorialByHospital(hospitalID uint, page, limit i
 		Limit(limit).
 		Order("fecha_ingreso DESC").
 		Find(&historiales).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = auditarLectura(s.db, actor, models.EntidadHistorial, historialIDs(historiales), map[string]interface{}{
+		"page":  page,
+		"limit": limit,
+	})
 
 	return historiales, total, err
 }
 
-// UpdateHistorial actualiza un historial clínico
-func (s *HistorialService) UpdateHistorial(id uint, updates *models.HistorialClinico) error {
-	return s.db.Model(&models.HistorialClinico{}).Where("id = ?", id).Updates(updates).Error
+// UpdateHistorial actualiza un historial clínico si su versión coincide con ifMatch
+func (s *HistorialService) UpdateHistorial(actor Actor, id uint, ifMatch []int, updates *models.HistorialClinico) (*models.HistorialClinico, error) {
+	// El hospital y el paciente de un historial no se reasignan desde una actualización
+	updates.IDHospital = 0
+	updates.IDPaciente = 0
+
+	// El distrito y el barrio oficiales salen de las coordenadas
+	updates.IDDistrito = nil
+	updates.IDBarrio = nil
+
+	// Los datos epidemiológicos salen del catálogo; como PUT ignora los valores cero,
+	// solo un is_contagious true o un motivo cuentan como indicación del médico
+	updates.ContagiosoManual = updates.IsContagious
+	updates.ViaTransmision = ""
+	updates.NotificacionObligatoria = false
+
+	var despues models.HistorialClinico
+	var evento *Evento
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var antes models.HistorialClinico
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(actor.historialWriteScope).First(&antes, id).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("historial clínico no encontrado")
+			}
+			return err
+		}
+
+		if err := verificarVersion(antes.Version, ifMatch); err != nil {
+			return err
+		}
+		updates.Version = antes.Version + 1
+
+		cambiaUbicacion := (updates.PatientLatitude != 0 && updates.PatientLatitude != antes.PatientLatitude) ||
+			(updates.PatientLongitude != 0 && updates.PatientLongitude != antes.PatientLongitude) ||
+			updates.PatientDistrict != "" || updates.PatientNeighborhood != ""
+		if cambiaUbicacion {
+			ubicacion := antes
+			if updates.PatientLatitude != 0 {
+				ubicacion.PatientLatitude = updates.PatientLatitude
+			}
+			if updates.PatientLongitude != 0 {
+				ubicacion.PatientLongitude = updates.PatientLongitude
+			}
+			if updates.PatientDistrict != "" {
+				ubicacion.PatientDistrict = updates.PatientDistrict
+			}
+			if updates.PatientNeighborhood != "" {
+				ubicacion.PatientNeighborhood = updates.PatientNeighborhood
+			}
+			if err := asignarDistrito(tx, &ubicacion); err != nil {
+				return err
+			}
+			updates.IDDistrito, updates.IDBarrio = ubicacion.IDDistrito, ubicacion.IDBarrio
+			updates.PatientDistrict, updates.PatientNeighborhood = ubicacion.PatientDistrict, ubicacion.PatientNeighborhood
+		}
+
+		cambiaEnfermedad := updates.Enfermedad != "" || updates.IDEnfermedad != nil
+		indicaContagio := updates.ContagiosoManual || updates.MotivoContagioso != ""
+		derivar := cambiaEnfermedad || (indicaContagio && antes.IDEnfermedad != nil)
+		if derivar {
+			if !cambiaEnfermedad {
+				// La indicación se contrasta con la enfermedad ya registrada
+				updates.Enfermedad = antes.Enfermedad
+				updates.IDEnfermedad = antes.IDEnfermedad
+			}
+			if err := asignarEnfermedad(tx, updates, updates.IDEnfermedad != nil); err != nil {
+				return err
+			}
+		} else {
+			// Sin enfermedad de catálogo (historiales antiguos) se conserva lo indicado por el médico
+			updates.ContagiosoManual = false
+		}
+
+		if err := tx.Model(&models.HistorialClinico{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+		if derivar {
+			err := tx.Model(&models.HistorialClinico{}).Where("id = ?", id).Select(columnasEpidemiologicas).Updates(updates).Error
+			if err != nil {
+				return err
+			}
+		}
+		if cambiaUbicacion {
+			// Fuera de los contornos el distrito y el barrio quedan nulos, que Updates omitiría
+			err := tx.Model(&models.HistorialClinico{}).Where("id = ?", id).Select("id_distrito", "id_barrio").Updates(updates).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := tx.First(&despues, id).Error; err != nil {
+			return err
+		}
+
+		if err := registrarRevisionActualizacion(tx, &antes, &despues, &actor.UsuarioID); err != nil {
+			return err
+		}
+
+		if err := auditar(tx, actor, models.AccionActualizar, models.EntidadHistorial, id, antes, despues, nil); err != nil {
+			return err
+		}
+		if err := registrarNotificacion(tx, actor, &despues); err != nil {
+			return err
+		}
+
+		// Un caso que pasa a ser contagioso se publica como caso nuevo
+		if !antes.IsContagious {
+			evento, err = registrarCasoContagioso(tx, &despues)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	difundirEvento(evento)
+	return &despues, nil
 }
 
-// DeleteHistorial elimina un historial clínico (soft delete)
-func (s *HistorialService) DeleteHistorial(id uint) error {
-	return s.db.Delete(&models.HistorialClinico{}, id).Error
+// PatchHistorial aplica un JSON Merge Patch (RFC 7396) a un historial clínico del hospital del actor
+// si su versión coincide con ifMatch.
+// Los campos enviados como null se vacían; si cambia patient_address se vuelve a geocodificar.
+// El patch se valida y la dirección se geocodifica antes de bloquear el registro; si otro usuario lo
+// modificó entretanto se devuelve un conflicto de versión.
+// Retorna el historial actualizado y, si hubo geocodificación, la información obtenida.
+func (s *HistorialService) PatchHistorial(actor Actor, id uint, ifMatch []int, patch []byte) (*models.HistorialClinico, *AddressComponents, error) {
+	var leido models.HistorialClinico
+	err := s.db.Scopes(actor.historialWriteScope).First(&leido, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("historial clínico no encontrado")
+		}
+		return nil, nil, err
+	}
+
+	if err := verificarVersion(leido.Version, ifMatch); err != nil {
+		return nil, nil, err
+	}
+
+	var parcheado models.HistorialClinico
+	campos, err := aplicarMergePatch(leido, patch, camposEditablesHistorial, &parcheado)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cambiaDireccion := parcheado.PatientAddress != leido.PatientAddress
+	excluidos := []string{"Paciente", "Hospital"}
+	if cambiaDireccion {
+		// El distrito y el barrio anteriores ya no corresponden salvo que se envíen en el mismo patch
+		if !campos["patient_district"] {
+			parcheado.PatientDistrict = ""
+		}
+		if !campos["patient_neighborhood"] {
+			parcheado.PatientNeighborhood = ""
+		}
+		// Las coordenadas y el distrito salen de la geocodificación y se validan al guardar
+		excluidos = append(excluidos, "PatientLatitude", "PatientLongitude", "PatientDistrict")
+	}
+
+	// Una dirección vacía o inválida se rechaza sin llegar al servicio de geocodificación
+	if err := validate.StructExcept(parcheado, excluidos...); err != nil {
+		return nil, nil, err
+	}
+
+	// La geocodificación es una llamada HTTP externa: se hace fuera de la transacción para no
+	// mantener el registro bloqueado mientras responde
+	var addressComponents *AddressComponents
+	if cambiaDireccion {
+		addressComponents, err = GeocodificarHistorial(&parcheado, parcheado.PatientAddress)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var despues models.HistorialClinico
+	var evento *Evento
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var antes models.HistorialClinico
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(actor.historialWriteScope).First(&antes, id).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("historial clínico no encontrado")
+			}
+			return err
+		}
+
+		// El patch se aplicó sobre la versión leída; si cambió mientras se geocodificaba no se pisa
+		if antes.Version != leido.Version {
+			return &VersionConflictError{Actual: antes.Version}
+		}
+
+		parcheado.Version = antes.Version + 1
+		extra := []string{"version"}
+
+		cambiaEnfermedad := campos["enfermedad"] || campos["id_enfermedad"]
+		if cambiaEnfermedad {
+			// Un id_enfermedad sin texto reemplaza el texto anterior por el nombre canónico
+			if campos["id_enfermedad"] && !campos["enfermedad"] {
+				parcheado.Enfermedad = ""
+			}
+			// Una corrección manual de la contagiosidad no se arrastra a otra enfermedad
+			if !campos["is_contagious"] && !campos["motivo_contagioso"] {
+				parcheado.ContagiosoManual = false
+				parcheado.MotivoContagioso = ""
+			}
+			extra = append(extra, "enfermedad", "id_enfermedad")
+		}
+		if campos["is_contagious"] {
+			parcheado.ContagiosoManual = true
+		}
+		if cambiaEnfermedad || campos["is_contagious"] || campos["motivo_contagioso"] {
+			// Sin enfermedad de catálogo (historiales antiguos) se conserva lo indicado por el médico
+			if cambiaEnfermedad || parcheado.IDEnfermedad != nil {
+				if err := asignarEnfermedad(tx, &parcheado, campos["id_enfermedad"] || !cambiaEnfermedad); err != nil {
+					return err
+				}
+			} else {
+				parcheado.ContagiosoManual = false
+			}
+			extra = append(extra, columnasEpidemiologicas...)
+		}
+		if cambiaDireccion {
+			extra = append(extra, "patient_latitude", "patient_longitude", "patient_district", "patient_neighborhood")
+		}
+		if cambiaDireccion || campos["patient_district"] || campos["patient_neighborhood"] {
+			if err := asignarDistrito(tx, &parcheado); err != nil {
+				return err
+			}
+			extra = append(extra, "id_distrito", "id_barrio", "patient_district", "patient_neighborhood")
+		}
+
+		if err := validate.StructExcept(parcheado, "Paciente", "Hospital"); err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.HistorialClinico{}).
+			Where("id = ?", id).
+			Select(columnasModificadas(campos, extra...)).
+			Updates(&parcheado).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.First(&despues, id).Error; err != nil {
+			return err
+		}
+
+		if err := registrarRevisionActualizacion(tx, &antes, &despues, &actor.UsuarioID); err != nil {
+			return err
+		}
+
+		err = auditar(tx, actor, models.AccionActualizar, models.EntidadHistorial, id, antes, despues, map[string]interface{}{
+			"patch": json.RawMessage(patch),
+		})
+		if err != nil {
+			return err
+		}
+		if err := registrarNotificacion(tx, actor, &despues); err != nil {
+			return err
+		}
+
+		if !antes.IsContagious {
+			evento, err = registrarCasoContagioso(tx, &despues)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	difundirEvento(evento)
+	return &despues, addressComponents, nil
+}
+
+// DeleteHistorial elimina un historial clínico (soft delete) si su versión coincide con ifMatch
+func (s *HistorialService) DeleteHistorial(actor Actor, id uint, ifMatch []int) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var antes models.HistorialClinico
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(actor.historialWriteScope).First(&antes, id).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("historial clínico no encontrado")
+			}
+			return err
+		}
+
+		if err := verificarVersion(antes.Version, ifMatch); err != nil {
+			return err
+		}
+
+		eliminado := antes
+		eliminado.Version = antes.Version + 1
+		if err := tx.Model(&models.HistorialClinico{}).Where("id = ?", id).Update("version", eliminado.Version).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.HistorialClinico{}, id).Error; err != nil {
+			return err
+		}
+		if err := registrarRevisionEliminacion(tx, &antes, &eliminado, &actor.UsuarioID); err != nil {
+			return err
+		}
+
+		return auditar(tx, actor, models.AccionEliminar, models.EntidadHistorial, id, antes, nil, nil)
+	})
 }
 
 // GetEpidemiologicalStats obtiene estadísticas epidemiológicas para mapas de calor
@@ -151,12 +661,13 @@ func (s *HistorialService) GetEpidemiologicalStats(startDate, endDate time.Time)
 		Scan(&dateStats)
 	stats.ByDate = dateStats
 
-	// Datos para mapa de calor (agrupado por coordenadas aproximadas)
+	// Datos para mapa de calor: los casos se agrupan en celdas de la grilla en la base de datos y cada
+	// celda se ubica en el centroide de sus casos
 	var heatMapData []HeatMapData
 	s.db.Model(&models.HistorialClinico{}).
-		Select("ROUND(patient_latitude::numeric, 4) as latitude, ROUND(patient_longitude::numeric, 4) as longitude, patient_district as district, COUNT(*) as count").
-		Where("consultation_date BETWEEN ? AND ?", startDate, endDate).
-		Group("ROUND(patient_latitude::numeric, 4), ROUND(patient_longitude::numeric, 4), patient_district").
+		Select("ST_Y(ST_Centroid(ST_Collect(ubicacion::geometry))) as latitude, ST_X(ST_Centroid(ST_Collect(ubicacion::geometry))) as longitude, patient_district as district, COUNT(*) as count").
+		Where("consultation_date BETWEEN ? AND ? AND ubicacion IS NOT NULL", startDate, endDate).
+		Group(fmt.Sprintf("ST_SnapToGrid(ubicacion::geometry, %g), patient_district", celdaMapaCalorGrados)).
 		Scan(&heatMapData)
 	stats.HeatMapData = heatMapData
 
@@ -164,11 +675,11 @@ func (s *HistorialService) GetEpidemiologicalStats(startDate, endDate time.Time)
 }
 
 // GetContagiousHistorial obtiene historiales de casos contagiosos
-func (s *HistorialService) GetContagiousHistorial(page, limit int) ([]models.HistorialClinico, int64, error) {
+func (s *HistorialService) GetContagiousHistorial(actor Actor, page, limit int) ([]models.HistorialClinico, int64, error) {
 	var historiales []models.HistorialClinico
 	var total int64
 
-	query := s.db.Where("is_contagious = ?", true)
+	query := s.db.Scopes(actor.historialScope).Where("is_contagious = ?", true)
 
 	// Contar total
 	query.Model(&models.HistorialClinico{}).Count(&total)
@@ -181,17 +692,26 @@ func (s *HistorialService) GetContagiousHistorial(page, limit int) ([]models.His
 		Limit(limit).
 		Order("fecha_ingreso DESC").
 		Find(&historiales).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = auditarLectura(s.db, actor, models.EntidadHistorial, historialIDs(historiales), map[string]interface{}{
+		"is_contagious": true,
+		"page":          page,
+		"limit":         limit,
+	})
 
 	return historiales, total, err
 }
 
 // GetHistorialByEnfermedad obtiene historiales clínicos por nombre de enfermedad
-func (s *HistorialService) GetHistorialByEnfermedad(enfermedad string, page, limit int) ([]models.HistorialClinico, int64, error) {
+func (s *HistorialService) GetHistorialByEnfermedad(actor Actor, enfermedad string, page, limit int) ([]models.HistorialClinico, int64, error) {
 	var historiales []models.HistorialClinico
 	var total int64
 
-	// Búsqueda case-insensitive de la enfermedad
-	query := s.db.Where("LOWER(enfermedad) = LOWER(?)", enfermedad)
+	// La enfermedad se busca en el catálogo por nombre, código CIE-10 o sinónimo
+	query := s.db.Scopes(actor.historialScope).Where(coincideEnfermedadSQL, sql.Named("enfermedad", enfermedad))
 
 	// Contar total
 	query.Model(&models.HistorialClinico{}).Count(&total)
@@ -204,6 +724,324 @@ func (s *HistorialService) GetHistorialByEnfermedad(enfermedad string, page, lim
 		Limit(limit).
 		Order("fecha_ingreso DESC").
 		Find(&historiales).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = auditarLectura(s.db, actor, models.EntidadHistorial, historialIDs(historiales), map[string]interface{}{
+		"enfermedad": enfermedad,
+		"page":       page,
+		"limit":      limit,
+	})
 
 	return historiales, total, err
 }
+
+// ListHistorial lista los historiales clínicos visibles para el actor que cumplen todos los filtros,
+// ordenados por orden ("campo" ascendente o "-campo" descendente) y paginados por cursor.
+// Retorna el cursor de la página siguiente, vacío si no hay más resultados.
+func (s *HistorialService) ListHistorial(actor Actor, filtro HistorialFilter, orden, cursor string, limit int) ([]models.HistorialClinico, string, error) {
+	campo, desc := strings.CutPrefix(orden, "-")
+	ordenamiento, ok := camposOrdenHistorial[campo]
+	if !ok {
+		return nil, "", ErrOrdenInvalido
+	}
+	direccion, comparador := "ASC", ">"
+	if desc {
+		direccion, comparador = "DESC", "<"
+	}
+
+	query := s.db.Scopes(actor.historialScope, filtro.scope)
+
+	if cursor != "" {
+		valor, id, err := utils.DecodeCursor(cursor, orden)
+		if err != nil {
+			return nil, "", err
+		}
+		if campo == "id" {
+			query = query.Where("historial_clinico.id "+comparador+" ?", id)
+		} else {
+			posicion, err := ordenamiento.parse(valor)
+			if err != nil {
+				return nil, "", utils.ErrCursorInvalido
+			}
+			query = query.Where("("+ordenamiento.columna+", historial_clinico.id) "+comparador+" (?, ?)", posicion, id)
+		}
+	}
+
+	var historiales []models.HistorialClinico
+	err := query.Preload("Paciente").
+		Preload("Hospital").
+		Preload("Catalogo").
+		Order(ordenamiento.columna + " " + direccion).
+		Order("historial_clinico.id " + direccion).
+		Limit(limit + 1).
+		Find(&historiales).Error
+	if err != nil {
+		return nil, "", err
+	}
+
+	siguiente := ""
+	if len(historiales) > limit {
+		historiales = historiales[:limit]
+		ultimo := &historiales[limit-1]
+		siguiente = utils.EncodeCursor(orden, ordenamiento.valor(ultimo), ultimo.ID)
+	}
+
+	err = auditarLectura(s.db, actor, models.EntidadHistorial, historialIDs(historiales), map[string]interface{}{
+		"filtros": filtro,
+		"orden":   orden,
+		"cursor":  cursor,
+		"limit":   limit,
+	})
+
+	return historiales, siguiente, err
+}
+
+// HistorialCercano historial clínico con la distancia en km entre la ubicación del paciente y el punto consultado
+type HistorialCercano struct {
+	models.HistorialClinico
+	DistanciaKm float64 `json:"distancia_km"`
+}
+
+// GetHistorialesCercanos obtiene los k historiales visibles para el actor que cumplen los filtros y cuya
+// ubicación está más cerca del punto, ordenados por distancia
+func (s *HistorialService) GetHistorialesCercanos(actor Actor, filtro HistorialFilter, lat, lng float64, k int) ([]HistorialCercano, error) {
+	var vecinos []struct {
+		ID          uint
+		DistanciaKm float64
+	}
+	err := s.db.Model(&models.HistorialClinico{}).
+		Scopes(actor.historialScope, filtro.scope).
+		Select("historial_clinico.id, "+distanciaKmSQL(ubicacionHistorial)+" AS distancia_km", lat, lng).
+		Where(ubicacionHistorial + " IS NOT NULL").
+		Scopes(ordenPorCercania(ubicacionHistorial, lat, lng)).
+		Limit(k).
+		Scan(&vecinos).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(vecinos))
+	for i, vecino := range vecinos {
+		ids[i] = vecino.ID
+	}
+
+	var historiales []models.HistorialClinico
+	if len(ids) > 0 {
+		err = s.db.Preload("Paciente").
+			Preload("Hospital").
+			Preload("Catalogo").
+			Where("id IN ?", ids).
+			Find(&historiales).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	porID := make(map[uint]models.HistorialClinico, len(historiales))
+	for _, historial := range historiales {
+		porID[historial.ID] = historial
+	}
+
+	cercanos := make([]HistorialCercano, 0, len(vecinos))
+	for _, vecino := range vecinos {
+		if historial, ok := porID[vecino.ID]; ok {
+			cercanos = append(cercanos, HistorialCercano{HistorialClinico: historial, DistanciaKm: vecino.DistanciaKm})
+		}
+	}
+
+	err = auditarLectura(s.db, actor, models.EntidadHistorial, ids, map[string]interface{}{
+		"filtros": filtro,
+		"lat":     lat,
+		"lng":     lng,
+		"k":       k,
+	})
+
+	return cercanos, err
+}
+
+// historialIDs retorna los IDs de una lista de historiales clínicos
+func historialIDs(historiales []models.HistorialClinico) []uint {
+	ids := make([]uint, len(historiales))
+	for i := range historiales {
+		ids[i] = historiales[i].ID
+	}
+	return ids
+}
+
+// GetRevisiones lista las versiones de un historial clínico visible para el actor, sin sus datos
+func (s *HistorialService) GetRevisiones(actor Actor, id uint) ([]models.HistorialRevision, error) {
+	if err := s.verificarHistorialVisible(actor, id); err != nil {
+		return nil, err
+	}
+
+	var revisiones []models.HistorialRevision
+	err := s.db.Select("id", "id_historial", "version", "id_usuario", "eliminada", "created_at").
+		Where("id_historial = ?", id).
+		Order("version").
+		Find(&revisiones).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = auditar(s.db, actor, models.AccionLeer, models.EntidadHistorial, id, nil, nil, map[string]interface{}{
+		"revisiones": true,
+	})
+	return revisiones, err
+}
+
+// GetRevision obtiene una versión específica de un historial clínico visible para el actor
+func (s *HistorialService) GetRevision(actor Actor, id uint, version int) (*models.HistorialRevision, error) {
+	if err := s.verificarHistorialVisible(actor, id); err != nil {
+		return nil, err
+	}
+
+	revision, err := s.findRevision(id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	err = auditar(s.db, actor, models.AccionLeer, models.EntidadHistorial, id, nil, nil, map[string]interface{}{
+		"version": version,
+	})
+	return revision, err
+}
+
+// DiffRevisiones compara dos versiones de un historial clínico visible para el actor
+func (s *HistorialService) DiffRevisiones(actor Actor, id uint, desde, hasta int) (*RevisionDiff, error) {
+	if err := s.verificarHistorialVisible(actor, id); err != nil {
+		return nil, err
+	}
+
+	revisionDesde, err := s.findRevision(id, desde)
+	if err != nil {
+		return nil, err
+	}
+	revisionHasta, err := s.findRevision(id, hasta)
+	if err != nil {
+		return nil, err
+	}
+
+	cambios, err := utils.Diff(revisionDesde.Datos, revisionHasta.Datos, "updated_at")
+	if err != nil {
+		return nil, err
+	}
+
+	err = auditar(s.db, actor, models.AccionLeer, models.EntidadHistorial, id, nil, nil, map[string]interface{}{
+		"diff": []int{desde, hasta},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &RevisionDiff{
+		IDHistorial: id,
+		Desde:       desde,
+		Hasta:       hasta,
+		Cambios:     cambios,
+	}, nil
+}
+
+// verificarHistorialVisible confirma que el historial existe y es visible para el actor
+func (s *HistorialService) verificarHistorialVisible(actor Actor, id uint) error {
+	var count int64
+	if err := s.db.Model(&models.HistorialClinico{}).Scopes(actor.historialScope).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("historial clínico no encontrado")
+	}
+	return nil
+}
+
+// findRevision busca una versión de un historial clínico
+func (s *HistorialService) findRevision(id uint, version int) (*models.HistorialRevision, error) {
+	var revision models.HistorialRevision
+	err := s.db.Where("id_historial = ? AND version = ?", id, version).First(&revision).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("revisión no encontrada")
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// registrarRevision guarda una instantánea del historial con el siguiente número de versión.
+// Debe ejecutarse en la transacción que modifica el historial, con la fila bloqueada.
+func registrarRevision(tx *gorm.DB, historial *models.HistorialClinico, usuarioID *uint) error {
+	return guardarRevision(tx, historial, usuarioID, false)
+}
+
+// guardarRevision guarda la instantánea con el siguiente número de versión, marcada como eliminación si se indica
+func guardarRevision(tx *gorm.DB, historial *models.HistorialClinico, usuarioID *uint, eliminada bool) error {
+	var ultima int
+	err := tx.Model(&models.HistorialRevision{}).
+		Where("id_historial = ?", historial.ID).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&ultima).Error
+	if err != nil {
+		return err
+	}
+
+	datos, err := historialSnapshot(historial)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&models.HistorialRevision{
+		IDHistorial: historial.ID,
+		Version:     ultima + 1,
+		Datos:       datos,
+		IDUsuario:   usuarioID,
+		Eliminada:   eliminada,
+	}).Error
+}
+
+// registrarRevisionActualizacion guarda la versión resultante de una actualización.
+// Los historiales creados antes del versionado guardan primero su estado original como versión 1.
+func registrarRevisionActualizacion(tx *gorm.DB, antes, despues *models.HistorialClinico, usuarioID *uint) error {
+	var revisiones int64
+	if err := tx.Model(&models.HistorialRevision{}).Where("id_historial = ?", antes.ID).Count(&revisiones).Error; err != nil {
+		return err
+	}
+	if revisiones == 0 {
+		if err := registrarRevision(tx, antes, nil); err != nil {
+			return err
+		}
+	}
+	return registrarRevision(tx, despues, usuarioID)
+}
+
+// registrarRevisionEliminacion guarda como última versión el estado con que se eliminó el historial.
+// Los historiales creados antes del versionado guardan primero su estado original como versión 1.
+func registrarRevisionEliminacion(tx *gorm.DB, antes, eliminado *models.HistorialClinico, usuarioID *uint) error {
+	var revisiones int64
+	if err := tx.Model(&models.HistorialRevision{}).Where("id_historial = ?", antes.ID).Count(&revisiones).Error; err != nil {
+		return err
+	}
+	if revisiones == 0 {
+		if err := registrarRevision(tx, antes, nil); err != nil {
+			return err
+		}
+	}
+	return guardarRevision(tx, eliminado, usuarioID, true)
+}
+
+// historialSnapshot serializa los campos propios del historial, sin relaciones precargadas
+func historialSnapshot(historial *models.HistorialClinico) (json.RawMessage, error) {
+	data, err := json.Marshal(historial)
+	if err != nil {
+		return nil, err
+	}
+
+	var campos map[string]interface{}
+	if err := json.Unmarshal(data, &campos); err != nil {
+		return nil, err
+	}
+	delete(campos, "paciente")
+	delete(campos, "hospital")
+	delete(campos, "enfermedad_catalogo")
+
+	return json.Marshal(campos)
+}
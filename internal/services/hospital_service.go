@@ -5,7 +5,6 @@ import (
 
 	"hospital-api/internal/database"
 	"hospital-api/internal/models"
-	"hospital-api/internal/utils"
 
 	"gorm.io/gorm"
 )
@@ -54,50 +53,73 @@ func (s *HospitalService) GetHospitalByID(id uint) (*models.Hospital, error) {
 	return &hospital, nil
 }
 
-// GetHospitalesNearby obtiene hospitales cercanos a unas coordenadas usando la fórmula de Haversine
+// GetHospitalesNearby obtiene los hospitales dentro de un radio (km) de unas coordenadas, del más cercano al más lejano
 func (s *HospitalService) GetHospitalesNearby(lat, lng, radius float64) ([]models.HospitalResponse, error) {
 	var hospitales []models.Hospital
 
-	// Obtener todos los hospitales
-	if err := s.db.Find(&hospitales).Error; err != nil {
+	err := s.db.Scopes(
+		dentroDeRadio(ubicacionHospital, Circulo{Lat: lat, Lng: lng, RadioKm: radius}),
+		ordenPorCercania(ubicacionHospital, lat, lng),
+	).Find(&hospitales).Error
+	if err != nil {
 		return nil, err
 	}
 
-	var hospitalesCercanos []models.HospitalResponse
-
-	// Filtrar hospitales dentro del radio especificado
+	hospitalesCercanos := make([]models.HospitalResponse, 0, len(hospitales))
 	for _, hospital := range hospitales {
-		distancia := utils.CalcularDistanciaHaversine(lat, lng, hospital.Latitud, hospital.Longitud)
-
-		if distancia <= radius {
-			hospitalResponse := hospital.ToResponse()
-			// Agregar la distancia como información adicional
-			hospitalesCercanos = append(hospitalesCercanos, hospitalResponse)
-		}
+		hospitalesCercanos = append(hospitalesCercanos, hospital.ToResponse())
 	}
 
 	return hospitalesCercanos, nil
 }
 
-// GetHospitalesWithDistances obtiene todos los hospitales con sus distancias a un punto
+// GetHospitalesWithDistances obtiene todos los hospitales con sus distancias a un punto, del más cercano al más lejano
 func (s *HospitalService) GetHospitalesWithDistances(lat, lng float64) ([]HospitalWithDistance, error) {
+	return s.hospitalesConDistancia(s.db, lat, lng)
+}
+
+// GetHospitalesMasCercanos obtiene los k hospitales más cercanos a un punto con su distancia
+func (s *HospitalService) GetHospitalesMasCercanos(lat, lng float64, k int) ([]HospitalWithDistance, error) {
+	return s.hospitalesConDistancia(s.db.Limit(k), lat, lng)
+}
+
+// GetHospitalesEnBBox obtiene los hospitales ubicados dentro de un rectángulo geográfico
+func (s *HospitalService) GetHospitalesEnBBox(bbox BoundingBox) ([]models.HospitalResponse, error) {
 	var hospitales []models.Hospital
 
-	if err := s.db.Find(&hospitales).Error; err != nil {
+	if err := s.db.Scopes(dentroDeCaja(ubicacionHospital, bbox)).Order("nombre").Find(&hospitales).Error; err != nil {
 		return nil, err
 	}
 
-	var hospitalesConDistancia []HospitalWithDistance
-
+	hospitalesResponse := make([]models.HospitalResponse, 0, len(hospitales))
 	for _, hospital := range hospitales {
-		distancia := utils.CalcularDistanciaHaversine(lat, lng, hospital.Latitud, hospital.Longitud)
+		hospitalesResponse = append(hospitalesResponse, hospital.ToResponse())
+	}
 
-		hospitalConDistancia := HospitalWithDistance{
-			Hospital:  hospital.ToResponse(),
-			Distancia: distancia,
-		}
+	return hospitalesResponse, nil
+}
+
+// hospitalesConDistancia calcula la distancia en la base de datos y ordena por cercanía usando el índice espacial
+func (s *HospitalService) hospitalesConDistancia(query *gorm.DB, lat, lng float64) ([]HospitalWithDistance, error) {
+	var filas []struct {
+		models.Hospital
+		DistanciaKm float64
+	}
+
+	err := query.Model(&models.Hospital{}).
+		Select("hospitales.*, "+distanciaKmSQL(ubicacionHospital)+" AS distancia_km", lat, lng).
+		Scopes(ordenPorCercania(ubicacionHospital, lat, lng)).
+		Find(&filas).Error
+	if err != nil {
+		return nil, err
+	}
 
-		hospitalesConDistancia = append(hospitalesConDistancia, hospitalConDistancia)
+	hospitalesConDistancia := make([]HospitalWithDistance, 0, len(filas))
+	for _, fila := range filas {
+		hospitalesConDistancia = append(hospitalesConDistancia, HospitalWithDistance{
+			Hospital:  fila.Hospital.ToResponse(),
+			Distancia: fila.DistanciaKm,
+		})
 	}
 
 	return hospitalesConDistancia, nil
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"hospital-api/internal/config"
+	"hospital-api/internal/database"
+	"hospital-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Claves por las que se acumulan los intentos fallidos
+const (
+	intentoPorEmail = "email"
+	intentoPorIP    = "ip"
+)
+
+// LoginBloqueadoError indica que el email o la IP están bloqueados temporalmente o que aún no pasó el
+// retraso progresivo desde su último fallo
+type LoginBloqueadoError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginBloqueadoError) Error() string {
+	return "demasiados intentos fallidos, intente nuevamente más tarde"
+}
+
+// LoginGuardService protege el login contra ataques de fuerza bruta
+type LoginGuardService struct {
+	db           *gorm.DB
+	cfg          config.LoginConfig
+	auditService *AuditService
+}
+
+// NewLoginGuardService crea una nueva instancia del servicio de protección de login
+func NewLoginGuardService() *LoginGuardService {
+	return &LoginGuardService{
+		db:           database.GetDB(),
+		cfg:          config.GetConfig().Login,
+		auditService: NewAuditService(),
+	}
+}
+
+// Verificar retorna LoginBloqueadoError si el email o la IP están bloqueados o si desde su último fallo
+// aún no pasó el retraso progresivo que corresponde a los fallos acumulados. El retraso se exige al
+// cliente con Retry-After en lugar de esperar en el servidor, para no retener la petición.
+func (s *LoginGuardService) Verificar(email, ip string) error {
+	var intentos []models.IntentoLogin
+	err := s.db.Where("(tipo = ? AND valor = ?) OR (tipo = ? AND valor = ?)",
+		intentoPorEmail, normalizarEmail(email), intentoPorIP, ip).Find(&intentos).Error
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var retryAfter time.Duration
+	for _, intento := range intentos {
+		if intento.BloqueadoHasta != nil && intento.BloqueadoHasta.After(now) {
+			retryAfter = max(retryAfter, intento.BloqueadoHasta.Sub(now))
+		}
+		if intento.Fallos > 0 && now.Sub(intento.PrimerFallo) <= s.cfg.Ventana {
+			retryAfter = max(retryAfter, intento.UpdatedAt.Add(s.retraso(intento.Fallos)).Sub(now))
+		}
+	}
+
+	if retryAfter > 0 {
+		return &LoginBloqueadoError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// RegistrarFallo suma un intento fallido al email y a la IP, bloqueándolos al superar el umbral
+func (s *LoginGuardService) RegistrarFallo(email, ip string) {
+	if err := s.registrarFallo(intentoPorEmail, normalizarEmail(email), ip, s.cfg.MaxIntentosEmail); err != nil {
+		log.Printf("Error registrando intento fallido para %s: %v", email, err)
+	}
+	if err := s.registrarFallo(intentoPorIP, ip, ip, s.cfg.MaxIntentosIP); err != nil {
+		log.Printf("Error registrando intento fallido para la IP %s: %v", ip, err)
+	}
+}
+
+// RegistrarExito reinicia los fallos del email tras un login correcto.
+// Los fallos de la IP se mantienen para no permitir probar muchas cuentas desde ella.
+func (s *LoginGuardService) RegistrarExito(email string) {
+	err := s.db.Where("tipo = ? AND valor = ?", intentoPorEmail, normalizarEmail(email)).Delete(&models.IntentoLogin{}).Error
+	if err != nil {
+		log.Printf("Error reiniciando intentos de %s: %v", email, err)
+	}
+}
+
+// registrarFallo incrementa de forma atómica los fallos de una clave dentro de la ventana
+func (s *LoginGuardService) registrarFallo(tipo, valor, ip string, maxIntentos int) error {
+	now := time.Now()
+	inicioVentana := now.Add(-s.cfg.Ventana)
+
+	var fallos int
+	err := s.db.Raw(`
+		INSERT INTO intentos_login (tipo, valor, fallos, primer_fallo, updated_at)
+		VALUES (?, ?, 1, ?, ?)
+		ON CONFLICT (tipo, valor) DO UPDATE SET
+			fallos = CASE WHEN intentos_login.primer_fallo < ? THEN 1 ELSE intentos_login.fallos + 1 END,
+			primer_fallo = CASE WHEN intentos_login.primer_fallo < ? THEN EXCLUDED.primer_fallo ELSE intentos_login.primer_fallo END,
+			updated_at = EXCLUDED.updated_at
+		RETURNING fallos`,
+		tipo, valor, now, now, inicioVentana, inicioVentana).Scan(&fallos).Error
+	if err != nil {
+		return err
+	}
+
+	if maxIntentos <= 0 || fallos < maxIntentos {
+		return nil
+	}
+
+	// Bloquear y reiniciar el contador para el siguiente período
+	bloqueadoHasta := now.Add(s.cfg.Bloqueo)
+	err = s.db.Model(&models.IntentoLogin{}).
+		Where("tipo = ? AND valor = ?", tipo, valor).
+		Updates(map[string]interface{}{"fallos": 0, "bloqueado_hasta": bloqueadoHasta}).Error
+	if err != nil {
+		return err
+	}
+
+	entrada := models.AuditLog{
+		Accion:    models.AccionLoginBloqueado,
+		Entidad:   tipo,
+		EntidadID: valor,
+		IP:        ip,
+	}
+	if tipo == intentoPorEmail {
+		var usuario models.Usuario
+		if s.db.Where("email = ?", valor).First(&usuario).Error == nil {
+			entrada.IDUsuario = &usuario.ID
+			entrada.IDHospital = &usuario.IDHospital
+		}
+	}
+
+	log.Printf("🔒 Login bloqueado para %s %s hasta %s", tipo, valor, bloqueadoHasta.Format(time.RFC3339))
+	return s.auditService.Registrar(entrada, map[string]interface{}{
+		"fallos":          fallos,
+		"bloqueado_hasta": bloqueadoHasta,
+	})
+}
+
+// retraso calcula el retraso exponencial para la cantidad de fallos acumulados
+func (s *LoginGuardService) retraso(fallos int) time.Duration {
+	retraso := s.cfg.RetrasoBase
+	for i := 1; i < fallos && retraso < s.cfg.RetrasoMax; i++ {
+		retraso *= 2
+	}
+	if retraso > s.cfg.RetrasoMax {
+		retraso = s.cfg.RetrasoMax
+	}
+	return retraso
+}
+
+// normalizarEmail unifica el email para que variaciones de mayúsculas cuenten como la misma clave
+func normalizarEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,459 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"hospital-api/internal/database"
+	"hospital-api/internal/models"
+	"hospital-api/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// Núcleos de la estimación de densidad
+const (
+	KernelCuartico     = "cuartico"
+	KernelEpanechnikov = "epanechnikov"
+	KernelGaussiano    = "gaussiano"
+	KernelUniforme     = "uniforme"
+)
+
+// Formatos de salida del mapa de calor
+const (
+	FormatoMapaCalorGrilla    = "grilla"
+	FormatoMapaCalorContornos = "contornos"
+)
+
+// Origen del área de la grilla del mapa de calor
+const (
+	AreaMapaCalorBBox      = "bbox"      // La indicada en la consulta
+	AreaMapaCalorDistritos = "distritos" // La que cubre los distritos del registro
+	AreaMapaCalorCasos     = "casos"     // La de los casos, si no hay distritos registrados
+)
+
+// Unidades de los valores del mapa de calor
+const (
+	UnidadCasosKm2       = "casos_km2"
+	UnidadIncidencia100k = "casos_100k_hab"
+)
+
+// Valores por defecto y límites de la grilla
+const (
+	celdaMapaCalorDefecto      = 250.0  // m
+	anchoBandaMapaCalorDefecto = 1000.0 // m
+	diasMapaCalorDefecto       = 30
+	nivelesMapaCalorDefecto    = 5
+	maxCeldasMapaCalor         = 250000
+	maxBandaPorCelda           = 20 // Ancho de banda máximo en celdas, acota el costo de cada caso
+	truncamientoGaussiano      = 3  // El núcleo gaussiano se corta a 3 anchos de banda (pierde ~1% de masa)
+	metrosPorGradoLatitud      = 111320.0
+)
+
+var (
+	// ErrParametrosMapaCalor indica una combinación de ventana, celda y ancho de banda no admitida
+	ErrParametrosMapaCalor = errors.New("parámetros del mapa de calor inválidos")
+	// ErrGrillaMapaCalor indica un área demasiado grande para el tamaño de celda pedido
+	ErrGrillaMapaCalor = errors.New("la grilla del mapa de calor supera el máximo de celdas")
+)
+
+// MapaCalorRequest parámetros de la estimación de densidad por núcleo (KDE) de los casos de una enfermedad
+type MapaCalorRequest struct {
+	Enfermedad  string       `form:"enfermedad" validate:"required,max=100"` // Nombre, código CIE-10 o sinónimo del catálogo
+	StartDate   string       `form:"start_date" validate:"omitempty,datetime=2006-01-02"`
+	EndDate     string       `form:"end_date" validate:"omitempty,datetime=2006-01-02"`
+	CeldaM      float64      `form:"celda_m" validate:"omitempty,min=25,max=5000"`
+	AnchoBandaM float64      `form:"ancho_banda_m" validate:"omitempty,min=50,max=50000"`
+	Kernel      string       `form:"kernel" validate:"omitempty,oneof=cuartico epanechnikov gaussiano uniforme"`
+	Incidencia  bool         `form:"incidencia"` // Divide por la población del distrito de cada celda
+	Formato     string       `form:"formato" validate:"omitempty,oneof=grilla contornos"`
+	Niveles     int          `form:"niveles" validate:"omitempty,min=1,max=20"` // Curvas de nivel en formato contornos
+	BBox        *BoundingBox `form:"-"`                                         // Área de la grilla; por defecto la de los distritos
+}
+
+// MapaCalor superficie de densidad de casos estimada sobre una grilla regular
+type MapaCalor struct {
+	Enfermedad         string                   `json:"enfermedad"`
+	Desde              string                   `json:"desde"`
+	Hasta              string                   `json:"hasta"`
+	Kernel             string                   `json:"kernel"`
+	AnchoBandaM        float64                  `json:"ancho_banda_m"`
+	CeldaM             float64                  `json:"celda_m"`
+	Area               string                   `json:"area"` // bbox, distritos o casos
+	Unidad             string                   `json:"unidad"`
+	TotalCasos         int                      `json:"total_casos"`
+	Maximo             float64                  `json:"maximo"`
+	CeldasSinPoblacion int                      `json:"celdas_sin_poblacion,omitempty"` // Celdas sin incidencia por falta de población del distrito
+	Grilla             *GrillaMapaCalor         `json:"grilla,omitempty"`
+	Contornos          *utils.FeatureCollection `json:"contornos,omitempty"`
+}
+
+// GrillaMapaCalor valores por celda: Valores[fila][columna], con la fila 0 al sur y la columna 0 al oeste.
+// Cada valor corresponde al centro de su celda; es null si la incidencia no se pudo calcular.
+type GrillaMapaCalor struct {
+	MinLat   float64      `json:"min_lat"`
+	MinLng   float64      `json:"min_lng"`
+	MaxLat   float64      `json:"max_lat"`
+	MaxLng   float64      `json:"max_lng"`
+	PasoLat  float64      `json:"paso_lat"`
+	PasoLng  float64      `json:"paso_lng"`
+	Filas    int          `json:"filas"`
+	Columnas int          `json:"columnas"`
+	Valores  [][]*float64 `json:"valores"`
+}
+
+type MapaCalorService struct {
+	db *gorm.DB
+}
+
+// NewMapaCalorService crea una nueva instancia del servicio de mapas de calor
+func NewMapaCalorService() *MapaCalorService {
+	return &MapaCalorService{
+		db: database.GetDB(),
+	}
+}
+
+// GenerarMapaCalor estima la densidad de casos de una enfermedad en la ventana (casos por km²) con un
+// núcleo del ancho de banda indicado. Los casos se agrupan primero en las celdas de la grilla (KDE
+// binned), lo que requiere un ancho de banda de al menos una celda. Con Incidencia la densidad se
+// divide por la densidad de población del distrito de cada celda para obtener casos por 100 mil habitantes.
+func (s *MapaCalorService) GenerarMapaCalor(req MapaCalorRequest) (*MapaCalor, error) {
+	completarMapaCalorRequest(&req)
+
+	hasta := diaUTC(time.Now()).AddDate(0, 0, 1)
+	if req.EndDate != "" {
+		fin, _ := time.Parse("2006-01-02", req.EndDate)
+		hasta = fin.AddDate(0, 0, 1)
+	}
+	desde := hasta.AddDate(0, 0, -diasMapaCalorDefecto)
+	if req.StartDate != "" {
+		desde, _ = time.Parse("2006-01-02", req.StartDate)
+	}
+	if !desde.Before(hasta) {
+		return nil, fmt.Errorf("%w: start_date debe ser anterior o igual a end_date", ErrParametrosMapaCalor)
+	}
+	if req.AnchoBandaM < req.CeldaM || req.AnchoBandaM > maxBandaPorCelda*req.CeldaM {
+		return nil, fmt.Errorf("%w: ancho_banda_m debe estar entre 1 y %d veces celda_m", ErrParametrosMapaCalor, maxBandaPorCelda)
+	}
+
+	radio := req.AnchoBandaM
+	if req.Kernel == KernelGaussiano {
+		radio *= truncamientoGaussiano
+	}
+
+	// Área de la grilla: la pedida o la que cubre los distritos del registro más el alcance del núcleo, para
+	// que un caso con coordenadas erróneas lejos de la ciudad no agrande la grilla. Sin distritos registrados
+	// se usa la de los casos.
+	area := AreaMapaCalorBBox
+	caja := req.BBox
+	if caja == nil {
+		extension, err := s.extensionDistritos()
+		if err != nil {
+			return nil, err
+		}
+		if extension != nil {
+			area, caja = AreaMapaCalorDistritos, expandirCaja(*extension, radio)
+		}
+	}
+
+	// Con un área fija se incluyen los casos cercanos a sus bordes, que también aportan densidad
+	filtro := HistorialFilter{Enfermedad: req.Enfermedad, Desde: &desde, Hasta: &hasta}
+	if caja != nil {
+		filtro.BBox = expandirCaja(*caja, radio)
+	}
+	var casos []struct {
+		PatientLatitude  float64
+		PatientLongitude float64
+	}
+	err := s.db.Model(&models.HistorialClinico{}).
+		Scopes(filtro.scope).
+		Select("historial_clinico.patient_latitude, historial_clinico.patient_longitude").
+		Scan(&casos).Error
+	if err != nil {
+		return nil, err
+	}
+
+	mapa := &MapaCalor{
+		Enfermedad:  req.Enfermedad,
+		Desde:       desde.Format("2006-01-02"),
+		Hasta:       hasta.AddDate(0, 0, -1).Format("2006-01-02"),
+		Kernel:      req.Kernel,
+		AnchoBandaM: req.AnchoBandaM,
+		CeldaM:      req.CeldaM,
+		Area:        area,
+		Unidad:      UnidadCasosKm2,
+		TotalCasos:  len(casos),
+	}
+	if req.Incidencia {
+		mapa.Unidad = UnidadIncidencia100k
+	}
+
+	if caja == nil {
+		if len(casos) == 0 {
+			return mapa, nil
+		}
+		mapa.Area = AreaMapaCalorCasos
+		extension := BoundingBox{MinLat: math.Inf(1), MinLng: math.Inf(1), MaxLat: math.Inf(-1), MaxLng: math.Inf(-1)}
+		for _, caso := range casos {
+			extension.MinLat = math.Min(extension.MinLat, caso.PatientLatitude)
+			extension.MaxLat = math.Max(extension.MaxLat, caso.PatientLatitude)
+			extension.MinLng = math.Min(extension.MinLng, caso.PatientLongitude)
+			extension.MaxLng = math.Max(extension.MaxLng, caso.PatientLongitude)
+		}
+		caja = expandirCaja(extension, radio)
+	}
+
+	// Grilla regular en grados con celdas de CeldaM metros a la latitud media (proyección equirectangular local)
+	pasoLat := req.CeldaM / metrosPorGradoLatitud
+	pasoLng := req.CeldaM / (metrosPorGradoLatitud * math.Cos((caja.MinLat+caja.MaxLat)/2*math.Pi/180))
+	filas := max(1, int(math.Ceil((caja.MaxLat-caja.MinLat)/pasoLat)))
+	columnas := max(1, int(math.Ceil((caja.MaxLng-caja.MinLng)/pasoLng)))
+	if filas*columnas > maxCeldasMapaCalor {
+		return nil, ErrGrillaMapaCalor
+	}
+
+	// Conteo de casos por celda, incluidas las celdas fuera de la grilla a las que alcanza el núcleo
+	conteos := make(map[[2]int]float64)
+	for _, caso := range casos {
+		f := int(math.Floor((caso.PatientLatitude - caja.MinLat) / pasoLat))
+		c := int(math.Floor((caso.PatientLongitude - caja.MinLng) / pasoLng))
+		conteos[[2]int{f, c}]++
+	}
+
+	// Pesos del núcleo por desplazamiento en celdas, en casos por km²
+	alcance := int(math.Ceil(radio / req.CeldaM))
+	lado := 2*alcance + 1
+	pesos := make([]float64, lado*lado)
+	for df := -alcance; df <= alcance; df++ {
+		for dc := -alcance; dc <= alcance; dc++ {
+			distancia := req.CeldaM * math.Hypot(float64(df), float64(dc))
+			if distancia > radio {
+				continue
+			}
+			pesos[(df+alcance)*lado+dc+alcance] = pesoKernel(req.Kernel, distancia/req.AnchoBandaM) /
+				(req.AnchoBandaM * req.AnchoBandaM) * 1e6
+		}
+	}
+
+	valores := make([][]float64, filas)
+	for f := range valores {
+		valores[f] = make([]float64, columnas)
+	}
+	for celda, n := range conteos {
+		for df := -alcance; df <= alcance; df++ {
+			f := celda[0] + df
+			if f < 0 || f >= filas {
+				continue
+			}
+			for dc := -alcance; dc <= alcance; dc++ {
+				c := celda[1] + dc
+				if c < 0 || c >= columnas {
+					continue
+				}
+				valores[f][c] += n * pesos[(df+alcance)*lado+dc+alcance]
+			}
+		}
+	}
+
+	if req.Incidencia {
+		if mapa.CeldasSinPoblacion, err = s.dividirPorPoblacion(valores, caja.MinLat, caja.MinLng, pasoLat, pasoLng); err != nil {
+			return nil, err
+		}
+	}
+
+	// Las celdas sin incidencia (NaN) se omiten del máximo; en los contornos cuentan como 0
+	celdas := make([][]*float64, filas)
+	for f := range valores {
+		celdas[f] = make([]*float64, columnas)
+		for c := range valores[f] {
+			if math.IsNaN(valores[f][c]) {
+				valores[f][c] = 0
+				continue
+			}
+			valor := redondear(valores[f][c], 4)
+			valores[f][c] = valor
+			celdas[f][c] = &valor
+			mapa.Maximo = math.Max(mapa.Maximo, valor)
+		}
+	}
+
+	if req.Formato == FormatoMapaCalorContornos {
+		mapa.Contornos = contornosMapaCalor(valores, mapa.Maximo, req.Niveles, mapa.Unidad, caja.MinLat, caja.MinLng, pasoLat, pasoLng)
+		return mapa, nil
+	}
+
+	mapa.Grilla = &GrillaMapaCalor{
+		MinLat:   caja.MinLat,
+		MinLng:   caja.MinLng,
+		MaxLat:   caja.MinLat + float64(filas)*pasoLat,
+		MaxLng:   caja.MinLng + float64(columnas)*pasoLng,
+		PasoLat:  pasoLat,
+		PasoLng:  pasoLng,
+		Filas:    filas,
+		Columnas: columnas,
+		Valores:  celdas,
+	}
+	return mapa, nil
+}
+
+// extensionDistritos caja que cubre los contornos oficiales de los distritos o, para los que no se
+// importaron, sus centroides; nil si no hay distritos registrados
+func (s *MapaCalorService) extensionDistritos() (*BoundingBox, error) {
+	var extension struct {
+		MinLat, MinLng, MaxLat, MaxLng *float64
+	}
+	err := s.db.Model(&models.Distrito{}).
+		Select(`MIN(COALESCE(limite_min_lat, latitud)) AS min_lat, MIN(COALESCE(limite_min_lng, longitud)) AS min_lng,
+			MAX(COALESCE(limite_max_lat, latitud)) AS max_lat, MAX(COALESCE(limite_max_lng, longitud)) AS max_lng`).
+		Scan(&extension).Error
+	if err != nil || extension.MinLat == nil {
+		return nil, err
+	}
+	return &BoundingBox{MinLat: *extension.MinLat, MinLng: *extension.MinLng, MaxLat: *extension.MaxLat, MaxLng: *extension.MaxLng}, nil
+}
+
+// dividirPorPoblacion convierte la densidad de casos de cada celda en casos por 100 mil habitantes con la
+// densidad de población del distrito que contiene su centro: por contorno oficial si se importó o, si no,
+// el de centroide más cercano. Las celdas con casos cuyo distrito no tiene habitantes o área quedan en NaN
+// y se retorna cuántas son.
+func (s *MapaCalorService) dividirPorPoblacion(valores [][]float64, minLat, minLng, pasoLat, pasoLng float64) (int, error) {
+	var distritos []models.Distrito
+	if err := s.db.Omit("limite").Find(&distritos).Error; err != nil {
+		return 0, err
+	}
+	localizador, err := cargarLocalizador(s.db, false)
+	if err != nil {
+		return 0, err
+	}
+
+	densidades := make(map[uint]float64, len(distritos))
+	for _, distrito := range distritos {
+		if distrito.AreaKm2 > 0 {
+			densidades[distrito.ID] = float64(distrito.Habitantes) / distrito.AreaKm2
+		}
+	}
+
+	sinPoblacion := 0
+	for f := range valores {
+		lat := minLat + (float64(f)+0.5)*pasoLat
+		for c := range valores[f] {
+			if valores[f][c] == 0 {
+				continue
+			}
+			lng := minLng + (float64(c)+0.5)*pasoLng
+
+			var idDistrito uint
+			if distrito, _ := localizador.ubicar(lat, lng); distrito != nil {
+				idDistrito = distrito.id
+			} else {
+				menor := math.Inf(1)
+				for _, candidato := range distritos {
+					if d := utils.CalcularDistanciaHaversine(lat, lng, candidato.Latitud, candidato.Longitud); d < menor {
+						menor, idDistrito = d, candidato.ID
+					}
+				}
+			}
+
+			if densidad := densidades[idDistrito]; densidad > 0 {
+				valores[f][c] = valores[f][c] / densidad * 100000
+			} else {
+				valores[f][c] = math.NaN()
+				sinPoblacion++
+			}
+		}
+	}
+	return sinPoblacion, nil
+}
+
+// contornosMapaCalor curvas de nivel equiespaciadas entre 0 y el máximo, una feature MultiLineString por nivel
+func contornosMapaCalor(valores [][]float64, maximo float64, niveles int, unidad string, minLat, minLng, pasoLat, pasoLng float64) *utils.FeatureCollection {
+	coleccion := utils.NuevaFeatureCollection()
+	if maximo <= 0 {
+		return coleccion
+	}
+
+	for nivel := 1; nivel <= niveles; nivel++ {
+		umbral := maximo * float64(nivel) / float64(niveles+1)
+		lineas := utils.Isolineas(valores, umbral)
+		if len(lineas) == 0 {
+			continue
+		}
+
+		coordenadas := make([][][2]float64, len(lineas))
+		for i, linea := range lineas {
+			coordenadas[i] = make([][2]float64, len(linea))
+			for j, punto := range linea {
+				// Los valores están en el centro de cada celda
+				coordenadas[i][j] = [2]float64{
+					redondear(minLng+(punto[0]+0.5)*pasoLng, 6),
+					redondear(minLat+(punto[1]+0.5)*pasoLat, 6),
+				}
+			}
+		}
+		geometria, _ := json.Marshal(map[string]interface{}{
+			"type":        "MultiLineString",
+			"coordinates": coordenadas,
+		})
+		coleccion.Agregar(nivel, geometria, map[string]interface{}{
+			"nivel":  nivel,
+			"valor":  redondear(umbral, 4),
+			"unidad": unidad,
+		})
+	}
+	return coleccion
+}
+
+// pesoKernel valor del núcleo bidimensional normalizado (integra 1 en el plano) a u anchos de banda del centro
+func pesoKernel(kernel string, u float64) float64 {
+	switch kernel {
+	case KernelGaussiano:
+		return math.Exp(-u*u/2) / (2 * math.Pi)
+	case KernelEpanechnikov:
+		if u < 1 {
+			return 2 / math.Pi * (1 - u*u)
+		}
+	case KernelUniforme:
+		if u < 1 {
+			return 1 / math.Pi
+		}
+	default:
+		if u < 1 {
+			return 3 / math.Pi * (1 - u*u) * (1 - u*u)
+		}
+	}
+	return 0
+}
+
+// completarMapaCalorRequest aplica los valores por defecto a los parámetros no enviados
+func completarMapaCalorRequest(req *MapaCalorRequest) {
+	if req.CeldaM == 0 {
+		req.CeldaM = celdaMapaCalorDefecto
+	}
+	if req.AnchoBandaM == 0 {
+		req.AnchoBandaM = anchoBandaMapaCalorDefecto
+	}
+	if req.Kernel == "" {
+		req.Kernel = KernelCuartico
+	}
+	if req.Formato == "" {
+		req.Formato = FormatoMapaCalorGrilla
+	}
+	if req.Niveles == 0 {
+		req.Niveles = nivelesMapaCalorDefecto
+	}
+}
+
+// expandirCaja agranda la caja en metros hacia cada lado
+func expandirCaja(caja BoundingBox, metros float64) *BoundingBox {
+	dLat := metros / metrosPorGradoLatitud
+	dLng := metros / (metrosPorGradoLatitud * math.Cos((caja.MinLat+caja.MaxLat)/2*math.Pi/180))
+	return &BoundingBox{
+		MinLat: math.Max(caja.MinLat-dLat, -90),
+		MinLng: math.Max(caja.MinLng-dLng, -180),
+		MaxLat: math.Min(caja.MaxLat+dLat, 90),
+		MaxLng: math.Min(caja.MaxLng+dLng, 180),
+	}
+}
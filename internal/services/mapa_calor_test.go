@@ -0,0 +1,95 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPesoKernel(t *testing.T) {
+	tests := []struct {
+		kernel string
+		u      float64
+		want   float64
+	}{
+		{KernelCuartico, 0, 3 / math.Pi},
+		{KernelCuartico, 0.5, 3 / math.Pi * 0.75 * 0.75},
+		{KernelCuartico, 1, 0},
+		{KernelEpanechnikov, 0, 2 / math.Pi},
+		{KernelEpanechnikov, 0.5, 2 / math.Pi * 0.75},
+		{KernelEpanechnikov, 1, 0},
+		{KernelUniforme, 0, 1 / math.Pi},
+		{KernelUniforme, 0.99, 1 / math.Pi},
+		{KernelUniforme, 1, 0},
+		{KernelGaussiano, 0, 1 / (2 * math.Pi)},
+		{KernelGaussiano, 1, math.Exp(-0.5) / (2 * math.Pi)},
+		{"", 0, 3 / math.Pi}, // Por defecto el cuártico
+	}
+
+	for _, tt := range tests {
+		if got := pesoKernel(tt.kernel, tt.u); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("pesoKernel(%q, %v) = %v, want %v", tt.kernel, tt.u, got, tt.want)
+		}
+	}
+}
+
+// Cada núcleo integra 1 en el plano: ∫ K(u) 2πu du = 1
+func TestPesoKernelNormalizado(t *testing.T) {
+	for _, kernel := range []string{KernelCuartico, KernelEpanechnikov, KernelUniforme, KernelGaussiano} {
+		t.Run(kernel, func(t *testing.T) {
+			const paso = 1e-4
+			integral := 0.0
+			for u := paso / 2; u < 10; u += paso {
+				integral += pesoKernel(kernel, u) * 2 * math.Pi * u * paso
+			}
+			if math.Abs(integral-1) > 1e-3 {
+				t.Errorf("integral = %v, want 1", integral)
+			}
+		})
+	}
+}
+
+func TestExpandirCaja(t *testing.T) {
+	tests := []struct {
+		name   string
+		caja   BoundingBox
+		metros float64
+		want   BoundingBox
+	}{
+		{
+			// En el ecuador 111,32 km son un grado de latitud y de longitud
+			name:   "ecuador",
+			caja:   BoundingBox{MinLat: -1, MinLng: -1, MaxLat: 1, MaxLng: 1},
+			metros: 111320,
+			want:   BoundingBox{MinLat: -2, MinLng: -2, MaxLat: 2, MaxLng: 2},
+		},
+		{
+			// A 60° un grado de longitud mide la mitad
+			name:   "latitud 60",
+			caja:   BoundingBox{MinLat: 59.5, MinLng: 10, MaxLat: 60.5, MaxLng: 11},
+			metros: 111320,
+			want:   BoundingBox{MinLat: 58.5, MinLng: 8, MaxLat: 61.5, MaxLng: 13},
+		},
+		{
+			name:   "se limita a la longitud 180",
+			caja:   BoundingBox{MinLat: -0.5, MinLng: 179.5, MaxLat: 0.5, MaxLng: 179.8},
+			metros: 111320,
+			want:   BoundingBox{MinLat: -1.5, MinLng: 178.5, MaxLat: 1.5, MaxLng: 180},
+		},
+		{
+			name:   "se limita al polo sur",
+			caja:   BoundingBox{MinLat: -89.5, MinLng: 0, MaxLat: -89.5, MaxLng: 0},
+			metros: 111320,
+			want:   BoundingBox{MinLat: -90, MinLng: -1 / math.Cos(89.5*math.Pi/180), MaxLat: -88.5, MaxLng: 1 / math.Cos(89.5*math.Pi/180)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := expandirCaja(tt.caja, tt.metros)
+			if math.Abs(got.MinLat-tt.want.MinLat) > 1e-9 || math.Abs(got.MinLng-tt.want.MinLng) > 1e-9 ||
+				math.Abs(got.MaxLat-tt.want.MaxLat) > 1e-9 || math.Abs(got.MaxLng-tt.want.MaxLng) > 1e-9 {
+				t.Errorf("expandirCaja() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"hospital-api/internal/database"
+	"hospital-api/internal/models"
+	"hospital-api/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// Parámetros de los vector tiles de casos (Mapbox Vector Tile)
+const (
+	extensionTile = 4096 // Resolución del sistema de coordenadas interno de cada tile
+	bufferTile    = 64   // Margen fuera del tile que se incluye para no cortar símbolos en los bordes
+	celdaTile     = 64   // Lado de la celda de agrupación en unidades del tile (4 px en un tile de 256 px)
+	zoomDetalle   = 14   // Desde este zoom cada caso es un punto; con menos zoom se agrupan en celdas
+)
+
+// MaxZoomTiles zoom máximo de los vector tiles
+const MaxZoomTiles = 22
+
+// LimiteCasosGeoJSON cantidad máxima de casos por FeatureCollection; para más casos se usan los vector tiles
+const LimiteCasosGeoJSON = 50000
+
+type MapaService struct {
+	db *gorm.DB
+}
+
+// NewMapaService crea una nueva instancia del servicio de capas de mapa
+func NewMapaService() *MapaService {
+	return &MapaService{
+		db: database.GetDB(),
+	}
+}
+
+// GetCasosGeoJSON obtiene los casos visibles para el actor que cumplen los filtros como puntos de GeoJSON,
+// del ingreso más reciente al más antiguo y hasta limit casos. No incluye datos personales del paciente.
+func (s *MapaService) GetCasosGeoJSON(actor Actor, filtro HistorialFilter, limit int) (*utils.FeatureCollection, error) {
+	var casos []struct {
+		ID                  uint
+		IDEnfermedad        *uint
+		Enfermedad          string
+		IDHospital          uint
+		FechaIngreso        string
+		ConsultationDate    string
+		IsContagious        bool
+		PatientDistrict     string
+		PatientNeighborhood string
+		PatientLatitude     float64
+		PatientLongitude    float64
+	}
+	err := s.db.Model(&models.HistorialClinico{}).
+		Scopes(actor.historialScope, filtro.scope).
+		Select(`historial_clinico.id, historial_clinico.id_enfermedad, historial_clinico.enfermedad, historial_clinico.id_hospital,
+			to_char(historial_clinico.fecha_ingreso, 'YYYY-MM-DD') AS fecha_ingreso,
+			to_char(historial_clinico.consultation_date, 'YYYY-MM-DD') AS consultation_date,
+			historial_clinico.is_contagious, historial_clinico.patient_district, historial_clinico.patient_neighborhood,
+			historial_clinico.patient_latitude, historial_clinico.patient_longitude`).
+		Order("historial_clinico.fecha_ingreso DESC").
+		Order("historial_clinico.id DESC").
+		Limit(limit).
+		Scan(&casos).Error
+	if err != nil {
+		return nil, err
+	}
+
+	coleccion := utils.NuevaFeatureCollection()
+	ids := make([]uint, len(casos))
+	for i, caso := range casos {
+		ids[i] = caso.ID
+		coleccion.Agregar(caso.ID, utils.PuntoGeoJSON(caso.PatientLatitude, caso.PatientLongitude), map[string]interface{}{
+			"id_enfermedad":     caso.IDEnfermedad,
+			"enfermedad":        caso.Enfermedad,
+			"id_hospital":       caso.IDHospital,
+			"fecha_ingreso":     caso.FechaIngreso,
+			"consultation_date": caso.ConsultationDate,
+			"is_contagious":     caso.IsContagious,
+			"distrito":          caso.PatientDistrict,
+			"barrio":            caso.PatientNeighborhood,
+		})
+	}
+
+	err = auditarLectura(s.db, actor, models.EntidadHistorial, ids, map[string]interface{}{
+		"filtros": filtro,
+		"formato": "geojson",
+		"limit":   limit,
+	})
+
+	return coleccion, err
+}
+
+// GetHospitalesGeoJSON obtiene los hospitales como puntos de GeoJSON
+func (s *MapaService) GetHospitalesGeoJSON() (*utils.FeatureCollection, error) {
+	var hospitales []models.Hospital
+	if err := s.db.Order("id").Find(&hospitales).Error; err != nil {
+		return nil, err
+	}
+
+	coleccion := utils.NuevaFeatureCollection()
+	for _, hospital := range hospitales {
+		coleccion.Agregar(hospital.ID, utils.PuntoGeoJSON(hospital.Latitud, hospital.Longitud), map[string]interface{}{
+			"nombre":    hospital.Nombre,
+			"direccion": hospital.Direccion,
+			"ciudad":    hospital.Ciudad,
+			"telefono":  hospital.Telefono,
+		})
+	}
+	return coleccion, nil
+}
+
+// GetDistritosGeoJSON obtiene los casos de todos los hospitales que cumplen los filtros agregados por
+// distrito. La geometría es el contorno oficial si se importó o, si no, el centroide del distrito. Los
+// casos sin distrito asignado se cuentan por el nombre registrado en el historial.
+func (s *MapaService) GetDistritosGeoJSON(filtro HistorialFilter) (*utils.FeatureCollection, error) {
+	var distritos []models.Distrito
+	if err := s.db.Order("nombre").Find(&distritos).Error; err != nil {
+		return nil, err
+	}
+
+	var conteos []struct {
+		IDDistrito       uint
+		TotalCasos       int64
+		CasosContagiosos int64
+	}
+	err := s.db.Model(&models.HistorialClinico{}).
+		Scopes(filtro.scope).
+		Joins(`JOIN distritos d ON d.id = historial_clinico.id_distrito OR
+			(historial_clinico.id_distrito IS NULL AND f_normalizar(d.nombre) = f_normalizar(historial_clinico.patient_district))`).
+		Select("d.id AS id_distrito, COUNT(*) AS total_casos, COUNT(*) FILTER (WHERE historial_clinico.is_contagious) AS casos_contagiosos").
+		Group("d.id").
+		Scan(&conteos).Error
+	if err != nil {
+		return nil, err
+	}
+	porDistrito := make(map[uint]int, len(conteos))
+	for i, conteo := range conteos {
+		porDistrito[conteo.IDDistrito] = i
+	}
+
+	coleccion := utils.NuevaFeatureCollection()
+	for i := range distritos {
+		distrito := &distritos[i]
+		completarDensidad(distrito)
+
+		var total, contagiosos int64
+		if j, ok := porDistrito[distrito.ID]; ok {
+			total, contagiosos = conteos[j].TotalCasos, conteos[j].CasosContagiosos
+		}
+		incidencia := 0.0
+		if distrito.Habitantes > 0 {
+			incidencia = redondear(float64(total)/float64(distrito.Habitantes)*100000, 2)
+		}
+
+		geometria := distrito.Limite
+		if len(geometria) == 0 {
+			geometria = utils.PuntoGeoJSON(distrito.Latitud, distrito.Longitud)
+		}
+		coleccion.Agregar(distrito.ID, geometria, map[string]interface{}{
+			"nombre":            distrito.Nombre,
+			"habitantes":        distrito.Habitantes,
+			"densidad_hab_km2":  distrito.Densidad,
+			"tipo_zona":         distrito.TipoZona,
+			"tiene_limite":      distrito.TieneLimite,
+			"total_casos":       total,
+			"casos_contagiosos": contagiosos,
+			"incidencia_100k":   incidencia,
+		})
+	}
+	return coleccion, nil
+}
+
+// GetTileCasos genera el vector tile z/x/y con la capa "casos" de los casos visibles para el actor que
+// cumplen los filtros. Desde el zoom 14 cada caso es un punto con su enfermedad, fecha y si es
+// contagioso; con menos zoom los casos cercanos se agrupan en un punto con los totales "casos" y
+// "contagiosos". El tile no identifica historiales ni pacientes. Un tile sin casos se retorna vacío.
+func (s *MapaService) GetTileCasos(actor Actor, filtro HistorialFilter, z, x, y int) ([]byte, error) {
+	puntos := s.db.Model(&models.HistorialClinico{}).
+		Scopes(actor.historialScope, filtro.scope).
+		Select(`ST_AsMVTGeom(ST_Transform(historial_clinico.ubicacion::geometry, 3857), ST_TileEnvelope(?, ?, ?), ?, ?, true) AS geom,
+			historial_clinico.id_enfermedad, historial_clinico.enfermedad, historial_clinico.is_contagious,
+			to_char(historial_clinico.fecha_ingreso, 'YYYY-MM-DD') AS fecha_ingreso`,
+			z, x, y, extensionTile, bufferTile).
+		Where("historial_clinico.ubicacion::geometry && ST_Transform(ST_TileEnvelope(?, ?, ?, margin => ?), 4326)",
+			z, x, y, float64(bufferTile)/extensionTile)
+
+	var consulta *gorm.DB
+	if z >= zoomDetalle {
+		consulta = s.db.Raw(`SELECT ST_AsMVT(t, 'casos', ?, 'geom') FROM (?) AS t WHERE t.geom IS NOT NULL`,
+			extensionTile, puntos)
+	} else {
+		consulta = s.db.Raw(`SELECT ST_AsMVT(t, 'casos', ?, 'geom') FROM (
+				SELECT ST_SnapToGrid(p.geom, ?) AS geom, COUNT(*) AS casos,
+					COUNT(*) FILTER (WHERE p.is_contagious) AS contagiosos
+				FROM (?) AS p
+				WHERE p.geom IS NOT NULL
+				GROUP BY 1
+			) AS t`, extensionTile, celdaTile, puntos)
+	}
+
+	var tile []byte
+	if err := consulta.Row().Scan(&tile); err != nil {
+		return nil, err
+	}
+	return tile, nil
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"hospital-api/internal/database"
+	"hospital-api/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrTransicionNotificacion indica un cambio de estado que no sigue pendiente → enviada → recibida
+var ErrTransicionNotificacion = errors.New("transición de estado de la notificación no permitida")
+
+type NotificacionService struct {
+	db *gorm.DB
+}
+
+// NotificacionFilter filtros de la bandeja de notificaciones
+type NotificacionFilter struct {
+	Estado       string
+	IDEnfermedad *uint
+	IDHospital   *uint
+	Desde        *time.Time // Fecha de generación, inclusiva
+	Hasta        *time.Time // Fecha de generación, exclusiva
+}
+
+// CambioEstadoNotificacionRequest cuerpo opcional al enviar una notificación o acusar su recibo
+type CambioEstadoNotificacionRequest struct {
+	Observaciones string `json:"observaciones" binding:"max=1000"`
+}
+
+// NewNotificacionService crea una nueva instancia del servicio de notificaciones epidemiológicas
+func NewNotificacionService() *NotificacionService {
+	return &NotificacionService{
+		db: database.GetDB(),
+	}
+}
+
+// GetNotificaciones obtiene la bandeja de notificaciones visible para el actor, las más recientes primero
+func (s *NotificacionService) GetNotificaciones(actor Actor, filtro NotificacionFilter, page, limit int) ([]models.NotificacionEpidemiologica, int64, error) {
+	var notificaciones []models.NotificacionEpidemiologica
+	var total int64
+
+	query := s.db.Model(&models.NotificacionEpidemiologica{}).Scopes(actor.notificacionScope)
+
+	if filtro.Estado != "" {
+		query = query.Where("estado = ?", filtro.Estado)
+	}
+	if filtro.IDEnfermedad != nil {
+		query = query.Where("id_enfermedad = ?", *filtro.IDEnfermedad)
+	}
+	if filtro.IDHospital != nil {
+		query = query.Where("id_hospital = ?", *filtro.IDHospital)
+	}
+	if filtro.Desde != nil {
+		query = query.Where("created_at >= ?", *filtro.Desde)
+	}
+	if filtro.Hasta != nil {
+		query = query.Where("created_at < ?", *filtro.Hasta)
+	}
+
+	// Contar total
+	query.Count(&total)
+
+	offset := (page - 1) * limit
+	err := query.Preload("Historial").Preload("Enfermedad").Preload("Hospital").
+		Offset(offset).Limit(limit).
+		Order("created_at DESC, id DESC").
+		Find(&notificaciones).Error
+
+	return notificaciones, total, err
+}
+
+// GetNotificacionByID obtiene una notificación visible para el actor con su historial y enfermedad
+func (s *NotificacionService) GetNotificacionByID(actor Actor, id uint) (*models.NotificacionEpidemiologica, error) {
+	var notificacion models.NotificacionEpidemiologica
+	err := s.db.Scopes(actor.notificacionScope).
+		Preload("Historial").Preload("Enfermedad").Preload("Hospital").
+		First(&notificacion, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("notificación no encontrada")
+		}
+		return nil, err
+	}
+	return &notificacion, nil
+}
+
+// EnviarNotificacion marca como enviada al equipo de vigilancia una notificación pendiente del hospital del actor
+func (s *NotificacionService) EnviarNotificacion(actor Actor, id uint, observaciones string) (*models.NotificacionEpidemiologica, error) {
+	return s.cambiarEstado(actor, id, models.NotificacionPendiente, func(n *models.NotificacionEpidemiologica, ahora time.Time) {
+		n.Estado = models.NotificacionEnviada
+		n.EnviadaAt = &ahora
+		n.IDUsuarioEnvio = &actor.UsuarioID
+		if observaciones != "" {
+			n.Observaciones = observaciones
+		}
+	})
+}
+
+// AcusarRecibo registra la recepción de una notificación enviada por parte del equipo de vigilancia
+func (s *NotificacionService) AcusarRecibo(actor Actor, id uint, observaciones string) (*models.NotificacionEpidemiologica, error) {
+	return s.cambiarEstado(actor, id, models.NotificacionEnviada, func(n *models.NotificacionEpidemiologica, ahora time.Time) {
+		n.Estado = models.NotificacionRecibida
+		n.RecibidaAt = &ahora
+		n.IDUsuarioRecepcion = &actor.UsuarioID
+		if observaciones != "" {
+			n.Observaciones = observaciones
+		}
+	})
+}
+
+// cambiarEstado aplica una transición sobre una notificación en estado desde, bloqueándola durante la operación
+func (s *NotificacionService) cambiarEstado(actor Actor, id uint, desde string, aplicar func(*models.NotificacionEpidemiologica, time.Time)) (*models.NotificacionEpidemiologica, error) {
+	var despues models.NotificacionEpidemiologica
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var antes models.NotificacionEpidemiologica
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(actor.notificacionScope).First(&antes, id).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("notificación no encontrada")
+			}
+			return err
+		}
+
+		if antes.Estado != desde {
+			return ErrTransicionNotificacion
+		}
+
+		despues = antes
+		aplicar(&despues, time.Now())
+		if err := tx.Save(&despues).Error; err != nil {
+			return err
+		}
+
+		return auditar(tx, actor, models.AccionActualizar, models.EntidadNotificacion, id, antes, despues, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &despues, nil
+}
+
+// registrarNotificacion genera la notificación pendiente de un historial cuya enfermedad es de notificación
+// obligatoria. Es idempotente: un historial no se notifica dos veces por la misma enfermedad.
+func registrarNotificacion(tx *gorm.DB, actor Actor, historial *models.HistorialClinico) error {
+	if !historial.NotificacionObligatoria || historial.IDEnfermedad == nil {
+		return nil
+	}
+
+	notificacion := models.NotificacionEpidemiologica{
+		IDHistorial:  historial.ID,
+		IDEnfermedad: *historial.IDEnfermedad,
+		IDHospital:   historial.IDHospital,
+		Estado:       models.NotificacionPendiente,
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&notificacion)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+
+	return auditar(tx, actor, models.AccionCrear, models.EntidadNotificacion, notificacion.ID, nil, notificacion, nil)
+}
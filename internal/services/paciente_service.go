@@ -1,18 +1,53 @@
 package services
 
 import (
+	"encoding/json"
 	"errors"
+	"html"
+	"strings"
+	"time"
 
 	"hospital-api/internal/database"
 	"hospital-api/internal/models"
+	"hospital-api/internal/utils"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// nombreBusquedaSQL nombre del paciente en minúsculas y sin acentos, cubierto por idx_pacientes_nombre_trgm
+const nombreBusquedaSQL = "f_unaccent(lower(pacientes.nombre))"
+
+// ultimoHospitalSQL hospital del historial clínico más reciente del paciente
+const ultimoHospitalSQL = `(SELECT hc.id_hospital FROM historial_clinico hc
+	WHERE hc.id_paciente = pacientes.id AND hc.deleted_at IS NULL
+	ORDER BY hc.fecha_ingreso DESC, hc.id DESC LIMIT 1)`
+
+// ErrPacienteConHospital indica que se intentó reclamar un paciente que ya tiene hospital de registro
+var ErrPacienteConHospital = errors.New("el paciente ya tiene hospital de registro")
+
 type PacienteService struct {
 	db *gorm.DB
 }
 
+// PacienteSearchFilter criterios de búsqueda de pacientes; los campos vacíos no filtran
+type PacienteSearchFilter struct {
+	Q              string // Nombre o CI
+	Sexo           string
+	TipoSangre     string
+	EdadMin        *int
+	EdadMax        *int
+	UltimoHospital *uint // Hospital de la última atención registrada
+}
+
+// PacienteBusqueda resultado de búsqueda: el paciente con su relevancia y las coincidencias resaltadas con <mark>
+type PacienteBusqueda struct {
+	models.Paciente
+	Relevancia       float64           `json:"relevancia"`
+	IDUltimoHospital *uint             `json:"id_ultimo_hospital"`
+	Resaltado        map[string]string `json:"resaltado,omitempty"`
+}
+
 // NewPacienteService crea una nueva instancia del servicio de pacientes
 func NewPacienteService() *PacienteService {
 	return &PacienteService{
@@ -20,63 +55,383 @@ func NewPacienteService() *PacienteService {
 	}
 }
 
-// CreatePaciente crea un nuevo paciente
-func (s *PacienteService) CreatePaciente(paciente *models.Paciente) error {
-	return s.db.Create(paciente).Error
+// CreatePaciente crea un nuevo paciente registrado por el hospital del actor
+func (s *PacienteService) CreatePaciente(actor Actor, paciente *models.Paciente) error {
+	paciente.IDHospital = &actor.HospitalID
+	paciente.Nombre = paciente.NombreCompleto()
+	paciente.Version = 1
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(paciente).Error; err != nil {
+			return err
+		}
+		return auditar(tx, actor, models.AccionCrear, models.EntidadPaciente, paciente.ID, nil, paciente, nil)
+	})
 }
 
 // GetPacienteByID obtiene un paciente por ID
-func (s *PacienteService) GetPacienteByID(id uint) (*models.Paciente, error) {
+func (s *PacienteService) GetPacienteByID(actor Actor, id uint) (*models.Paciente, error) {
 	var paciente models.Paciente
-	err := s.db.First(&paciente, id).Error
+	err := s.db.Scopes(actor.pacienteScope).First(&paciente, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("paciente no encontrado")
 		}
 		return nil, err
 	}
+
+	if err := auditar(s.db, actor, models.AccionLeer, models.EntidadPaciente, paciente.ID, nil, nil, nil); err != nil {
+		return nil, err
+	}
 	return &paciente, nil
 }
 
 // GetAllPacientes obtiene todos los pacientes con paginación
-func (s *PacienteService) GetAllPacientes(page, limit int) ([]models.Paciente, int64, error) {
+func (s *PacienteService) GetAllPacientes(actor Actor, page, limit int) ([]models.Paciente, int64, error) {
 	var pacientes []models.Paciente
 	var total int64
 
 	// Contar total de registros
-	s.db.Model(&models.Paciente{}).Count(&total)
+	s.db.Model(&models.Paciente{}).Scopes(actor.pacienteScope).Count(&total)
 
 	// Obtener registros con paginación
 	offset := (page - 1) * limit
-	err := s.db.Offset(offset).Limit(limit).Find(&pacientes).Error
+	err := s.db.Scopes(actor.pacienteScope).Offset(offset).Limit(limit).Find(&pacientes).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = auditarLectura(s.db, actor, models.EntidadPaciente, pacienteIDs(pacientes), map[string]interface{}{
+		"page":  page,
+		"limit": limit,
+	})
 
 	return pacientes, total, err
 }
 
-// UpdatePaciente actualiza un paciente
-func (s *PacienteService) UpdatePaciente(id uint, updates *models.Paciente) error {
-	return s.db.Model(&models.Paciente{}).Where("id = ?", id).Updates(updates).Error
+// UpdatePaciente actualiza un paciente registrado por el hospital del actor si su versión coincide con ifMatch
+func (s *PacienteService) UpdatePaciente(actor Actor, id uint, ifMatch []int, updates *models.Paciente) (*models.Paciente, error) {
+	// El hospital de registro no se puede reasignar desde una actualización
+	updates.IDHospital = nil
+
+	var despues models.Paciente
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var antes models.Paciente
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(actor.pacienteWriteScope).First(&antes, id).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("paciente no encontrado")
+			}
+			return err
+		}
+
+		if err := verificarVersion(antes.Version, ifMatch); err != nil {
+			return err
+		}
+		updates.Version = antes.Version + 1
+
+		// El nombre completo se recalcula si cambia el nombre estructurado
+		if updates.Nombres != "" || updates.Apellidos != "" {
+			combinado := antes
+			if updates.Nombres != "" {
+				combinado.Nombres = updates.Nombres
+			}
+			if updates.Apellidos != "" {
+				combinado.Apellidos = updates.Apellidos
+			}
+			updates.Nombre = combinado.NombreCompleto()
+		}
+
+		if err := tx.Model(&models.Paciente{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if err := tx.First(&despues, id).Error; err != nil {
+			return err
+		}
+
+		return auditar(tx, actor, models.AccionActualizar, models.EntidadPaciente, id, antes, despues, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &despues, nil
 }
 
-// DeletePaciente elimina un paciente (soft delete)
-func (s *PacienteService) DeletePaciente(id uint) error {
-	return s.db.Delete(&models.Paciente{}, id).Error
+// PatchPaciente aplica un JSON Merge Patch (RFC 7396) a un paciente registrado por el hospital del actor si su versión coincide con ifMatch.
+// Los campos enviados como null se vacían y el resultado se valida completo antes de guardarse.
+func (s *PacienteService) PatchPaciente(actor Actor, id uint, ifMatch []int, patch []byte) (*models.Paciente, error) {
+	var despues models.Paciente
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var antes models.Paciente
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(actor.pacienteWriteScope).First(&antes, id).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("paciente no encontrado")
+			}
+			return err
+		}
+
+		if err := verificarVersion(antes.Version, ifMatch); err != nil {
+			return err
+		}
+
+		var parcheado models.Paciente
+		campos, err := aplicarMergePatch(antes, patch, camposEditablesPaciente, &parcheado)
+		if err != nil {
+			return err
+		}
+
+		if campos["nombres"] || campos["apellidos"] {
+			parcheado.Nombre = parcheado.NombreCompleto()
+			campos["nombre"] = true
+		}
+
+		if err := validate.Struct(parcheado); err != nil {
+			return err
+		}
+		parcheado.Version = antes.Version + 1
+
+		err = tx.Model(&models.Paciente{}).
+			Where("id = ?", id).
+			Select(columnasModificadas(campos, "version")).
+			Updates(&parcheado).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.First(&despues, id).Error; err != nil {
+			return err
+		}
+
+		return auditar(tx, actor, models.AccionActualizar, models.EntidadPaciente, id, antes, despues, map[string]interface{}{
+			"patch": json.RawMessage(patch),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &despues, nil
 }
 
-// SearchPacientes busca pacientes por nombre
-func (s *PacienteService) SearchPacientes(query string, page, limit int) ([]models.Paciente, int64, error) {
-	var pacientes []models.Paciente
-	var total int64
+// DeletePaciente elimina un paciente registrado por el hospital del actor (soft delete) si su versión coincide con ifMatch
+func (s *PacienteService) DeletePaciente(actor Actor, id uint, ifMatch []int) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var antes models.Paciente
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(actor.pacienteWriteScope).First(&antes, id).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("paciente no encontrado")
+			}
+			return err
+		}
+
+		if err := verificarVersion(antes.Version, ifMatch); err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&models.Paciente{}, id).Error; err != nil {
+			return err
+		}
+
+		return auditar(tx, actor, models.AccionEliminar, models.EntidadPaciente, id, antes, nil, nil)
+	})
+}
 
-	// Buscar por nombre (insensible a mayúsculas)
-	searchQuery := s.db.Where("LOWER(nombre) LIKE LOWER(?)", "%"+query+"%")
+// SearchPacientes busca pacientes por nombre (sin distinguir acentos, por similitud de trigramas) o por CI,
+// con filtros opcionales. Los resultados se ordenan por relevancia e incluyen las coincidencias resaltadas.
+func (s *PacienteService) SearchPacientes(actor Actor, filtro PacienteSearchFilter, page, limit int) ([]PacienteBusqueda, int64, error) {
+	query := s.db.Model(&models.Paciente{}).Scopes(actor.pacienteScope)
 
-	// Contar total
-	searchQuery.Model(&models.Paciente{}).Count(&total)
+	terminos := strings.Fields(utils.NormalizarTexto(filtro.Q))
+	ci := ""
+	if strings.ContainsAny(filtro.Q, "0123456789") {
+		ci = utils.NormalizarCI(filtro.Q)
+	}
+
+	relevancia := "0"
+	var relevanciaArgs []interface{}
+	if len(terminos) > 0 {
+		q := strings.Join(terminos, " ")
+
+		// Todas las palabras contenidas en el nombre, o el texto parecido a alguna parte del nombre
+		condiciones := make([]string, len(terminos))
+		args := make([]interface{}, 0, len(terminos)+2)
+		for i, termino := range terminos {
+			condiciones[i] = nombreBusquedaSQL + " LIKE ?"
+			args = append(args, "%"+termino+"%")
+		}
+		condicion := "((" + strings.Join(condiciones, " AND ") + ") OR ? <% " + nombreBusquedaSQL
+		args = append(args, q)
+		relevancia = "word_similarity(?, " + nombreBusquedaSQL + ")"
+		relevanciaArgs = append(relevanciaArgs, q)
 
-	// Obtener resultados con paginación
+		if ci != "" {
+			condicion += " OR (" + ciNormalizadoSQL + " <> '' AND " + ciNormalizadoSQL + " LIKE ?)"
+			args = append(args, ci+"%")
+			relevancia = "GREATEST(" + relevancia + ", CASE WHEN " + ciNormalizadoSQL + " LIKE ? THEN 1 ELSE 0 END)"
+			relevanciaArgs = append(relevanciaArgs, ci+"%")
+		}
+		query = query.Where(condicion+")", args...)
+	}
+
+	if filtro.Sexo != "" {
+		query = query.Where("pacientes.sexo = ?", filtro.Sexo)
+	}
+	if filtro.TipoSangre != "" {
+		query = query.Where("pacientes.tipo_sangre = ?", filtro.TipoSangre)
+	}
+	hoy := time.Now()
+	if filtro.EdadMin != nil {
+		query = query.Where("pacientes.fecha_nacimiento <= ?", hoy.AddDate(-*filtro.EdadMin, 0, 0))
+	}
+	if filtro.EdadMax != nil {
+		query = query.Where("pacientes.fecha_nacimiento > ?", hoy.AddDate(-(*filtro.EdadMax+1), 0, 0))
+	}
+	if filtro.UltimoHospital != nil {
+		query = query.Where(ultimoHospitalSQL+" = ?", *filtro.UltimoHospital)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var filas []struct {
+		ID               uint
+		Relevancia       float64
+		IDUltimoHospital *uint
+	}
 	offset := (page - 1) * limit
-	err := searchQuery.Offset(offset).Limit(limit).Find(&pacientes).Error
+	err := query.
+		Select("pacientes.id, "+relevancia+" AS relevancia, "+ultimoHospitalSQL+" AS id_ultimo_hospital", relevanciaArgs...).
+		Order("relevancia DESC, pacientes.nombre, pacientes.id").
+		Offset(offset).Limit(limit).
+		Scan(&filas).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]uint, len(filas))
+	for i, fila := range filas {
+		ids[i] = fila.ID
+	}
+	var pacientes []models.Paciente
+	if err := s.db.Where("id IN ?", ids).Find(&pacientes).Error; err != nil {
+		return nil, 0, err
+	}
+	porID := make(map[uint]models.Paciente, len(pacientes))
+	for _, p := range pacientes {
+		porID[p.ID] = p
+	}
+
+	resultados := make([]PacienteBusqueda, 0, len(filas))
+	for _, fila := range filas {
+		resultado := PacienteBusqueda{
+			Paciente:         porID[fila.ID],
+			Relevancia:       fila.Relevancia,
+			IDUltimoHospital: fila.IDUltimoHospital,
+		}
+		resaltado := map[string]string{}
+		if nombre, ok := utils.Resaltar(resultado.Nombre, terminos); ok {
+			resaltado["nombre"] = nombre
+		}
+		if ci != "" && strings.HasPrefix(utils.NormalizarCI(resultado.CI), ci) {
+			resaltado["ci"] = "<mark>" + html.EscapeString(resultado.CI) + "</mark>"
+		}
+		if len(resaltado) > 0 {
+			resultado.Resaltado = resaltado
+		}
+		resultados = append(resultados, resultado)
+	}
+
+	err = auditarLectura(s.db, actor, models.EntidadPaciente, ids, map[string]interface{}{
+		"q":               filtro.Q,
+		"sexo":            filtro.Sexo,
+		"tipo_sangre":     filtro.TipoSangre,
+		"edad_min":        filtro.EdadMin,
+		"edad_max":        filtro.EdadMax,
+		"ultimo_hospital": filtro.UltimoHospital,
+		"page":            page,
+		"limit":           limit,
+	})
+
+	return resultados, total, err
+}
+
+// GetPacientesSinHospital lista los pacientes sin hospital de registro ni historiales, que ningún
+// hospital puede ver hasta que un administrador los reclama
+func (s *PacienteService) GetPacientesSinHospital(actor Actor, page, limit int) ([]models.Paciente, int64, error) {
+	query := s.db.Model(&models.Paciente{}).Where("pacientes.id_hospital IS NULL")
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var pacientes []models.Paciente
+	err := query.Order("pacientes.id").Offset((page - 1) * limit).Limit(limit).Find(&pacientes).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = auditarLectura(s.db, actor, models.EntidadPaciente, pacienteIDs(pacientes), map[string]interface{}{
+		"reporte": "sin_hospital",
+		"page":    page,
+		"limit":   limit,
+	})
 
 	return pacientes, total, err
 }
+
+// ReclamarPaciente asigna al hospital del actor un paciente sin hospital de registro
+func (s *PacienteService) ReclamarPaciente(actor Actor, id uint) (*models.Paciente, error) {
+	var despues models.Paciente
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var antes models.Paciente
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&antes, id).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("paciente no encontrado")
+			}
+			return err
+		}
+		if antes.IDHospital != nil {
+			return ErrPacienteConHospital
+		}
+
+		err = tx.Model(&models.Paciente{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"id_hospital": actor.HospitalID,
+			"version":     antes.Version + 1,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.First(&despues, id).Error; err != nil {
+			return err
+		}
+
+		return auditar(tx, actor, models.AccionActualizar, models.EntidadPaciente, id, antes, despues, map[string]interface{}{
+			"reclamado": true,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &despues, nil
+}
+
+// pacienteIDs retorna los IDs de una lista de pacientes
+func pacienteIDs(pacientes []models.Paciente) []uint {
+	ids := make([]uint, len(pacientes))
+	for i := range pacientes {
+		ids[i] = pacientes[i].ID
+	}
+	return ids
+}
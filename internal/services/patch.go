@@ -0,0 +1,103 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"hospital-api/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ErrPatchInvalido indica un documento merge patch mal formado o que modifica campos no editables
+var ErrPatchInvalido = errors.New("merge patch inválido")
+
+// validate valida los recursos resultantes de aplicar un merge patch
+var validate = validator.New()
+
+// camposEditablesPaciente campos de un paciente que se pueden modificar con PATCH
+var camposEditablesPaciente = map[string]bool{
+	"ci":               true,
+	"nombres":          true,
+	"apellidos":        true,
+	"nombre":           true,
+	"fecha_nacimiento": true,
+	"sexo":             true,
+	"tipo_sangre":      true,
+	"peso_kg":          true,
+	"altura_cm":        true,
+	"telefono":         true,
+	"direccion":        true,
+}
+
+// camposEditablesHistorial campos de un historial clínico que se pueden modificar con PATCH.
+// Las coordenadas no son editables: se recalculan al cambiar patient_address.
+var camposEditablesHistorial = map[string]bool{
+	"fecha_ingreso":        true,
+	"motivo_consulta":      true,
+	"enfermedad":           true,
+	"id_enfermedad":        true,
+	"diagnostico":          true,
+	"tratamiento":          true,
+	"medicamentos":         true,
+	"observaciones":        true,
+	"patient_address":      true,
+	"patient_district":     true,
+	"patient_neighborhood": true,
+	"consultation_date":    true,
+	"symptoms_start_date":  true,
+	"is_contagious":        true,
+	"motivo_contagioso":    true,
+}
+
+// aplicarMergePatch aplica patch (RFC 7396) sobre actual y decodifica el resultado en destino.
+// Retorna los campos modificados; falla si el patch toca campos fuera de editables.
+func aplicarMergePatch(actual interface{}, patch []byte, editables map[string]bool, destino interface{}) (map[string]bool, error) {
+	campos, err := utils.MergePatchKeys(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPatchInvalido, err)
+	}
+	if len(campos) == 0 {
+		return nil, fmt.Errorf("%w: el documento no contiene cambios", ErrPatchInvalido)
+	}
+
+	var noEditables []string
+	for campo := range campos {
+		if !editables[campo] {
+			noEditables = append(noEditables, campo)
+		}
+	}
+	if len(noEditables) > 0 {
+		sort.Strings(noEditables)
+		return nil, fmt.Errorf("%w: campos no editables %v", ErrPatchInvalido, noEditables)
+	}
+
+	original, err := json.Marshal(actual)
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := utils.ApplyMergePatch(original, patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPatchInvalido, err)
+	}
+
+	if err := json.Unmarshal(merged, destino); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPatchInvalido, err)
+	}
+
+	return campos, nil
+}
+
+// columnasModificadas retorna los campos modificados como lista de columnas para Select
+func columnasModificadas(campos map[string]bool, extra ...string) []string {
+	columnas := make([]string, 0, len(campos)+len(extra))
+	for campo := range campos {
+		columnas = append(columnas, campo)
+	}
+	columnas = append(columnas, extra...)
+	sort.Strings(columnas)
+	return columnas
+}
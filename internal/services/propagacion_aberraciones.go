@@ -0,0 +1,490 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"hospital-api/internal/models"
+	"hospital-api/internal/utils"
+)
+
+// Métodos de detección de aberraciones sobre la serie diaria de casos
+const (
+	MetodoEARSC1     = "ears_c1"
+	MetodoEARSC2     = "ears_c2"
+	MetodoEARSC3     = "ears_c3"
+	MetodoCUSUM      = "cusum"
+	MetodoFarrington = "farrington"
+)
+
+// MetodosAberracion métodos disponibles, en el orden en que se reportan
+var MetodosAberracion = []string{MetodoEARSC1, MetodoEARSC2, MetodoEARSC3, MetodoCUSUM, MetodoFarrington}
+
+// Parámetros de los algoritmos. Son los valores habituales de los sistemas de vigilancia (CDC EARS, Farrington 1996).
+const (
+	earsLineaBase   = 7   // Días de línea base de C1, C2 y C3
+	earsGuarda      = 2   // Días de separación entre la línea base y el día evaluado en C2 y C3
+	earsUmbral      = 3.0 // Desviaciones estándar sobre la media para C1 y C2
+	earsUmbralC3    = 2.0 // Umbral de la suma de C3
+	earsSigmaMinima = 1.0 // Evita que una línea base sin variación alerte con un solo caso
+
+	cusumLineaBase = 28  // Días de línea base de CUSUM
+	cusumGuarda    = 7   // Separación que mantiene un brote en curso fuera de la línea base
+	cusumK         = 0.5 // Valor de referencia (desviaciones estándar toleradas por día)
+	cusumH         = 4.0 // Umbral de decisión
+
+	farringtonAnios        = 3    // Años anteriores usados como línea base
+	farringtonVentana      = 7    // Días a cada lado de la misma fecha en los años anteriores
+	farringtonMinimoLineas = 10   // Días de línea base mínimos para evaluar
+	farringtonZ            = 1.96 // Cuantil normal del umbral superior (alfa = 0,05)
+)
+
+// AnalisisAberraciones días en que la serie diaria de casos se aparta de lo esperado, por distrito
+type AnalisisAberraciones struct {
+	Enfermedad      string              `json:"enfermedad"`
+	PeriodoAnalisis PeriodoAnalisis     `json:"periodo_analisis"`
+	Metodos         []string            `json:"metodos"`
+	InicioDatos     *time.Time          `json:"inicio_datos"` // Primer registro del sistema; antes no hay línea base
+	Distritos       []SerieAberraciones `json:"distritos"`
+}
+
+// SerieAberraciones resultado de un distrito
+type SerieAberraciones struct {
+	Distrito         string         `json:"distrito"`
+	TotalCasos       int            `json:"total_casos"`
+	SenalesPorMetodo map[string]int `json:"senales_por_metodo"`
+	DiasSenalados    []DiaAberrante `json:"dias_senalados"`
+}
+
+// DiaAberrante día señalado por al menos un método
+type DiaAberrante struct {
+	Fecha   time.Time         `json:"fecha"`
+	Casos   int               `json:"casos"`
+	Senales []SenalAberracion `json:"senales"`
+}
+
+// SenalAberracion resultado de un método en un día señalado
+type SenalAberracion struct {
+	Metodo      string   `json:"metodo"`
+	Estadistico float64  `json:"estadistico"`     // C1/C2/C3, suma acumulada o casos observados (Farrington)
+	Umbral      float64  `json:"umbral"`          // Valor que el estadístico superó
+	Esperado    float64  `json:"casos_esperados"` // Media de la línea base
+	PValor      *float64 `json:"p_valor"`         // Nil en C3 y CUSUM, que no tienen una distribución de referencia directa
+}
+
+// serieDiaria casos por día de un distrito, desde el inicio de la línea base hasta el fin del análisis
+type serieDiaria struct {
+	inicio      time.Time
+	casos       []float64
+	validoDesde int // Primer índice con datos reales; antes no existían registros en el sistema
+}
+
+// DetectarAberraciones aplica EARS C1/C2/C3, CUSUM y una línea base de tipo Farrington a los casos diarios
+// de una enfermedad en cada distrito (o solo en distrito) durante los últimos dias días
+func (s *PropagacionService) DetectarAberraciones(enfermedad, distrito string, dias int, metodos []string) (*AnalisisAberraciones, error) {
+	hoy := time.Now().UTC().Truncate(24 * time.Hour)
+	fechaFin := hoy.AddDate(0, 0, 1)
+	fechaInicio := fechaFin.AddDate(0, 0, -dias)
+	inicioSerie := fechaInicio.AddDate(-farringtonAnios, 0, -farringtonVentana)
+	if !slices.Contains(metodos, MetodoFarrington) {
+		inicioSerie = fechaInicio.AddDate(0, 0, -(cusumLineaBase + cusumGuarda))
+	}
+
+	var inicioDatos sql.NullTime
+	if err := s.db.Model(&models.HistorialClinico{}).Select("MIN(consultation_date)").Scan(&inicioDatos).Error; err != nil {
+		return nil, err
+	}
+
+	var filas []struct {
+		Fecha    time.Time
+		Distrito string
+		Casos    int
+	}
+	query := s.db.Model(&models.HistorialClinico{}).
+		Select("consultation_date::date AS fecha, patient_district AS distrito, COUNT(*) AS casos").
+		Where(coincideEnfermedadSQL, sql.Named("enfermedad", enfermedad)).
+		Where("consultation_date >= ? AND consultation_date < ?", inicioSerie, fechaFin)
+	if distrito != "" {
+		query = query.Where("f_normalizar(patient_district) = f_normalizar(?)", distrito)
+	}
+	err := query.Group("consultation_date::date, patient_district").Order("fecha, distrito").Scan(&filas).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Las series se agrupan por nombre normalizado, como en el registro de distritos, para que las variantes
+	// de mayúsculas y acentos de los historiales cuenten en el mismo distrito, que se muestra con su nombre
+	// del registro
+	var registrados []string
+	if err := s.db.Model(&models.Distrito{}).Pluck("nombre", &registrados).Error; err != nil {
+		return nil, err
+	}
+	nombres := make(map[string]string, len(registrados))
+	for _, nombre := range registrados {
+		nombres[utils.NormalizarTexto(nombre)] = nombre
+	}
+
+	validoDesde := 0
+	if inicioDatos.Valid {
+		validoDesde = max(0, diasEntre(inicioSerie, diaUTC(inicioDatos.Time)))
+	}
+
+	totalDias := diasEntre(inicioSerie, fechaFin)
+	series := make(map[string]*serieDiaria)
+	if distrito != "" {
+		clave := utils.NormalizarTexto(distrito)
+		if _, ok := nombres[clave]; !ok {
+			nombres[clave] = distrito
+		}
+		series[clave] = &serieDiaria{inicio: inicioSerie, casos: make([]float64, totalDias), validoDesde: validoDesde}
+	}
+	for _, fila := range filas {
+		clave := utils.NormalizarTexto(fila.Distrito)
+		if _, ok := nombres[clave]; !ok {
+			nombres[clave] = fila.Distrito
+		}
+		serie, ok := series[clave]
+		if !ok {
+			serie = &serieDiaria{inicio: inicioSerie, casos: make([]float64, totalDias), validoDesde: validoDesde}
+			series[clave] = serie
+		}
+		if i := diasEntre(inicioSerie, diaUTC(fila.Fecha)); i >= 0 && i < totalDias {
+			serie.casos[i] += float64(fila.Casos)
+		}
+	}
+
+	analisis := &AnalisisAberraciones{
+		Enfermedad: enfermedad,
+		PeriodoAnalisis: PeriodoAnalisis{
+			FechaInicio: fechaInicio,
+			FechaFin:    fechaFin,
+			DiasTotales: dias,
+		},
+		Metodos:   metodos,
+		Distritos: []SerieAberraciones{},
+	}
+	if inicioDatos.Valid {
+		analisis.InicioDatos = &inicioDatos.Time
+	}
+
+	desde := diasEntre(inicioSerie, fechaInicio)
+	for clave, serie := range series {
+		resultado := SerieAberraciones{
+			Distrito:         nombres[clave],
+			SenalesPorMetodo: make(map[string]int, len(metodos)),
+			DiasSenalados:    []DiaAberrante{},
+		}
+		for _, casos := range serie.casos[desde:] {
+			resultado.TotalCasos += int(casos)
+		}
+
+		senales := make(map[int][]SenalAberracion)
+		for _, metodo := range metodos {
+			for t, senal := range serie.detectar(metodo, desde) {
+				senales[t] = append(senales[t], senal)
+				resultado.SenalesPorMetodo[metodo]++
+			}
+		}
+
+		for t := desde; t < totalDias; t++ {
+			if len(senales[t]) == 0 {
+				continue
+			}
+			resultado.DiasSenalados = append(resultado.DiasSenalados, DiaAberrante{
+				Fecha:   serie.inicio.AddDate(0, 0, t),
+				Casos:   int(serie.casos[t]),
+				Senales: senales[t],
+			})
+		}
+
+		analisis.Distritos = append(analisis.Distritos, resultado)
+	}
+
+	// Primero los distritos con más días señalados
+	sort.Slice(analisis.Distritos, func(i, j int) bool {
+		a, b := analisis.Distritos[i], analisis.Distritos[j]
+		if len(a.DiasSenalados) != len(b.DiasSenalados) {
+			return len(a.DiasSenalados) > len(b.DiasSenalados)
+		}
+		return a.Distrito < b.Distrito
+	})
+
+	return analisis, nil
+}
+
+// detectar aplica un método a los días desde el índice desde y retorna las señales por índice de día
+func (serie *serieDiaria) detectar(metodo string, desde int) map[int]SenalAberracion {
+	switch metodo {
+	case MetodoEARSC1:
+		return serie.earsC(desde, 0, MetodoEARSC1)
+	case MetodoEARSC2:
+		return serie.earsC(desde, earsGuarda, MetodoEARSC2)
+	case MetodoEARSC3:
+		return serie.earsC3(desde)
+	case MetodoCUSUM:
+		return serie.cusum(desde)
+	case MetodoFarrington:
+		return serie.farrington(desde)
+	}
+	return nil
+}
+
+// estadisticoEARS (casos - media) / desviación de los earsLineaBase días que terminan guarda días antes de t.
+// ok es false si la línea base cae antes del inicio de los datos.
+func (serie *serieDiaria) estadisticoEARS(t, guarda int) (c, media, sigma float64, ok bool) {
+	inicio := t - guarda - earsLineaBase
+	if inicio < serie.validoDesde {
+		return 0, 0, 0, false
+	}
+	media, sigma = mediaDesviacion(serie.casos[inicio : t-guarda])
+	sigma = math.Max(sigma, earsSigmaMinima)
+	return (serie.casos[t] - media) / sigma, media, sigma, true
+}
+
+// earsC C1 (sin guarda) o C2 (con guarda): señala cuando el estadístico supera earsUmbral
+func (serie *serieDiaria) earsC(desde, guarda int, metodo string) map[int]SenalAberracion {
+	senales := make(map[int]SenalAberracion)
+	for t := desde; t < len(serie.casos); t++ {
+		c, media, _, ok := serie.estadisticoEARS(t, guarda)
+		if !ok || c <= earsUmbral {
+			continue
+		}
+		pValor := redondear(1-normalCDF(c), 6)
+		senales[t] = SenalAberracion{
+			Metodo:      metodo,
+			Estadistico: redondear(c, 3),
+			Umbral:      earsUmbral,
+			Esperado:    redondear(media, 2),
+			PValor:      &pValor,
+		}
+	}
+	return senales
+}
+
+// earsC3 suma de los excesos de C2 sobre 1 en el día evaluado y los dos anteriores
+func (serie *serieDiaria) earsC3(desde int) map[int]SenalAberracion {
+	senales := make(map[int]SenalAberracion)
+	for t := desde; t < len(serie.casos); t++ {
+		suma := 0.0
+		media := 0.0
+		ok := true
+		for d := 0; d < 3 && ok; d++ {
+			c, m, _, valido := serie.estadisticoEARS(t-d, earsGuarda)
+			if d == 0 {
+				media = m
+			}
+			ok = valido
+			suma += math.Max(0, c-1)
+		}
+		if !ok || suma <= earsUmbralC3 {
+			continue
+		}
+		senales[t] = SenalAberracion{
+			Metodo:      MetodoEARSC3,
+			Estadistico: redondear(suma, 3),
+			Umbral:      earsUmbralC3,
+			Esperado:    redondear(media, 2),
+		}
+	}
+	return senales
+}
+
+// cusum suma acumulada estandarizada S(t) = max(0, S(t-1) + z(t) - k) contra una línea base de cusumLineaBase
+// días separada por cusumGuarda días. No se reinicia al alertar, por lo que señala todos los días del exceso.
+func (serie *serieDiaria) cusum(desde int) map[int]SenalAberracion {
+	senales := make(map[int]SenalAberracion)
+	suma := 0.0
+	for t := desde; t < len(serie.casos); t++ {
+		inicio := t - cusumGuarda - cusumLineaBase
+		if inicio < serie.validoDesde {
+			suma = 0
+			continue
+		}
+		media, sigma := mediaDesviacion(serie.casos[inicio : t-cusumGuarda])
+		sigma = math.Max(sigma, earsSigmaMinima)
+		suma = math.Max(0, suma+(serie.casos[t]-media)/sigma-cusumK)
+		if suma <= cusumH {
+			continue
+		}
+		senales[t] = SenalAberracion{
+			Metodo:      MetodoCUSUM,
+			Estadistico: redondear(suma, 3),
+			Umbral:      cusumH,
+			Esperado:    redondear(media, 2),
+		}
+	}
+	return senales
+}
+
+// farrington compara cada día con los mismos días (± farringtonVentana) de los farringtonAnios años anteriores.
+// Ajusta un modelo cuasi-Poisson con tendencia lineal, descarta la tendencia si no es significativa o si la
+// predicción excede lo observado en la línea base, y usa el umbral superior con transformación 2/3.
+func (serie *serieDiaria) farrington(desde int) map[int]SenalAberracion {
+	senales := make(map[int]SenalAberracion)
+	for t := desde; t < len(serie.casos); t++ {
+		fecha := serie.inicio.AddDate(0, 0, t)
+		var x, y []float64
+		for anio := 1; anio <= farringtonAnios; anio++ {
+			centro := diasEntre(serie.inicio, fecha.AddDate(-anio, 0, 0))
+			for d := -farringtonVentana; d <= farringtonVentana; d++ {
+				i := centro + d
+				if i < serie.validoDesde || i < 0 || i >= len(serie.casos) {
+					continue
+				}
+				x = append(x, float64(i-t)/365.25)
+				y = append(y, serie.casos[i])
+			}
+		}
+		if len(y) < farringtonMinimoLineas {
+			continue
+		}
+
+		esperado, varianzaEta, phi, ok := lineaBaseFarrington(x, y)
+		if !ok || esperado <= 0 {
+			continue
+		}
+
+		tau := phi*esperado + esperado*esperado*varianzaEta
+		umbral := esperado * math.Pow(1+(2.0/3.0)*farringtonZ*math.Sqrt(tau)/esperado, 1.5)
+		observado := serie.casos[t]
+		if observado <= umbral {
+			continue
+		}
+
+		z := (math.Pow(observado, 2.0/3.0) - math.Pow(esperado, 2.0/3.0)) /
+			((2.0 / 3.0) * math.Pow(esperado, -1.0/3.0) * math.Sqrt(tau))
+		pValor := redondear(1-normalCDF(z), 6)
+		senales[t] = SenalAberracion{
+			Metodo:      MetodoFarrington,
+			Estadistico: observado,
+			Umbral:      redondear(umbral, 2),
+			Esperado:    redondear(esperado, 2),
+			PValor:      &pValor,
+		}
+	}
+	return senales
+}
+
+// lineaBaseFarrington ajusta log(mu) = a + b*x por mínimos cuadrados iterativamente reponderados (Poisson) y
+// retorna la predicción en x = 0, la varianza de su predictor lineal y la sobredispersión (mínimo 1)
+func lineaBaseFarrington(x, y []float64) (esperado, varianzaEta, phi float64, ok bool) {
+	maximo, suma := 0.0, 0.0
+	for _, v := range y {
+		suma += v
+		maximo = math.Max(maximo, v)
+	}
+	if suma == 0 {
+		return 0, 0, 0, false
+	}
+
+	a, b, varA, varB, _, convergio := ajustarPoissonLineal(x, y)
+	conTendencia := convergio && varB > 0
+	if conTendencia {
+		phi = sobredispersion(x, y, a, b, 2)
+		conTendencia = math.Abs(b)/math.Sqrt(phi*varB) >= farringtonZ && math.Exp(a) <= maximo
+	}
+	if !conTendencia {
+		// Sin tendencia el estimador es la media y la varianza del intercepto es 1/suma
+		a, b = math.Log(suma/float64(len(y))), 0
+		varA = 1 / suma
+		phi = sobredispersion(x, y, a, b, 1)
+	}
+
+	// En x = 0 la varianza del predictor lineal es la del intercepto
+	return math.Exp(a), phi * varA, phi, true
+}
+
+// ajustarPoissonLineal regresión de Poisson con enlace logarítmico y una covariable.
+// Retorna los coeficientes y la inversa de la matriz de información.
+func ajustarPoissonLineal(x, y []float64) (a, b, varA, varB, covAB float64, ok bool) {
+	media := 0.0
+	for _, v := range y {
+		media += v
+	}
+	a = math.Log(math.Max(media/float64(len(y)), 0.5))
+
+	for iter := 0; iter < 25; iter++ {
+		var s00, s01, s11, g0, g1 float64
+		for i := range y {
+			mu := math.Exp(a + b*x[i])
+			s00 += mu
+			s01 += mu * x[i]
+			s11 += mu * x[i] * x[i]
+			g0 += y[i] - mu
+			g1 += (y[i] - mu) * x[i]
+		}
+		det := s00*s11 - s01*s01
+		if det <= 1e-12 {
+			return 0, 0, 0, 0, 0, false
+		}
+		varA, varB, covAB = s11/det, s00/det, -s01/det
+		da := varA*g0 + covAB*g1
+		db := covAB*g0 + varB*g1
+		a += da
+		b += db
+		if math.IsNaN(a) || math.IsNaN(b) || math.Abs(b) > 50 {
+			return 0, 0, 0, 0, 0, false
+		}
+		if math.Abs(da) < 1e-8 && math.Abs(db) < 1e-8 {
+			return a, b, varA, varB, covAB, true
+		}
+	}
+	return a, b, varA, varB, covAB, false
+}
+
+// sobredispersion estadístico de Pearson dividido por los grados de libertad, nunca menor que 1
+func sobredispersion(x, y []float64, a, b float64, parametros int) float64 {
+	if len(y) <= parametros {
+		return 1
+	}
+	pearson := 0.0
+	for i := range y {
+		mu := math.Exp(a + b*x[i])
+		pearson += (y[i] - mu) * (y[i] - mu) / mu
+	}
+	return math.Max(1, pearson/float64(len(y)-parametros))
+}
+
+// mediaDesviacion media y desviación estándar muestral
+func mediaDesviacion(valores []float64) (media, desviacion float64) {
+	for _, v := range valores {
+		media += v
+	}
+	media /= float64(len(valores))
+	if len(valores) < 2 {
+		return media, 0
+	}
+	for _, v := range valores {
+		desviacion += (v - media) * (v - media)
+	}
+	return media, math.Sqrt(desviacion / float64(len(valores)-1))
+}
+
+// normalCDF función de distribución de la normal estándar
+func normalCDF(z float64) float64 {
+	return 0.5 * math.Erfc(-z/math.Sqrt2)
+}
+
+func redondear(valor float64, decimales int) float64 {
+	factor := math.Pow(10, float64(decimales))
+	return math.Round(valor*factor) / factor
+}
+
+// diasEntre días completos de desde a hasta (ambas fechas a medianoche UTC)
+func diasEntre(desde, hasta time.Time) int {
+	return int(math.Round(hasta.Sub(desde).Hours() / 24))
+}
+
+// ValidarMetodosAberracion retorna error si algún método no existe
+func ValidarMetodosAberracion(metodos []string) error {
+	for _, metodo := range metodos {
+		if !slices.Contains(MetodosAberracion, metodo) {
+			return fmt.Errorf("método de detección desconocido: %s", metodo)
+		}
+	}
+	return nil
+}
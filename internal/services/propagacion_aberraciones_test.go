@@ -0,0 +1,200 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// serieConstante serie de n días con valor casos, salvo los índices de extra
+func serieConstante(n int, casos float64, extra map[int]float64) []float64 {
+	serie := make([]float64, n)
+	for i := range serie {
+		serie[i] = casos
+	}
+	for i, v := range extra {
+		serie[i] = v
+	}
+	return serie
+}
+
+func TestDetectarAberraciones(t *testing.T) {
+	// Línea base 1,2,3,4,5,3,3: media 3 y desviación muestral sqrt(10/6), de modo que 8 casos dan
+	// C = 5/sqrt(10/6) = 3,873 y p = 1 - Φ(3,873) = 0,000054
+	lineaBase := []float64{1, 2, 3, 4, 5, 3, 3}
+
+	// Farrington con 45 días de línea base de 2 casos: sin tendencia, esperado 2, phi 1, varianza del
+	// intercepto 1/90; tau = 2 + 4/90 y el umbral es 2(1 + (2/3)(1,96)sqrt(tau)/2)^1,5 = 5,38
+	inicio := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	tFarrington := diasEntre(inicio, inicio.AddDate(farringtonAnios, 0, farringtonVentana))
+
+	tests := []struct {
+		name        string
+		metodo      string
+		casos       []float64
+		validoDesde int
+		desde       int
+		want        map[int]SenalAberracion
+	}{
+		{
+			name:   "C1 sobre el umbral",
+			metodo: MetodoEARSC1,
+			casos:  append(append([]float64{}, lineaBase...), 8),
+			desde:  7,
+			want:   map[int]SenalAberracion{7: {Estadistico: 3.873, Umbral: 3, Esperado: 3, PValor: ptrFloat(0.000054)}},
+		},
+		{
+			name:   "C1 bajo el umbral",
+			metodo: MetodoEARSC1,
+			casos:  append(append([]float64{}, lineaBase...), 6),
+			desde:  7,
+			want:   map[int]SenalAberracion{},
+		},
+		{
+			name:   "C1 con línea base sin variación usa la desviación mínima",
+			metodo: MetodoEARSC1,
+			casos:  serieConstante(8, 0, map[int]float64{7: 4}),
+			desde:  7,
+			want:   map[int]SenalAberracion{7: {Estadistico: 4, Umbral: 3, Esperado: 0, PValor: ptrFloat(0.000032)}},
+		},
+		{
+			name:        "C1 sin línea base anterior al inicio de los datos",
+			metodo:      MetodoEARSC1,
+			casos:       serieConstante(8, 0, map[int]float64{7: 4}),
+			validoDesde: 1,
+			desde:       7,
+			want:        map[int]SenalAberracion{},
+		},
+		{
+			name:   "C2 deja fuera los dos días de guarda",
+			metodo: MetodoEARSC2,
+			casos:  append(append([]float64{}, lineaBase...), 50, 50, 8),
+			desde:  9,
+			want:   map[int]SenalAberracion{9: {Estadistico: 3.873, Umbral: 3, Esperado: 3, PValor: ptrFloat(0.000054)}},
+		},
+		{
+			name:   "C1 incluye los días que C2 deja de guarda",
+			metodo: MetodoEARSC1,
+			casos:  append(append([]float64{}, lineaBase...), 50, 50, 8),
+			desde:  9,
+			want:   map[int]SenalAberracion{},
+		},
+		{
+			// C2 vale 2 en los días 11, 12 y 13: C3 suma 1, 2 y 3 excesos sobre 1
+			name:   "C3 acumula tres días",
+			metodo: MetodoEARSC3,
+			casos:  serieConstante(14, 0, map[int]float64{11: 2, 12: 2, 13: 2}),
+			desde:  11,
+			want:   map[int]SenalAberracion{13: {Estadistico: 3, Umbral: 2, Esperado: 0}},
+		},
+		{
+			// Con media 0 y desviación 1, S = 1,5; 3; 4,5; 5; 4,5; 4
+			name:   "CUSUM señala mientras la suma supera h",
+			metodo: MetodoCUSUM,
+			casos:  serieConstante(41, 0, map[int]float64{35: 2, 36: 2, 37: 2, 38: 1}),
+			desde:  35,
+			want: map[int]SenalAberracion{
+				37: {Estadistico: 4.5, Umbral: 4, Esperado: 0},
+				38: {Estadistico: 5, Umbral: 4, Esperado: 0},
+				39: {Estadistico: 4.5, Umbral: 4, Esperado: 0},
+			},
+		},
+		{
+			name:        "CUSUM sin línea base completa",
+			metodo:      MetodoCUSUM,
+			casos:       serieConstante(41, 0, map[int]float64{35: 2, 36: 2, 37: 2, 38: 1}),
+			validoDesde: 1,
+			desde:       35,
+			want:        map[int]SenalAberracion{},
+		},
+		{
+			name:   "Farrington sobre el umbral",
+			metodo: MetodoFarrington,
+			casos:  serieConstante(tFarrington+1, 2, map[int]float64{tFarrington: 6}),
+			desde:  tFarrington,
+			want:   map[int]SenalAberracion{tFarrington: {Estadistico: 6, Umbral: 5.38, Esperado: 2, PValor: ptrFloat(0.011721)}},
+		},
+		{
+			name:   "Farrington bajo el umbral",
+			metodo: MetodoFarrington,
+			casos:  serieConstante(tFarrington+1, 2, map[int]float64{tFarrington: 5}),
+			desde:  tFarrington,
+			want:   map[int]SenalAberracion{},
+		},
+		{
+			name:   "Farrington sin casos en la línea base",
+			metodo: MetodoFarrington,
+			casos:  serieConstante(tFarrington+1, 0, map[int]float64{tFarrington: 5}),
+			desde:  tFarrington,
+			want:   map[int]SenalAberracion{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serie := &serieDiaria{inicio: inicio, casos: tt.casos, validoDesde: tt.validoDesde}
+			got := serie.detectar(tt.metodo, tt.desde)
+			if len(got) != len(tt.want) {
+				t.Fatalf("detectar() = %+v, want %+v", got, tt.want)
+			}
+			for dia, want := range tt.want {
+				senal, ok := got[dia]
+				if !ok {
+					t.Fatalf("detectar() no señala el día %d: %+v", dia, got)
+				}
+				if senal.Metodo != tt.metodo || senal.Estadistico != want.Estadistico ||
+					senal.Umbral != want.Umbral || senal.Esperado != want.Esperado {
+					t.Errorf("día %d = %+v, want %+v", dia, senal, want)
+				}
+				switch {
+				case (senal.PValor == nil) != (want.PValor == nil):
+					t.Errorf("día %d p = %v, want %v", dia, senal.PValor, want.PValor)
+				case senal.PValor != nil && *senal.PValor != *want.PValor:
+					t.Errorf("día %d p = %v, want %v", dia, *senal.PValor, *want.PValor)
+				}
+			}
+		})
+	}
+}
+
+func TestAjustarPoissonLineal(t *testing.T) {
+	// Con observaciones iguales a la media del modelo la verosimilitud se maximiza en los coeficientes exactos
+	tests := []struct {
+		a, b float64
+	}{
+		{math.Log(5), 0},
+		{math.Log(5), 0.3},
+		{math.Log(20), -0.5},
+	}
+
+	for _, tt := range tests {
+		var x, y []float64
+		for i := -3.0; i <= 0; i += 0.25 {
+			x = append(x, i)
+			y = append(y, math.Exp(tt.a+tt.b*i))
+		}
+
+		a, b, _, _, _, ok := ajustarPoissonLineal(x, y)
+		if !ok || math.Abs(a-tt.a) > 1e-6 || math.Abs(b-tt.b) > 1e-6 {
+			t.Errorf("ajustarPoissonLineal() = %v, %v, %v; want %v, %v", a, b, ok, tt.a, tt.b)
+		}
+	}
+}
+
+func TestMediaDesviacion(t *testing.T) {
+	tests := []struct {
+		valores           []float64
+		media, desviacion float64
+	}{
+		{[]float64{1, 2, 3, 4, 5, 3, 3}, 3, math.Sqrt(10.0 / 6)},
+		{[]float64{2, 4, 4, 4, 5, 5, 7, 9}, 5, math.Sqrt(32.0 / 7)},
+		{[]float64{7}, 7, 0},
+	}
+
+	for _, tt := range tests {
+		media, desviacion := mediaDesviacion(tt.valores)
+		if math.Abs(media-tt.media) > 1e-12 || math.Abs(desviacion-tt.desviacion) > 1e-12 {
+			t.Errorf("mediaDesviacion(%v) = %v, %v; want %v, %v", tt.valores, media, desviacion, tt.media, tt.desviacion)
+		}
+	}
+}
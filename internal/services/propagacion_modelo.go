@@ -0,0 +1,475 @@
+package services
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"hospital-api/internal/models"
+	"hospital-api/internal/utils"
+)
+
+// HorizontePrediccionDefecto días pronosticados cuando no se indica un horizonte
+const HorizontePrediccionDefecto = 7
+
+// Parámetros del modelo SEIR metapoblacional. La transmisibilidad se estima de los casos observados.
+const (
+	seirMovilidad            = 0.15 // Fracción de los contactos de un distrito que ocurre en los distritos conectados
+	seirVentanaAjuste        = 21   // Últimos días de la serie con los que se estima la tasa de crecimiento
+	seirErrorDefecto         = 0.05 // Error estándar de la tasa de crecimiento cuando no se puede ajustar
+	seirCrecimientoMaximo    = 0.5  // Límite de |r| (duplicación en 1,4 días) para series con muy pocos casos
+	seirCasosMinimosDistrito = 30   // Casos en la ventana de ajuste para estimar la tasa propia de un distrito
+	seirPasosPorDia          = 4
+	seirSimulaciones         = 500
+	seirSemilla              = 1 // Semilla fija: la misma serie produce siempre el mismo pronóstico
+)
+
+// Periodos genéricos de enfermedades infecciosas agudas, para las que el catálogo no los define
+const (
+	seirLatenciaGenerica   = 5.0 // Días promedio en E (infectado, aún sin síntomas)
+	seirInfecciosoGenerico = 7.0 // Días promedio en I
+)
+
+// Orígenes de los periodos del modelo
+const (
+	OrigenCatalogo   = "catalogo"
+	OrigenParametros = "parametros"
+	OrigenGenerico   = "generico"
+)
+
+// Ajustes de la tasa de crecimiento de un distrito
+const (
+	AjusteDistrito = "distrito" // Ajustada con los casos del distrito
+	AjusteCiudad   = "ciudad"   // Tasa de toda la ciudad, por tener el distrito menos de seirCasosMinimosDistrito casos
+)
+
+// PeriodosSEIR duración media en días de los compartimentos E (latencia) e I (periodo infeccioso) y de
+// dónde se tomó cada una: de los parámetros de la consulta, del catálogo de enfermedades o los genéricos
+type PeriodosSEIR struct {
+	Latencia         float64
+	Infeccioso       float64
+	OrigenLatencia   string
+	OrigenInfeccioso string
+}
+
+// ModeloSEIR parámetros con que se generó el pronóstico. La tasa de crecimiento y el número reproductivo son
+// los de toda la ciudad; cada predicción informa los de su distrito.
+type ModeloSEIR struct {
+	TasaCrecimiento       float64    `json:"tasa_crecimiento_diaria"` // r del ajuste log-lineal de los casos diarios
+	IntervaloCrecimiento  [2]float64 `json:"intervalo_crecimiento_95"`
+	NumeroReproductivo    float64    `json:"numero_reproductivo"` // R = (1 + r·latencia)(1 + r·periodo infeccioso)
+	IntervaloReproductivo [2]float64 `json:"intervalo_reproductivo_95"`
+	PeriodoLatencia       float64    `json:"periodo_latencia_dias"`
+	OrigenLatencia        string     `json:"origen_latencia"` // parametros, catalogo (punto medio de la incubación) o generico
+	PeriodoInfeccioso     float64    `json:"periodo_infeccioso_dias"`
+	OrigenInfeccioso      string     `json:"origen_infeccioso"` // parametros, catalogo o generico
+	Movilidad             float64    `json:"movilidad"`
+	VentanaAjuste         int        `json:"ventana_ajuste_dias"`
+	CasosMinimosDistrito  int        `json:"casos_minimos_ajuste_distrito"` // Por debajo el distrito usa la tasa de la ciudad
+	Horizonte             int        `json:"horizonte_dias"`
+	Simulaciones          int        `json:"simulaciones"`
+}
+
+// PronosticoDiario casos nuevos esperados en un día: mediana e intervalo del 95% de las simulaciones
+type PronosticoDiario struct {
+	Fecha    time.Time `json:"fecha"`
+	Casos    float64   `json:"casos"`
+	Inferior float64   `json:"ic95_inferior"`
+	Superior float64   `json:"ic95_superior"`
+}
+
+// crecimientoDistrito tasa de crecimiento con que se simula un distrito y su error estándar
+type crecimientoDistrito struct {
+	r, errorEstandar float64
+	ajuste           string // AjusteDistrito o AjusteCiudad
+}
+
+// compartimentosSEIR estado de un distrito
+type compartimentosSEIR struct {
+	S, E, I, R float64
+}
+
+// modeloMetapoblacional distritos del registro con su estado inicial y sus vecinos ponderados
+type modeloMetapoblacional struct {
+	distritos []string
+	poblacion []float64
+	vecinos   [][]int
+	pesos     [][]float64 // Peso de la conexión con cada vecino
+	inicial   []compartimentosSEIR
+	periodos  PeriodosSEIR
+}
+
+// generarPredicciones ajusta el modelo SEIR a los casos observados y pronostica los casos nuevos por día
+// de cada distrito durante horizonte días, con intervalos del 95% por simulación estocástica
+func (s *PropagacionService) generarPredicciones(casos []CasoTemporal, red *redDistritos, fechaFin time.Time, horizonte int, periodos PeriodosSEIR) ([]PrediccionPropagacion, ModeloSEIR) {
+	hoy := diaUTC(fechaFin)
+	r, errorR := ajustarCrecimiento(casos, hoy)
+	modelo := nuevoModeloMetapoblacional(casos, red, hoy, periodos)
+	crecimientos := ajustarCrecimientoDistritos(casos, red, hoy, crecimientoDistrito{r: r, errorEstandar: errorR, ajuste: AjusteCiudad})
+
+	parametros := ModeloSEIR{
+		TasaCrecimiento:       redondear(r, 4),
+		IntervaloCrecimiento:  [2]float64{redondear(r-1.96*errorR, 4), redondear(r+1.96*errorR, 4)},
+		NumeroReproductivo:    redondear(reproductivoDesdeCrecimiento(r, periodos), 3),
+		IntervaloReproductivo: [2]float64{redondear(reproductivoDesdeCrecimiento(r-1.96*errorR, periodos), 3), redondear(reproductivoDesdeCrecimiento(r+1.96*errorR, periodos), 3)},
+		PeriodoLatencia:       redondear(periodos.Latencia, 2),
+		OrigenLatencia:        periodos.OrigenLatencia,
+		PeriodoInfeccioso:     redondear(periodos.Infeccioso, 2),
+		OrigenInfeccioso:      periodos.OrigenInfeccioso,
+		Movilidad:             seirMovilidad,
+		VentanaAjuste:         seirVentanaAjuste,
+		CasosMinimosDistrito:  seirCasosMinimosDistrito,
+		Horizonte:             horizonte,
+		Simulaciones:          seirSimulaciones,
+	}
+
+	// casosSimulados[distrito][dia][simulacion]
+	casosSimulados := make([][][]float64, len(modelo.distritos))
+	for i := range casosSimulados {
+		casosSimulados[i] = make([][]float64, horizonte)
+		for d := range casosSimulados[i] {
+			casosSimulados[i][d] = make([]float64, seirSimulaciones)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(seirSemilla))
+	betas := make([]float64, len(modelo.distritos))
+	for sim := 0; sim < seirSimulaciones; sim++ {
+		// La incertidumbre de la tasa de crecimiento se propaga muestreando r en cada simulación, con el mismo
+		// desvío normal en todos los distritos: los que usan la tasa de la ciudad comparten una sola estimación
+		desvio := rng.NormFloat64()
+		for i, crecimiento := range crecimientos {
+			muestra := math.Max(-seirCrecimientoMaximo, math.Min(seirCrecimientoMaximo, crecimiento.r+crecimiento.errorEstandar*desvio))
+			betas[i] = reproductivoDesdeCrecimiento(muestra, periodos) / periodos.Infeccioso
+		}
+		for i, dias := range modelo.simular(betas, horizonte, rng) {
+			for d, nuevos := range dias {
+				casosSimulados[i][d][sim] = nuevos
+			}
+		}
+	}
+
+	predicciones := make([]PrediccionPropagacion, len(modelo.distritos))
+	for i, distrito := range modelo.distritos {
+		totales := make([]float64, seirSimulaciones)
+		pronostico := make([]PronosticoDiario, horizonte)
+		for d, simulaciones := range casosSimulados[i] {
+			for sim, nuevos := range simulaciones {
+				totales[sim] += nuevos
+			}
+			sort.Float64s(simulaciones)
+			pronostico[d] = PronosticoDiario{
+				Fecha:    hoy.AddDate(0, 0, d+1),
+				Casos:    percentil(simulaciones, 0.5),
+				Inferior: percentil(simulaciones, 0.025),
+				Superior: percentil(simulaciones, 0.975),
+			}
+		}
+
+		conCasos := 0
+		for _, total := range totales {
+			if total > 0 {
+				conCasos++
+			}
+		}
+		sort.Float64s(totales)
+		probabilidad := float64(conCasos) / seirSimulaciones * 100
+
+		predicciones[i] = PrediccionPropagacion{
+			Distrito:           distrito,
+			FechaPrediccion:    hoy.AddDate(0, 0, horizonte),
+			CasosPredichos:     int(percentil(totales, 0.5)),
+			CasosInferior:      int(percentil(totales, 0.025)),
+			CasosSuperior:      int(percentil(totales, 0.975)),
+			Probabilidad:       math.Round(probabilidad*100) / 100,
+			NivelRiesgo:        nivelRiesgoPrediccion(probabilidad),
+			PronosticoDiario:   pronostico,
+			TasaCrecimiento:    redondear(crecimientos[i].r, 4),
+			NumeroReproductivo: redondear(reproductivoDesdeCrecimiento(crecimientos[i].r, periodos), 3),
+			AjusteCrecimiento:  crecimientos[i].ajuste,
+		}
+	}
+
+	// Ordenar por probabilidad y casos predichos descendentes
+	sort.Slice(predicciones, func(i, j int) bool {
+		if predicciones[i].Probabilidad != predicciones[j].Probabilidad {
+			return predicciones[i].Probabilidad > predicciones[j].Probabilidad
+		}
+		return predicciones[i].CasosPredichos > predicciones[j].CasosPredichos
+	})
+
+	return predicciones, parametros
+}
+
+// ajustarCrecimiento estima la tasa de crecimiento diaria r con una regresión de Poisson log-lineal sobre el
+// total de casos diarios de los últimos seirVentanaAjuste días, y su error estándar corregido por sobredispersión
+func ajustarCrecimiento(casos []CasoTemporal, hoy time.Time) (r, errorEstandar float64) {
+	y := make([]float64, seirVentanaAjuste)
+	for _, caso := range casos {
+		if i := diaVentanaAjuste(caso, hoy); i >= 0 {
+			y[i] += float64(caso.TotalCasos)
+		}
+	}
+
+	r, errorEstandar, ok := crecimientoDeSerie(y)
+	if !ok {
+		return 0, seirErrorDefecto
+	}
+	return r, errorEstandar
+}
+
+// ajustarCrecimientoDistritos estima la tasa de crecimiento de cada distrito de la red con sus propios casos.
+// Los distritos con menos de seirCasosMinimosDistrito casos en la ventana, o cuya serie no se puede ajustar,
+// usan la tasa de toda la ciudad.
+func ajustarCrecimientoDistritos(casos []CasoTemporal, red *redDistritos, hoy time.Time, ciudad crecimientoDistrito) []crecimientoDistrito {
+	series := make([][]float64, len(red.distritos))
+	totales := make([]float64, len(red.distritos))
+	for i := range series {
+		series[i] = make([]float64, seirVentanaAjuste)
+	}
+	for _, caso := range casos {
+		j, ok := red.indice[utils.NormalizarTexto(caso.Distrito)]
+		if !ok {
+			continue
+		}
+		if i := diaVentanaAjuste(caso, hoy); i >= 0 {
+			series[j][i] += float64(caso.TotalCasos)
+			totales[j] += float64(caso.TotalCasos)
+		}
+	}
+
+	crecimientos := make([]crecimientoDistrito, len(red.distritos))
+	for j, y := range series {
+		crecimientos[j] = ciudad
+		if totales[j] < seirCasosMinimosDistrito {
+			continue
+		}
+		if r, errorEstandar, ok := crecimientoDeSerie(y); ok {
+			crecimientos[j] = crecimientoDistrito{r: r, errorEstandar: errorEstandar, ajuste: AjusteDistrito}
+		}
+	}
+	return crecimientos
+}
+
+// diaVentanaAjuste posición del día del caso en la ventana de ajuste que termina en hoy, o -1 si queda fuera
+func diaVentanaAjuste(caso CasoTemporal, hoy time.Time) int {
+	i := diasEntre(hoy.AddDate(0, 0, -(seirVentanaAjuste-1)), diaUTC(caso.Fecha))
+	if i < 0 || i >= seirVentanaAjuste {
+		return -1
+	}
+	return i
+}
+
+// crecimientoDeSerie ajusta la regresión de Poisson log-lineal a los casos diarios de la ventana de ajuste
+func crecimientoDeSerie(y []float64) (r, errorEstandar float64, ok bool) {
+	x := make([]float64, len(y))
+	for i := range x {
+		x[i] = float64(i - (len(y) - 1))
+	}
+
+	a, b, _, varB, _, ok := ajustarPoissonLineal(x, y)
+	if !ok || varB <= 0 {
+		return 0, 0, false
+	}
+	phi := sobredispersion(x, y, a, b, 2)
+	return math.Max(-seirCrecimientoMaximo, math.Min(seirCrecimientoMaximo, b)), math.Sqrt(phi * varB), true
+}
+
+// reproductivoDesdeCrecimiento número reproductivo de un SEIR con periodos exponenciales y tasa de crecimiento r.
+// La fórmula vale mientras ambos factores sean positivos; un descenso más rápido que la salida de E o de I
+// solo es compatible con R = 0.
+func reproductivoDesdeCrecimiento(r float64, periodos PeriodosSEIR) float64 {
+	latencia, infeccioso := 1+r*periodos.Latencia, 1+r*periodos.Infeccioso
+	if latencia <= 0 || infeccioso <= 0 {
+		return 0
+	}
+	return latencia * infeccioso
+}
+
+// periodosSEIR completa los periodos que no se indicaron en la consulta con los de la enfermedad del
+// catálogo (nil si no está en él) o, si no los define, con los genéricos. La latencia del catálogo es el
+// punto medio del periodo de incubación: el modelo no distingue la infección asintomática de la incubación.
+func periodosSEIR(consulta PeriodosSEIR, enfermedad *models.Enfermedad) PeriodosSEIR {
+	periodos := consulta
+
+	switch {
+	case consulta.Latencia > 0:
+		periodos.OrigenLatencia = OrigenParametros
+	case enfermedad != nil && (enfermedad.IncubacionMinDias != nil || enfermedad.IncubacionMaxDias != nil):
+		minimo, maximo := enfermedad.IncubacionMinDias, enfermedad.IncubacionMaxDias
+		if minimo == nil {
+			minimo = maximo
+		}
+		if maximo == nil {
+			maximo = minimo
+		}
+		// Una incubación de 0 días se toma como un día para que E tenga una duración
+		periodos.Latencia = math.Max(1, float64(*minimo+*maximo)/2)
+		periodos.OrigenLatencia = OrigenCatalogo
+	default:
+		periodos.Latencia = seirLatenciaGenerica
+		periodos.OrigenLatencia = OrigenGenerico
+	}
+
+	switch {
+	case consulta.Infeccioso > 0:
+		periodos.OrigenInfeccioso = OrigenParametros
+	case enfermedad != nil && enfermedad.PeriodoInfecciosoDias != nil:
+		periodos.Infeccioso = *enfermedad.PeriodoInfecciosoDias
+		periodos.OrigenInfeccioso = OrigenCatalogo
+	default:
+		periodos.Infeccioso = seirInfecciosoGenerico
+		periodos.OrigenInfeccioso = OrigenGenerico
+	}
+
+	return periodos
+}
+
+// nuevoModeloMetapoblacional arma el grafo de distritos y su estado actual a partir de los casos observados:
+// los casos de los últimos días del periodo infeccioso están en I, los que se esperan por la incidencia
+// reciente durante la latencia en E, y el resto de los casos del periodo en R
+func nuevoModeloMetapoblacional(casos []CasoTemporal, red *redDistritos, hoy time.Time, periodos PeriodosSEIR) *modeloMetapoblacional {
+	modelo := &modeloMetapoblacional{periodos: periodos}
+	for _, distrito := range red.distritos {
+		modelo.distritos = append(modelo.distritos, distrito.Nombre)
+	}
+
+	recientes := make([]float64, len(modelo.distritos))
+	totales := make([]float64, len(modelo.distritos))
+	for _, caso := range casos {
+		i, ok := red.indice[utils.NormalizarTexto(caso.Distrito)]
+		if !ok {
+			continue
+		}
+		totales[i] += float64(caso.TotalCasos)
+		if diasEntre(diaUTC(caso.Fecha), hoy) < int(math.Ceil(periodos.Infeccioso)) {
+			recientes[i] += float64(caso.TotalCasos)
+		}
+	}
+
+	modelo.vecinos = make([][]int, len(modelo.distritos))
+	modelo.pesos = make([][]float64, len(modelo.distritos))
+	for i, info := range red.distritos {
+		for _, conexion := range info.Conexiones {
+			if j, ok := red.indice[utils.NormalizarTexto(conexion.Distrito)]; ok {
+				modelo.vecinos[i] = append(modelo.vecinos[i], j)
+				modelo.pesos[i] = append(modelo.pesos[i], conexion.Peso)
+			}
+		}
+
+		poblacion := float64(info.Habitantes)
+		infecciosos := recientes[i]
+		expuestos := math.Round(recientes[i] / periodos.Infeccioso * periodos.Latencia)
+		recuperados := math.Max(0, totales[i]-infecciosos)
+		modelo.poblacion = append(modelo.poblacion, poblacion)
+		modelo.inicial = append(modelo.inicial, compartimentosSEIR{
+			S: math.Max(0, poblacion-expuestos-infecciosos-recuperados),
+			E: expuestos,
+			I: infecciosos,
+			R: recuperados,
+		})
+	}
+
+	return modelo
+}
+
+// simular ejecuta una trayectoria estocástica (cadena de Poisson con pasos de 1/seirPasosPorDia días) con la
+// tasa de transmisión betas[i] en cada distrito y retorna los casos nuevos (transiciones E → I) por día
+func (m *modeloMetapoblacional) simular(betas []float64, horizonte int, rng *rand.Rand) [][]float64 {
+	estado := make([]compartimentosSEIR, len(m.inicial))
+	copy(estado, m.inicial)
+
+	nuevos := make([][]float64, len(estado))
+	for i := range nuevos {
+		nuevos[i] = make([]float64, horizonte)
+	}
+
+	dt := 1.0 / seirPasosPorDia
+	pIncubacion := 1 - math.Exp(-dt/m.periodos.Latencia)
+	pRecuperacion := 1 - math.Exp(-dt/m.periodos.Infeccioso)
+	prevalencia := make([]float64, len(estado))
+
+	for dia := 0; dia < horizonte; dia++ {
+		for paso := 0; paso < seirPasosPorDia; paso++ {
+			for i := range estado {
+				prevalencia[i] = estado[i].I / m.poblacion[i]
+			}
+			for i := range estado {
+				// Fuerza de infección: contactos locales y, en fracción seirMovilidad, con los distritos conectados
+				// ponderados por el peso de cada conexión
+				externa := prevalencia[i]
+				if len(m.vecinos[i]) > 0 {
+					externa = 0
+					pesoTotal := 0.0
+					for k, j := range m.vecinos[i] {
+						externa += m.pesos[i][k] * prevalencia[j]
+						pesoTotal += m.pesos[i][k]
+					}
+					externa /= pesoTotal
+				}
+				lambda := betas[i] * ((1-seirMovilidad)*prevalencia[i] + seirMovilidad*externa)
+
+				infectados := math.Min(estado[i].S, muestraPoisson(rng, estado[i].S*(1-math.Exp(-lambda*dt))))
+				incubados := math.Min(estado[i].E, muestraPoisson(rng, estado[i].E*pIncubacion))
+				recuperados := math.Min(estado[i].I, muestraPoisson(rng, estado[i].I*pRecuperacion))
+
+				estado[i].S -= infectados
+				estado[i].E += infectados - incubados
+				estado[i].I += incubados - recuperados
+				estado[i].R += recuperados
+				nuevos[i][dia] += incubados
+			}
+		}
+	}
+
+	return nuevos
+}
+
+// muestraPoisson variable de Poisson; para medias grandes usa la aproximación normal
+func muestraPoisson(rng *rand.Rand, media float64) float64 {
+	if media <= 0 {
+		return 0
+	}
+	if media > 30 {
+		return math.Max(0, math.Round(media+math.Sqrt(media)*rng.NormFloat64()))
+	}
+	limite := math.Exp(-media)
+	k, p := 0.0, rng.Float64()
+	for p > limite {
+		k++
+		p *= rng.Float64()
+	}
+	return k
+}
+
+// percentil de valores ordenados, con interpolación lineal
+func percentil(ordenados []float64, p float64) float64 {
+	if len(ordenados) == 0 {
+		return 0
+	}
+	posicion := p * float64(len(ordenados)-1)
+	inferior := int(math.Floor(posicion))
+	superior := min(inferior+1, len(ordenados)-1)
+	valor := ordenados[inferior] + (posicion-float64(inferior))*(ordenados[superior]-ordenados[inferior])
+	return math.Round(valor*100) / 100
+}
+
+// nivelRiesgoPrediccion nivel según la probabilidad de registrar casos nuevos en el horizonte
+func nivelRiesgoPrediccion(probabilidad float64) string {
+	switch {
+	case probabilidad >= 80:
+		return "CRÍTICO"
+	case probabilidad >= 60:
+		return "ALTO"
+	case probabilidad >= 40:
+		return "MEDIO"
+	default:
+		return "BAJO"
+	}
+}
+
+// diaUTC medianoche UTC de la fecha calendario de t
+func diaUTC(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
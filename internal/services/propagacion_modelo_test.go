@@ -0,0 +1,207 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"hospital-api/internal/models"
+)
+
+func TestReproductivoDesdeCrecimiento(t *testing.T) {
+	genericos := PeriodosSEIR{Latencia: 5, Infeccioso: 7}
+
+	tests := []struct {
+		name     string
+		r        float64
+		periodos PeriodosSEIR
+		want     float64
+	}{
+		{"sin crecimiento", 0, genericos, 1},
+		{"crecimiento del 10% diario", 0.1, genericos, 1.5 * 1.7},
+		{"descenso del 10% diario", -0.1, genericos, 0.5 * 0.3},
+		{"descenso más rápido que la salida de I", -0.5, genericos, 0},
+		{"descenso en el límite de la latencia", -0.2, genericos, 0},
+		{"sin latencia equivale a SIR", 0.2, PeriodosSEIR{Infeccioso: 10}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reproductivoDesdeCrecimiento(tt.r, tt.periodos); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("reproductivoDesdeCrecimiento(%v) = %v, want %v", tt.r, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPeriodosSEIR(t *testing.T) {
+	dias := func(v int) *int { return &v }
+	dengue := &models.Enfermedad{IncubacionMinDias: dias(4), IncubacionMaxDias: dias(10), PeriodoInfecciosoDias: ptrFloat(5)}
+
+	tests := []struct {
+		name       string
+		consulta   PeriodosSEIR
+		enfermedad *models.Enfermedad
+		want       PeriodosSEIR
+	}{
+		{
+			name: "genéricos sin catálogo",
+			want: PeriodosSEIR{Latencia: 5, Infeccioso: 7, OrigenLatencia: OrigenGenerico, OrigenInfeccioso: OrigenGenerico},
+		},
+		{
+			name:       "catálogo: punto medio de la incubación",
+			enfermedad: dengue,
+			want:       PeriodosSEIR{Latencia: 7, Infeccioso: 5, OrigenLatencia: OrigenCatalogo, OrigenInfeccioso: OrigenCatalogo},
+		},
+		{
+			name:       "la consulta tiene prioridad sobre el catálogo",
+			consulta:   PeriodosSEIR{Latencia: 3, Infeccioso: 9},
+			enfermedad: dengue,
+			want:       PeriodosSEIR{Latencia: 3, Infeccioso: 9, OrigenLatencia: OrigenParametros, OrigenInfeccioso: OrigenParametros},
+		},
+		{
+			name:       "consulta parcial",
+			consulta:   PeriodosSEIR{Infeccioso: 9},
+			enfermedad: dengue,
+			want:       PeriodosSEIR{Latencia: 7, Infeccioso: 9, OrigenLatencia: OrigenCatalogo, OrigenInfeccioso: OrigenParametros},
+		},
+		{
+			name:       "solo incubación máxima y sin periodo infeccioso",
+			enfermedad: &models.Enfermedad{IncubacionMaxDias: dias(6)},
+			want:       PeriodosSEIR{Latencia: 6, Infeccioso: 7, OrigenLatencia: OrigenCatalogo, OrigenInfeccioso: OrigenGenerico},
+		},
+		{
+			name:       "incubación de cero días",
+			enfermedad: &models.Enfermedad{IncubacionMinDias: dias(0), IncubacionMaxDias: dias(1)},
+			want:       PeriodosSEIR{Latencia: 1, Infeccioso: 7, OrigenLatencia: OrigenCatalogo, OrigenInfeccioso: OrigenGenerico},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := periodosSEIR(tt.consulta, tt.enfermedad); got != tt.want {
+				t.Errorf("periodosSEIR() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAjustarCrecimiento(t *testing.T) {
+	hoy := time.Date(2024, 3, 21, 0, 0, 0, 0, time.UTC)
+	serie := func(casos func(x float64) float64) []CasoTemporal {
+		var serie []CasoTemporal
+		for i := 0; i < seirVentanaAjuste; i++ {
+			x := float64(i - (seirVentanaAjuste - 1))
+			serie = append(serie, CasoTemporal{Fecha: hoy.AddDate(0, 0, int(x)), TotalCasos: int(math.Round(casos(x)))})
+		}
+		return serie
+	}
+
+	tests := []struct {
+		name        string
+		casos       []CasoTemporal
+		r           float64
+		toleranciaR float64
+		error       *float64 // nil si no se compara
+	}{
+		{
+			// Con 10 casos diarios el error es 1/sqrt(10·Σ(x - x̄)²) = 1/sqrt(7700)
+			name:        "constante",
+			casos:       serie(func(float64) float64 { return 10 }),
+			r:           0,
+			toleranciaR: 1e-9,
+			error:       ptrFloat(1 / math.Sqrt(7700)),
+		},
+		{
+			name:        "exponencial al 10% diario",
+			casos:       serie(func(x float64) float64 { return 1000 * math.Exp(0.1*x) }),
+			r:           0.1,
+			toleranciaR: 1e-3,
+		},
+		{
+			name:        "duplicación diaria se limita al crecimiento máximo",
+			casos:       serie(func(x float64) float64 { return math.Pow(2, x+seirVentanaAjuste-1) }),
+			r:           seirCrecimientoMaximo,
+			toleranciaR: 0,
+		},
+		{
+			name:  "sin casos",
+			r:     0,
+			error: ptrFloat(seirErrorDefecto),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, errorEstandar := ajustarCrecimiento(tt.casos, hoy)
+			if math.Abs(r-tt.r) > tt.toleranciaR {
+				t.Errorf("r = %v, want %v", r, tt.r)
+			}
+			if tt.error != nil && math.Abs(errorEstandar-*tt.error) > 1e-6 {
+				t.Errorf("error estándar = %v, want %v", errorEstandar, *tt.error)
+			}
+		})
+	}
+}
+
+func TestAjustarCrecimientoDistritos(t *testing.T) {
+	hoy := time.Date(2024, 3, 21, 0, 0, 0, 0, time.UTC)
+	red := &redDistritos{
+		distritos: []models.Distrito{{Nombre: "Centro"}, {Nombre: "Plan 3000"}, {Nombre: "Palmasola"}},
+		indice:    map[string]int{"centro": 0, "plan 3000": 1, "palmasola": 2},
+	}
+
+	var casos []CasoTemporal
+	for i := 0; i < seirVentanaAjuste; i++ {
+		x := float64(i - (seirVentanaAjuste - 1))
+		fecha := hoy.AddDate(0, 0, int(x))
+		casos = append(casos,
+			CasoTemporal{Fecha: fecha, Distrito: "CENTRO", TotalCasos: int(math.Round(1000 * math.Exp(0.1*x)))},
+			CasoTemporal{Fecha: fecha, Distrito: "Plan 3000", TotalCasos: 10},
+		)
+	}
+	// Palmasola no alcanza los casos mínimos: usa la tasa de la ciudad
+	casos = append(casos, CasoTemporal{Fecha: hoy, Distrito: "Palmasola", TotalCasos: seirCasosMinimosDistrito - 1})
+
+	ciudad := crecimientoDistrito{r: 0.05, errorEstandar: 0.01, ajuste: AjusteCiudad}
+	got := ajustarCrecimientoDistritos(casos, red, hoy, ciudad)
+
+	tests := []struct {
+		distrito string
+		r        float64
+		ajuste   string
+	}{
+		{"Centro", 0.1, AjusteDistrito},
+		{"Plan 3000", 0, AjusteDistrito},
+		{"Palmasola", 0.05, AjusteCiudad},
+	}
+	for i, tt := range tests {
+		t.Run(tt.distrito, func(t *testing.T) {
+			if math.Abs(got[i].r-tt.r) > 1e-3 || got[i].ajuste != tt.ajuste {
+				t.Errorf("crecimiento = %+v, want r %v y ajuste %s", got[i], tt.r, tt.ajuste)
+			}
+		})
+	}
+}
+
+func TestPercentil(t *testing.T) {
+	tests := []struct {
+		ordenados []float64
+		p         float64
+		want      float64
+	}{
+		{[]float64{1, 2, 3, 4}, 0.5, 2.5},
+		{[]float64{1, 2, 3, 4}, 0, 1},
+		{[]float64{1, 2, 3, 4}, 1, 4},
+		{[]float64{1, 2, 3, 4, 5}, 0.975, 4.9},
+		{[]float64{0, 1}, 1.0 / 3, 0.33},
+		{[]float64{7}, 0.5, 7},
+		{nil, 0.5, 0},
+	}
+
+	for _, tt := range tests {
+		if got := percentil(tt.ordenados, tt.p); got != tt.want {
+			t.Errorf("percentil(%v, %v) = %v, want %v", tt.ordenados, tt.p, got, tt.want)
+		}
+	}
+}
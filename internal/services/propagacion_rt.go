@@ -0,0 +1,323 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+
+	"hospital-api/internal/models"
+)
+
+// ErrIntervaloSerialNoConfigurado indica que la enfermedad no tiene intervalo serial y no se indicó uno en la consulta
+var ErrIntervaloSerialNoConfigurado = errors.New("la enfermedad no tiene intervalo serial configurado")
+
+// Parámetros del método de Cori et al. (2013), con los valores por defecto de EpiEstim
+const (
+	RtVentanaDefecto    = 7   // Días de la ventana deslizante
+	rtPriorForma        = 1.0 // Prior gamma de Rt: media 5, desviación 5
+	rtPriorEscala       = 5.0
+	rtCasosMinimos      = 12 // Casos acumulados desde el inicio de la serie a partir de los cuales se estima
+	rtIntervaloMaximo   = 90 // Días máximos del intervalo serial discretizado
+	rtProbabilidadClara = 0.95
+)
+
+// fechaInicioSintomasSQL fecha de inicio de un caso: inicio de síntomas o, si no se registró, la consulta
+const fechaInicioSintomasSQL = "COALESCE(symptoms_start_date, consultation_date::date)"
+
+// Tendencias de la epidemia según la probabilidad de que Rt supere 1
+const (
+	TendenciaCreciendo     = "creciendo"
+	TendenciaDecreciendo   = "decreciendo"
+	TendenciaIndeterminada = "indeterminada" // El intervalo creíble incluye 1
+)
+
+// IntervaloSerial distribución usada en la estimación y su origen (catálogo o parámetros de la consulta)
+type IntervaloSerial struct {
+	Distribucion string  `json:"distribucion"`
+	Media        float64 `json:"media"`
+	Desviacion   float64 `json:"desviacion"`
+	Origen       string  `json:"origen,omitempty"`
+}
+
+// EstimacionRt serie diaria del número reproductivo efectivo de una enfermedad
+type EstimacionRt struct {
+	Enfermedad      string          `json:"enfermedad"`
+	Distrito        string          `json:"distrito"` // Vacío: todos los distritos
+	PeriodoAnalisis PeriodoAnalisis `json:"periodo_analisis"`
+	IntervaloSerial IntervaloSerial `json:"intervalo_serial"`
+	VentanaDias     int             `json:"ventana_dias"`
+	Actual          *RtDiario       `json:"actual"` // Última estimación disponible
+	Tendencia       string          `json:"tendencia"`
+	Serie           []RtDiario      `json:"serie"`
+}
+
+// RtDiario estimación de Rt en la ventana que termina en Fecha, con su intervalo creíble del 95%
+type RtDiario struct {
+	Fecha                   time.Time `json:"fecha"`
+	Casos                   int       `json:"casos"` // Casos con inicio de síntomas en Fecha
+	Rt                      float64   `json:"rt"`
+	Inferior                float64   `json:"ic95_inferior"`
+	Superior                float64   `json:"ic95_superior"`
+	ProbabilidadCrecimiento float64   `json:"probabilidad_crecimiento"` // P(Rt > 1)
+}
+
+// ResumenTransmisibilidad Rt actual incluido en el análisis de propagación
+type ResumenTransmisibilidad struct {
+	Rt                      float64   `json:"rt"`
+	Inferior                float64   `json:"ic95_inferior"`
+	Superior                float64   `json:"ic95_superior"`
+	ProbabilidadCrecimiento float64   `json:"probabilidad_crecimiento"`
+	Tendencia               string    `json:"tendencia"`
+	Fecha                   time.Time `json:"fecha"`
+}
+
+// EstimarRt estima el Rt diario de una enfermedad (en un distrito o en toda la ciudad) con el método de Cori,
+// fechando cada caso por su inicio de síntomas o, si no se registró, por su consulta. Sin intervalo se usa
+// el del catálogo.
+func (s *PropagacionService) EstimarRt(enfermedad, distrito string, dias, ventana int, intervalo *IntervaloSerial) (*EstimacionRt, error) {
+	if intervalo == nil {
+		catalogo, err := resolverEnfermedad(s.db, nil, enfermedad)
+		if err != nil {
+			return nil, err
+		}
+		if catalogo.IntervaloSerialMedia == nil || catalogo.IntervaloSerialDesviacion == nil {
+			return nil, ErrIntervaloSerialNoConfigurado
+		}
+		intervalo = &IntervaloSerial{
+			Distribucion: catalogo.IntervaloSerialDistribucion,
+			Media:        *catalogo.IntervaloSerialMedia,
+			Desviacion:   *catalogo.IntervaloSerialDesviacion,
+			Origen:       "catalogo",
+		}
+	} else if intervalo.Origen == "" {
+		intervalo.Origen = "parametros"
+	}
+
+	w := discretizarIntervaloSerial(*intervalo)
+	hoy := diaUTC(time.Now())
+	fechaFin := hoy.AddDate(0, 0, 1)
+	fechaInicio := fechaFin.AddDate(0, 0, -dias)
+	inicioSerie := fechaInicio.AddDate(0, 0, -(len(w) + ventana))
+
+	var filas []struct {
+		Fecha time.Time
+		Casos int
+	}
+	query := s.db.Model(&models.HistorialClinico{}).
+		Select(fechaInicioSintomasSQL+" AS fecha, COUNT(*) AS casos").
+		Where(coincideEnfermedadSQL, sql.Named("enfermedad", enfermedad)).
+		Where(fechaInicioSintomasSQL+" >= ? AND "+fechaInicioSintomasSQL+" < ?", inicioSerie, fechaFin)
+	if distrito != "" {
+		query = query.Where("f_normalizar(patient_district) = f_normalizar(?)", distrito)
+	}
+	if err := query.Group(fechaInicioSintomasSQL).Scan(&filas).Error; err != nil {
+		return nil, err
+	}
+
+	incidencia := make([]float64, diasEntre(inicioSerie, fechaFin))
+	for _, fila := range filas {
+		if i := diasEntre(inicioSerie, diaUTC(fila.Fecha)); i >= 0 && i < len(incidencia) {
+			incidencia[i] += float64(fila.Casos)
+		}
+	}
+
+	estimacion := &EstimacionRt{
+		Enfermedad: enfermedad,
+		Distrito:   distrito,
+		PeriodoAnalisis: PeriodoAnalisis{
+			FechaInicio: fechaInicio,
+			FechaFin:    fechaFin,
+			DiasTotales: dias,
+		},
+		IntervaloSerial: *intervalo,
+		VentanaDias:     ventana,
+		Tendencia:       TendenciaIndeterminada,
+		Serie:           []RtDiario{},
+	}
+
+	for t, rt := range estimarRtCori(incidencia, w, ventana) {
+		if rt == nil || t < diasEntre(inicioSerie, fechaInicio) {
+			continue
+		}
+		rt.Fecha = inicioSerie.AddDate(0, 0, t)
+		estimacion.Serie = append(estimacion.Serie, *rt)
+	}
+
+	if n := len(estimacion.Serie); n > 0 {
+		estimacion.Actual = &estimacion.Serie[n-1]
+		estimacion.Tendencia = tendenciaRt(estimacion.Actual.ProbabilidadCrecimiento)
+	}
+
+	return estimacion, nil
+}
+
+// resumenTransmisibilidad Rt actual de la enfermedad en toda la ciudad; nil si no se puede estimar
+func (s *PropagacionService) resumenTransmisibilidad(enfermedad string, dias int) *ResumenTransmisibilidad {
+	estimacion, err := s.EstimarRt(enfermedad, "", dias, RtVentanaDefecto, nil)
+	if err != nil || estimacion.Actual == nil {
+		return nil
+	}
+
+	actual := estimacion.Actual
+	return &ResumenTransmisibilidad{
+		Rt:                      actual.Rt,
+		Inferior:                actual.Inferior,
+		Superior:                actual.Superior,
+		ProbabilidadCrecimiento: actual.ProbabilidadCrecimiento,
+		Tendencia:               estimacion.Tendencia,
+		Fecha:                   actual.Fecha,
+	}
+}
+
+// estimarRtCori posterior gamma de Rt en cada ventana [t-ventana+1, t]: forma = a + casos de la ventana y
+// escala = 1 / (1/b + infectividad total de la ventana), donde la infectividad de un día es la suma de los casos
+// anteriores ponderados por el intervalo serial. Retorna nil en los días sin casos suficientes para estimar.
+func estimarRtCori(incidencia, w []float64, ventana int) []*RtDiario {
+	infectividad := make([]float64, len(incidencia))
+	for t := range incidencia {
+		for s := 1; s < len(w) && s <= t; s++ {
+			infectividad[t] += incidencia[t-s] * w[s]
+		}
+	}
+
+	resultado := make([]*RtDiario, len(incidencia))
+	acumulados := 0.0
+	for t := range incidencia {
+		acumulados += incidencia[t]
+		inicio := t - ventana + 1
+		if inicio < 1 || acumulados < rtCasosMinimos {
+			continue
+		}
+
+		casos, lambda := 0.0, 0.0
+		for k := inicio; k <= t; k++ {
+			casos += incidencia[k]
+			lambda += infectividad[k]
+		}
+		if lambda <= 0 {
+			continue
+		}
+
+		forma := rtPriorForma + casos
+		escala := 1 / (1/rtPriorEscala + lambda)
+		resultado[t] = &RtDiario{
+			Casos:                   int(incidencia[t]),
+			Rt:                      redondear(forma*escala, 3),
+			Inferior:                redondear(cuantilGamma(0.025, forma, escala), 3),
+			Superior:                redondear(cuantilGamma(0.975, forma, escala), 3),
+			ProbabilidadCrecimiento: redondear(1-gammaRegularizada(forma, 1/escala), 4),
+		}
+	}
+	return resultado
+}
+
+// discretizarIntervaloSerial probabilidad de cada día s >= 1 del intervalo serial: F(s+0.5) - F(s-0.5),
+// con la masa de s < 1 descartada y el resto normalizado
+func discretizarIntervaloSerial(intervalo IntervaloSerial) []float64 {
+	cdf := func(x float64) float64 {
+		if x <= 0 {
+			return 0
+		}
+		if intervalo.Distribucion == models.IntervaloSerialLognormal {
+			sigma2 := math.Log(1 + intervalo.Desviacion*intervalo.Desviacion/(intervalo.Media*intervalo.Media))
+			mu := math.Log(intervalo.Media) - sigma2/2
+			return normalCDF((math.Log(x) - mu) / math.Sqrt(sigma2))
+		}
+		forma := intervalo.Media * intervalo.Media / (intervalo.Desviacion * intervalo.Desviacion)
+		escala := intervalo.Desviacion * intervalo.Desviacion / intervalo.Media
+		return gammaRegularizada(forma, x/escala)
+	}
+
+	w := []float64{0}
+	total := 0.0
+	for s := 1; s <= rtIntervaloMaximo; s++ {
+		p := cdf(float64(s)+0.5) - cdf(float64(s)-0.5)
+		w = append(w, p)
+		total += p
+		if cdf(float64(s)+0.5) > 0.999 {
+			break
+		}
+	}
+	for s := range w {
+		w[s] /= total
+	}
+	return w
+}
+
+// tendenciaRt interpreta la probabilidad de que Rt supere 1
+func tendenciaRt(probabilidadCrecimiento float64) string {
+	switch {
+	case probabilidadCrecimiento >= rtProbabilidadClara:
+		return TendenciaCreciendo
+	case probabilidadCrecimiento <= 1-rtProbabilidadClara:
+		return TendenciaDecreciendo
+	default:
+		return TendenciaIndeterminada
+	}
+}
+
+// gammaRegularizada función gamma incompleta inferior regularizada P(a, x): la CDF de una gamma de forma a
+// y escala 1 evaluada en x. Serie para x < a+1 y fracción continua en otro caso (Numerical Recipes).
+func gammaRegularizada(a, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	lgamma, _ := math.Lgamma(a)
+	prefactor := math.Exp(-x + a*math.Log(x) - lgamma)
+
+	if x < a+1 {
+		termino := 1 / a
+		suma := termino
+		for n := 1; n < 1000; n++ {
+			termino *= x / (a + float64(n))
+			suma += termino
+			if math.Abs(termino) < math.Abs(suma)*1e-15 {
+				break
+			}
+		}
+		return math.Min(1, suma*prefactor)
+	}
+
+	const minimo = 1e-300
+	b := x + 1 - a
+	c := 1 / minimo
+	d := 1 / b
+	h := d
+	for i := 1; i < 1000; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < minimo {
+			d = minimo
+		}
+		c = b + an/c
+		if math.Abs(c) < minimo {
+			c = minimo
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+	return math.Max(0, 1-prefactor*h)
+}
+
+// cuantilGamma cuantil p de una gamma de forma y escala dadas, por bisección
+func cuantilGamma(p, forma, escala float64) float64 {
+	inferior, superior := 0.0, forma+10*math.Sqrt(forma)+10
+	for gammaRegularizada(forma, superior) < p {
+		superior *= 2
+	}
+	for i := 0; i < 100; i++ {
+		medio := (inferior + superior) / 2
+		if gammaRegularizada(forma, medio) < p {
+			inferior = medio
+		} else {
+			superior = medio
+		}
+	}
+	return (inferior + superior) / 2 * escala
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"hospital-api/internal/models"
+)
+
+func TestGammaRegularizada(t *testing.T) {
+	tests := []struct {
+		a, x float64
+		want float64
+	}{
+		// P(1, x) = 1 - e^-x, con la serie (x < 2) y con la fracción continua
+		{1, 0.5, 1 - math.Exp(-0.5)},
+		{1, 1, 1 - math.Exp(-1)},
+		{1, 5, 1 - math.Exp(-5)},
+		{2, 1, 1 - 2/math.E},
+		{3, 2, 1 - 5*math.Exp(-2)},
+		{10, 10, 0.5420702855},
+		// P(1/2, x) = erf(sqrt(x))
+		{0.5, 0.25, math.Erf(0.5)},
+		{0.5, 4, math.Erf(2)},
+		{2, 0, 0},
+		{2, -1, 0},
+	}
+
+	for _, tt := range tests {
+		if got := gammaRegularizada(tt.a, tt.x); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("gammaRegularizada(%v, %v) = %.10f, want %.10f", tt.a, tt.x, got, tt.want)
+		}
+	}
+}
+
+func TestCuantilGamma(t *testing.T) {
+	tests := []struct {
+		name             string
+		p, forma, escala float64
+		want             float64
+	}{
+		// Chi-cuadrado con k grados de libertad: gamma de forma k/2 y escala 2
+		{"chi2(1) al 95%", 0.95, 0.5, 2, 3.8414588207},
+		{"chi2(2) al 97,5%", 0.975, 1, 2, 7.3777589082},
+		{"chi2(10) al 5%", 0.05, 5, 2, 3.9402991361},
+		{"chi2(4) mediana", 0.5, 2, 2, 3.3566939800},
+		{"mediana exponencial", 0.5, 1, 3, 3 * math.Ln2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cuantilGamma(tt.p, tt.forma, tt.escala); math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("cuantilGamma(%v, %v, %v) = %.10f, want %.10f", tt.p, tt.forma, tt.escala, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscretizarIntervaloSerial(t *testing.T) {
+	tests := []struct {
+		name      string
+		intervalo IntervaloSerial
+	}{
+		{"gamma", IntervaloSerial{Distribucion: models.IntervaloSerialGamma, Media: 5, Desviacion: 2}},
+		{"gamma con mucha dispersión", IntervaloSerial{Distribucion: models.IntervaloSerialGamma, Media: 15, Desviacion: 9}},
+		{"lognormal", IntervaloSerial{Distribucion: models.IntervaloSerialLognormal, Media: 5, Desviacion: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := discretizarIntervaloSerial(tt.intervalo)
+			if w[0] != 0 {
+				t.Errorf("w[0] = %v, want 0", w[0])
+			}
+			suma, media := 0.0, 0.0
+			for s, p := range w {
+				if p < 0 {
+					t.Errorf("w[%d] = %v, want >= 0", s, p)
+				}
+				suma += p
+				media += float64(s) * p
+			}
+			if math.Abs(suma-1) > 1e-12 {
+				t.Errorf("suma = %v, want 1", suma)
+			}
+			// Redondear al día más cercano y descartar s < 1 apenas desplaza la media
+			if math.Abs(media-tt.intervalo.Media) > 0.1 {
+				t.Errorf("media = %v, want %v", media, tt.intervalo.Media)
+			}
+		})
+	}
+}
+
+func TestEstimarRtCori(t *testing.T) {
+	// Con un intervalo serial de exactamente un día la infectividad de t son los casos de t-1
+	w := []float64{0, 1}
+	constante := make([]float64, 10)
+	duplicacion := make([]float64, 10)
+	for i := range constante {
+		constante[i] = 10
+		duplicacion[i] = math.Pow(2, float64(i))
+	}
+
+	tests := []struct {
+		name       string
+		incidencia []float64
+		ventana    int
+		t          int
+		want       *RtDiario
+	}{
+		{
+			// 70 casos y 70 de infectividad en la ventana: posterior gamma de forma 1 + 70 y tasa 1/5 + 70
+			name:       "incidencia constante",
+			incidencia: constante,
+			ventana:    7,
+			t:          7,
+			want:       &RtDiario{Casos: 10, Rt: 1.011, Inferior: 0.79, Superior: 1.26, ProbabilidadCrecimiento: 0.5222},
+		},
+		{
+			name:       "ventana incompleta",
+			incidencia: constante,
+			ventana:    7,
+			t:          6,
+		},
+		{
+			// Ventana 2..8: 508 casos y 254 de infectividad; Rt = 509/254,2
+			name:       "duplicación diaria",
+			incidencia: duplicacion,
+			ventana:    7,
+			t:          8,
+			want:       &RtDiario{Casos: 256, Rt: redondear(509/254.2, 3)},
+		},
+		{
+			// Hasta el día 3 se acumulan 1+2+4+8 = 15 casos, recién entonces se estima
+			name:       "casos acumulados insuficientes",
+			incidencia: duplicacion,
+			ventana:    2,
+			t:          2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := estimarRtCori(tt.incidencia, w, tt.ventana)[tt.t]
+			switch {
+			case tt.want == nil && got != nil:
+				t.Fatalf("estimarRtCori() = %+v, want nil", got)
+			case tt.want == nil:
+				return
+			case got == nil:
+				t.Fatalf("estimarRtCori() = nil, want %+v", tt.want)
+			}
+			if got.Casos != tt.want.Casos || got.Rt != tt.want.Rt {
+				t.Errorf("estimarRtCori() = %+v, want %+v", got, tt.want)
+			}
+			if tt.want.Superior > 0 && (got.Inferior != tt.want.Inferior || got.Superior != tt.want.Superior ||
+				got.ProbabilidadCrecimiento != tt.want.ProbabilidadCrecimiento) {
+				t.Errorf("estimarRtCori() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"math"
 	"sort"
@@ -8,6 +10,7 @@ import (
 
 	"hospital-api/internal/database"
 	"hospital-api/internal/models"
+	"hospital-api/internal/utils"
 
 	"gorm.io/gorm"
 )
@@ -16,74 +19,6 @@ type PropagacionService struct {
 	db *gorm.DB
 }
 
-// Datos demográficos de Santa Cruz por distrito (habitantes por km²)
-var densidadPoblacionalSantaCruz = map[string]DensidadDistrito{
-	"Equipetrol": {
-		Habitantes:  85000,
-		AreaKm2:     12.5,
-		Densidad:    6800, // hab/km²
-		Conectividad: []string{"Norte", "Centro", "Sur"},
-		TipoZona:    "Residencial-Comercial",
-	},
-	"Norte": {
-		Habitantes:  320000,
-		AreaKm2:     45.8,
-		Densidad:    6986,
-		Conectividad: []string{"Equipetrol", "Plan Tres Mil", "Este"},
-		TipoZona:    "Residencial-Popular",
-	},
-	"Plan Tres Mil": {
-		Habitantes:  180000,
-		AreaKm2:     22.3,
-		Densidad:    8072,
-		Conectividad: []string{"Norte", "Sur", "Este"},
-		TipoZona:    "Popular-Alta Densidad",
-	},
-	"Villa 1ro de Mayo": {
-		Habitantes:  95000,
-		AreaKm2:     18.7,
-		Densidad:    5080,
-		Conectividad: []string{"Oeste", "Centro"},
-		TipoZona:    "Residencial",
-	},
-	"Sur": {
-		Habitantes:  125000,
-		AreaKm2:     28.4,
-		Densidad:    4401,
-		Conectividad: []string{"Equipetrol", "Plan Tres Mil", "Centro"},
-		TipoZona:    "Residencial-Comercial",
-	},
-	"Oeste": {
-		Habitantes:  75000,
-		AreaKm2:     35.2,
-		Densidad:    2131,
-		Conectividad: []string{"Villa 1ro de Mayo", "Centro"},
-		TipoZona:    "Residencial-Periférico",
-	},
-	"Este": {
-		Habitantes:  60000,
-		AreaKm2:     42.1,
-		Densidad:    1425,
-		Conectividad: []string{"Norte", "Plan Tres Mil"},
-		TipoZona:    "Periférico-Rural",
-	},
-	"Centro": {
-		Habitantes:  45000,
-		AreaKm2:     8.2,
-		Densidad:    5488,
-		Conectividad: []string{"Equipetrol", "Sur", "Oeste", "Villa 1ro de Mayo"},
-		TipoZona:    "Comercial-Histórico",
-	},
-}
-
-type DensidadDistrito struct {
-	Habitantes   int      `json:"habitantes"`
-	AreaKm2      float64  `json:"area_km2"`
-	Densidad     int      `json:"densidad_hab_km2"`
-	Conectividad []string `json:"conectividad"`
-	TipoZona     string   `json:"tipo_zona"`
-}
-
 type CasoTemporal struct {
 	Fecha           time.Time `json:"fecha"`
 	Distrito        string    `json:"distrito"`
@@ -106,6 +41,8 @@ type VelocidadPropagacion struct {
 	RutasPropagacion     []RutaPropagacion        `json:"rutas_propagacion"`
 	FactorDensidad       float64                  `json:"factor_densidad"`
 	PredictedSpread      []PrediccionPropagacion  `json:"prediccion_propagacion"`
+	ModeloPrediccion     ModeloSEIR               `json:"modelo_prediccion"`
+	Transmisibilidad     *ResumenTransmisibilidad `json:"transmisibilidad"` // Rt actual; nil si no se puede estimar
 	RecomendacionesAlert []string                 `json:"recomendaciones_alerta"`
 }
 
@@ -134,12 +71,20 @@ type RutaPropagacion struct {
 	VelocidadKmDia  float64   `json:"velocidad_km_por_dia"`
 }
 
+// PrediccionPropagacion pronóstico del modelo SEIR para un distrito. Los casos son los nuevos del horizonte
+// (mediana e intervalo del 95%) y la probabilidad es la de registrar al menos un caso nuevo.
 type PrediccionPropagacion struct {
-	Distrito        string    `json:"distrito"`
-	FechaPrediccion time.Time `json:"fecha_prediccion"`
-	CasosPredichos  int       `json:"casos_predichos"`
-	Probabilidad    float64   `json:"probabilidad"`
-	NivelRiesgo     string    `json:"nivel_riesgo"`
+	Distrito           string             `json:"distrito"`
+	FechaPrediccion    time.Time          `json:"fecha_prediccion"`
+	CasosPredichos     int                `json:"casos_predichos"`
+	CasosInferior      int                `json:"casos_ic95_inferior"`
+	CasosSuperior      int                `json:"casos_ic95_superior"`
+	Probabilidad       float64            `json:"probabilidad"`
+	NivelRiesgo        string             `json:"nivel_riesgo"`
+	PronosticoDiario   []PronosticoDiario `json:"pronostico_diario"`
+	TasaCrecimiento    float64            `json:"tasa_crecimiento_diaria"`
+	NumeroReproductivo float64            `json:"numero_reproductivo"`
+	AjusteCrecimiento  string             `json:"ajuste_crecimiento"` // distrito o ciudad (pocos casos en el distrito)
 }
 
 func NewPropagacionService() *PropagacionService {
@@ -148,8 +93,9 @@ func NewPropagacionService() *PropagacionService {
 	}
 }
 
-// AnalyzeSpreadVelocity analiza la velocidad de propagación de una enfermedad específica
-func (s *PropagacionService) AnalyzeSpreadVelocity(enfermedad string, diasAnalisis int) (*VelocidadPropagacion, error) {
+// AnalyzeSpreadVelocity analiza la velocidad de propagación de una enfermedad específica y pronostica
+// los próximos horizonte días. Los periodos del modelo que no se indican en periodos se toman del catálogo.
+func (s *PropagacionService) AnalyzeSpreadVelocity(enfermedad string, diasAnalisis, horizonte int, periodos PeriodosSEIR) (*VelocidadPropagacion, error) {
 	// Calcular período de análisis
 	fechaFin := time.Now()
 	fechaInicio := fechaFin.AddDate(0, 0, -diasAnalisis)
@@ -164,11 +110,17 @@ func (s *PropagacionService) AnalyzeSpreadVelocity(enfermedad string, diasAnalis
 		return nil, fmt.Errorf("no se encontraron casos para la enfermedad %s en el período especificado", enfermedad)
 	}
 
+	// Registro de distritos: densidad, conexiones y centroides
+	red, err := cargarRedDistritos(s.db)
+	if err != nil {
+		return nil, err
+	}
+
 	// Analizar distritos afectados
-	distritosAfectados := s.analizarDistritosAfectados(casosTemporales)
+	distritosAfectados := s.analizarDistritosAfectados(casosTemporales, red)
 
 	// Calcular rutas de propagación
-	rutasPropagacion := s.calcularRutasPropagacion(distritosAfectados)
+	rutasPropagacion := s.calcularRutasPropagacion(distritosAfectados, red)
 
 	// Calcular velocidades
 	velocidadPromedio, velocidadMaxima := s.calcularVelocidades(casosTemporales, diasAnalisis)
@@ -176,8 +128,14 @@ func (s *PropagacionService) AnalyzeSpreadVelocity(enfermedad string, diasAnalis
 	// Calcular factor de densidad
 	factorDensidad := s.calcularFactorDensidad(distritosAfectados)
 
-	// Generar predicciones
-	predicciones := s.generarPredicciones(distritosAfectados, velocidadPromedio, factorDensidad)
+	// Periodos de latencia e infeccioso de la enfermedad; fuera del catálogo se usan los genéricos
+	catalogo, err := resolverEnfermedad(s.db, nil, enfermedad)
+	if err != nil && !errors.Is(err, ErrEnfermedadDesconocida) {
+		return nil, err
+	}
+
+	// Generar predicciones con el modelo SEIR ajustado a los casos observados
+	predicciones, modelo := s.generarPredicciones(casosTemporales, red, fechaFin, horizonte, periodosSEIR(periodos, catalogo))
 
 	// Generar recomendaciones
 	recomendaciones := s.generarRecomendaciones(distritosAfectados, velocidadPromedio, factorDensidad)
@@ -195,6 +153,8 @@ func (s *PropagacionService) AnalyzeSpreadVelocity(enfermedad string, diasAnalis
 		RutasPropagacion:     rutasPropagacion,
 		FactorDensidad:       factorDensidad,
 		PredictedSpread:      predicciones,
+		ModeloPrediccion:     modelo,
+		Transmisibilidad:     s.resumenTransmisibilidad(enfermedad, diasAnalisis),
 		RecomendacionesAlert: recomendaciones,
 	}
 
@@ -220,7 +180,8 @@ func (s *PropagacionService) obtenerCasosTemporales(enfermedad string, fechaInic
 			AVG(patient_latitude) as avg_lat,
 			AVG(patient_longitude) as avg_lng
 		`).
-		Where("LOWER(enfermedad) = LOWER(?) AND consultation_date BETWEEN ? AND ?", enfermedad, fechaInicio, fechaFin).
+		Where(coincideEnfermedadSQL, sql.Named("enfermedad", enfermedad)).
+		Where("consultation_date BETWEEN ? AND ?", fechaInicio, fechaFin).
 		Group("consultation_date::date, patient_district").
 		Order("fecha ASC, distrito").
 		Scan(&resultados).Error
@@ -246,7 +207,7 @@ func (s *PropagacionService) obtenerCasosTemporales(enfermedad string, fechaInic
 	return casosTemporales, nil
 }
 
-func (s *PropagacionService) analizarDistritosAfectados(casos []CasoTemporal) []DistritoAfectado {
+func (s *PropagacionService) analizarDistritosAfectados(casos []CasoTemporal, red *redDistritos) []DistritoAfectado {
 	distritoMap := make(map[string]*DistritoAfectado)
 
 	// Agrupar casos por distrito
@@ -261,7 +222,7 @@ func (s *PropagacionService) analizarDistritosAfectados(casos []CasoTemporal) []
 			}
 		} else {
 			densidad := 0
-			if info, exists := densidadPoblacionalSantaCruz[caso.Distrito]; exists {
+			if info, exists := red.buscar(caso.Distrito); exists {
 				densidad = info.Densidad
 			}
 
@@ -300,7 +261,7 @@ func (s *PropagacionService) analizarDistritosAfectados(casos []CasoTemporal) []
 	return distritos
 }
 
-func (s *PropagacionService) calcularRutasPropagacion(distritos []DistritoAfectado) []RutaPropagacion {
+func (s *PropagacionService) calcularRutasPropagacion(distritos []DistritoAfectado, red *redDistritos) []RutaPropagacion {
 	var rutas []RutaPropagacion
 
 	// Ordenar distritos por fecha del primer caso
@@ -310,14 +271,14 @@ func (s *PropagacionService) calcularRutasPropagacion(distritos []DistritoAfecta
 
 	// Analizar propagación entre distritos conectados
 	for i, origen := range distritos {
-		if conectividad, exists := densidadPoblacionalSantaCruz[origen.Distrito]; exists {
-			for _, distritoConectado := range conectividad.Conectividad {
+		if conectividad, exists := red.buscar(origen.Distrito); exists {
+			for _, distritoConectado := range conectividad.Conexiones {
 				// Buscar el distrito conectado en la lista de afectados
 				for j, destino := range distritos {
-					if j > i && destino.Distrito == distritoConectado {
+					if j > i && utils.NormalizarTexto(destino.Distrito) == utils.NormalizarTexto(distritoConectado.Distrito) {
 						diasTransicion := int(destino.PrimerCaso.Sub(origen.PrimerCaso).Hours() / 24)
 						if diasTransicion > 0 && diasTransicion <= 14 { // Máximo 14 días para considerar propagación directa
-							distancia := s.calcularDistanciaKm(origen.Distrito, destino.Distrito)
+							distancia := s.calcularDistanciaKm(red, origen.Distrito, destino.Distrito)
 							velocidadKm := 0.0
 							if diasTransicion > 0 {
 								velocidadKm = distancia / float64(diasTransicion)
@@ -342,21 +303,10 @@ func (s *PropagacionService) calcularRutasPropagacion(distritos []DistritoAfecta
 	return rutas
 }
 
-func (s *PropagacionService) calcularDistanciaKm(distrito1, distrito2 string) float64 {
-	// Coordenadas aproximadas del centro de cada distrito en Santa Cruz
-	coordenadas := map[string]Coordenada{
-		"Equipetrol":       {-17.7690416, -63.1956686},
-		"Norte":            {-17.7987909, -63.210345},
-		"Plan Tres Mil":    {-17.798792, -63.210345},
-		"Villa 1ro de Mayo": {-17.7379806, -63.2484834},
-		"Sur":              {-17.7441931, -63.1801563},
-		"Oeste":            {-17.7439533, -63.1756103},
-		"Este":             {-17.7728417, -63.2374135},
-		"Centro":           {-17.7807346, -63.1890985},
-	}
-
-	coord1, exists1 := coordenadas[distrito1]
-	coord2, exists2 := coordenadas[distrito2]
+// calcularDistanciaKm distancia entre los centroides de dos distritos del registro
+func (s *PropagacionService) calcularDistanciaKm(red *redDistritos, distrito1, distrito2 string) float64 {
+	coord1, exists1 := red.buscar(distrito1)
+	coord2, exists2 := red.buscar(distrito2)
 
 	if !exists1 || !exists2 {
 		return 0
@@ -440,74 +390,6 @@ func (s *PropagacionService) calcularRiesgoExpansion(densidad int, velocidad flo
 	}
 }
 
-func (s *PropagacionService) generarPredicciones(distritos []DistritoAfectado, velocidadPromedio, factorDensidad float64) []PrediccionPropagacion {
-	var predicciones []PrediccionPropagacion
-
-	// Obtener distritos no afectados o con baja incidencia
-	for distritoNombre, info := range densidadPoblacionalSantaCruz {
-		afectado := false
-		casosActuales := 0
-
-		for _, distrito := range distritos {
-			if distrito.Distrito == distritoNombre {
-				afectado = true
-				casosActuales = distrito.TotalCasos
-				break
-			}
-		}
-
-		// Predecir para distritos no afectados o con pocos casos
-		if !afectado || casosActuales < 5 {
-			prediccion := s.calcularPrediccion(distritoNombre, info, velocidadPromedio, factorDensidad, casosActuales)
-			predicciones = append(predicciones, prediccion)
-		}
-	}
-
-	// Ordenar por probabilidad descendente
-	sort.Slice(predicciones, func(i, j int) bool {
-		return predicciones[i].Probabilidad > predicciones[j].Probabilidad
-	})
-
-	return predicciones
-}
-
-func (s *PropagacionService) calcularPrediccion(distrito string, info DensidadDistrito, velocidadPromedio, factorDensidad float64, casosActuales int) PrediccionPropagacion {
-	// Calcular probabilidad basada en densidad, conectividad y velocidad de propagación
-	probabilidadBase := float64(info.Densidad) / 10000 // Normalizar densidad
-	factorConectividad := float64(len(info.Conectividad)) / 10
-	factorVelocidad := velocidadPromedio / 10
-
-	probabilidad := (probabilidadBase + factorConectividad + factorVelocidad + factorDensidad/10) * 100
-	if probabilidad > 100 {
-		probabilidad = 100
-	}
-
-	// Calcular casos predichos
-	casosPredichos := int(velocidadPromedio * probabilidad / 100)
-	if casosActuales > 0 {
-		casosPredichos += casosActuales
-	}
-
-	// Determinar nivel de riesgo
-	nivelRiesgo := "BAJO"
-	switch {
-	case probabilidad >= 80:
-		nivelRiesgo = "CRÍTICO"
-	case probabilidad >= 60:
-		nivelRiesgo = "ALTO"
-	case probabilidad >= 40:
-		nivelRiesgo = "MEDIO"
-	}
-
-	return PrediccionPropagacion{
-		Distrito:        distrito,
-		FechaPrediccion: time.Now().AddDate(0, 0, 7), // Predicción a 7 días
-		CasosPredichos:  casosPredichos,
-		Probabilidad:    math.Round(probabilidad*100) / 100,
-		NivelRiesgo:     nivelRiesgo,
-	}
-}
-
 func (s *PropagacionService) generarRecomendaciones(distritos []DistritoAfectado, velocidadPromedio, factorDensidad float64) []string {
 	var recomendaciones []string
 
@@ -536,8 +418,8 @@ func (s *PropagacionService) generarRecomendaciones(distritos []DistritoAfectado
 }
 
 // GetSpreadPredictionsByDistrict obtiene predicciones específicas para un distrito
-func (s *PropagacionService) GetSpreadPredictionsByDistrict(distrito, enfermedad string, dias int) (*PrediccionPropagacion, error) {
-	analisis, err := s.AnalyzeSpreadVelocity(enfermedad, dias)
+func (s *PropagacionService) GetSpreadPredictionsByDistrict(distrito, enfermedad string, dias, horizonte int, periodos PeriodosSEIR) (*PrediccionPropagacion, error) {
+	analisis, err := s.AnalyzeSpreadVelocity(enfermedad, dias, horizonte, periodos)
 	if err != nil {
 		return nil, err
 	}
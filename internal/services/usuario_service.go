@@ -0,0 +1,161 @@
+package services
+
+import (
+	"errors"
+
+	"hospital-api/internal/database"
+	"hospital-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type UsuarioService struct {
+	db *gorm.DB
+}
+
+type CreateUsuarioRequest struct {
+	Nombre   string `json:"nombre" validate:"required,min=2,max=100"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=6"`
+	Rol      string `json:"rol" validate:"required,oneof=admin medico enfermeria epidemiologo analista"`
+}
+
+type UpdateUsuarioRequest struct {
+	Nombre   *string `json:"nombre" validate:"omitempty,min=2,max=100"`
+	Password *string `json:"password" validate:"omitempty,min=6"`
+	Rol      *string `json:"rol" validate:"omitempty,oneof=admin medico enfermeria epidemiologo analista"`
+	Activo   *bool   `json:"activo"`
+}
+
+// NewUsuarioService crea una nueva instancia del servicio de usuarios
+func NewUsuarioService() *UsuarioService {
+	return &UsuarioService{
+		db: database.GetDB(),
+	}
+}
+
+// GetUsuarios obtiene los usuarios del hospital del actor con paginación
+func (s *UsuarioService) GetUsuarios(actor Actor, page, limit int) ([]models.Usuario, int64, error) {
+	var usuarios []models.Usuario
+	var total int64
+
+	query := s.db.Where("id_hospital = ?", actor.HospitalID)
+
+	// Contar total
+	query.Model(&models.Usuario{}).Count(&total)
+
+	// Obtener registros con paginación
+	offset := (page - 1) * limit
+	err := query.Offset(offset).Limit(limit).Order("nombre").Find(&usuarios).Error
+
+	return usuarios, total, err
+}
+
+// GetUsuarioByID obtiene un usuario del hospital del actor
+func (s *UsuarioService) GetUsuarioByID(actor Actor, id uint) (*models.Usuario, error) {
+	var usuario models.Usuario
+	err := s.db.Where("id_hospital = ?", actor.HospitalID).First(&usuario, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("usuario no encontrado")
+		}
+		return nil, err
+	}
+	return &usuario, nil
+}
+
+// CreateUsuario crea un usuario en el hospital del actor
+func (s *UsuarioService) CreateUsuario(actor Actor, req CreateUsuarioRequest) (*models.Usuario, error) {
+	var existing models.Usuario
+	err := s.db.Unscoped().Where("email = ?", req.Email).First(&existing).Error
+	if err == nil {
+		return nil, errors.New("el email ya está registrado")
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	hashedPassword, err := HashPassword(req.Password)
+	if err != nil {
+		return nil, errors.New("error al procesar la contraseña")
+	}
+
+	usuario := models.Usuario{
+		IDHospital: actor.HospitalID,
+		Nombre:     req.Nombre,
+		Email:      req.Email,
+		Password:   hashedPassword,
+		Rol:        req.Rol,
+		Activo:     true,
+	}
+
+	if err := s.db.Create(&usuario).Error; err != nil {
+		return nil, err
+	}
+
+	return &usuario, nil
+}
+
+// UpdateUsuario actualiza nombre, contraseña, rol o estado de un usuario del hospital del actor
+func (s *UsuarioService) UpdateUsuario(actor Actor, id uint, req UpdateUsuarioRequest) (*models.Usuario, error) {
+	usuario, err := s.GetUsuarioByID(actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Un administrador no puede quitarse a sí mismo el rol ni desactivarse
+	if usuario.ID == actor.UsuarioID {
+		if (req.Rol != nil && *req.Rol != models.RolAdmin) || (req.Activo != nil && !*req.Activo) {
+			return nil, errors.New("no puede quitarse el rol de administrador ni desactivar su propia cuenta")
+		}
+	}
+
+	updates := map[string]interface{}{}
+	if req.Nombre != nil {
+		updates["nombre"] = *req.Nombre
+	}
+	if req.Rol != nil {
+		updates["rol"] = *req.Rol
+	}
+	if req.Activo != nil {
+		updates["activo"] = *req.Activo
+	}
+	if req.Password != nil {
+		hashedPassword, err := HashPassword(*req.Password)
+		if err != nil {
+			return nil, errors.New("error al procesar la contraseña")
+		}
+		updates["password"] = hashedPassword
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.Model(usuario).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// Desactivar, cambiar el rol o la contraseña obliga a iniciar sesión nuevamente
+	if (req.Activo != nil && !*req.Activo) || req.Rol != nil || req.Password != nil {
+		if err := revokeRefreshTokensUsuario(s.db, usuario.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetUsuarioByID(actor, id)
+}
+
+// DeleteUsuario elimina un usuario del hospital del actor (soft delete)
+func (s *UsuarioService) DeleteUsuario(actor Actor, id uint) error {
+	if id == actor.UsuarioID {
+		return errors.New("no puede eliminar su propia cuenta")
+	}
+
+	result := s.db.Where("id_hospital = ?", actor.HospitalID).Delete(&models.Usuario{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("usuario no encontrado")
+	}
+	return revokeRefreshTokensUsuario(s.db, id)
+}
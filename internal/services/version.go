@@ -0,0 +1,26 @@
+package services
+
+import "fmt"
+
+// VersionConflictError indica que el recurso cambió desde la versión indicada en If-Match
+type VersionConflictError struct {
+	Actual int // Versión actual del recurso
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("el recurso fue modificado por otro usuario (versión actual %d)", e.Actual)
+}
+
+// verificarVersion comprueba que la versión actual esté entre las aceptadas por If-Match.
+// ifMatch nil acepta cualquier versión ("*").
+func verificarVersion(actual int, ifMatch []int) error {
+	if ifMatch == nil {
+		return nil
+	}
+	for _, version := range ifMatch {
+		if version == actual {
+			return nil
+		}
+	}
+	return &VersionConflictError{Actual: actual}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// Las URLs de webhook las elige un administrador pero las visita el servidor, que puede alcanzar
+// servicios internos y metadatos de la nube que desde afuera no se ven. Por eso se rechazan los
+// destinos que resuelven a direcciones privadas, tanto al registrar la URL como al conectar: la
+// resolución puede cambiar entre ambos momentos y las redirecciones llevan a otros hosts.
+
+// ErrURLWebhookNoPermitida indica que la URL del webhook apunta a una dirección privada o no resoluble
+var ErrURLWebhookNoPermitida = errors.New("la URL del webhook no puede apuntar a direcciones privadas, locales o reservadas")
+
+// timeoutResolucionWebhook tiempo máximo para resolver el host de una URL al registrarla
+const timeoutResolucionWebhook = 5 * time.Second
+
+// redesNoPermitidas rangos reservados que no cubren los métodos de net.IP
+var redesNoPermitidas = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),     // "Esta" red
+	mustParseCIDR("100.64.0.0/10"), // NAT de operador (RFC 6598)
+	mustParseCIDR("192.0.0.0/24"),  // Asignaciones de protocolo del IETF
+	mustParseCIDR("198.18.0.0/15"), // Pruebas de rendimiento (RFC 2544)
+	mustParseCIDR("240.0.0.0/4"),   // Reservada, incluye 255.255.255.255
+	mustParseCIDR("64:ff9b::/96"),  // NAT64, puede traducir a cualquier IPv4
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, red, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return red
+}
+
+// ipNoPermitida indica si ip es de loopback, privada (RFC 1918 y fc00::/7), de enlace local (incluida
+// la de metadatos 169.254.169.254), multicast, no especificada o de otro rango reservado
+func ipNoPermitida(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, red := range redesNoPermitidas {
+		if red.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// validarURLWebhook comprueba que la URL sea http(s) y que todas las direcciones de su host sean públicas
+func validarURLWebhook(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrURLWebhookNoPermitida
+	}
+
+	if ip := net.ParseIP(u.Hostname()); ip != nil {
+		if ipNoPermitida(ip) {
+			return ErrURLWebhookNoPermitida
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutResolucionWebhook)
+	defer cancel()
+	direcciones, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: no se pudo resolver %s", ErrURLWebhookNoPermitida, u.Hostname())
+	}
+	for _, direccion := range direcciones {
+		if ipNoPermitida(direccion.IP) {
+			return ErrURLWebhookNoPermitida
+		}
+	}
+	return nil
+}
+
+// controlarDestinoWebhook se ejecuta antes de cada conexión, ya resuelto el host, y la rechaza si la
+// dirección no es pública. Cubre los cambios de DNS posteriores al registro y las redirecciones.
+func controlarDestinoWebhook(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || ipNoPermitida(ip) {
+		return fmt.Errorf("%w: %s", ErrURLWebhookNoPermitida, host)
+	}
+	return nil
+}
+
+// nuevoClienteWebhooks crea el cliente HTTP de las entregas. No usa proxy, que conectaría en nombre del
+// servidor a cualquier destino, y verifica cada dirección en el momento de conectar.
+func nuevoClienteWebhooks(timeout time.Duration) *http.Client {
+	transporte := http.DefaultTransport.(*http.Transport).Clone()
+	transporte.Proxy = nil
+	transporte.DialContext = (&net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control:   controlarDestinoWebhook,
+	}).DialContext
+	return &http.Client{Timeout: timeout, Transport: transporte}
+}
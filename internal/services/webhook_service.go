@@ -0,0 +1,388 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"hospital-api/internal/config"
+	"hospital-api/internal/database"
+	"hospital-api/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrEntregaNoReintentable indica que solo las entregas fallidas se pueden reintentar manualmente
+var ErrEntregaNoReintentable = errors.New("solo se pueden reintentar entregas fallidas")
+
+// loteEntregas entregas que el despachador reserva en cada vuelta
+const loteEntregas = 20
+
+// despertarWebhooks avisa al despachador que hay entregas nuevas sin esperar al próximo ciclo
+var despertarWebhooks = make(chan struct{}, 1)
+
+type WebhookService struct {
+	db     *gorm.DB
+	cfg    config.WebhooksConfig
+	client *http.Client
+}
+
+// WebhookRequest datos para crear o reemplazar una suscripción de webhook
+type WebhookRequest struct {
+	Nombre  string   `json:"nombre" validate:"required,min=3,max=150"`
+	URL     string   `json:"url" validate:"required,url,startswith=http,max=500"`
+	Eventos []string `json:"eventos" validate:"required,min=1,dive,oneof=alerta.nueva caso.contagioso"`
+	Activa  *bool    `json:"activa"`
+}
+
+// WebhookCreado suscripción recién creada junto con su secreto, que no se vuelve a mostrar
+type WebhookCreado struct {
+	models.SuscripcionWebhook
+	Secreto string `json:"secreto"`
+}
+
+// NewWebhookService crea una nueva instancia del servicio de webhooks
+func NewWebhookService() *WebhookService {
+	cfg := config.GetConfig().Webhooks
+	return &WebhookService{
+		db:     database.GetDB(),
+		cfg:    cfg,
+		client: nuevoClienteWebhooks(cfg.Timeout),
+	}
+}
+
+// GetWebhooks obtiene las suscripciones de webhook con paginación
+func (s *WebhookService) GetWebhooks(page, limit int) ([]models.SuscripcionWebhook, int64, error) {
+	var suscripciones []models.SuscripcionWebhook
+	var total int64
+
+	s.db.Model(&models.SuscripcionWebhook{}).Count(&total)
+
+	offset := (page - 1) * limit
+	err := s.db.Offset(offset).Limit(limit).Order("id").Find(&suscripciones).Error
+
+	return suscripciones, total, err
+}
+
+// GetWebhookByID obtiene una suscripción de webhook
+func (s *WebhookService) GetWebhookByID(id uint) (*models.SuscripcionWebhook, error) {
+	var suscripcion models.SuscripcionWebhook
+	if err := s.db.First(&suscripcion, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("webhook no encontrado")
+		}
+		return nil, err
+	}
+	return &suscripcion, nil
+}
+
+// CreateWebhook crea una suscripción con un secreto aleatorio para firmar las entregas
+func (s *WebhookService) CreateWebhook(actor Actor, req WebhookRequest) (*WebhookCreado, error) {
+	if err := validarURLWebhook(req.URL); err != nil {
+		return nil, err
+	}
+
+	secreto, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	suscripcion := models.SuscripcionWebhook{
+		Nombre:    req.Nombre,
+		URL:       req.URL,
+		Secreto:   secreto,
+		Eventos:   strings.Join(req.Eventos, ","),
+		Activa:    req.Activa == nil || *req.Activa,
+		IDUsuario: &actor.UsuarioID,
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&suscripcion).Error; err != nil {
+			return err
+		}
+		return auditar(tx, actor, models.AccionCrear, models.EntidadWebhook, suscripcion.ID, nil, suscripcion, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebhookCreado{SuscripcionWebhook: suscripcion, Secreto: secreto}, nil
+}
+
+// UpdateWebhook reemplaza nombre, URL, eventos y estado de una suscripción; el secreto se conserva
+func (s *WebhookService) UpdateWebhook(actor Actor, id uint, req WebhookRequest) (*models.SuscripcionWebhook, error) {
+	antes, err := s.GetWebhookByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := validarURLWebhook(req.URL); err != nil {
+		return nil, err
+	}
+
+	despues := *antes
+	despues.Nombre = req.Nombre
+	despues.URL = req.URL
+	despues.Eventos = strings.Join(req.Eventos, ",")
+	despues.Activa = req.Activa == nil || *req.Activa
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&despues).Error; err != nil {
+			return err
+		}
+		return auditar(tx, actor, models.AccionActualizar, models.EntidadWebhook, id, antes, despues, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &despues, nil
+}
+
+// DeleteWebhook elimina (soft delete) una suscripción; sus entregas pendientes se descartan al procesarse
+func (s *WebhookService) DeleteWebhook(actor Actor, id uint) error {
+	suscripcion, err := s.GetWebhookByID(id)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(suscripcion).Error; err != nil {
+			return err
+		}
+		return auditar(tx, actor, models.AccionEliminar, models.EntidadWebhook, id, suscripcion, nil, nil)
+	})
+}
+
+// GetEntregas obtiene las entregas de una suscripción con todos sus intentos, las más recientes primero
+func (s *WebhookService) GetEntregas(id uint, estado string, page, limit int) ([]models.EntregaWebhook, int64, error) {
+	if _, err := s.GetWebhookByID(id); err != nil {
+		return nil, 0, err
+	}
+
+	var entregas []models.EntregaWebhook
+	var total int64
+
+	query := s.db.Model(&models.EntregaWebhook{}).Where("id_suscripcion = ?", id)
+	if estado != "" {
+		query = query.Where("estado = ?", estado)
+	}
+
+	// Contar total
+	query.Count(&total)
+
+	offset := (page - 1) * limit
+	err := query.Preload("IntentosEntrega", func(db *gorm.DB) *gorm.DB { return db.Order("numero") }).
+		Offset(offset).Limit(limit).
+		Order("created_at DESC, id DESC").
+		Find(&entregas).Error
+
+	return entregas, total, err
+}
+
+// ReintentarEntrega vuelve a poner en cola una entrega fallida con un nuevo ciclo de reintentos
+func (s *WebhookService) ReintentarEntrega(id, entregaID uint) (*models.EntregaWebhook, error) {
+	var entrega models.EntregaWebhook
+	err := s.db.Where("id_suscripcion = ?", id).First(&entrega, entregaID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("entrega no encontrada")
+		}
+		return nil, err
+	}
+
+	if entrega.Estado != models.EntregaFallida {
+		return nil, ErrEntregaNoReintentable
+	}
+
+	err = s.db.Model(&entrega).Updates(map[string]interface{}{
+		"estado":          models.EntregaPendiente,
+		"intentos":        0,
+		"proximo_intento": time.Now(),
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	avisarDespachador()
+	return &entrega, nil
+}
+
+// IniciarDespachador envía en segundo plano las entregas pendientes cuando se encolan y, como respaldo,
+// cada pocos segundos para los reintentos programados, hasta que se cancela ctx
+func (s *WebhookService) IniciarDespachador(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			if err := s.procesarPendientes(); err != nil {
+				log.Printf("Error al despachar webhooks: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				log.Println("Despachador de webhooks detenido")
+				return
+			case <-ticker.C:
+			case <-despertarWebhooks:
+			}
+		}
+	}()
+}
+
+// procesarPendientes reserva y envía lotes de entregas vencidas hasta vaciar la cola.
+// La reserva adelanta proximo_intento para que otra instancia no tome las mismas entregas.
+func (s *WebhookService) procesarPendientes() error {
+	for {
+		var entregas []models.EntregaWebhook
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			ahora := time.Now()
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("estado = ? AND proximo_intento <= ?", models.EntregaPendiente, ahora).
+				Order("proximo_intento").Limit(loteEntregas).
+				Find(&entregas).Error
+			if err != nil || len(entregas) == 0 {
+				return err
+			}
+
+			ids := make([]uint, len(entregas))
+			for i := range entregas {
+				ids[i] = entregas[i].ID
+			}
+			return tx.Model(&models.EntregaWebhook{}).Where("id IN ?", ids).
+				Update("proximo_intento", ahora.Add(2*s.cfg.Timeout)).Error
+		})
+		if err != nil || len(entregas) == 0 {
+			return err
+		}
+
+		for i := range entregas {
+			if err := s.entregar(&entregas[i]); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// entregar envía una entrega, registra el intento y programa el siguiente con backoff exponencial si falla
+func (s *WebhookService) entregar(entrega *models.EntregaWebhook) error {
+	var suscripcion models.SuscripcionWebhook
+	if err := s.db.Unscoped().First(&suscripcion, entrega.IDSuscripcion).Error; err != nil {
+		return err
+	}
+
+	intento := models.IntentoWebhook{IDEntrega: entrega.ID, Numero: entrega.Intentos + 1}
+	if suscripcion.DeletedAt.Valid || !suscripcion.Activa {
+		intento.Error = "suscripción eliminada o desactivada"
+	} else {
+		inicio := time.Now()
+		intento.CodigoHTTP, intento.Error = s.enviar(&suscripcion, entrega)
+		intento.DuracionMs = time.Since(inicio).Milliseconds()
+	}
+
+	ahora := time.Now()
+	updates := map[string]interface{}{"intentos": intento.Numero}
+	switch {
+	case intento.Error == "":
+		updates["estado"] = models.EntregaEntregada
+		updates["entregada_at"] = ahora
+	case intento.Numero >= s.cfg.MaxIntentos || !suscripcion.Activa || suscripcion.DeletedAt.Valid:
+		updates["estado"] = models.EntregaFallida
+	default:
+		updates["proximo_intento"] = ahora.Add(s.backoff(intento.Numero))
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&intento).Error; err != nil {
+			return err
+		}
+		return tx.Model(entrega).Updates(updates).Error
+	})
+}
+
+// enviar hace el POST firmado y retorna el código HTTP y, si no fue 2xx, la descripción del error.
+// La firma es HMAC-SHA256 de "<timestamp>.<cuerpo>" con el secreto de la suscripción.
+func (s *WebhookService) enviar(suscripcion *models.SuscripcionWebhook, entrega *models.EntregaWebhook) (int, string) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(suscripcion.Secreto))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(entrega.Payload)
+
+	req, err := http.NewRequest("POST", suscripcion.URL, bytes.NewReader(entrega.Payload))
+	if err != nil {
+		return 0, err.Error()
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "hospital-api-webhooks/1.0")
+	req.Header.Set("X-Webhook-Evento", entrega.Evento)
+	req.Header.Set("X-Webhook-Entrega", strconv.FormatUint(uint64(entrega.ID), 10))
+	req.Header.Set("X-Webhook-Firma", fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil))))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Sprintf("respuesta HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, ""
+}
+
+// backoff espera antes del siguiente intento: BackoffBase duplicado por cada fallo, hasta BackoffMax
+func (s *WebhookService) backoff(intentos int) time.Duration {
+	espera := s.cfg.BackoffBase
+	for i := 1; i < intentos && espera < s.cfg.BackoffMax; i++ {
+		espera *= 2
+	}
+	return min(espera, s.cfg.BackoffMax)
+}
+
+// encolarWebhooks crea en la transacción tx una entrega pendiente del evento para cada suscripción activa
+// a su tipo. El despachador las envía cuando la transacción se confirma.
+func encolarWebhooks(tx *gorm.DB, evento *Evento) error {
+	var suscripciones []models.SuscripcionWebhook
+	err := tx.Where("activa = ? AND ? = ANY(string_to_array(eventos, ','))", true, evento.Tipo).
+		Find(&suscripciones).Error
+	if err != nil || len(suscripciones) == 0 {
+		return err
+	}
+
+	payload, err := json.Marshal(evento)
+	if err != nil {
+		return err
+	}
+
+	entregas := make([]models.EntregaWebhook, len(suscripciones))
+	for i, suscripcion := range suscripciones {
+		entregas[i] = models.EntregaWebhook{
+			IDSuscripcion:  suscripcion.ID,
+			Evento:         evento.Tipo,
+			Payload:        payload,
+			Estado:         models.EntregaPendiente,
+			ProximoIntento: evento.Fecha,
+		}
+	}
+	return tx.Create(&entregas).Error
+}
+
+// avisarDespachador despierta al despachador de webhooks si está esperando
+func avisarDespachador() {
+	select {
+	case despertarWebhooks <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,117 @@
+package utils
+
+import "slices"
+
+// aristaGrilla lado entre dos nodos vecinos de la grilla: hacia la columna siguiente o, si es vertical,
+// hacia la fila siguiente
+type aristaGrilla struct {
+	fila, columna int
+	vertical      bool
+}
+
+// tramosIsolinea lados que une cada tramo según qué esquinas de la celda superan el umbral (bit 0: abajo
+// a la izquierda, 1: abajo a la derecha, 2: arriba a la derecha, 3: arriba a la izquierda). Los lados son
+// 0: abajo, 1: derecha, 2: arriba, 3: izquierda. Los casos 5 y 10 son ambiguos y se resuelven con el centro.
+var tramosIsolinea = [16][][2]int{
+	1: {{3, 0}}, 2: {{0, 1}}, 3: {{3, 1}}, 4: {{1, 2}},
+	6: {{0, 2}}, 7: {{3, 2}}, 8: {{2, 3}}, 9: {{0, 2}},
+	11: {{1, 2}}, 12: {{1, 3}}, 13: {{0, 1}}, 14: {{3, 0}},
+}
+
+// Isolineas traza con marching squares las curvas de nivel de una grilla (valores[fila][columna]) para un
+// umbral. Cada línea es una secuencia de puntos (columna, fila) con decimales, interpolados sobre los lados
+// de las celdas; las curvas cerradas repiten el primer punto al final.
+func Isolineas(valores [][]float64, umbral float64) [][][2]float64 {
+	filas := len(valores)
+	if filas < 2 || len(valores[0]) < 2 {
+		return nil
+	}
+	columnas := len(valores[0])
+
+	var tramos [][2]aristaGrilla
+	adyacentes := make(map[aristaGrilla][]int)
+	for f := 0; f < filas-1; f++ {
+		for c := 0; c < columnas-1; c++ {
+			esquinas := [4]float64{valores[f][c], valores[f][c+1], valores[f+1][c+1], valores[f+1][c]}
+			caso := 0
+			for i, valor := range esquinas {
+				if valor >= umbral {
+					caso |= 1 << i
+				}
+			}
+
+			pares := tramosIsolinea[caso]
+			if caso == 5 || caso == 10 {
+				centroAlto := (esquinas[0]+esquinas[1]+esquinas[2]+esquinas[3])/4 >= umbral
+				if (caso == 5) == centroAlto {
+					pares = [][2]int{{3, 2}, {0, 1}}
+				} else {
+					pares = [][2]int{{3, 0}, {1, 2}}
+				}
+			}
+
+			lados := [4]aristaGrilla{{f, c, false}, {f, c + 1, true}, {f + 1, c, false}, {f, c, true}}
+			for _, par := range pares {
+				tramo := [2]aristaGrilla{lados[par[0]], lados[par[1]]}
+				adyacentes[tramo[0]] = append(adyacentes[tramo[0]], len(tramos))
+				adyacentes[tramo[1]] = append(adyacentes[tramo[1]], len(tramos))
+				tramos = append(tramos, tramo)
+			}
+		}
+	}
+
+	// Encadena los tramos que comparten un lado, extendiendo cada línea por sus dos extremos
+	usado := make([]bool, len(tramos))
+	var lineas [][][2]float64
+	for i, tramo := range tramos {
+		if usado[i] {
+			continue
+		}
+		usado[i] = true
+		cadena := []aristaGrilla{tramo[0], tramo[1]}
+		for extremo := 0; extremo < 2; extremo++ {
+			for {
+				ultimo := cadena[len(cadena)-1]
+				siguiente := -1
+				for _, j := range adyacentes[ultimo] {
+					if !usado[j] {
+						siguiente = j
+						break
+					}
+				}
+				if siguiente < 0 {
+					break
+				}
+				usado[siguiente] = true
+				otro := tramos[siguiente][0]
+				if otro == ultimo {
+					otro = tramos[siguiente][1]
+				}
+				cadena = append(cadena, otro)
+			}
+			slices.Reverse(cadena)
+		}
+
+		linea := make([][2]float64, len(cadena))
+		for k, arista := range cadena {
+			linea[k] = puntoIsolinea(valores, arista, umbral)
+		}
+		lineas = append(lineas, linea)
+	}
+	return lineas
+}
+
+// puntoIsolinea interpola linealmente dónde cruza el umbral el lado entre dos nodos
+func puntoIsolinea(valores [][]float64, arista aristaGrilla, umbral float64) [2]float64 {
+	f, c := arista.fila, arista.columna
+	f2, c2 := f, c+1
+	if arista.vertical {
+		f2, c2 = f+1, c
+	}
+
+	t := 0.5
+	if a, b := valores[f][c], valores[f2][c2]; a != b {
+		t = (umbral - a) / (b - a)
+	}
+	return [2]float64{float64(c) + t*float64(c2-c), float64(f) + t*float64(f2-f)}
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// lineaEsperada puntos distintos de una isolínea en cualquier orden y si es cerrada
+type lineaEsperada struct {
+	puntos  [][2]float64
+	cerrada bool
+}
+
+// claveLinea representación de una línea independiente del punto de inicio y del sentido de recorrido
+func claveLinea(puntos [][2]float64, cerrada bool) string {
+	claves := make([]string, 0, len(puntos))
+	for _, p := range puntos {
+		claves = append(claves, fmt.Sprintf("(%.6f,%.6f)", p[0], p[1]))
+	}
+	sort.Strings(claves)
+	return fmt.Sprint(claves, cerrada)
+}
+
+func TestIsolineas(t *testing.T) {
+	tests := []struct {
+		name    string
+		valores [][]float64
+		umbral  float64
+		want    []lineaEsperada
+	}{
+		{
+			name:    "pico central: rombo cerrado",
+			valores: [][]float64{{0, 0, 0}, {0, 1, 0}, {0, 0, 0}},
+			umbral:  0.5,
+			want:    []lineaEsperada{{puntos: [][2]float64{{1, 0.5}, {1.5, 1}, {1, 1.5}, {0.5, 1}}, cerrada: true}},
+		},
+		{
+			name:    "columna alta: línea abierta de borde a borde",
+			valores: [][]float64{{1, 0, 0}, {1, 0, 0}},
+			umbral:  0.5,
+			want:    []lineaEsperada{{puntos: [][2]float64{{0.5, 0}, {0.5, 1}}}},
+		},
+		{
+			name:    "interpolación lineal sobre el lado",
+			valores: [][]float64{{0, 4}, {0, 4}},
+			umbral:  1,
+			want:    []lineaEsperada{{puntos: [][2]float64{{0.25, 0}, {0.25, 1}}}},
+		},
+		{
+			// Caso 5 con el centro sobre el umbral: las esquinas altas quedan unidas por el centro
+			name:    "punto de silla con centro alto",
+			valores: [][]float64{{1, 0}, {0, 1}},
+			umbral:  0.5,
+			want: []lineaEsperada{
+				{puntos: [][2]float64{{0, 0.5}, {0.5, 1}}},
+				{puntos: [][2]float64{{0.5, 0}, {1, 0.5}}},
+			},
+		},
+		{
+			// Caso 10 con el centro bajo el umbral: las esquinas altas quedan separadas
+			name:    "punto de silla con centro bajo",
+			valores: [][]float64{{0, 1}, {1, 0}},
+			umbral:  0.6,
+			want: []lineaEsperada{
+				{puntos: [][2]float64{{0, 0.6}, {0.4, 1}}},
+				{puntos: [][2]float64{{0.6, 0}, {1, 0.4}}},
+			},
+		},
+		{
+			name:    "sin cruces",
+			valores: [][]float64{{0, 0}, {0, 0}},
+			umbral:  0.5,
+		},
+		{
+			name:    "grilla de una fila",
+			valores: [][]float64{{0, 1, 0}},
+			umbral:  0.5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lineas := Isolineas(tt.valores, tt.umbral)
+
+			var got, want []string
+			for _, linea := range lineas {
+				cerrada := len(linea) > 2 && linea[0] == linea[len(linea)-1]
+				puntos := linea
+				if cerrada {
+					puntos = linea[:len(linea)-1]
+				}
+				for _, p := range puntos {
+					if math.IsNaN(p[0]) || math.IsNaN(p[1]) {
+						t.Fatalf("Isolineas() punto inválido %v", p)
+					}
+				}
+				got = append(got, claveLinea(puntos, cerrada))
+			}
+			for _, linea := range tt.want {
+				want = append(want, claveLinea(linea.puntos, linea.cerrada))
+			}
+			slices.Sort(got)
+			slices.Sort(want)
+
+			if !slices.Equal(got, want) {
+				t.Errorf("Isolineas() = %v, want %v", lineas, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrCursorInvalido indica un cursor de paginación mal formado
+var ErrCursorInvalido = errors.New("cursor inválido")
+
+// cursor posición de la última fila devuelta: valor del campo de orden y su ID como desempate
+type cursor struct {
+	Orden string `json:"o"`
+	Valor string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+// EncodeCursor codifica la posición de la última fila de una página como un token opaco.
+// orden identifica el criterio de ordenamiento para rechazar cursores de otra consulta.
+func EncodeCursor(orden, valor string, id uint) string {
+	data, _ := json.Marshal(cursor{Orden: orden, Valor: valor, ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor decodifica un cursor generado por EncodeCursor para el mismo criterio de orden
+func DecodeCursor(token, orden string) (string, uint, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", 0, ErrCursorInvalido
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == 0 || c.Orden != orden {
+		return "", 0, ErrCursorInvalido
+	}
+	return c.Valor, c.ID, nil
+}
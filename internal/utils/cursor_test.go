@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestCursorIdaYVuelta(t *testing.T) {
+	tests := []struct {
+		orden string
+		valor string
+		id    uint
+	}{
+		{"-fecha_ingreso", "2024-03-07T10:15:00Z", 42},
+		{"patient_district", "San Lorenzo", 1},
+		{"-patient_district", "", 7},
+		{"enfermedad", "Ñandú \"ü\" / + =", 1<<32 + 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.orden, func(t *testing.T) {
+			token := EncodeCursor(tt.orden, tt.valor, tt.id)
+			valor, id, err := DecodeCursor(token, tt.orden)
+			if err != nil {
+				t.Fatalf("DecodeCursor() error = %v", err)
+			}
+			if valor != tt.valor || id != tt.id {
+				t.Errorf("DecodeCursor() = %q, %d; want %q, %d", valor, id, tt.valor, tt.id)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalido(t *testing.T) {
+	valido := EncodeCursor("-fecha_ingreso", "2024-03-07", 42)
+	codificar := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name  string
+		token string
+		orden string
+	}{
+		{"otro criterio de orden", valido, "fecha_ingreso"},
+		{"no es base64", "%%%", "-fecha_ingreso"},
+		{"base64 con relleno", valido + "==", "-fecha_ingreso"},
+		{"token truncado", valido[:len(valido)-4], "-fecha_ingreso"},
+		{"no es JSON", codificar("-fecha_ingreso|42"), "-fecha_ingreso"},
+		{"sin ID", codificar(`{"o":"-fecha_ingreso","v":"2024-03-07"}`), "-fecha_ingreso"},
+		{"ID negativo", codificar(`{"o":"-fecha_ingreso","v":"2024-03-07","id":-1}`), "-fecha_ingreso"},
+		{"vacío", "", "-fecha_ingreso"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := DecodeCursor(tt.token, tt.orden); !errors.Is(err, ErrCursorInvalido) {
+				t.Errorf("DecodeCursor() error = %v, want %v", err, ErrCursorInvalido)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// CambioCampo valor de un campo antes y después de una modificación
+type CambioCampo struct {
+	Antes   interface{} `json:"antes"`
+	Despues interface{} `json:"despues"`
+}
+
+// Diff compara dos valores a través de su representación JSON y retorna los campos que cambiaron.
+// Un valor nil se trata como un objeto vacío (creación o eliminación). Los campos en ignorar se omiten.
+func Diff(antes, despues interface{}, ignorar ...string) (map[string]CambioCampo, error) {
+	antesMap, err := toJSONMap(antes)
+	if err != nil {
+		return nil, err
+	}
+	despuesMap, err := toJSONMap(despues)
+	if err != nil {
+		return nil, err
+	}
+
+	omitir := make(map[string]bool, len(ignorar))
+	for _, campo := range ignorar {
+		omitir[campo] = true
+	}
+
+	cambios := make(map[string]CambioCampo)
+	for campo, valor := range antesMap {
+		if omitir[campo] {
+			continue
+		}
+		if nuevo, ok := despuesMap[campo]; !ok || !reflect.DeepEqual(valor, nuevo) {
+			cambios[campo] = CambioCampo{Antes: valor, Despues: despuesMap[campo]}
+		}
+	}
+	for campo, valor := range despuesMap {
+		if omitir[campo] {
+			continue
+		}
+		if _, ok := antesMap[campo]; !ok {
+			cambios[campo] = CambioCampo{Antes: nil, Despues: valor}
+		}
+	}
+
+	return cambios, nil
+}
+
+// toJSONMap convierte un valor en un mapa usando sus tags JSON
+func toJSONMap(v interface{}) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return m, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		antes   interface{}
+		despues interface{}
+		ignorar []string
+		want    map[string]CambioCampo
+	}{
+		{
+			name:    "sin cambios",
+			antes:   map[string]interface{}{"diagnostico": "Dengue", "dias": 3},
+			despues: map[string]interface{}{"diagnostico": "Dengue", "dias": 3},
+			want:    map[string]CambioCampo{},
+		},
+		{
+			name:    "campo modificado",
+			antes:   map[string]interface{}{"diagnostico": "Dengue", "dias": 3},
+			despues: map[string]interface{}{"diagnostico": "Dengue grave", "dias": 3},
+			want: map[string]CambioCampo{
+				"diagnostico": {Antes: "Dengue", Despues: "Dengue grave"},
+			},
+		},
+		{
+			name:    "campo agregado y eliminado",
+			antes:   map[string]interface{}{"barrio": "Centro"},
+			despues: map[string]interface{}{"distrito": "Luque"},
+			want: map[string]CambioCampo{
+				"barrio":   {Antes: "Centro", Despues: nil},
+				"distrito": {Antes: nil, Despues: "Luque"},
+			},
+		},
+		{
+			name:    "creación desde nil",
+			antes:   nil,
+			despues: map[string]interface{}{"dias": 3},
+			want: map[string]CambioCampo{
+				"dias": {Antes: nil, Despues: float64(3)},
+			},
+		},
+		{
+			name:    "eliminación hacia un puntero nil",
+			antes:   &struct{ Dias int }{Dias: 3},
+			despues: (*struct{ Dias int })(nil),
+			want: map[string]CambioCampo{
+				"Dias": {Antes: float64(3), Despues: nil},
+			},
+		},
+		{
+			name: "usa los tags JSON del struct",
+			antes: struct {
+				FechaAlta string `json:"fecha_alta"`
+			}{"2024-01-01"},
+			despues: struct {
+				FechaAlta string `json:"fecha_alta"`
+			}{"2024-01-05"},
+			want: map[string]CambioCampo{
+				"fecha_alta": {Antes: "2024-01-01", Despues: "2024-01-05"},
+			},
+		},
+		{
+			name:    "revisiones en JSON ignorando updated_at",
+			antes:   json.RawMessage(`{"diagnostico":"Dengue","updated_at":"2024-01-01T10:00:00Z","signos":{"fiebre":true}}`),
+			despues: json.RawMessage(`{"diagnostico":"Dengue","updated_at":"2024-01-02T10:00:00Z","signos":{"fiebre":false}}`),
+			ignorar: []string{"updated_at"},
+			want: map[string]CambioCampo{
+				"signos": {
+					Antes:   map[string]interface{}{"fiebre": true},
+					Despues: map[string]interface{}{"fiebre": false},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Diff(tt.antes, tt.despues, tt.ignorar...)
+			if err != nil {
+				t.Fatalf("Diff() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ETag genera el ETag de un recurso a partir de su número de versión
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ParseIfMatch interpreta un header If-Match y retorna las versiones aceptadas.
+// Para "*" retorna nil (cualquier versión). Las etiquetas que no generó ETag se ignoran,
+// por lo que un header sin etiquetas válidas retorna una lista vacía que no coincide con ninguna versión.
+func ParseIfMatch(header string) []int {
+	if strings.TrimSpace(header) == "*" {
+		return nil
+	}
+
+	versions := []int{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		// If-Match usa comparación fuerte: las etiquetas débiles nunca coinciden
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		if version, err := strconv.Atoi(tag[1 : len(tag)-1]); err == nil {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
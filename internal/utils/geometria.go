@@ -0,0 +1,253 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Anillo contorno cerrado de un polígono como pares [longitud, latitud] (orden GeoJSON)
+type Anillo [][2]float64
+
+// Poligono anillo exterior seguido de sus huecos
+type Poligono []Anillo
+
+// MultiPoligono uno o más polígonos en coordenadas geográficas WGS84
+type MultiPoligono []Poligono
+
+// CajaLimite rectángulo que contiene una geometría
+type CajaLimite struct {
+	MinLat, MinLng, MaxLat, MaxLng float64
+}
+
+// ErrGeometriaInvalida indica una geometría que no es un polígono utilizable
+var ErrGeometriaInvalida = errors.New("geometría inválida")
+
+// geometriaGeoJSON geometría de GeoJSON (RFC 7946) de tipo Polygon o MultiPolygon
+type geometriaGeoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// ParseGeometriaGeoJSON convierte una geometría GeoJSON Polygon o MultiPolygon en un MultiPoligono
+func ParseGeometriaGeoJSON(data []byte) (MultiPoligono, error) {
+	var geometria geometriaGeoJSON
+	if err := json.Unmarshal(data, &geometria); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGeometriaInvalida, err)
+	}
+
+	var multi MultiPoligono
+	switch geometria.Type {
+	case "Polygon":
+		var poligono Poligono
+		if err := json.Unmarshal(geometria.Coordinates, &poligono); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrGeometriaInvalida, err)
+		}
+		multi = MultiPoligono{poligono}
+	case "MultiPolygon":
+		if err := json.Unmarshal(geometria.Coordinates, &multi); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrGeometriaInvalida, err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: tipo %q, se esperaba Polygon o MultiPolygon", ErrGeometriaInvalida, geometria.Type)
+	}
+
+	if err := multi.Validar(); err != nil {
+		return nil, err
+	}
+	return multi, nil
+}
+
+// Validar comprueba que cada anillo tenga al menos 4 vértices y coordenadas geográficas válidas
+func (m MultiPoligono) Validar() error {
+	if len(m) == 0 {
+		return fmt.Errorf("%w: sin polígonos", ErrGeometriaInvalida)
+	}
+	for _, poligono := range m {
+		if len(poligono) == 0 {
+			return fmt.Errorf("%w: polígono sin anillos", ErrGeometriaInvalida)
+		}
+		for _, anillo := range poligono {
+			if len(anillo) < 4 {
+				return fmt.Errorf("%w: un anillo necesita al menos 4 vértices", ErrGeometriaInvalida)
+			}
+			for _, punto := range anillo {
+				if punto[0] < -180 || punto[0] > 180 || punto[1] < -90 || punto[1] > 90 {
+					return fmt.Errorf("%w: coordenada fuera de rango (%g, %g); se esperan longitud y latitud WGS84", ErrGeometriaInvalida, punto[0], punto[1])
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// GeoJSON serializa la geometría como MultiPolygon de GeoJSON
+func (m MultiPoligono) GeoJSON() (json.RawMessage, error) {
+	return json.Marshal(map[string]interface{}{
+		"type":        "MultiPolygon",
+		"coordinates": m,
+	})
+}
+
+// PuntoGeoJSON geometría Point de GeoJSON para unas coordenadas WGS84
+func PuntoGeoJSON(lat, lng float64) json.RawMessage {
+	geometria, _ := json.Marshal(map[string]interface{}{
+		"type":        "Point",
+		"coordinates": [2]float64{lng, lat},
+	})
+	return geometria
+}
+
+// Contiene indica si el punto está dentro de algún polígono (dentro del anillo exterior y fuera de sus huecos)
+func (m MultiPoligono) Contiene(lat, lng float64) bool {
+	for _, poligono := range m {
+		if !poligono[0].contiene(lat, lng) {
+			continue
+		}
+		enHueco := false
+		for _, hueco := range poligono[1:] {
+			if hueco.contiene(lat, lng) {
+				enHueco = true
+				break
+			}
+		}
+		if !enHueco {
+			return true
+		}
+	}
+	return false
+}
+
+// contiene aplica la regla par-impar: un rayo desde el punto cruza el contorno un número impar de veces
+func (a Anillo) contiene(lat, lng float64) bool {
+	dentro := false
+	for i, j := 0, len(a)-1; i < len(a); j, i = i, i+1 {
+		xi, yi := a[i][0], a[i][1]
+		xj, yj := a[j][0], a[j][1]
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			dentro = !dentro
+		}
+	}
+	return dentro
+}
+
+// Caja rectángulo que contiene todos los vértices
+func (m MultiPoligono) Caja() CajaLimite {
+	caja := CajaLimite{MinLat: math.Inf(1), MinLng: math.Inf(1), MaxLat: math.Inf(-1), MaxLng: math.Inf(-1)}
+	for _, poligono := range m {
+		for _, punto := range poligono[0] {
+			caja.MinLng = math.Min(caja.MinLng, punto[0])
+			caja.MaxLng = math.Max(caja.MaxLng, punto[0])
+			caja.MinLat = math.Min(caja.MinLat, punto[1])
+			caja.MaxLat = math.Max(caja.MaxLat, punto[1])
+		}
+	}
+	return caja
+}
+
+// AreaKm2 área aproximada en km², proyectando cada anillo sobre un plano local (suficiente a escala de ciudad)
+func (m MultiPoligono) AreaKm2() float64 {
+	const kmPorGrado = 111.32
+	total := 0.0
+	for _, poligono := range m {
+		for k, anillo := range poligono {
+			area := math.Abs(anillo.areaFirmada())
+			latMedia := 0.0
+			for _, punto := range anillo {
+				latMedia += punto[1]
+			}
+			latMedia /= float64(len(anillo))
+			area *= kmPorGrado * kmPorGrado * math.Cos(latMedia*math.Pi/180)
+			if k == 0 {
+				total += area
+			} else {
+				total -= area
+			}
+		}
+	}
+	return total
+}
+
+// areaFirmada área en grados² por la fórmula del polígono; positiva si el anillo va en sentido antihorario
+func (a Anillo) areaFirmada() float64 {
+	suma := 0.0
+	for i, j := 0, len(a)-1; i < len(a); j, i = i, i+1 {
+		suma += (a[j][0] - a[i][0]) * (a[j][1] + a[i][1])
+	}
+	return suma / 2
+}
+
+// PuntoInterior punto representativo de la geometría: el centroide del anillo exterior del polígono más
+// grande o, si cae fuera (polígonos cóncavos), el punto medio de la primera cuerda horizontal interior
+func (m MultiPoligono) PuntoInterior() (lat, lng float64) {
+	mayor := m[0]
+	for _, poligono := range m[1:] {
+		if math.Abs(poligono[0].areaFirmada()) > math.Abs(mayor[0].areaFirmada()) {
+			mayor = poligono
+		}
+	}
+
+	exterior := mayor[0]
+	area := exterior.areaFirmada()
+	if area != 0 {
+		cx, cy := 0.0, 0.0
+		for i, j := 0, len(exterior)-1; i < len(exterior); j, i = i, i+1 {
+			factor := exterior[j][0]*exterior[i][1] - exterior[i][0]*exterior[j][1]
+			cx += (exterior[i][0] + exterior[j][0]) * factor
+			cy += (exterior[i][1] + exterior[j][1]) * factor
+		}
+		lng, lat = cx/(6*area), cy/(6*area)
+		if (MultiPoligono{mayor}).Contiene(lat, lng) {
+			return lat, lng
+		}
+	}
+
+	// Cuerda horizontal a la altura media de la caja: el punto medio entre los dos primeros cruces
+	caja := MultiPoligono{mayor}.Caja()
+	lat = (caja.MinLat + caja.MaxLat) / 2
+	var cruces []float64
+	for i, j := 0, len(exterior)-1; i < len(exterior); j, i = i, i+1 {
+		xi, yi := exterior[i][0], exterior[i][1]
+		xj, yj := exterior[j][0], exterior[j][1]
+		if (yi > lat) != (yj > lat) {
+			cruces = append(cruces, (xj-xi)*(lat-yi)/(yj-yi)+xi)
+		}
+	}
+	if len(cruces) < 2 {
+		return lat, exterior[0][0]
+	}
+	primero, segundo := math.Inf(1), math.Inf(1)
+	for _, x := range cruces {
+		if x < primero {
+			primero, segundo = x, primero
+		} else if x < segundo {
+			segundo = x
+		}
+	}
+	return lat, (primero + segundo) / 2
+}
+
+// FeatureCollection colección de features de GeoJSON (RFC 7946)
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Feature feature de GeoJSON con su geometría y propiedades
+type Feature struct {
+	Type       string                 `json:"type"`
+	ID         interface{}            `json:"id,omitempty"`
+	Geometry   json.RawMessage        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// NuevaFeatureCollection colección vacía lista para agregar features
+func NuevaFeatureCollection() *FeatureCollection {
+	return &FeatureCollection{Type: "FeatureCollection", Features: []Feature{}}
+}
+
+// Agregar añade una feature con la geometría y propiedades indicadas
+func (fc *FeatureCollection) Agregar(id interface{}, geometria json.RawMessage, propiedades map[string]interface{}) {
+	fc.Features = append(fc.Features, Feature{Type: "Feature", ID: id, Geometry: geometria, Properties: propiedades})
+}
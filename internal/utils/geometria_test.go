@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// cuadrado anillo antihorario de lado lado con la esquina inferior izquierda en (lng, lat)
+func cuadrado(lng, lat, lado float64) Anillo {
+	return Anillo{{lng, lat}, {lng + lado, lat}, {lng + lado, lat + lado}, {lng, lat + lado}, {lng, lat}}
+}
+
+// Forma de U: un cuadrado de 10 con una muesca de 4 de ancho abierta hacia arriba desde y = 3
+var formaU = Anillo{{0, 0}, {10, 0}, {10, 10}, {7, 10}, {7, 3}, {3, 3}, {3, 10}, {0, 10}, {0, 0}}
+
+func TestMultiPoligonoContiene(t *testing.T) {
+	conHueco := MultiPoligono{{cuadrado(0, 0, 10), cuadrado(4, 4, 2)}}
+	multi := MultiPoligono{{cuadrado(0, 0, 10), cuadrado(4, 4, 2)}, {cuadrado(20, 20, 10)}}
+
+	tests := []struct {
+		name      string
+		geometria MultiPoligono
+		lat, lng  float64
+		want      bool
+	}{
+		{"dentro del exterior", conHueco, 2, 2, true},
+		{"dentro del hueco", conHueco, 5, 5, false},
+		{"entre el hueco y el borde", conHueco, 5, 7, true},
+		{"fuera a la derecha", conHueco, 5, 11, false},
+		{"fuera arriba", conHueco, 11, 5, false},
+		{"segundo polígono", multi, 25, 25, true},
+		{"entre los dos polígonos", multi, 15, 15, false},
+		{"en la muesca de la U", MultiPoligono{{formaU}}, 5, 5, false},
+		{"en un brazo de la U", MultiPoligono{{formaU}}, 8, 1.5, true},
+		{"en la base de la U", MultiPoligono{{formaU}}, 1, 5, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.geometria.Contiene(tt.lat, tt.lng); got != tt.want {
+				t.Errorf("Contiene(%v, %v) = %v, want %v", tt.lat, tt.lng, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultiPoligonoAreaKm2(t *testing.T) {
+	// En el ecuador un grado mide 111,32 km: un cuadrado de 0,1° tiene 123,92 km²
+	tests := []struct {
+		name      string
+		geometria MultiPoligono
+		want      float64
+	}{
+		{"cuadrado", MultiPoligono{{cuadrado(0, -0.05, 0.1)}}, 123.92},
+		{"con hueco de un cuarto", MultiPoligono{{cuadrado(0, -0.05, 0.1), cuadrado(0.025, -0.025, 0.05)}}, 123.92 * 0.75},
+		{"dos cuadrados", MultiPoligono{{cuadrado(0, -0.05, 0.1)}, {cuadrado(1, -0.05, 0.1)}}, 2 * 123.92},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.geometria.AreaKm2(); math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("AreaKm2() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultiPoligonoCaja(t *testing.T) {
+	multi := MultiPoligono{{cuadrado(-57.7, -25.4, 0.1)}, {cuadrado(-57.5, -25.2, 0.05)}}
+	got := multi.Caja()
+	want := CajaLimite{MinLat: -25.4, MinLng: -57.7, MaxLat: -25.15, MaxLng: -57.45}
+	if math.Abs(got.MinLat-want.MinLat) > 1e-9 || math.Abs(got.MinLng-want.MinLng) > 1e-9 ||
+		math.Abs(got.MaxLat-want.MaxLat) > 1e-9 || math.Abs(got.MaxLng-want.MaxLng) > 1e-9 {
+		t.Errorf("Caja() = %+v, want %+v", got, want)
+	}
+}
+
+func TestMultiPoligonoPuntoInterior(t *testing.T) {
+	tests := []struct {
+		name             string
+		geometria        MultiPoligono
+		wantLat, wantLng float64
+	}{
+		{"centroide del cuadrado", MultiPoligono{{cuadrado(0, 0, 10)}}, 5, 5},
+		{"centroide del polígono más grande", MultiPoligono{{cuadrado(0, 0, 1)}, {cuadrado(20, 20, 10)}}, 25, 25},
+		// El centroide de la U, (5; 4,42), cae en la muesca: se usa la cuerda en y = 5 entre x = 0 y x = 3
+		{"U con el centroide en la muesca", MultiPoligono{{formaU}}, 5, 1.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lat, lng := tt.geometria.PuntoInterior()
+			if math.Abs(lat-tt.wantLat) > 1e-9 || math.Abs(lng-tt.wantLng) > 1e-9 {
+				t.Errorf("PuntoInterior() = %v, %v; want %v, %v", lat, lng, tt.wantLat, tt.wantLng)
+			}
+			if !tt.geometria.Contiene(lat, lng) {
+				t.Errorf("PuntoInterior() = %v, %v está fuera de la geometría", lat, lng)
+			}
+		})
+	}
+}
+
+func TestParseGeometriaGeoJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		geojson   string
+		poligonos int
+		anillos   int // Anillos del primer polígono
+		wantErr   bool
+	}{
+		{
+			name:      "Polygon con hueco",
+			geojson:   `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]],[[4,4],[6,4],[6,6],[4,6],[4,4]]]}`,
+			poligonos: 1,
+			anillos:   2,
+		},
+		{
+			name:      "MultiPolygon",
+			geojson:   `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]],[[[2,2],[3,2],[3,3],[2,2]]]]}`,
+			poligonos: 2,
+			anillos:   1,
+		},
+		{name: "Point", geojson: `{"type":"Point","coordinates":[0,0]}`, wantErr: true},
+		{name: "anillo de tres vértices", geojson: `{"type":"Polygon","coordinates":[[[0,0],[1,0],[0,0]]]}`, wantErr: true},
+		{name: "sin anillos", geojson: `{"type":"Polygon","coordinates":[]}`, wantErr: true},
+		{name: "coordenadas proyectadas", geojson: `{"type":"Polygon","coordinates":[[[440000,7190000],[441000,7190000],[441000,7191000],[440000,7190000]]]}`, wantErr: true},
+		{name: "JSON mal formado", geojson: `{"type":"Polygon",`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGeometriaGeoJSON([]byte(tt.geojson))
+			if tt.wantErr {
+				if !errors.Is(err, ErrGeometriaInvalida) {
+					t.Errorf("ParseGeometriaGeoJSON() error = %v, want %v", err, ErrGeometriaInvalida)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseGeometriaGeoJSON() error = %v", err)
+			}
+			if len(got) != tt.poligonos || len(got[0]) != tt.anillos {
+				t.Errorf("ParseGeometriaGeoJSON() = %v, want %d polígonos y %d anillos", got, tt.poligonos, tt.anillos)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Resaltar envuelve en <mark></mark> las apariciones de los términos dentro del texto, sin distinguir
+// mayúsculas ni acentos. Los términos deben venir normalizados con NormalizarTexto.
+// El texto se escapa como HTML; retorna false si ningún término aparece.
+func Resaltar(texto string, terminos []string) (string, bool) {
+	original := []rune(texto)
+	plegado := make([]rune, len(original))
+	for i, r := range original {
+		plegado[i] = plegarRune(r)
+	}
+
+	marcado := make([]bool, len(original))
+	encontrado := false
+	for _, termino := range terminos {
+		t := []rune(termino)
+		if len(t) == 0 {
+			continue
+		}
+		for i := 0; i+len(t) <= len(plegado); i++ {
+			if string(plegado[i:i+len(t)]) != termino {
+				continue
+			}
+			for j := i; j < i+len(t); j++ {
+				marcado[j] = true
+			}
+			encontrado = true
+		}
+	}
+	if !encontrado {
+		return "", false
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(original); {
+		j := i
+		for j < len(original) && marcado[j] == marcado[i] {
+			j++
+		}
+		segmento := html.EscapeString(string(original[i:j]))
+		if marcado[i] {
+			b.WriteString("<mark>" + segmento + "</mark>")
+		} else {
+			b.WriteString(segmento)
+		}
+		i = j
+	}
+
+	return b.String(), true
+}
+
+// plegarRune pasa una letra a minúscula sin acento, conservando una runa por runa para alinear posiciones
+func plegarRune(r rune) rune {
+	plegada := []rune(acentos.Replace(string(unicode.ToLower(r))))
+	if len(plegada) != 1 {
+		return r
+	}
+	return plegada[0]
+}
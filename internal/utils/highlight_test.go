@@ -0,0 +1,30 @@
+package utils
+
+import "testing"
+
+func TestResaltar(t *testing.T) {
+	tests := []struct {
+		name        string
+		texto       string
+		terminos    []string
+		want        string
+		wantHallado bool
+	}{
+		{"sin acentos en el término", "José Pérez", []string{"jose"}, "<mark>José</mark> Pérez", true},
+		{"varios términos", "María José Benítez", []string{"maria", "benitez"}, "<mark>María</mark> José <mark>Benítez</mark>", true},
+		{"términos superpuestos se unen", "Fernández", []string{"fernan", "nandez"}, "<mark>Fernández</mark>", true},
+		{"todas las apariciones", "Ana Ana", []string{"ana"}, "<mark>Ana</mark> <mark>Ana</mark>", true},
+		{"escapa HTML", "<b>Luis</b>", []string{"luis"}, "&lt;b&gt;<mark>Luis</mark>&lt;/b&gt;", true},
+		{"término vacío", "Luis", []string{""}, "", false},
+		{"sin coincidencias", "Luis", []string{"pedro"}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, hallado := Resaltar(tt.texto, tt.terminos)
+			if got != tt.want || hallado != tt.wantHallado {
+				t.Errorf("Resaltar(%q) = %q, %v; want %q, %v", tt.texto, got, hallado, tt.want, tt.wantHallado)
+			}
+		})
+	}
+}
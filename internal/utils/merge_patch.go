@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// ApplyMergePatch aplica un documento JSON Merge Patch (RFC 7396) sobre un documento JSON.
+// Los miembros con valor null se eliminan, los objetos se combinan recursivamente y
+// cualquier otro valor reemplaza al original.
+func ApplyMergePatch(document, patch []byte) ([]byte, error) {
+	target, err := decodeJSON(document)
+	if err != nil {
+		return nil, err
+	}
+	patchValue, err := decodeJSON(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(mergePatch(target, patchValue))
+}
+
+// MergePatchKeys retorna los miembros de primer nivel de un merge patch, que debe ser un objeto JSON
+func MergePatchKeys(patch []byte) (map[string]bool, error) {
+	value, err := decodeJSON(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("el merge patch debe ser un objeto JSON")
+	}
+
+	keys := make(map[string]bool, len(object))
+	for key := range object {
+		keys[key] = true
+	}
+	return keys, nil
+}
+
+// mergePatch implementa el algoritmo MergePatch de la RFC 7396
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergePatch(targetObject[key], value)
+		}
+	}
+
+	return targetObject
+}
+
+// decodeJSON decodifica JSON conservando los números tal cual (evita perder precisión en IDs)
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("JSON inválido: contenido adicional después del documento")
+	}
+	return value, nil
+}
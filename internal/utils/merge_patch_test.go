@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// Casos del apéndice A de la RFC 7396
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		document string
+		patch    string
+		want     string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		// Los números se conservan sin pasar por float64
+		{`{"id":9007199254740993}`, `{"dias":2}`, `{"dias":2,"id":9007199254740993}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.document+" + "+tt.patch, func(t *testing.T) {
+			got, err := ApplyMergePatch([]byte(tt.document), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("ApplyMergePatch() error = %v", err)
+			}
+			if !jsonIgual(t, got, []byte(tt.want)) {
+				t.Errorf("ApplyMergePatch() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyMergePatchJSONInvalido(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		patch    string
+	}{
+		{"documento mal formado", `{"a":`, `{}`},
+		{"patch mal formado", `{}`, `{"a"}`},
+		{"contenido adicional", `{}`, `{"a":1} {"b":2}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ApplyMergePatch([]byte(tt.document), []byte(tt.patch)); err == nil {
+				t.Error("ApplyMergePatch() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestMergePatchKeys(t *testing.T) {
+	tests := []struct {
+		patch   string
+		want    map[string]bool
+		wantErr bool
+	}{
+		{patch: `{}`, want: map[string]bool{}},
+		{patch: `{"nombre":"Ana","ci":null,"contacto":{"telefono":"0981"}}`, want: map[string]bool{"nombre": true, "ci": true, "contacto": true}},
+		{patch: `["nombre"]`, wantErr: true},
+		{patch: `null`, wantErr: true},
+		{patch: `"nombre"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.patch, func(t *testing.T) {
+			got, err := MergePatchKeys([]byte(tt.patch))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MergePatchKeys() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergePatchKeys() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// jsonIgual compara dos documentos JSON sin importar el orden de los miembros
+func jsonIgual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var va, vb interface{}
+	for _, par := range []struct {
+		data []byte
+		v    *interface{}
+	}{{a, &va}, {b, &vb}} {
+		decoder := json.NewDecoder(bytes.NewReader(par.data))
+		decoder.UseNumber()
+		if err := decoder.Decode(par.v); err != nil {
+			t.Fatalf("JSON inválido %s: %v", par.data, err)
+		}
+	}
+	return reflect.DeepEqual(va, vb)
+}
@@ -39,6 +39,21 @@ type Pagination struct {
 	TotalPages int   `json:"total_pages"`
 }
 
+// CursorResponse estructura para respuestas paginadas por cursor
+type CursorResponse struct {
+	Success    bool             `json:"success"`
+	Data       interface{}      `json:"data"`
+	Message    string           `json:"message"`
+	Pagination CursorPagination `json:"pagination"`
+}
+
+// CursorPagination información de paginación por cursor; NextCursor está vacío en la última página
+type CursorPagination struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
 // ErrorResponse envía una respuesta de error estandarizada
 func ErrorResponse(c *gin.Context, statusCode int, message, code, details string) {
 	c.JSON(statusCode, APIErrorResponse{
@@ -75,6 +90,20 @@ func PaginatedSuccessResponse(c *gin.Context, data interface{}, message string,
 	})
 }
 
+// CursorSuccessResponse envía una respuesta exitosa paginada por cursor
+func CursorSuccessResponse(c *gin.Context, data interface{}, message string, limit int, nextCursor string) {
+	c.JSON(http.StatusOK, CursorResponse{
+		Success: true,
+		Data:    data,
+		Message: message,
+		Pagination: CursorPagination{
+			Limit:      limit,
+			NextCursor: nextCursor,
+			HasMore:    nextCursor != "",
+		},
+	})
+}
+
 // ValidationErrorResponse envía una respuesta de error de validación
 func ValidationErrorResponse(c *gin.Context, err error) {
 	var errors []string
@@ -0,0 +1,278 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// Tipos de forma de ESRI Shapefile con anillos de polígono
+const (
+	shapeNulo      = 0
+	shapePoligono  = 5
+	shapePoligonoZ = 15
+	shapePoligonoM = 25
+)
+
+// ErrShapefileInvalido indica un archivo que no es un Shapefile de polígonos legible
+var ErrShapefileInvalido = errors.New("shapefile inválido")
+
+// FeatureShapefile registro de un Shapefile: su polígono y los atributos de su fila en el .dbf
+type FeatureShapefile struct {
+	Atributos map[string]string
+	Geometria MultiPoligono
+}
+
+// LeerShapefileZip lee un Shapefile de polígonos comprimido en ZIP (.shp y .dbf, y opcionalmente .prj y
+// .cpg con el mismo nombre). Las coordenadas deben ser geográficas WGS84; los registros sin forma y los
+// marcados como eliminados en el .dbf se omiten.
+func LeerShapefileZip(data []byte) ([]FeatureShapefile, error) {
+	lector, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: el archivo no es un ZIP: %v", ErrShapefileInvalido, err)
+	}
+
+	archivos := make(map[string]*zip.File)
+	base := ""
+	for _, archivo := range lector.File {
+		nombre := strings.ToLower(archivo.Name)
+		archivos[nombre] = archivo
+		if base == "" && strings.HasSuffix(nombre, ".shp") && !strings.HasPrefix(path.Base(nombre), "._") {
+			base = strings.TrimSuffix(nombre, ".shp")
+		}
+	}
+	if base == "" {
+		return nil, fmt.Errorf("%w: el ZIP no contiene un archivo .shp", ErrShapefileInvalido)
+	}
+
+	leer := func(extension string, requerido bool) ([]byte, error) {
+		archivo, ok := archivos[base+extension]
+		if !ok {
+			if requerido {
+				return nil, fmt.Errorf("%w: falta %s%s en el ZIP", ErrShapefileInvalido, path.Base(base), extension)
+			}
+			return nil, nil
+		}
+		contenido, err := archivo.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer contenido.Close()
+		return io.ReadAll(contenido)
+	}
+
+	shp, err := leer(".shp", true)
+	if err != nil {
+		return nil, err
+	}
+	dbf, err := leer(".dbf", true)
+	if err != nil {
+		return nil, err
+	}
+	prj, err := leer(".prj", false)
+	if err != nil {
+		return nil, err
+	}
+	cpg, err := leer(".cpg", false)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(string(prj))), "PROJCS") {
+		return nil, fmt.Errorf("%w: el shapefile está proyectado; se requieren coordenadas geográficas WGS84 (EPSG:4326)", ErrShapefileInvalido)
+	}
+
+	geometrias, err := leerFormasShp(shp)
+	if err != nil {
+		return nil, err
+	}
+	filas, err := leerTablaDbf(dbf, strings.TrimSpace(string(cpg)))
+	if err != nil {
+		return nil, err
+	}
+	if len(filas) != len(geometrias) {
+		return nil, fmt.Errorf("%w: el .shp tiene %d registros y el .dbf %d", ErrShapefileInvalido, len(geometrias), len(filas))
+	}
+
+	features := make([]FeatureShapefile, 0, len(geometrias))
+	for i, geometria := range geometrias {
+		if geometria == nil || filas[i] == nil {
+			continue
+		}
+		features = append(features, FeatureShapefile{Atributos: filas[i], Geometria: geometria})
+	}
+	return features, nil
+}
+
+// leerFormasShp lee los registros del .shp; un registro sin forma queda como nil
+func leerFormasShp(data []byte) ([]MultiPoligono, error) {
+	if len(data) < 100 || binary.BigEndian.Uint32(data[0:4]) != 9994 {
+		return nil, fmt.Errorf("%w: encabezado .shp no reconocido", ErrShapefileInvalido)
+	}
+
+	var geometrias []MultiPoligono
+	for pos := 100; pos+8 <= len(data); {
+		largo := int(binary.BigEndian.Uint32(data[pos+4:pos+8])) * 2
+		contenido := pos + 8
+		pos = contenido + largo
+		if largo < 4 || pos > len(data) {
+			return nil, fmt.Errorf("%w: registro truncado en el .shp", ErrShapefileInvalido)
+		}
+		registro := data[contenido:pos]
+
+		switch binary.LittleEndian.Uint32(registro[0:4]) {
+		case shapeNulo:
+			geometrias = append(geometrias, nil)
+		case shapePoligono, shapePoligonoZ, shapePoligonoM:
+			geometria, err := leerPoligonoShp(registro)
+			if err != nil {
+				return nil, err
+			}
+			geometrias = append(geometrias, geometria)
+		default:
+			return nil, fmt.Errorf("%w: solo se admiten polígonos (tipo de forma %d)", ErrShapefileInvalido, binary.LittleEndian.Uint32(registro[0:4]))
+		}
+	}
+	return geometrias, nil
+}
+
+// leerPoligonoShp arma los polígonos de un registro: en Shapefile los anillos exteriores van en sentido
+// horario y los huecos en antihorario. Se invierten para seguir la regla de la mano derecha de GeoJSON.
+func leerPoligonoShp(registro []byte) (MultiPoligono, error) {
+	if len(registro) < 44 {
+		return nil, fmt.Errorf("%w: polígono truncado", ErrShapefileInvalido)
+	}
+	numPartes := int(binary.LittleEndian.Uint32(registro[36:40]))
+	numPuntos := int(binary.LittleEndian.Uint32(registro[40:44]))
+	inicioPuntos := 44 + 4*numPartes
+	if numPartes == 0 || inicioPuntos+16*numPuntos > len(registro) {
+		return nil, fmt.Errorf("%w: polígono truncado", ErrShapefileInvalido)
+	}
+
+	var exteriores, huecos []Anillo
+	for p := 0; p < numPartes; p++ {
+		desde := int(binary.LittleEndian.Uint32(registro[44+4*p:]))
+		hasta := numPuntos
+		if p+1 < numPartes {
+			hasta = int(binary.LittleEndian.Uint32(registro[44+4*(p+1):]))
+		}
+		if desde < 0 || hasta > numPuntos || desde >= hasta {
+			return nil, fmt.Errorf("%w: partes de polígono inconsistentes", ErrShapefileInvalido)
+		}
+
+		anillo := make(Anillo, 0, hasta-desde)
+		for i := hasta - 1; i >= desde; i-- {
+			offset := inicioPuntos + 16*i
+			anillo = append(anillo, [2]float64{
+				math.Float64frombits(binary.LittleEndian.Uint64(registro[offset:])),
+				math.Float64frombits(binary.LittleEndian.Uint64(registro[offset+8:])),
+			})
+		}
+
+		// Invertido, un exterior queda en sentido antihorario (área positiva)
+		if anillo.areaFirmada() >= 0 {
+			exteriores = append(exteriores, anillo)
+		} else {
+			huecos = append(huecos, anillo)
+		}
+	}
+
+	multi := make(MultiPoligono, 0, len(exteriores))
+	for _, exterior := range exteriores {
+		multi = append(multi, Poligono{exterior})
+	}
+	for _, hueco := range huecos {
+		asignado := false
+		for i := range multi {
+			if multi[i][0].contiene(hueco[0][1], hueco[0][0]) {
+				multi[i] = append(multi[i], hueco)
+				asignado = true
+				break
+			}
+		}
+		if !asignado {
+			// Orientación incorrecta en el archivo: el anillo se toma como exterior
+			for i, j := 0, len(hueco)-1; i < j; i, j = i+1, j-1 {
+				hueco[i], hueco[j] = hueco[j], hueco[i]
+			}
+			multi = append(multi, Poligono{hueco})
+		}
+	}
+
+	if err := multi.Validar(); err != nil {
+		return nil, err
+	}
+	return multi, nil
+}
+
+// leerTablaDbf lee las filas del .dbf (dBase III) como texto. Las filas eliminadas son nil. Sin .cpg, un
+// texto que no es UTF-8 válido se interpreta como Latin-1.
+func leerTablaDbf(data []byte, codificacion string) ([]map[string]string, error) {
+	if len(data) < 32 {
+		return nil, fmt.Errorf("%w: encabezado .dbf truncado", ErrShapefileInvalido)
+	}
+	numFilas := int(binary.LittleEndian.Uint32(data[4:8]))
+	largoEncabezado := int(binary.LittleEndian.Uint16(data[8:10]))
+	largoFila := int(binary.LittleEndian.Uint16(data[10:12]))
+	if largoEncabezado > len(data) || largoFila < 1 {
+		return nil, fmt.Errorf("%w: encabezado .dbf inconsistente", ErrShapefileInvalido)
+	}
+
+	type campo struct {
+		nombre string
+		inicio int
+		largo  int
+	}
+	var campos []campo
+	inicio := 1 // El primer byte de cada fila marca si está eliminada
+	for pos := 32; pos+32 <= largoEncabezado && data[pos] != 0x0D; pos += 32 {
+		nombre := string(bytes.TrimRight(data[pos:pos+11], "\x00 "))
+		largo := int(data[pos+16])
+		campos = append(campos, campo{nombre: nombre, inicio: inicio, largo: largo})
+		inicio += largo
+	}
+
+	latin1 := !strings.EqualFold(strings.ReplaceAll(codificacion, "-", ""), "UTF8")
+	filas := make([]map[string]string, 0, numFilas)
+	for f := 0; f < numFilas; f++ {
+		offset := largoEncabezado + f*largoFila
+		if offset+largoFila > len(data) {
+			return nil, fmt.Errorf("%w: tabla .dbf truncada", ErrShapefileInvalido)
+		}
+		fila := data[offset : offset+largoFila]
+		if fila[0] == '*' {
+			// Fila eliminada: se conserva como nil para no desalinear las formas y omitir la suya
+			filas = append(filas, nil)
+			continue
+		}
+
+		atributos := make(map[string]string, len(campos))
+		for _, c := range campos {
+			if c.inicio+c.largo > len(fila) {
+				break
+			}
+			valor := bytes.TrimSpace(fila[c.inicio : c.inicio+c.largo])
+			atributos[c.nombre] = decodificarTextoDbf(valor, latin1)
+		}
+		filas = append(filas, atributos)
+	}
+	return filas, nil
+}
+
+func decodificarTextoDbf(valor []byte, latin1 bool) string {
+	if !latin1 || utf8.Valid(valor) {
+		return string(valor)
+	}
+	runas := make([]rune, len(valor))
+	for i, b := range valor {
+		runas[i] = rune(b)
+	}
+	return string(runas)
+}
@@ -0,0 +1,292 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// registroShp forma de un registro del .shp: nil es un registro sin forma
+type registroShp [][][2]float64
+
+// escribirShp arma un .shp con un registro de polígono por forma, con los anillos en el orden dado
+func escribirShp(formas []registroShp, tipo uint32) []byte {
+	var registros bytes.Buffer
+	for n, forma := range formas {
+		var contenido bytes.Buffer
+		if forma == nil {
+			binary.Write(&contenido, binary.LittleEndian, uint32(shapeNulo))
+		} else {
+			numPuntos := 0
+			for _, anillo := range forma {
+				numPuntos += len(anillo)
+			}
+			binary.Write(&contenido, binary.LittleEndian, tipo)
+			binary.Write(&contenido, binary.LittleEndian, [4]float64{}) // Caja, no se lee
+			binary.Write(&contenido, binary.LittleEndian, uint32(len(forma)))
+			binary.Write(&contenido, binary.LittleEndian, uint32(numPuntos))
+			inicio := 0
+			for _, anillo := range forma {
+				binary.Write(&contenido, binary.LittleEndian, uint32(inicio))
+				inicio += len(anillo)
+			}
+			for _, anillo := range forma {
+				binary.Write(&contenido, binary.LittleEndian, anillo)
+			}
+		}
+		binary.Write(&registros, binary.BigEndian, uint32(n+1))
+		binary.Write(&registros, binary.BigEndian, uint32(contenido.Len()/2))
+		registros.Write(contenido.Bytes())
+	}
+
+	encabezado := make([]byte, 100)
+	binary.BigEndian.PutUint32(encabezado[0:], 9994)
+	binary.BigEndian.PutUint32(encabezado[24:], uint32((100+registros.Len())/2))
+	binary.LittleEndian.PutUint32(encabezado[28:], 1000)
+	binary.LittleEndian.PutUint32(encabezado[32:], tipo)
+	return append(encabezado, registros.Bytes()...)
+}
+
+// filaDbf fila del .dbf con sus valores en el orden de los campos
+type filaDbf struct {
+	eliminada bool
+	valores   []string
+}
+
+// escribirDbf arma un .dbf (dBase III) de campos de texto con los largos dados
+func escribirDbf(campos []string, largos []int, filas []filaDbf) []byte {
+	largoFila := 1
+	for _, largo := range largos {
+		largoFila += largo
+	}
+	largoEncabezado := 32 + 32*len(campos) + 1
+
+	var dbf bytes.Buffer
+	encabezado := make([]byte, 32)
+	encabezado[0] = 0x03
+	binary.LittleEndian.PutUint32(encabezado[4:], uint32(len(filas)))
+	binary.LittleEndian.PutUint16(encabezado[8:], uint16(largoEncabezado))
+	binary.LittleEndian.PutUint16(encabezado[10:], uint16(largoFila))
+	dbf.Write(encabezado)
+	for i, campo := range campos {
+		descriptor := make([]byte, 32)
+		copy(descriptor, campo)
+		descriptor[11] = 'C'
+		descriptor[16] = byte(largos[i])
+		dbf.Write(descriptor)
+	}
+	dbf.WriteByte(0x0D)
+
+	for _, fila := range filas {
+		marca := byte(' ')
+		if fila.eliminada {
+			marca = '*'
+		}
+		dbf.WriteByte(marca)
+		for i, valor := range fila.valores {
+			dbf.Write(append([]byte(valor), bytes.Repeat([]byte(" "), largos[i]-len(valor))...))
+		}
+	}
+	dbf.WriteByte(0x1A)
+	return dbf.Bytes()
+}
+
+func comprimir(t *testing.T, archivos map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	escritor := zip.NewWriter(&buf)
+	for nombre, contenido := range archivos {
+		archivo, err := escritor.Create(nombre)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := archivo.Write(contenido); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := escritor.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// Anillos en el orden de Shapefile: exterior en sentido horario y hueco en antihorario
+var (
+	exteriorHorario  = [][2]float64{{0, 0}, {0, 10}, {10, 10}, {10, 0}, {0, 0}}
+	huecoAntihorario = [][2]float64{{4, 4}, {6, 4}, {6, 6}, {4, 6}, {4, 4}}
+	otroHorario      = [][2]float64{{20, 20}, {20, 30}, {30, 30}, {30, 20}, {20, 20}}
+)
+
+func TestLeerShapefileZip(t *testing.T) {
+	campos, largos := []string{"NOMBRE", "CODIGO"}, []int{20, 4}
+	dosDistritos := escribirShp([]registroShp{{exteriorHorario}, {otroHorario}}, shapePoligono)
+	filasDosDistritos := []filaDbf{{valores: []string{"Luque", "11"}}, {valores: []string{"Limpio", "12"}}}
+
+	tests := []struct {
+		name     string
+		archivos map[string][]byte
+		want     []FeatureShapefile
+		wantErr  bool
+	}{
+		{
+			name: "exterior con hueco y orientación invertida a GeoJSON",
+			archivos: map[string][]byte{
+				"distritos.shp": escribirShp([]registroShp{{exteriorHorario, huecoAntihorario}}, shapePoligono),
+				"distritos.dbf": escribirDbf(campos, largos, []filaDbf{{valores: []string{"Luque", "11"}}}),
+			},
+			want: []FeatureShapefile{{
+				Atributos: map[string]string{"NOMBRE": "Luque", "CODIGO": "11"},
+				Geometria: MultiPoligono{{
+					{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+					{{4, 4}, {4, 6}, {6, 6}, {6, 4}, {4, 4}},
+				}},
+			}},
+		},
+		{
+			name: "dos exteriores en un registro forman un multipolígono",
+			archivos: map[string][]byte{
+				"distritos.shp": escribirShp([]registroShp{{exteriorHorario, otroHorario}}, shapePoligonoZ),
+				"distritos.dbf": escribirDbf(campos, largos, []filaDbf{{valores: []string{"Luque", "11"}}}),
+			},
+			want: []FeatureShapefile{{
+				Atributos: map[string]string{"NOMBRE": "Luque", "CODIGO": "11"},
+				Geometria: MultiPoligono{
+					{{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}},
+					{{{20, 20}, {30, 20}, {30, 30}, {20, 30}, {20, 20}}},
+				},
+			}},
+		},
+		{
+			name: "omite las filas eliminadas en el .dbf",
+			archivos: map[string][]byte{
+				"distritos.shp": dosDistritos,
+				"distritos.dbf": escribirDbf(campos, largos, []filaDbf{{eliminada: true, valores: []string{"Luque", "11"}}, {valores: []string{"Limpio", "12"}}}),
+			},
+			want: []FeatureShapefile{{
+				Atributos: map[string]string{"NOMBRE": "Limpio", "CODIGO": "12"},
+				Geometria: MultiPoligono{{{{20, 20}, {30, 20}, {30, 30}, {20, 30}, {20, 20}}}},
+			}},
+		},
+		{
+			name: "omite los registros sin forma",
+			archivos: map[string][]byte{
+				"distritos.shp": escribirShp([]registroShp{nil, {otroHorario}}, shapePoligono),
+				"distritos.dbf": escribirDbf(campos, largos, filasDosDistritos),
+			},
+			want: []FeatureShapefile{{
+				Atributos: map[string]string{"NOMBRE": "Limpio", "CODIGO": "12"},
+				Geometria: MultiPoligono{{{{20, 20}, {30, 20}, {30, 30}, {20, 30}, {20, 20}}}},
+			}},
+		},
+		{
+			name: "texto Latin-1 sin .cpg y nombres en mayúsculas dentro de una carpeta",
+			archivos: map[string][]byte{
+				"capa/DISTRITOS.SHP": escribirShp([]registroShp{{exteriorHorario}}, shapePoligono),
+				"capa/DISTRITOS.DBF": escribirDbf(campos, largos, []filaDbf{{valores: []string{"\xd1emby", "13"}}}),
+			},
+			want: []FeatureShapefile{{
+				Atributos: map[string]string{"NOMBRE": "Ñemby", "CODIGO": "13"},
+				Geometria: MultiPoligono{{{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}}},
+			}},
+		},
+		{
+			name: "texto UTF-8 con .cpg",
+			archivos: map[string][]byte{
+				"distritos.shp": escribirShp([]registroShp{{exteriorHorario}}, shapePoligono),
+				"distritos.dbf": escribirDbf(campos, largos, []filaDbf{{valores: []string{"Ñemby", "13"}}}),
+				"distritos.cpg": []byte("UTF-8\n"),
+			},
+			want: []FeatureShapefile{{
+				Atributos: map[string]string{"NOMBRE": "Ñemby", "CODIGO": "13"},
+				Geometria: MultiPoligono{{{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}}},
+			}},
+		},
+		{
+			name: "coordenadas proyectadas",
+			archivos: map[string][]byte{
+				"distritos.shp": dosDistritos,
+				"distritos.dbf": escribirDbf(campos, largos, filasDosDistritos),
+				"distritos.prj": []byte(`PROJCS["WGS 84 / UTM zone 21S",GEOGCS["WGS 84"]]`),
+			},
+			wantErr: true,
+		},
+		{
+			name:     "falta el .dbf",
+			archivos: map[string][]byte{"distritos.shp": dosDistritos},
+			wantErr:  true,
+		},
+		{
+			name:     "sin .shp",
+			archivos: map[string][]byte{"distritos.dbf": escribirDbf(campos, largos, filasDosDistritos)},
+			wantErr:  true,
+		},
+		{
+			name: "distinta cantidad de registros",
+			archivos: map[string][]byte{
+				"distritos.shp": dosDistritos,
+				"distritos.dbf": escribirDbf(campos, largos, filasDosDistritos[:1]),
+			},
+			wantErr: true,
+		},
+		{
+			name: "formas que no son polígonos",
+			archivos: map[string][]byte{
+				"distritos.shp": escribirShp([]registroShp{{exteriorHorario}}, 3),
+				"distritos.dbf": escribirDbf(campos, largos, filasDosDistritos[:1]),
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LeerShapefileZip(comprimir(t, tt.archivos))
+			if tt.wantErr {
+				if !errors.Is(err, ErrShapefileInvalido) {
+					t.Errorf("LeerShapefileZip() error = %v, want %v", err, ErrShapefileInvalido)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LeerShapefileZip() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LeerShapefileZip() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeerShapefileZipNoEsZip(t *testing.T) {
+	if _, err := LeerShapefileZip([]byte("no es un zip")); !errors.Is(err, ErrShapefileInvalido) {
+		t.Errorf("LeerShapefileZip() error = %v, want %v", err, ErrShapefileInvalido)
+	}
+}
+
+func TestLeerShapefileZipHuecoDelDistrito(t *testing.T) {
+	archivo := comprimir(t, map[string][]byte{
+		"distritos.shp": escribirShp([]registroShp{{exteriorHorario, huecoAntihorario}}, shapePoligono),
+		"distritos.dbf": escribirDbf([]string{"NOMBRE"}, []int{10}, []filaDbf{{valores: []string{"Luque"}}}),
+	})
+	features, err := LeerShapefileZip(archivo)
+	if err != nil {
+		t.Fatalf("LeerShapefileZip() error = %v", err)
+	}
+
+	geometria := features[0].Geometria
+	for _, punto := range []struct {
+		lat, lng float64
+		want     bool
+	}{{2, 2, true}, {5, 5, false}, {5, 11, false}} {
+		if got := geometria.Contiene(punto.lat, punto.lng); got != punto.want {
+			t.Errorf("Contiene(%v, %v) = %v, want %v", punto.lat, punto.lng, got, punto.want)
+		}
+	}
+	if area := geometria.AreaKm2(); math.Abs(area) < 1e-9 {
+		t.Errorf("AreaKm2() = %v, want > 0", area)
+	}
+}
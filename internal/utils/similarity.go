@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// acentos reemplaza las vocales acentuadas y la ñ por su letra base
+var acentos = strings.NewReplacer(
+	"á", "a", "à", "a", "ä", "a", "â", "a",
+	"é", "e", "è", "e", "ë", "e", "ê", "e",
+	"í", "i", "ì", "i", "ï", "i", "î", "i",
+	"ó", "o", "ò", "o", "ö", "o", "ô", "o",
+	"ú", "u", "ù", "u", "ü", "u", "û", "u",
+	"ñ", "n", "ç", "c",
+)
+
+// NormalizarTexto pasa el texto a minúsculas, quita acentos y signos, y deja las palabras separadas por un espacio
+func NormalizarTexto(s string) string {
+	s = acentos.Replace(strings.ToLower(s))
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return ' '
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// NormalizarCI deja solo los dígitos y letras (en mayúsculas) de una cédula de identidad,
+// de modo que "1234567-1B LP" y "12345671blp" coincidan
+func NormalizarCI(ci string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, ci)
+}
+
+// JaroWinkler retorna la similitud de Jaro-Winkler entre dos cadenas, entre 0 (distintas) y 1 (iguales).
+// Favorece a las cadenas que comparten prefijo, lo que la hace adecuada para comparar nombres.
+func JaroWinkler(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	ventana := max(len(ra), len(rb))/2 - 1
+	if ventana < 0 {
+		ventana = 0
+	}
+
+	coincideA := make([]bool, len(ra))
+	coincideB := make([]bool, len(rb))
+	coincidencias := 0
+	for i := range ra {
+		desde := max(0, i-ventana)
+		hasta := min(len(rb), i+ventana+1)
+		for j := desde; j < hasta; j++ {
+			if coincideB[j] || ra[i] != rb[j] {
+				continue
+			}
+			coincideA[i], coincideB[j] = true, true
+			coincidencias++
+			break
+		}
+	}
+	if coincidencias == 0 {
+		return 0
+	}
+
+	// Transposiciones: coincidencias que aparecen en distinto orden
+	transposiciones, j := 0, 0
+	for i := range ra {
+		if !coincideA[i] {
+			continue
+		}
+		for !coincideB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transposiciones++
+		}
+		j++
+	}
+
+	m := float64(coincidencias)
+	jaro := (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transposiciones)/2)/m) / 3
+
+	// Bonificación por prefijo común (hasta 4 caracteres)
+	prefijo := 0
+	for prefijo < min(4, len(ra), len(rb)) && ra[prefijo] == rb[prefijo] {
+		prefijo++
+	}
+
+	return jaro + float64(prefijo)*0.1*(1-jaro)
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+// Valores de referencia de Winkler (1990) y de la literatura de record linkage
+func TestJaroWinkler(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"MARTHA", "MARHTA", 0.961111},
+		{"DWAYNE", "DUANE", 0.840000},
+		{"DIXON", "DICKSONX", 0.813333},
+		{"JELLYFISH", "SMELLYFISH", 0.896296},
+		{"CRATE", "TRACE", 0.733333},
+		{"ABC", "XYZ", 0},
+		{"José", "José", 1},
+		{"", "", 1},
+		{"ANA", "", 0},
+		{"", "ANA", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := JaroWinkler(tt.a, tt.b); math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("JaroWinkler(%q, %q) = %.6f, want %.6f", tt.a, tt.b, got, tt.want)
+			}
+			if got, inverso := JaroWinkler(tt.a, tt.b), JaroWinkler(tt.b, tt.a); math.Abs(got-inverso) > 1e-12 {
+				t.Errorf("JaroWinkler no es simétrica: %.6f y %.6f", got, inverso)
+			}
+		})
+	}
+}
+
+func TestNormalizarTexto(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"José María", "jose maria"},
+		{"  PEÑA   Ñandú ", "pena nandu"},
+		{"San Lorenzo-Barrio Güemes", "san lorenzo barrio guemes"},
+		{"Dengue (A90)", "dengue a90"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := NormalizarTexto(tt.in); got != tt.want {
+				t.Errorf("NormalizarTexto(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizarCI(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"1234567-1B LP", "12345671BLP"},
+		{"12345671blp", "12345671BLP"},
+		{"1.234.567", "1234567"},
+		{" - ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := NormalizarCI(tt.in); got != tt.want {
+				t.Errorf("NormalizarCI(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}